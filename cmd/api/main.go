@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/config"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/db"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/health"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
+	dynamoRepo "github.com/DiegoSantos90/chargeback-lambda/internal/infra/repository"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/server"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+func main() {
+	// Cancelled on SIGINT/SIGTERM so the server drains in-flight requests
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx); err != nil {
+		log.Fatalf("Server error: %v", err)
+	}
+}
+
+func run(ctx context.Context) error {
+	// Load and validate configuration
+	cfg, err := config.Load(ctx, config.Defaults())
+	if err != nil {
+		return err
+	}
+
+	// Initialize logger, reloading its level while running if configured
+	loggerConfig := cfg.LoggerConfig()
+	if loggerConfig.LevelSource, err = cfg.LogLevelSource(ctx); err != nil {
+		return err
+	}
+
+	logger, err := logging.NewStructuredLogger(loggerConfig, nil)
+	if err != nil {
+		return err
+	}
+	// Export buffered entries and close log files once the server has stopped
+	defer logger.Close(context.Background())
+
+	logger.Debug(ctx, "Effective configuration", cfg.Redacted())
+	for _, deprecation := range cfg.Deprecations() {
+		logger.Warn(ctx, "Deprecated configuration", map[string]interface{}{"detail": deprecation})
+	}
+
+	// Initialize tracing; spans still ending at shutdown are exported before exit
+	tracerProvider, err := tracing.Setup(ctx, cfg.TracingConfig())
+	if err != nil {
+		return err
+	}
+	defer tracerProvider.Shutdown(context.Background())
+
+	// Initialize metrics; buffered values are written before exit
+	metricsProvider, err := metrics.Setup(cfg.MetricsConfig())
+	if err != nil {
+		return err
+	}
+	defer metricsProvider.Close(context.Background())
+
+	// Initialize DynamoDB client
+	dynamoClient, err := db.NewDynamoDBClient(ctx, cfg.DynamoDBClientConfig())
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize DynamoDB client", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	// Probe the table and the configured dependencies for readiness
+	healthRegistry := health.NewRegistry(cfg.HealthConfig())
+	healthRegistry.Register("dynamodb", db.NewTableProbe(dynamoClient, cfg.DynamoDB.TableName))
+
+	// Authenticate callers with tokens, API keys or authorizer claims
+	authenticator, err := cfg.Authenticator(dynamoClient)
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize authentication", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	// Verify the callbacks signed by acquirers
+	signatureVerifier, err := cfg.SignatureVerifier(dynamoClient)
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize callback signatures", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	// Authorize authenticated callers by role, scope and merchant
+	var authorizer usecase.Authorizer
+	if authenticator != nil {
+		authorizer = usecase.NewPolicy(usecase.DefaultPolicyConfig(), logger)
+	}
+
+	// Limit the request rate of callers and the daily chargebacks of merchants
+	rateLimiter, quota, err := cfg.RateLimits(dynamoClient)
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize rate limits", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	// Encrypt sensitive attributes before they reach the table
+	storedRepo, err := cfg.EncryptChargebacks(ctx, dynamoRepo.NewDynamoDBChargebackRepository(dynamoClient, cfg.DynamoDB.TableName))
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize encryption", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return err
+	}
+
+	// Initialize repository and use case, wired as in the Lambda
+	chargebackRepo := tracing.NewChargebackRepository(
+		metrics.NewChargebackRepository(
+			storedRepo,
+			metricsProvider.Metrics(),
+		),
+		cfg.DynamoDB.TableName,
+	)
+	createChargebackUC := usecase.NewCreateChargebackUseCase(chargebackRepo, metricsProvider.Metrics(), authorizer, quota)
+
+	// Serve until a shutdown signal arrives
+	serverConfig := cfg.ServerConfig()
+	serverConfig.LogLevels = logger.Levels()
+	serverConfig.API.Metrics = metricsProvider.Metrics()
+	serverConfig.API.Health = healthRegistry
+	serverConfig.Authenticator = authenticator
+	serverConfig.SignatureVerifier = signatureVerifier
+	serverConfig.RateLimiter = rateLimiter
+	serverConfig.MetricsHandler = metricsProvider.Handler()
+
+	srv := server.NewServer(serverConfig, createChargebackUC, logger)
+	return srv.Run(ctx)
+}
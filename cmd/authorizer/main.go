@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambda"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/authorizer"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/config"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/db"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+)
+
+// Global dependencies (initialized once during cold start)
+var (
+	authorizerHandler *authorizer.Handler
+	logger            *logging.StructuredLogger
+	tracerProvider    *tracing.Provider
+)
+
+func init() {
+	ctx := context.Background()
+
+	// Load and validate configuration; the authorizer always authenticates
+	defaults := config.Defaults()
+	defaults.Service.Name = "chargeback-authorizer"
+	defaults.Auth.Enabled = true
+	cfg, err := config.Load(ctx, defaults)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize logger, reloading its level while running if configured
+	loggerConfig := cfg.LoggerConfig()
+	if loggerConfig.LevelSource, err = cfg.LogLevelSource(ctx); err != nil {
+		log.Fatalf("Failed to initialize log level source: %v", err)
+	}
+
+	logger, err = logging.NewStructuredLogger(loggerConfig, nil)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+
+	logger.Debug(ctx, "Effective configuration", cfg.Redacted())
+
+	// Initialize tracing
+	tracerProvider, err = tracing.Setup(ctx, cfg.TracingConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
+	}
+
+	// Initialize the DynamoDB client reading API keys
+	dynamoClient, err := db.NewDynamoDBClient(ctx, cfg.DynamoDBClientConfig())
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize DynamoDB client", map[string]interface{}{
+			"error": err.Error(),
+		})
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	}
+
+	// Check credentials with the authenticator of the API function
+	authenticator, err := cfg.Authenticator(dynamoClient)
+	if err == nil && authenticator == nil {
+		err = errors.New("AUTH_ENABLED must not be false")
+	}
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize authentication", map[string]interface{}{
+			"error": err.Error(),
+		})
+		log.Fatalf("Failed to initialize authentication: %v", err)
+	}
+
+	authorizerHandler = authorizer.NewHandler(authenticator, logger, cfg.AuthorizerConfig())
+
+	logger.Info(ctx, "Authorizer function initialized", map[string]interface{}{
+		"api_keys_table": cfg.Auth.APIKeysTable,
+		"jwks":           cfg.Auth.JWKSURL != "" || cfg.Auth.JWKSFile != "",
+	})
+}
+
+// handler answers REST API REQUEST authorizer events
+func handler(ctx context.Context, event events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	// The environment may be frozen once the handler returns, so buffered
+	// spans and entries are exported before then
+	defer func() {
+		flushCtx := context.WithoutCancel(ctx)
+		tracerProvider.ForceFlush(flushCtx)
+		logger.Flush(flushCtx)
+	}()
+
+	return authorizerHandler.Handle(ctx, event)
+}
+
+func main() {
+	lambda.Start(handler)
+}
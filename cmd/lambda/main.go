@@ -3,204 +3,177 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"fmt"
 	"log"
-	"net/http"
-	"os"
-	"strings"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 
-	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/apigateway"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/config"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/db"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/health"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/ratelimit"
 	dynamoRepo "github.com/DiegoSantos90/chargeback-lambda/internal/infra/repository"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
 )
 
 // Global dependencies (initialized once during cold start)
 var (
-	createChargebackUC *usecase.CreateChargebackUseCase
-	logger             service.Logger
+	apiAdapter      *apigateway.Adapter
+	logger          *logging.StructuredLogger
+	tracerProvider  *tracing.Provider
+	metricsProvider *metrics.Provider
 )
 
 func init() {
 	ctx := context.Background()
 
-	// Load configuration
-	config := loadConfiguration()
+	// Load and validate configuration
+	defaults := config.Defaults()
+	defaults.Service.Name = "chargeback-lambda"
+	defaults.RateLimit.Backend = string(ratelimit.BackendDynamoDB)
+	cfg, err := config.Load(ctx, defaults)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
 
-	// Initialize logger
-	loggerConfig := logging.LoggerConfig{
-		Level:       parseLogLevel(getEnvOrDefault("LOG_LEVEL", "info")),
-		Format:      logging.FormatJSON, // Lambda always uses JSON
-		ServiceName: getEnvOrDefault("SERVICE_NAME", "chargeback-lambda"),
-		Version:     getEnvOrDefault("APP_VERSION", "1.0.0"),
+	// Initialize logger, reloading its level while running if configured
+	loggerConfig := cfg.LoggerConfig()
+	if loggerConfig.LevelSource, err = cfg.LogLevelSource(ctx); err != nil {
+		log.Fatalf("Failed to initialize log level source: %v", err)
 	}
 
-	var err error
 	logger, err = logging.NewStructuredLogger(loggerConfig, nil)
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
 
-	// Initialize DynamoDB client
-	dynamoClient, err := db.NewDynamoDBClient(ctx, config)
-	if err != nil {
-		logger.Error(ctx, "Failed to initialize DynamoDB client", map[string]interface{}{
-			"error": err.Error(),
-		})
-		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
+	logger.Debug(ctx, "Effective configuration", cfg.Redacted())
+	for _, deprecation := range cfg.Deprecations() {
+		logger.Warn(ctx, "Deprecated configuration", map[string]interface{}{"detail": deprecation})
 	}
 
-	// Initialize repository and use case
-	chargebackRepo := dynamoRepo.NewDynamoDBChargebackRepository(dynamoClient, config.TableName)
-	createChargebackUC = usecase.NewCreateChargebackUseCase(chargebackRepo)
-
-	logger.Info(ctx, "Lambda function initialized", map[string]interface{}{
-		"table_name": config.TableName,
-		"region":     config.Region,
-	})
-}
-
-func handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	logger.Info(ctx, "Received request", map[string]interface{}{
-		"method": request.HTTPMethod,
-		"path":   request.Path,
-	})
-
-	// Route based on path and method
-	switch {
-	case request.Path == "/health" && request.HTTPMethod == http.MethodGet:
-		return handleHealth(ctx)
-	case strings.HasPrefix(request.Path, "/chargebacks") && request.HTTPMethod == http.MethodPost:
-		return handleCreateChargeback(ctx, request)
-	case strings.HasPrefix(request.Path, "/api/v1/chargebacks") && request.HTTPMethod == http.MethodPost:
-		return handleCreateChargeback(ctx, request)
-	default:
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusNotFound,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-			Body:       `{"error":"Not Found","message":"Route not found"}`,
-		}, nil
+	// Initialize tracing
+	tracerProvider, err = tracing.Setup(ctx, cfg.TracingConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize tracing: %v", err)
 	}
-}
 
-func handleHealth(ctx context.Context) (events.APIGatewayProxyResponse, error) {
-	response := map[string]interface{}{
-		"status":  "healthy",
-		"service": "chargeback-lambda",
+	// Initialize metrics
+	metricsProvider, err = metrics.Setup(cfg.MetricsConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize metrics: %v", err)
 	}
 
-	body, err := json.Marshal(response)
+	// Initialize DynamoDB client
+	dynamoClient, err := db.NewDynamoDBClient(ctx, cfg.DynamoDBClientConfig())
 	if err != nil {
-		logger.Error(ctx, "Failed to marshal health response", map[string]interface{}{
+		logger.Error(ctx, "Failed to initialize DynamoDB client", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-			Body:       `{"error":"Internal Server Error"}`,
-		}, nil
+		log.Fatalf("Failed to initialize DynamoDB client: %v", err)
 	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusOK,
-		Headers:    map[string]string{"Content-Type": "application/json"},
-		Body:       string(body),
-	}, nil
-}
+	// Probe the table and the configured dependencies for readiness
+	healthRegistry := health.NewRegistry(cfg.HealthConfig())
+	healthRegistry.Register("dynamodb", db.NewTableProbe(dynamoClient, cfg.DynamoDB.TableName))
 
-func handleCreateChargeback(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// Parse request body
-	var req usecase.CreateChargebackRequest
-	if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
-		logger.Error(ctx, "Failed to parse request body", map[string]interface{}{
+	// Authenticate callers with tokens, API keys or authorizer claims
+	authenticator, err := cfg.Authenticator(dynamoClient)
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize authentication", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusBadRequest,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-			Body:       fmt.Sprintf(`{"error":"Bad Request","message":"Invalid JSON: %s"}`, err.Error()),
-		}, nil
+		log.Fatalf("Failed to initialize authentication: %v", err)
 	}
 
-	// Execute use case
-	chargeback, err := createChargebackUC.Execute(ctx, req)
+	// Verify the callbacks signed by acquirers
+	signatureVerifier, err := cfg.SignatureVerifier(dynamoClient)
 	if err != nil {
-		logger.Error(ctx, "Failed to create chargeback", map[string]interface{}{
+		logger.Error(ctx, "Failed to initialize callback signatures", map[string]interface{}{
 			"error": err.Error(),
 		})
+		log.Fatalf("Failed to initialize callback signatures: %v", err)
+	}
 
-		// Check if it's a validation error
-		if strings.Contains(err.Error(), "validation") {
-			return events.APIGatewayProxyResponse{
-				StatusCode: http.StatusBadRequest,
-				Headers:    map[string]string{"Content-Type": "application/json"},
-				Body:       fmt.Sprintf(`{"error":"Validation Error","message":"%s"}`, err.Error()),
-			}, nil
-		}
-
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-			Body:       `{"error":"Internal Server Error","message":"Failed to create chargeback"}`,
-		}, nil
+	// Authorize authenticated callers by role, scope and merchant
+	var authorizer usecase.Authorizer
+	if authenticator != nil {
+		authorizer = usecase.NewPolicy(usecase.DefaultPolicyConfig(), logger)
 	}
 
-	// Marshal response
-	body, err := json.Marshal(chargeback)
+	// Limit the request rate of callers and the daily chargebacks of merchants
+	rateLimiter, quota, err := cfg.RateLimits(dynamoClient)
 	if err != nil {
-		logger.Error(ctx, "Failed to marshal chargeback response", map[string]interface{}{
+		logger.Error(ctx, "Failed to initialize rate limits", map[string]interface{}{
 			"error": err.Error(),
 		})
-		return events.APIGatewayProxyResponse{
-			StatusCode: http.StatusInternalServerError,
-			Headers:    map[string]string{"Content-Type": "application/json"},
-			Body:       `{"error":"Internal Server Error"}`,
-		}, nil
+		log.Fatalf("Failed to initialize rate limits: %v", err)
 	}
 
-	logger.Info(ctx, "Chargeback created successfully", map[string]interface{}{
-		"chargeback_id": chargeback.ID,
-	})
+	// Encrypt sensitive attributes before they reach the table
+	storedRepo, err := cfg.EncryptChargebacks(ctx, dynamoRepo.NewDynamoDBChargebackRepository(dynamoClient, cfg.DynamoDB.TableName))
+	if err != nil {
+		logger.Error(ctx, "Failed to initialize encryption", map[string]interface{}{
+			"error": err.Error(),
+		})
+		log.Fatalf("Failed to initialize encryption: %v", err)
+	}
 
-	return events.APIGatewayProxyResponse{
-		StatusCode: http.StatusCreated,
-		Headers:    map[string]string{"Content-Type": "application/json"},
-		Body:       string(body),
-	}, nil
-}
+	// Initialize repository and use case
+	chargebackRepo := tracing.NewChargebackRepository(
+		metrics.NewChargebackRepository(
+			storedRepo,
+			metricsProvider.Metrics(),
+		),
+		cfg.DynamoDB.TableName,
+	)
+	createChargebackUC := usecase.NewCreateChargebackUseCase(chargebackRepo, metricsProvider.Metrics(), authorizer, quota)
+
+	// Initialize the API router shared with the local HTTP server
+	routerConfig := cfg.RouterConfig()
+	routerConfig.Metrics = metricsProvider.Metrics()
+	routerConfig.Health = healthRegistry
+	apiRouter := router.NewChargebackRouter(routerConfig, createChargebackUC, logger)
+	middlewareOptions := cfg.MiddlewareOptions(logger, apiRouter)
+	middlewareOptions.Metrics = routerConfig.Metrics
+	middlewareOptions.LogLevels = logger.Levels()
+	middlewareOptions.Authenticator = authenticator
+	middlewareOptions.SignatureVerifier = signatureVerifier
+	middlewareOptions.RateLimiter = rateLimiter
+	chain := middleware.Standard(middlewareOptions)
+	apiAdapter = apigateway.NewAdapter(chain.Then(apiRouter))
 
-func loadConfiguration() db.DynamoDBConfig {
-	return db.DynamoDBConfig{
-		Endpoint:  getEnvOrDefault("DYNAMODB_ENDPOINT", ""),
-		Region:    getEnvOrDefault("AWS_REGION", "us-east-1"),
-		TableName: getEnvOrDefault("DYNAMODB_TABLE", "chargebacks-lambda"),
-	}
+	logger.Info(ctx, "Lambda function initialized", map[string]interface{}{
+		"table_name": cfg.DynamoDB.TableName,
+		"region":     cfg.DynamoDB.Region,
+	})
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// handler accepts REST API (v1), HTTP API (v2) and Function URL events
+func handler(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	// The environment may be frozen once the handler returns, so buffered
+	// spans, metrics and entries are exported before then
+	defer func() {
+		flushCtx := context.WithoutCancel(ctx)
+		tracerProvider.ForceFlush(flushCtx)
+		metricsProvider.Flush(flushCtx)
+		logger.Flush(flushCtx)
+	}()
+
+	response, err := apiAdapter.Handle(ctx, payload)
+	if err != nil {
+		logger.Error(ctx, "Failed to handle event", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return nil, err
 	}
-	return defaultValue
-}
 
-func parseLogLevel(level string) service.LogLevel {
-	switch strings.ToLower(level) {
-	case "debug":
-		return service.LogLevelDebug
-	case "info":
-		return service.LogLevelInfo
-	case "warn", "warning":
-		return service.LogLevelWarn
-	case "error":
-		return service.LogLevelError
-	default:
-		return service.LogLevelInfo
-	}
+	return response, nil
 }
 
 func main() {
@@ -0,0 +1,271 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// instrumentationName identifies the spans created by the adapter
+	instrumentationName = "github.com/DiegoSantos90/chargeback-lambda/internal/api/apigateway"
+
+	// traceHeader carries the X-Ray trace header of an invocation
+	traceHeader = "X-Amzn-Trace-Id"
+
+	// lambdaTraceHeaderKey is the context key of the trace header set by the
+	// aws-lambda-go runtime
+	lambdaTraceHeaderKey = "x-amzn-trace-id"
+)
+
+// PayloadFormat identifies the shape of an incoming Lambda HTTP event
+type PayloadFormat int
+
+const (
+	// FormatUnknown is returned when the payload is not an HTTP event
+	FormatUnknown PayloadFormat = iota
+
+	// FormatRESTV1 is the API Gateway REST API (payload format 1.0) event
+	FormatRESTV1
+
+	// FormatHTTPV2 is the API Gateway HTTP API (payload format 2.0) event
+	FormatHTTPV2
+
+	// FormatFunctionURL is the Lambda Function URL event
+	FormatFunctionURL
+)
+
+// String returns the string representation of the payload format
+func (f PayloadFormat) String() string {
+	switch f {
+	case FormatRESTV1:
+		return "rest_v1"
+	case FormatHTTPV2:
+		return "http_v2"
+	case FormatFunctionURL:
+		return "function_url"
+	default:
+		return "unknown"
+	}
+}
+
+// Adapter translates API Gateway events into http.Request values and serves
+// them with a standard http.Handler, so the Lambda function and the local HTTP
+// server share the same router
+type Adapter struct {
+	handler http.Handler
+	tracer  trace.Tracer
+
+	// invoked is set after the first invocation, to mark cold starts
+	invoked atomic.Bool
+}
+
+// NewAdapter creates a new adapter serving events with the given handler
+func NewAdapter(handler http.Handler) *Adapter {
+	return &Adapter{
+		handler: handler,
+		tracer:  otel.Tracer(instrumentationName),
+	}
+}
+
+// payloadProbe holds the fields needed to tell the event formats apart
+type payloadProbe struct {
+	Version        string `json:"version"`
+	HTTPMethod     string `json:"httpMethod"`
+	RequestContext struct {
+		DomainName string `json:"domainName"`
+		HTTP       struct {
+			Method string `json:"method"`
+		} `json:"http"`
+	} `json:"requestContext"`
+}
+
+// DetectFormat inspects a raw Lambda payload and reports which HTTP event
+// format it uses
+func DetectFormat(payload []byte) (PayloadFormat, error) {
+	var probe payloadProbe
+	if err := json.Unmarshal(payload, &probe); err != nil {
+		return FormatUnknown, fmt.Errorf("failed to decode event payload: %w", err)
+	}
+
+	switch {
+	case probe.Version == "2.0" && strings.Contains(probe.RequestContext.DomainName, ".lambda-url."):
+		return FormatFunctionURL, nil
+	case probe.Version == "2.0" && probe.RequestContext.HTTP.Method != "":
+		return FormatHTTPV2, nil
+	case probe.HTTPMethod != "":
+		return FormatRESTV1, nil
+	default:
+		return FormatUnknown, fmt.Errorf("unsupported event payload format")
+	}
+}
+
+// Handle serves a raw Lambda payload in any of the supported HTTP event
+// formats and returns the matching response type
+func (a *Adapter) Handle(ctx context.Context, payload json.RawMessage) (interface{}, error) {
+	format, err := DetectFormat(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatFunctionURL:
+		var event events.LambdaFunctionURLRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode function URL event: %w", err)
+		}
+		return a.ProxyFunctionURL(ctx, event)
+	case FormatHTTPV2:
+		var event events.APIGatewayV2HTTPRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode HTTP API event: %w", err)
+		}
+		return a.ProxyV2(ctx, event)
+	default:
+		var event events.APIGatewayProxyRequest
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return nil, fmt.Errorf("failed to decode REST API event: %w", err)
+		}
+		return a.ProxyV1(ctx, event)
+	}
+}
+
+// serve runs the request through the handler within the span of the
+// invocation and returns the buffered response
+func (a *Adapter) serve(req *http.Request) *responseRecorder {
+	ctx, span := a.startSpan(req)
+	defer span.End()
+
+	recorder := newResponseRecorder()
+	a.handler.ServeHTTP(recorder, req.WithContext(ctx))
+
+	span.SetAttributes(attribute.Int("http.response.status_code", recorder.statusCode))
+	if recorder.statusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(recorder.statusCode))
+	}
+	return recorder
+}
+
+// startSpan opens the handler span of an invocation. It continues the trace
+// of the W3C traceparent or X-Ray header of the event, and the trace header
+// of the Lambda runtime takes precedence so the span nests under the
+// invocation segment.
+func (a *Adapter) startSpan(req *http.Request) (context.Context, trace.Span) {
+	ctx := req.Context()
+	propagator := otel.GetTextMapPropagator()
+
+	ctx = propagator.Extract(ctx, propagation.HeaderCarrier(req.Header))
+	if header, ok := ctx.Value(lambdaTraceHeaderKey).(string); ok && header != "" {
+		ctx = propagator.Extract(ctx, propagation.MapCarrier{traceHeader: header})
+	}
+
+	name := lambdacontext.FunctionName
+	if name == "" {
+		name = "lambda.handler"
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("cloud.provider", "aws"),
+		attribute.String("faas.trigger", "http"),
+		attribute.Bool("faas.coldstart", !a.invoked.Swap(true)),
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.path", req.URL.Path),
+	}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		attrs = append(attrs, attribute.String("faas.invocation_id", lc.AwsRequestID))
+	}
+
+	return a.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// malformedRequestBody is returned when an event cannot be turned into a request
+const malformedRequestBody = `{"error":"Bad Request","message":"Malformed request"}`
+
+// decodeBody returns the raw request body, decoding base64 payloads
+func decodeBody(body string, isBase64Encoded bool) ([]byte, error) {
+	if !isBase64Encoded {
+		return []byte(body), nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode base64 body: %w", err)
+	}
+
+	return decoded, nil
+}
+
+// responseRecorder is an http.ResponseWriter that buffers the response so it
+// can be converted into an API Gateway response
+type responseRecorder struct {
+	header      http.Header
+	body        bytes.Buffer
+	statusCode  int
+	wroteHeader bool
+}
+
+func newResponseRecorder() *responseRecorder {
+	return &responseRecorder{
+		header:     make(http.Header),
+		statusCode: http.StatusOK,
+	}
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if !r.wroteHeader {
+		r.WriteHeader(http.StatusOK)
+	}
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.wroteHeader {
+		return
+	}
+	r.statusCode = code
+	r.wroteHeader = true
+}
+
+// singleValueHeaders flattens the response headers into a single-value map,
+// leaving out Set-Cookie when cookies are returned separately
+func (r *responseRecorder) singleValueHeaders(excludeCookies bool) map[string]string {
+	headers := make(map[string]string, len(r.header))
+	for key, values := range r.header {
+		if excludeCookies && key == "Set-Cookie" {
+			continue
+		}
+		headers[key] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+// cookies returns the Set-Cookie header values of the response
+func (r *responseRecorder) cookies() []string {
+	return r.header.Values("Set-Cookie")
+}
+
+// encodedBody returns the response body, base64 encoding non UTF-8 payloads
+func (r *responseRecorder) encodedBody() (string, bool) {
+	if utf8.Valid(r.body.Bytes()) {
+		return r.body.String(), false
+	}
+	return base64.StdEncoding.EncodeToString(r.body.Bytes()), true
+}
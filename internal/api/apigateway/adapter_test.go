@@ -0,0 +1,437 @@
+package apigateway
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+func TestAdapter_ProxyV1_TranslatesRequest(t *testing.T) {
+	// Arrange
+	var captured *http.Request
+	var capturedBody string
+	adapter := NewAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r
+		body, _ := io.ReadAll(r.Body)
+		capturedBody = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodPost,
+		Path:       "/chargebacks",
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		MultiValueHeaders: map[string][]string{
+			"Accept": {"application/json", "text/plain"},
+		},
+		QueryStringParameters: map[string]string{"page": "2"},
+		Body:                  `{"transaction_id":"txn-1"}`,
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Identity: events.APIGatewayRequestIdentity{SourceIP: "10.0.0.1"},
+		},
+	}
+
+	// Act
+	response, err := adapter.ProxyV1(context.Background(), event)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	if response.Body != `{"ok":true}` {
+		t.Errorf("Expected body '{\"ok\":true}', got '%s'", response.Body)
+	}
+
+	if response.Headers["Content-Type"] != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", response.Headers["Content-Type"])
+	}
+
+	if captured.Method != http.MethodPost || captured.URL.Path != "/chargebacks" {
+		t.Errorf("Expected POST /chargebacks, got %s %s", captured.Method, captured.URL.Path)
+	}
+
+	if captured.URL.Query().Get("page") != "2" {
+		t.Errorf("Expected query page=2, got '%s'", captured.URL.Query().Get("page"))
+	}
+
+	if values := captured.Header.Values("Accept"); len(values) != 2 {
+		t.Errorf("Expected 2 Accept header values, got %v", values)
+	}
+
+	if captured.RemoteAddr != "10.0.0.1" {
+		t.Errorf("Expected remote addr '10.0.0.1', got '%s'", captured.RemoteAddr)
+	}
+
+	if capturedBody != `{"transaction_id":"txn-1"}` {
+		t.Errorf("Unexpected body: %s", capturedBody)
+	}
+}
+
+func TestAdapter_ProxyV1_Base64Body(t *testing.T) {
+	// Arrange
+	var capturedBody string
+	adapter := NewAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		capturedBody = string(body)
+	}))
+
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:      http.MethodPost,
+		Path:            "/chargebacks",
+		Body:            base64.StdEncoding.EncodeToString([]byte(`{"a":1}`)),
+		IsBase64Encoded: true,
+	}
+
+	// Act
+	response, _ := adapter.ProxyV1(context.Background(), event)
+
+	// Assert
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	if capturedBody != `{"a":1}` {
+		t.Errorf("Expected decoded body '{\"a\":1}', got '%s'", capturedBody)
+	}
+}
+
+func TestAdapter_ProxyV1_InvalidBase64(t *testing.T) {
+	// Arrange
+	adapter := NewAdapter(http.NotFoundHandler())
+
+	event := events.APIGatewayProxyRequest{
+		HTTPMethod:      http.MethodPost,
+		Path:            "/chargebacks",
+		Body:            "not base64!",
+		IsBase64Encoded: true,
+	}
+
+	// Act
+	response, _ := adapter.ProxyV1(context.Background(), event)
+
+	// Assert
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestDetectFormat_EventFixtures(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected PayloadFormat
+	}{
+		{file: "create-chargeback.json", expected: FormatRESTV1},
+		{file: "health-check.json", expected: FormatRESTV1},
+		{file: "create-chargeback-http-api.json", expected: FormatHTTPV2},
+		{file: "create-chargeback-function-url.json", expected: FormatFunctionURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			payload, err := os.ReadFile(filepath.Join("..", "..", "..", "events", tt.file))
+			if err != nil {
+				t.Fatalf("Failed to read fixture: %v", err)
+			}
+
+			format, err := DetectFormat(payload)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if format != tt.expected {
+				t.Errorf("Expected format %s, got %s", tt.expected, format)
+			}
+		})
+	}
+}
+
+func TestDetectFormat_Unsupported(t *testing.T) {
+	if _, err := DetectFormat([]byte(`{"Records":[]}`)); err == nil {
+		t.Error("Expected error for non-HTTP event")
+	}
+
+	if _, err := DetectFormat([]byte(`not json`)); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+// echoHandler records the incoming request and sets a cookie on the response
+func echoHandler(captured **http.Request, capturedBody *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*captured = r
+		body, _ := io.ReadAll(r.Body)
+		*capturedBody = string(body)
+		http.SetCookie(w, &http.Cookie{Name: "seen", Value: "1"})
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"ok":true}`))
+	})
+}
+
+func TestAdapter_Handle_HTTPV2(t *testing.T) {
+	// Arrange
+	var captured *http.Request
+	var capturedBody string
+	adapter := NewAdapter(echoHandler(&captured, &capturedBody))
+
+	payload, err := os.ReadFile(filepath.Join("..", "..", "..", "events", "create-chargeback-http-api.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	// Act
+	result, err := adapter.Handle(context.Background(), payload)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	response, ok := result.(events.APIGatewayV2HTTPResponse)
+	if !ok {
+		t.Fatalf("Expected APIGatewayV2HTTPResponse, got %T", result)
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	if len(response.Cookies) != 1 || response.Cookies[0] != "seen=1" {
+		t.Errorf("Expected cookies [seen=1], got %v", response.Cookies)
+	}
+
+	if _, ok := response.Headers["Set-Cookie"]; ok {
+		t.Error("Set-Cookie should be returned in Cookies, not Headers")
+	}
+
+	if captured.Method != http.MethodPost || captured.URL.Path != "/chargebacks" {
+		t.Errorf("Expected POST /chargebacks, got %s %s", captured.Method, captured.URL.Path)
+	}
+
+	if cookie, err := captured.Cookie("theme"); err != nil || cookie.Value != "dark" {
+		t.Errorf("Expected theme cookie 'dark', got %v (err: %v)", cookie, err)
+	}
+
+	if captured.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", captured.Header.Get("Content-Type"))
+	}
+
+	if !strings.Contains(capturedBody, "txn_sam_test_002") {
+		t.Errorf("Unexpected body: %s", capturedBody)
+	}
+
+	if id := requestctx.RequestID(captured.Context()); id != "sam-http-api-request-id" {
+		t.Errorf("Expected the event request ID in the request context, got %q", id)
+	}
+}
+
+func TestAdapter_Handle_FunctionURL(t *testing.T) {
+	// Arrange
+	var captured *http.Request
+	var capturedBody string
+	adapter := NewAdapter(echoHandler(&captured, &capturedBody))
+
+	payload, err := os.ReadFile(filepath.Join("..", "..", "..", "events", "create-chargeback-function-url.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	// Act
+	result, err := adapter.Handle(context.Background(), payload)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	response, ok := result.(events.LambdaFunctionURLResponse)
+	if !ok {
+		t.Fatalf("Expected LambdaFunctionURLResponse, got %T", result)
+	}
+
+	if response.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	if len(response.Cookies) != 1 {
+		t.Errorf("Expected 1 cookie, got %v", response.Cookies)
+	}
+
+	if cookie, err := captured.Cookie("session"); err != nil || cookie.Value != "abc123" {
+		t.Errorf("Expected session cookie 'abc123', got %v (err: %v)", cookie, err)
+	}
+
+	// Function URL fixture carries a base64 encoded body
+	if !strings.Contains(capturedBody, "txn_sam_test_003") {
+		t.Errorf("Expected decoded body, got: %s", capturedBody)
+	}
+}
+
+func TestAdapter_ProxyV2_EncodedPath(t *testing.T) {
+	// Arrange
+	var captured *http.Request
+	adapter := NewAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r
+	}))
+
+	event := events.APIGatewayV2HTTPRequest{
+		RawPath:        "/chargebacks/txn%2F42%20a",
+		RawQueryString: "q=a%26b",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet, Path: "/chargebacks/txn/42 a"},
+		},
+	}
+
+	// Act
+	response, err := adapter.ProxyV2(context.Background(), event)
+
+	// Assert
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("ProxyV2() = %d, %v", response.StatusCode, err)
+	}
+	if captured.URL.Path != "/chargebacks/txn/42 a" {
+		t.Errorf("Expected the decoded path, got %q", captured.URL.Path)
+	}
+	if captured.URL.EscapedPath() != "/chargebacks/txn%2F42%20a" {
+		t.Errorf("Expected the encoded path to be kept, got %q", captured.URL.EscapedPath())
+	}
+	if captured.URL.Query().Get("q") != "a&b" {
+		t.Errorf("Expected query q 'a&b', got %q", captured.URL.Query().Get("q"))
+	}
+}
+
+func TestAdapter_ProxyV2_InvalidPath(t *testing.T) {
+	adapter := NewAdapter(http.NotFoundHandler())
+
+	response, _ := adapter.ProxyV2(context.Background(), events.APIGatewayV2HTTPRequest{
+		RawPath: "/chargebacks/%zz",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, response.StatusCode)
+	}
+}
+
+func TestAdapter_Handle_RESTV1(t *testing.T) {
+	// Arrange
+	var captured *http.Request
+	var capturedBody string
+	adapter := NewAdapter(echoHandler(&captured, &capturedBody))
+
+	payload, err := os.ReadFile(filepath.Join("..", "..", "..", "events", "create-chargeback.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	// Act
+	result, err := adapter.Handle(context.Background(), payload)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	response, ok := result.(events.APIGatewayProxyResponse)
+	if !ok {
+		t.Fatalf("Expected APIGatewayProxyResponse, got %T", result)
+	}
+
+	if values := response.MultiValueHeaders["Set-Cookie"]; len(values) != 1 {
+		t.Errorf("Expected Set-Cookie in multi-value headers, got %v", values)
+	}
+
+	if !strings.Contains(capturedBody, "txn_sam_test_001") {
+		t.Errorf("Unexpected body: %s", capturedBody)
+	}
+}
+
+func TestAdapter_Handle_Tracing(t *testing.T) {
+	// Arrange
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	spans := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, xray.Propagator{}))
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	var captured *http.Request
+	var capturedBody string
+	adapter := NewAdapter(echoHandler(&captured, &capturedBody))
+
+	payload, err := os.ReadFile(filepath.Join("..", "..", "..", "events", "create-chargeback-http-api.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "invocation-1"})
+	ctx = context.WithValue(ctx, lambdaTraceHeaderKey, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1")
+
+	// Act
+	for range 2 {
+		if _, err := adapter.Handle(ctx, payload); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// Assert
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(ended))
+	}
+
+	for i, span := range ended {
+		if got := span.SpanContext().TraceID().String(); got != "5759e988bd862e3fe1be46a994272793" {
+			t.Errorf("Expected the trace of the invocation, got %s", got)
+		}
+		if got := span.Parent().SpanID().String(); got != "53995c3f42cd8ad8" {
+			t.Errorf("Expected the invocation segment as parent, got %s", got)
+		}
+
+		attrs := make(map[attribute.Key]attribute.Value)
+		for _, attr := range span.Attributes() {
+			attrs[attr.Key] = attr.Value
+		}
+		if got := attrs["faas.coldstart"].AsBool(); got != (i == 0) {
+			t.Errorf("Invocation %d: expected faas.coldstart %v, got %v", i, i == 0, got)
+		}
+		if got := attrs["faas.invocation_id"].AsString(); got != "invocation-1" {
+			t.Errorf("Expected faas.invocation_id 'invocation-1', got '%s'", got)
+		}
+		if got := attrs["http.response.status_code"].AsInt64(); got != http.StatusCreated {
+			t.Errorf("Expected status code %d, got %d", http.StatusCreated, got)
+		}
+	}
+
+	if !trace.SpanContextFromContext(captured.Context()).Equal(ended[1].SpanContext()) {
+		t.Error("Expected the handler to run within the invocation span")
+	}
+}
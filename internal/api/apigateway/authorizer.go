@@ -0,0 +1,75 @@
+package apigateway
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// authorizerClaimsV1 flattens the authorizer of a REST API event: the
+// principalId and context of a Lambda authorizer, or the "claims" of a
+// Cognito authorizer
+func authorizerClaimsV1(authorizer map[string]interface{}) map[string]string {
+	if len(authorizer) == 0 {
+		return nil
+	}
+
+	claims := make(map[string]string, len(authorizer))
+	for key, value := range authorizer {
+		if nested, ok := value.(map[string]interface{}); ok && key == "claims" {
+			for name, claim := range nested {
+				claims[name] = claimString(claim)
+			}
+			continue
+		}
+		claims[key] = claimString(value)
+	}
+	return claims
+}
+
+// authorizerClaimsV2 flattens the authorizer of an HTTP API event: the claims
+// and scopes of a JWT authorizer, or the context of a Lambda authorizer. IAM
+// authorization identifies AWS callers, not API principals, and is ignored.
+func authorizerClaimsV2(authorizer *events.APIGatewayV2HTTPRequestContextAuthorizerDescription) map[string]string {
+	if authorizer == nil {
+		return nil
+	}
+
+	claims := make(map[string]string)
+	if authorizer.JWT != nil {
+		for name, claim := range authorizer.JWT.Claims {
+			claims[name] = claim
+		}
+		if _, ok := claims["scope"]; !ok && len(authorizer.JWT.Scopes) > 0 {
+			claims["scope"] = strings.Join(authorizer.JWT.Scopes, " ")
+		}
+	}
+	for key, value := range authorizer.Lambda {
+		claims[key] = claimString(value)
+	}
+
+	if len(claims) == 0 {
+		return nil
+	}
+	return claims
+}
+
+// claimString formats an authorizer value. Lists are space-separated, as API
+// Gateway does when it flattens JWT claims.
+func claimString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = claimString(item)
+		}
+		return strings.Join(parts, " ")
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,153 @@
+package apigateway
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+func TestAuthorizerClaimsV1(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorizer map[string]interface{}
+		want       map[string]string
+	}{
+		{name: "no authorizer", authorizer: nil, want: nil},
+		{
+			name: "Lambda authorizer",
+			authorizer: map[string]interface{}{
+				"principalId":        "key-1",
+				"merchant_id":        "merchant-456",
+				"roles":              "merchant",
+				"integrationLatency": float64(12),
+			},
+			want: map[string]string{"principalId": "key-1", "merchant_id": "merchant-456", "roles": "merchant", "integrationLatency": "12"},
+		},
+		{
+			name: "Cognito authorizer",
+			authorizer: map[string]interface{}{
+				"claims": map[string]interface{}{
+					"sub":            "user-123",
+					"cognito:groups": []interface{}{"analyst", "admin"},
+				},
+			},
+			want: map[string]string{"sub": "user-123", "cognito:groups": "analyst admin"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := authorizerClaimsV1(tt.authorizer)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("Expected %s=%q, got %q", name, value, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestAuthorizerClaimsV2(t *testing.T) {
+	tests := []struct {
+		name       string
+		authorizer *events.APIGatewayV2HTTPRequestContextAuthorizerDescription
+		want       map[string]string
+	}{
+		{name: "no authorizer", authorizer: nil, want: nil},
+		{
+			name: "JWT authorizer",
+			authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{
+					Claims: map[string]string{"sub": "user-123", "roles": "[analyst admin]"},
+					Scopes: []string{"chargebacks:read", "chargebacks:write"},
+				},
+			},
+			want: map[string]string{"sub": "user-123", "roles": "[analyst admin]", "scope": "chargebacks:read chargebacks:write"},
+		},
+		{
+			name: "Lambda authorizer",
+			authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				Lambda: map[string]interface{}{"principal_id": "key-1", "merchant_id": "merchant-456"},
+			},
+			want: map[string]string{"principal_id": "key-1", "merchant_id": "merchant-456"},
+		},
+		{
+			name: "IAM authorizer",
+			authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				IAM: &events.APIGatewayV2HTTPRequestContextAuthorizerIAMDescription{UserARN: "arn:aws:iam::123456789012:user/ops"},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := authorizerClaimsV2(tt.authorizer)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for name, value := range tt.want {
+				if got[name] != value {
+					t.Errorf("Expected %s=%q, got %q", name, value, got[name])
+				}
+			}
+		})
+	}
+}
+
+func TestAdapter_AuthorizerClaimsInContext(t *testing.T) {
+	// Arrange
+	var captured *http.Request
+	adapter := NewAdapter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r
+	}))
+	ctx := context.Background()
+
+	// Act & Assert: REST API
+	_, err := adapter.ProxyV1(ctx, events.APIGatewayProxyRequest{
+		HTTPMethod: http.MethodGet,
+		Path:       "/chargebacks",
+		RequestContext: events.APIGatewayProxyRequestContext{
+			Authorizer: map[string]interface{}{"principalId": "key-1", "merchant_id": "merchant-456"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProxyV1() error = %v", err)
+	}
+	if claims := requestctx.AuthorizerClaims(captured.Context()); claims["principalId"] != "key-1" || claims["merchant_id"] != "merchant-456" {
+		t.Errorf("Expected the REST authorizer claims, got %v", claims)
+	}
+
+	// HTTP API
+	_, err = adapter.ProxyV2(ctx, events.APIGatewayV2HTTPRequest{
+		RawPath: "/chargebacks",
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet, Path: "/chargebacks"},
+			Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+				JWT: &events.APIGatewayV2HTTPRequestContextAuthorizerJWTDescription{Claims: map[string]string{"sub": "user-123"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ProxyV2() error = %v", err)
+	}
+	if claims := requestctx.AuthorizerClaims(captured.Context()); claims["sub"] != "user-123" {
+		t.Errorf("Expected the JWT authorizer claims, got %v", claims)
+	}
+
+	// Requests without an authorizer carry no claims
+	_, err = adapter.ProxyV1(ctx, events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/chargebacks"})
+	if err != nil {
+		t.Fatalf("ProxyV1() error = %v", err)
+	}
+	if claims := requestctx.AuthorizerClaims(captured.Context()); claims != nil {
+		t.Errorf("Expected no claims, got %v", claims)
+	}
+}
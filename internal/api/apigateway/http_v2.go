@@ -0,0 +1,141 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// ProxyV2 serves an API Gateway HTTP API (payload format 2.0) event
+func (a *Adapter) ProxyV2(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
+	req, err := newRequestV2(ctx, v2Request{
+		method:           event.RequestContext.HTTP.Method,
+		rawPath:          event.RawPath,
+		rawQueryString:   event.RawQueryString,
+		cookies:          event.Cookies,
+		headers:          event.Headers,
+		body:             event.Body,
+		isBase64Encoded:  event.IsBase64Encoded,
+		sourceIP:         event.RequestContext.HTTP.SourceIP,
+		requestID:        event.RequestContext.RequestID,
+		authorizerClaims: authorizerClaimsV2(event.RequestContext.Authorizer),
+	})
+	if err != nil {
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       malformedRequestBody,
+		}, nil
+	}
+
+	recorder := a.serve(req)
+
+	body, isBase64 := recorder.encodedBody()
+	return events.APIGatewayV2HTTPResponse{
+		StatusCode:      recorder.statusCode,
+		Headers:         recorder.singleValueHeaders(true),
+		Body:            body,
+		IsBase64Encoded: isBase64,
+		Cookies:         recorder.cookies(),
+	}, nil
+}
+
+// ProxyFunctionURL serves a Lambda Function URL event
+func (a *Adapter) ProxyFunctionURL(ctx context.Context, event events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	req, err := newRequestV2(ctx, v2Request{
+		method:          event.RequestContext.HTTP.Method,
+		rawPath:         event.RawPath,
+		rawQueryString:  event.RawQueryString,
+		cookies:         event.Cookies,
+		headers:         event.Headers,
+		body:            event.Body,
+		isBase64Encoded: event.IsBase64Encoded,
+		sourceIP:        event.RequestContext.HTTP.SourceIP,
+		requestID:       event.RequestContext.RequestID,
+	})
+	if err != nil {
+		return events.LambdaFunctionURLResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       malformedRequestBody,
+		}, nil
+	}
+
+	recorder := a.serve(req)
+
+	body, isBase64 := recorder.encodedBody()
+	return events.LambdaFunctionURLResponse{
+		StatusCode:      recorder.statusCode,
+		Headers:         recorder.singleValueHeaders(true),
+		Body:            body,
+		IsBase64Encoded: isBase64,
+		Cookies:         recorder.cookies(),
+	}, nil
+}
+
+// v2Request holds the fields shared by the HTTP API and Function URL events
+type v2Request struct {
+	method          string
+	rawPath         string
+	rawQueryString  string
+	cookies         []string
+	headers         map[string]string
+	body            string
+	isBase64Encoded bool
+	sourceIP        string
+	requestID       string
+
+	// authorizerClaims were established by an API Gateway authorizer
+	authorizerClaims map[string]string
+}
+
+// newRequestV2 builds an http.Request from a payload format 2.0 event
+func newRequestV2(ctx context.Context, event v2Request) (*http.Request, error) {
+	body, err := decodeBody(event.body, event.isBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	if event.requestID != "" {
+		ctx = requestctx.WithRequestID(ctx, event.requestID)
+	}
+	if event.authorizerClaims != nil {
+		ctx = requestctx.WithAuthorizerClaims(ctx, event.authorizerClaims)
+	}
+
+	// rawPath arrives percent-encoded; keeping it as RawPath preserves
+	// encoded slashes instead of encoding the escapes a second time
+	path, err := url.PathUnescape(event.rawPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request path: %w", err)
+	}
+	target := &url.URL{Path: path, RawPath: event.rawPath, RawQuery: event.rawQueryString}
+	req, err := http.NewRequestWithContext(ctx, event.method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	// Payload format 2.0 already joins repeated headers with commas, which is
+	// equivalent to a multi-value header for list-based fields
+	for key, value := range event.headers {
+		req.Header.Set(key, value)
+	}
+
+	// Cookies are sent apart from the headers in payload format 2.0
+	if len(event.cookies) > 0 {
+		req.Header.Set("Cookie", strings.Join(event.cookies, "; "))
+	}
+
+	req.Host = req.Header.Get("Host")
+	req.RemoteAddr = event.sourceIP
+	req.ContentLength = int64(len(body))
+
+	return req, nil
+}
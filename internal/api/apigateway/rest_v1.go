@@ -0,0 +1,84 @@
+package apigateway
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// ProxyV1 serves an API Gateway REST API (payload format 1.0) event
+func (a *Adapter) ProxyV1(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	req, err := newRequestV1(ctx, event)
+	if err != nil {
+		return events.APIGatewayProxyResponse{
+			StatusCode: http.StatusBadRequest,
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       malformedRequestBody,
+		}, nil
+	}
+
+	recorder := a.serve(req)
+
+	body, isBase64 := recorder.encodedBody()
+	return events.APIGatewayProxyResponse{
+		StatusCode:        recorder.statusCode,
+		Headers:           recorder.singleValueHeaders(false),
+		MultiValueHeaders: recorder.header.Clone(),
+		Body:              body,
+		IsBase64Encoded:   isBase64,
+	}, nil
+}
+
+// newRequestV1 builds an http.Request from a REST API proxy event
+func newRequestV1(ctx context.Context, event events.APIGatewayProxyRequest) (*http.Request, error) {
+	body, err := decodeBody(event.Body, event.IsBase64Encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	for key, values := range event.MultiValueQueryStringParameters {
+		query[key] = append(query[key], values...)
+	}
+	for key, value := range event.QueryStringParameters {
+		if _, ok := query[key]; !ok {
+			query.Set(key, value)
+		}
+	}
+
+	if event.RequestContext.RequestID != "" {
+		ctx = requestctx.WithRequestID(ctx, event.RequestContext.RequestID)
+	}
+	if claims := authorizerClaimsV1(event.RequestContext.Authorizer); claims != nil {
+		ctx = requestctx.WithAuthorizerClaims(ctx, claims)
+	}
+
+	target := &url.URL{Path: event.Path, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, event.HTTPMethod, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	for key, values := range event.MultiValueHeaders {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	for key, value := range event.Headers {
+		if req.Header.Get(key) == "" {
+			req.Header.Set(key, value)
+		}
+	}
+
+	req.Host = req.Header.Get("Host")
+	req.RemoteAddr = event.RequestContext.Identity.SourceIP
+	req.ContentLength = int64(len(body))
+
+	return req, nil
+}
@@ -0,0 +1,214 @@
+// Package authorizer implements an API Gateway REST API custom authorizer of
+// the REQUEST type. It checks bearer tokens and API keys with the
+// authenticator of the API itself, so bad credentials are rejected before the
+// API function is invoked, and passes the caller to that function in the
+// authorizer context.
+package authorizer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+const (
+	// DefaultCacheTTL is how long the caller of the same credentials is
+	// reused, never past the expiry of the credentials. It bounds how long a
+	// revoked key is still accepted.
+	DefaultCacheTTL = time.Minute
+
+	// maxCachedCallers bounds the cache, which also remembers rejected
+	// credentials
+	maxCachedCallers = 10000
+)
+
+// ErrUnauthorized makes API Gateway answer 401. The message is matched by
+// API Gateway and must not change.
+var ErrUnauthorized = errors.New("Unauthorized")
+
+// Config configures the authorizer
+type Config struct {
+	// CacheTTL is how long the caller of the same credentials is reused,
+	// DefaultCacheTTL if zero, and never past their expiry. Negative
+	// disables the cache.
+	CacheTTL time.Duration
+
+	// MerchantKey, RolesKey and ScopesKey name the context entries describing
+	// the caller. They must match the claim names read by the API function.
+	MerchantKey string
+	RolesKey    string
+	ScopesKey   string
+}
+
+// Handler answers authorizer events with an IAM policy allowing the caller
+// to invoke the API
+type Handler struct {
+	authenticator service.Authenticator
+	logger        service.Logger
+	config        Config
+	now           func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedCaller
+}
+
+// cachedCaller is the principal of credentials, or why they were rejected
+type cachedCaller struct {
+	principal *entity.Principal
+	err       error
+	expires   time.Time
+}
+
+// NewHandler creates an authorizer checking credentials with authenticator
+func NewHandler(authenticator service.Authenticator, logger service.Logger, config Config) *Handler {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = DefaultCacheTTL
+	}
+	if config.MerchantKey == "" {
+		config.MerchantKey = "merchant_id"
+	}
+	if config.RolesKey == "" {
+		config.RolesKey = "roles"
+	}
+	if config.ScopesKey == "" {
+		config.ScopesKey = "scope"
+	}
+
+	return &Handler{
+		authenticator: authenticator,
+		logger:        logger,
+		config:        config,
+		now:           time.Now,
+		cache:         make(map[string]cachedCaller),
+	}
+}
+
+// Handle allows a caller with valid credentials to invoke every method of the
+// stage, so API Gateway may cache the policy for the caller. Invalid
+// credentials return ErrUnauthorized; failures to check them return an error,
+// which API Gateway answers with 500.
+func (h *Handler) Handle(ctx context.Context, event events.APIGatewayCustomAuthorizerRequestTypeRequest) (events.APIGatewayCustomAuthorizerResponse, error) {
+	header := make(http.Header, len(event.Headers))
+	for name, value := range event.Headers {
+		header.Set(name, value)
+	}
+	credentials := middleware.HeaderCredentials(header)
+	if credentials.Empty() {
+		return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+	}
+
+	principal, err := h.authenticate(ctx, credentials)
+	if err != nil {
+		if errors.Is(err, service.ErrUnauthenticated) {
+			h.logger.Warn(ctx, "Rejected credentials", map[string]interface{}{
+				"reason": err.Error(),
+				"path":   event.Path,
+			})
+			return events.APIGatewayCustomAuthorizerResponse{}, ErrUnauthorized
+		}
+
+		h.logger.Error(ctx, "Failed to check credentials", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return events.APIGatewayCustomAuthorizerResponse{}, err
+	}
+
+	h.logger.Debug(ctx, "Authorized caller", map[string]interface{}{
+		"principal_id": principal.ID,
+		"auth_method":  string(principal.Method),
+		"merchant_id":  principal.MerchantID,
+	})
+
+	return events.APIGatewayCustomAuthorizerResponse{
+		PrincipalID: principal.ID,
+		PolicyDocument: events.APIGatewayCustomAuthorizerPolicy{
+			Version: "2012-10-17",
+			Statement: []events.IAMPolicyStatement{{
+				Action:   []string{"execute-api:Invoke"},
+				Effect:   "Allow",
+				Resource: []string{stageResource(event.MethodArn)},
+			}},
+		},
+		Context: h.context(principal),
+	}, nil
+}
+
+// authenticate returns the cached caller of the credentials, checking them
+// first if the cached entry expired. Callers are cached until their
+// credentials expire at the latest. Failures to check are not cached.
+func (h *Handler) authenticate(ctx context.Context, credentials service.Credentials) (*entity.Principal, error) {
+	if h.config.CacheTTL < 0 {
+		return h.authenticator.Authenticate(ctx, credentials)
+	}
+
+	key := cacheKey(credentials)
+	now := h.now()
+
+	h.mu.Lock()
+	cached, ok := h.cache[key]
+	h.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.principal, cached.err
+	}
+
+	principal, err := h.authenticator.Authenticate(ctx, credentials)
+	if err != nil && !errors.Is(err, service.ErrUnauthenticated) {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.cache) >= maxCachedCallers {
+		h.cache = make(map[string]cachedCaller)
+	}
+	expires := now.Add(h.config.CacheTTL)
+	if principal != nil && !principal.ExpiresAt.IsZero() && principal.ExpiresAt.Before(expires) {
+		expires = principal.ExpiresAt
+	}
+	h.cache[key] = cachedCaller{principal: principal, err: err, expires: expires}
+	return principal, err
+}
+
+// context describes the caller to the API function, which reads it as
+// authorizer claims. Values must be strings, numbers or booleans.
+func (h *Handler) context(principal *entity.Principal) map[string]interface{} {
+	roles := make([]string, len(principal.Roles))
+	for i, role := range principal.Roles {
+		roles[i] = string(role)
+	}
+
+	return map[string]interface{}{
+		"auth_method":        string(principal.Method),
+		h.config.MerchantKey: principal.MerchantID,
+		h.config.RolesKey:    strings.Join(roles, " "),
+		h.config.ScopesKey:   strings.Join(principal.Scopes, " "),
+	}
+}
+
+// cacheKey hashes the credentials, so the cache holds no secret
+func cacheKey(credentials service.Credentials) string {
+	sum := sha256.Sum256([]byte(credentials.BearerToken + "\x00" + credentials.APIKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// stageResource widens a method ARN,
+// arn:aws:execute-api:{region}:{account}:{api}/{stage}/{method}/{path}, to
+// every method of the stage
+func stageResource(methodARN string) string {
+	parts := strings.SplitN(methodARN, "/", 3)
+	if len(parts) < 2 {
+		return methodARN
+	}
+	return parts[0] + "/" + parts[1] + "/*"
+}
@@ -0,0 +1,267 @@
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// stubAuthenticator accepts the API key "sk_test_merchant_123", and bearer
+// tokens as analysts whose token expires at expires, counting its calls, and
+// fails with err if set
+type stubAuthenticator struct {
+	calls   int
+	err     error
+	expires time.Time
+}
+
+func (a *stubAuthenticator) Authenticate(ctx context.Context, credentials service.Credentials) (*entity.Principal, error) {
+	a.calls++
+	switch {
+	case a.err != nil:
+		return nil, a.err
+	case credentials.APIKey == "sk_test_merchant_123":
+		return &entity.Principal{
+			ID:         "key-1",
+			Method:     entity.AuthMethodAPIKey,
+			MerchantID: "merchant-456",
+			Roles:      []entity.Role{entity.RoleMerchant},
+			Scopes:     []string{"chargebacks:read", "chargebacks:write"},
+		}, nil
+	case credentials.BearerToken != "":
+		return &entity.Principal{
+			ID:        "user-1",
+			Method:    entity.AuthMethodJWT,
+			Roles:     []entity.Role{entity.RoleAnalyst},
+			ExpiresAt: a.expires,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown API key", service.ErrUnauthenticated)
+	}
+}
+
+// nopLogger discards every entry
+type nopLogger struct{}
+
+func (nopLogger) Log(ctx context.Context, entry service.LogEntry) error { return nil }
+func (nopLogger) Debug(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (nopLogger) Info(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (nopLogger) Warn(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (nopLogger) Error(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (l nopLogger) WithContext(ctx context.Context) service.Logger { return l }
+
+// loadEvent reads the authorizer event fixture
+func loadEvent(t *testing.T) events.APIGatewayCustomAuthorizerRequestTypeRequest {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("..", "..", "..", "events", "authorizer-request.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	var event events.APIGatewayCustomAuthorizerRequestTypeRequest
+	if err := json.Unmarshal(payload, &event); err != nil {
+		t.Fatalf("Failed to decode fixture: %v", err)
+	}
+	return event
+}
+
+func TestHandler_Handle_Allow(t *testing.T) {
+	// Arrange
+	handler := NewHandler(&stubAuthenticator{}, nopLogger{}, Config{})
+	event := loadEvent(t)
+
+	// Act
+	response, err := handler.Handle(context.Background(), event)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if response.PrincipalID != "key-1" {
+		t.Errorf("Expected principal key-1, got %q", response.PrincipalID)
+	}
+
+	statements := response.PolicyDocument.Statement
+	if len(statements) != 1 || statements[0].Effect != "Allow" || statements[0].Action[0] != "execute-api:Invoke" {
+		t.Fatalf("Expected one statement allowing invocation, got %+v", statements)
+	}
+	wantResource := "arn:aws:execute-api:us-east-1:123456789012:abcdef1234/Prod/*"
+	if len(statements[0].Resource) != 1 || statements[0].Resource[0] != wantResource {
+		t.Errorf("Expected the policy to cover the stage %s, got %v", wantResource, statements[0].Resource)
+	}
+
+	want := map[string]interface{}{
+		"auth_method": "api_key",
+		"merchant_id": "merchant-456",
+		"roles":       "merchant",
+		"scope":       "chargebacks:read chargebacks:write",
+	}
+	for key, value := range want {
+		if response.Context[key] != value {
+			t.Errorf("Expected context %s=%v, got %v", key, value, response.Context[key])
+		}
+	}
+}
+
+func TestHandler_Handle_ContextKeys(t *testing.T) {
+	handler := NewHandler(&stubAuthenticator{}, nopLogger{}, Config{MerchantKey: "merchant", RolesKey: "groups", ScopesKey: "scp"})
+
+	response, err := handler.Handle(context.Background(), loadEvent(t))
+	if err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if response.Context["merchant"] != "merchant-456" || response.Context["groups"] != "merchant" || response.Context["scp"] == nil {
+		t.Errorf("Expected the configured context keys, got %v", response.Context)
+	}
+}
+
+func TestHandler_Handle_Rejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{name: "no credentials", headers: map[string]string{"content-type": "application/json"}},
+		{name: "unknown API key", headers: map[string]string{"X-Api-Key": "sk_unknown"}},
+		{name: "basic credentials", headers: map[string]string{"authorization": "Basic dXNlcjpwYXNz"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&stubAuthenticator{}, nopLogger{}, Config{})
+			event := loadEvent(t)
+			event.Headers = tt.headers
+
+			_, err := handler.Handle(context.Background(), event)
+			if !errors.Is(err, ErrUnauthorized) {
+				t.Fatalf("Expected %v, got %v", ErrUnauthorized, err)
+			}
+			if err.Error() != "Unauthorized" {
+				t.Errorf("Expected the message API Gateway matches, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestHandler_Handle_Cache(t *testing.T) {
+	// Arrange
+	authenticator := &stubAuthenticator{}
+	handler := NewHandler(authenticator, nopLogger{}, Config{})
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	valid := loadEvent(t)
+	invalid := loadEvent(t)
+	invalid.Headers = map[string]string{"x-api-key": "sk_unknown"}
+
+	// Act & Assert: accepted and rejected credentials are both cached
+	for i := 0; i < 3; i++ {
+		if _, err := handler.Handle(ctx, valid); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+		if _, err := handler.Handle(ctx, invalid); !errors.Is(err, ErrUnauthorized) {
+			t.Fatalf("Expected a cached rejection to stay unauthorized, got %v", err)
+		}
+	}
+	if authenticator.calls != 2 {
+		t.Errorf("Expected 2 authentications, got %d", authenticator.calls)
+	}
+
+	now = now.Add(DefaultCacheTTL)
+	handler.Handle(ctx, valid)
+	if authenticator.calls != 3 {
+		t.Errorf("Expected an expired entry to be checked again, got %d authentications", authenticator.calls)
+	}
+
+	// Failures to check credentials are neither cached nor reported as 401
+	authenticator.err = errors.New("throttled")
+	other := loadEvent(t)
+	other.Headers = map[string]string{"authorization": "Bearer some.jwt.token"}
+	for i := 0; i < 2; i++ {
+		_, err := handler.Handle(ctx, other)
+		if err == nil || errors.Is(err, ErrUnauthorized) {
+			t.Errorf("Expected the check to fail, got %v", err)
+		}
+	}
+	if authenticator.calls != 5 {
+		t.Errorf("Expected failed checks to be retried, got %d authentications", authenticator.calls)
+	}
+}
+
+func TestHandler_Handle_CacheTokenExpiry(t *testing.T) {
+	// Arrange: the token expires well before the cache TTL
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	authenticator := &stubAuthenticator{expires: now.Add(10 * time.Second)}
+	handler := NewHandler(authenticator, nopLogger{}, Config{})
+	handler.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	event := loadEvent(t)
+	event.Headers = map[string]string{"authorization": "Bearer some.jwt.token"}
+
+	// Act & Assert
+	handler.Handle(ctx, event)
+	now = now.Add(9 * time.Second)
+	handler.Handle(ctx, event)
+	if authenticator.calls != 1 {
+		t.Fatalf("Expected the caller cached until the token expires, got %d authentications", authenticator.calls)
+	}
+
+	now = now.Add(time.Second)
+	handler.Handle(ctx, event)
+	if authenticator.calls != 2 {
+		t.Errorf("Expected the token checked again once expired, got %d authentications", authenticator.calls)
+	}
+}
+
+func TestHandler_Handle_CacheDisabled(t *testing.T) {
+	authenticator := &stubAuthenticator{}
+	handler := NewHandler(authenticator, nopLogger{}, Config{CacheTTL: -1})
+
+	for i := 0; i < 2; i++ {
+		handler.Handle(context.Background(), loadEvent(t))
+	}
+	if authenticator.calls != 2 {
+		t.Errorf("Expected every request to be checked, got %d authentications", authenticator.calls)
+	}
+}
+
+func TestStageResource(t *testing.T) {
+	tests := []struct {
+		methodARN string
+		want      string
+	}{
+		{
+			methodARN: "arn:aws:execute-api:us-east-1:123456789012:abcdef1234/Prod/GET/chargebacks/cb-1",
+			want:      "arn:aws:execute-api:us-east-1:123456789012:abcdef1234/Prod/*",
+		},
+		{
+			methodARN: "arn:aws:execute-api:us-east-1:123456789012:abcdef1234/Prod/POST/",
+			want:      "arn:aws:execute-api:us-east-1:123456789012:abcdef1234/Prod/*",
+		},
+		{methodARN: "not-an-arn", want: "not-an-arn"},
+	}
+
+	for _, tt := range tests {
+		if got := stageResource(tt.methodARN); got != tt.want {
+			t.Errorf("stageResource(%q) = %q, want %q", tt.methodARN, got, tt.want)
+		}
+	}
+}
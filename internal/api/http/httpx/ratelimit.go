@@ -0,0 +1,33 @@
+// Package httpx holds the HTTP helpers shared by the router and the
+// middlewares, so neither has to import the other for them
+package httpx
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// RateLimitHeaders are the response headers describing rate limits and
+// quotas, for CORS policies to expose
+var RateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"}
+
+// SetRateLimitHeaders describes a limit in the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers, adding Retry-After to
+// rejections. Durations are rounded up to whole seconds.
+func SetRateLimitHeaders(header http.Header, result service.RateLimitResult) {
+	header.Set("RateLimit-Limit", strconv.FormatInt(result.Limit, 10))
+	header.Set("RateLimit-Remaining", strconv.FormatInt(result.Remaining, 10))
+	header.Set("RateLimit-Reset", strconv.FormatInt(seconds(result.Reset), 10))
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.FormatInt(max(1, seconds(result.RetryAfter)), 10))
+	}
+}
+
+// seconds rounds a duration up to whole seconds
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
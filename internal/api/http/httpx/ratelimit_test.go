@@ -0,0 +1,45 @@
+package httpx
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		result service.RateLimitResult
+		want   map[string]string
+	}{
+		{
+			name:   "allowed",
+			result: service.RateLimitResult{Allowed: true, Limit: 20, Remaining: 19, Reset: 100 * time.Millisecond},
+			want:   map[string]string{"RateLimit-Limit": "20", "RateLimit-Remaining": "19", "RateLimit-Reset": "1", "Retry-After": ""},
+		},
+		{
+			name:   "rejected",
+			result: service.RateLimitResult{Limit: 20, Reset: 2500 * time.Millisecond, RetryAfter: 100 * time.Millisecond},
+			want:   map[string]string{"RateLimit-Limit": "20", "RateLimit-Remaining": "0", "RateLimit-Reset": "3", "Retry-After": "1"},
+		},
+		{
+			name:   "rejected until tomorrow",
+			result: service.RateLimitResult{Limit: 1000, Reset: 14 * time.Hour, RetryAfter: 14 * time.Hour},
+			want:   map[string]string{"RateLimit-Limit": "1000", "RateLimit-Remaining": "0", "RateLimit-Reset": "50400", "Retry-After": "50400"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			SetRateLimitHeaders(header, tt.result)
+			for name, value := range tt.want {
+				if got := header.Get(name); got != value {
+					t.Errorf("Expected %s '%s', got '%s'", name, value, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// APIKeyHeader carries the API key of a merchant integration
+const APIKeyHeader = "X-API-Key"
+
+// DefaultPublicPaths are served without credentials: probes and the API
+// contract
+var DefaultPublicPaths = []string{"/health", "/health/live", "/health/ready", "/openapi.json"}
+
+// Authentication rejects requests without valid credentials with 401 and
+// stores the authenticated principal in the request context. Credentials are
+// the claims of an API Gateway authorizer set by the Lambda adapter, a bearer
+// token or an API key. Paths in publicPaths, and requests whose principal is
+// already set, such as verified callbacks, are served without credentials;
+// credentials presented to a public path still set the principal, so
+// handlers may show more to authenticated callers. Failures to check
+// credentials, such as an unreachable key store, get 503.
+func Authentication(authenticator service.Authenticator, logger service.Logger, publicPaths []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(publicPaths, r.URL.Path) {
+				next.ServeHTTP(w, withOptionalPrincipal(r, authenticator, logger))
+				return
+			}
+
+			// Callbacks are already authenticated by their signature
+			ctx := r.Context()
+			if requestctx.Principal(ctx) != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			credentials := HeaderCredentials(r.Header)
+			credentials.Claims = requestctx.AuthorizerClaims(ctx)
+
+			if credentials.Empty() {
+				writeUnauthorized(w, false)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(ctx, credentials)
+			if err != nil {
+				if errors.Is(err, service.ErrUnauthenticated) {
+					logger.Warn(ctx, "Rejected credentials", map[string]interface{}{
+						"reason": err.Error(),
+					})
+					writeUnauthorized(w, credentials.BearerToken != "")
+					return
+				}
+
+				logger.Error(ctx, "Failed to check credentials", map[string]interface{}{
+					"error": err.Error(),
+				})
+				writeError(w, http.StatusServiceUnavailable, "Service Unavailable", "Authentication is temporarily unavailable")
+				return
+			}
+
+			trace.SpanFromContext(ctx).SetAttributes(
+				attribute.String("enduser.id", principal.ID),
+				attribute.String("auth.method", string(principal.Method)),
+			)
+
+			next.ServeHTTP(w, r.WithContext(requestctx.WithPrincipal(ctx, principal)))
+		})
+	}
+}
+
+// withOptionalPrincipal authenticates the credentials presented to a public
+// path, if any. Missing or rejected credentials never fail the request.
+func withOptionalPrincipal(r *http.Request, authenticator service.Authenticator, logger service.Logger) *http.Request {
+	ctx := r.Context()
+	credentials := HeaderCredentials(r.Header)
+	credentials.Claims = requestctx.AuthorizerClaims(ctx)
+	if requestctx.Principal(ctx) != nil || credentials.Empty() {
+		return r
+	}
+
+	principal, err := authenticator.Authenticate(ctx, credentials)
+	if err != nil {
+		logger.Debug(ctx, "Ignored credentials of a public path", map[string]interface{}{
+			"reason": err.Error(),
+		})
+		return r
+	}
+	return r.WithContext(requestctx.WithPrincipal(ctx, principal))
+}
+
+// HeaderCredentials returns the bearer token and API key of request headers.
+// The Lambda authorizer reads them the same way.
+func HeaderCredentials(header http.Header) service.Credentials {
+	return service.Credentials{
+		BearerToken: bearerToken(header),
+		APIKey:      strings.TrimSpace(header.Get(APIKeyHeader)),
+	}
+}
+
+// bearerToken returns the token of a "Bearer" Authorization header
+func bearerToken(header http.Header) string {
+	scheme, token, ok := strings.Cut(header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// writeUnauthorized answers 401 with the challenge of RFC 6750, flagging an
+// invalid token when one was presented. The reason is logged, not returned.
+func writeUnauthorized(w http.ResponseWriter, invalidToken bool) {
+	challenge := `Bearer realm="chargeback-api"`
+	if invalidToken {
+		challenge += `, error="invalid_token"`
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	writeError(w, http.StatusUnauthorized, "Unauthorized", "Missing or invalid credentials")
+}
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// stubAuthenticator accepts the API key "valid" and the bearer token
+// "valid-token", and returns err for anything else if set
+type stubAuthenticator struct {
+	err         error
+	credentials service.Credentials
+}
+
+func (a *stubAuthenticator) Authenticate(ctx context.Context, credentials service.Credentials) (*entity.Principal, error) {
+	a.credentials = credentials
+	switch {
+	case len(credentials.Claims) > 0:
+		return &entity.Principal{ID: credentials.Claims["sub"], Method: entity.AuthMethodAuthorizer}, nil
+	case credentials.APIKey == "valid":
+		return &entity.Principal{ID: "key-1", Method: entity.AuthMethodAPIKey, MerchantID: "merchant-456"}, nil
+	case credentials.BearerToken == "valid-token":
+		return &entity.Principal{ID: "user-123", Method: entity.AuthMethodJWT}, nil
+	case a.err != nil:
+		return nil, a.err
+	default:
+		return nil, fmt.Errorf("%w: unknown credentials", service.ErrUnauthenticated)
+	}
+}
+
+// newAuthHandler echoes the authenticated principal
+func newAuthHandler(authenticator service.Authenticator, logger service.Logger) http.Handler {
+	return Authentication(authenticator, logger, DefaultPublicPaths)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := requestctx.Principal(r.Context())
+		if principal == nil {
+			io.WriteString(w, "anonymous")
+			return
+		}
+		io.WriteString(w, principal.ID+" "+requestctx.UserID(r.Context()))
+	}))
+}
+
+func TestAuthentication(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		headers       map[string]string
+		claims        map[string]string
+		err           error
+		wantStatus    int
+		wantBody      string
+		wantChallenge string
+		wantLog       string
+	}{
+		{
+			name:       "public path",
+			path:       "/health/live",
+			wantStatus: http.StatusOK,
+			wantBody:   "anonymous",
+		},
+		{
+			name:       "public path with credentials",
+			path:       "/health/ready",
+			headers:    map[string]string{APIKeyHeader: "valid"},
+			wantStatus: http.StatusOK,
+			wantBody:   "key-1 key-1",
+		},
+		{
+			name:       "public path with rejected credentials",
+			path:       "/health/ready",
+			headers:    map[string]string{"Authorization": "Bearer forged"},
+			wantStatus: http.StatusOK,
+			wantBody:   "anonymous",
+		},
+		{
+			name:       "API key",
+			path:       "/chargebacks",
+			headers:    map[string]string{APIKeyHeader: "valid"},
+			wantStatus: http.StatusOK,
+			wantBody:   "key-1 key-1",
+		},
+		{
+			name:       "bearer token",
+			path:       "/chargebacks",
+			headers:    map[string]string{"Authorization": "bearer valid-token"},
+			wantStatus: http.StatusOK,
+			wantBody:   "user-123 user-123",
+		},
+		{
+			name:       "authorizer claims",
+			path:       "/chargebacks",
+			claims:     map[string]string{"sub": "user-789"},
+			wantStatus: http.StatusOK,
+			wantBody:   "user-789 user-789",
+		},
+		{
+			name:          "no credentials",
+			path:          "/chargebacks",
+			headers:       map[string]string{"Authorization": "Basic dXNlcjpwYXNz"},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chargeback-api"`,
+		},
+		{
+			name:          "invalid API key",
+			path:          "/chargebacks",
+			headers:       map[string]string{APIKeyHeader: "invalid"},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chargeback-api"`,
+			wantLog:       "Rejected credentials",
+		},
+		{
+			name:          "invalid bearer token",
+			path:          "/chargebacks",
+			headers:       map[string]string{"Authorization": "Bearer expired"},
+			wantStatus:    http.StatusUnauthorized,
+			wantChallenge: `Bearer realm="chargeback-api", error="invalid_token"`,
+			wantLog:       "Rejected credentials",
+		},
+		{
+			name:       "key store unavailable",
+			path:       "/chargebacks",
+			headers:    map[string]string{APIKeyHeader: "other"},
+			err:        errors.New("throttled"),
+			wantStatus: http.StatusServiceUnavailable,
+			wantLog:    "Failed to check credentials",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			logger := &recordingLogger{}
+			handler := newAuthHandler(&stubAuthenticator{err: tt.err}, logger)
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			if tt.claims != nil {
+				req = req.WithContext(requestctx.WithAuthorizerClaims(req.Context(), tt.claims))
+			}
+			rec := httptest.NewRecorder()
+
+			// Act
+			handler.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+			if got := rec.Header().Get("WWW-Authenticate"); got != tt.wantChallenge {
+				t.Errorf("Expected challenge %q, got %q", tt.wantChallenge, got)
+			}
+			if tt.wantLog != "" {
+				if _, ok := logger.find(tt.wantLog); !ok {
+					t.Errorf("Expected log %q", tt.wantLog)
+				}
+			}
+		})
+	}
+}
+
+func TestStandard_Authentication(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	handler := newStandardHandler(logger, Options{Authenticator: &stubAuthenticator{}})
+
+	// Act & Assert
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", rec.Code)
+	}
+	if rec.Header().Get(requestctx.RequestIDHeader) == "" {
+		t.Error("Expected rejected requests to carry a request ID")
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(APIKeyHeader, "valid")
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with an API key, got %d", rec.Code)
+	}
+}
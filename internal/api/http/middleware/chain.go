@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// Middleware wraps an http.Handler with cross-cutting behaviour
+type Middleware func(http.Handler) http.Handler
+
+// Chain is an ordered list of middlewares. The first registered middleware
+// is the outermost, so it sees the request first and the response last.
+type Chain struct {
+	middlewares []Middleware
+}
+
+// NewChain creates a chain from the given middlewares, outermost first
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain{middlewares: append([]Middleware(nil), middlewares...)}
+}
+
+// Use returns a new chain with the middlewares appended inside the existing ones
+func (c Chain) Use(middlewares ...Middleware) Chain {
+	combined := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
+	combined = append(combined, c.middlewares...)
+	combined = append(combined, middlewares...)
+	return Chain{middlewares: combined}
+}
+
+// Then wraps the handler with every middleware of the chain
+func (c Chain) Then(handler http.Handler) http.Handler {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		handler = c.middlewares[i](handler)
+	}
+	return handler
+}
+
+// Options configures the standard chain
+type Options struct {
+	// Logger receives request and panic logs
+	Logger service.Logger
+
+	// Metrics counts recovered panics, discarded if nil
+	Metrics service.Metrics
+
+	// LogLevels adjusts the log level while running, disabled if nil
+	LogLevels LogLevels
+
+	// CORS is the cross-origin policy
+	CORS CORSPolicy
+
+	// Routes reports the methods of each path, for CORS preflight requests
+	Routes RouteMethods
+
+	// RequestTimeout bounds request handling, disabled if zero
+	RequestTimeout time.Duration
+
+	// MaxBodyBytes limits request bodies, disabled if zero
+	MaxBodyBytes int64
+
+	// Authenticator checks the credentials of every request outside
+	// PublicPaths, and those presented to them, disabled if nil
+	Authenticator service.Authenticator
+
+	// SignatureVerifier verifies the requests to SignedPaths, which then
+	// need no other credentials, disabled if nil
+	SignatureVerifier service.SignatureVerifier
+
+	// SignedPaths are the callbacks signed by acquirers,
+	// DefaultSignedPaths if nil
+	SignedPaths []string
+
+	// RateLimiter limits the request rate of each caller outside
+	// PublicPaths, disabled if nil
+	RateLimiter service.RateLimiter
+
+	// PublicPaths are served without credentials or rate limits,
+	// DefaultPublicPaths if nil
+	PublicPaths []string
+}
+
+// Standard returns the chain applied in front of the API router by both the
+// local server and the Lambda adapter
+func Standard(opts Options) Chain {
+	// The span is opened once the identifiers are known, so every later
+	// entry carries its trace ID
+	chain := NewChain(RequestID(), Tracing())
+
+	// The level is settled before the request is logged
+	if opts.LogLevels != nil {
+		chain = chain.Use(LogLevel(opts.LogLevels, opts.Logger))
+	}
+
+	chain = chain.Use(
+		Logging(opts.Logger),
+		CORS(opts.CORS, opts.Routes),
+	)
+
+	if opts.RequestTimeout > 0 {
+		chain = chain.Use(Timeout(opts.RequestTimeout))
+	}
+
+	// Recovery runs inside Timeout, on the goroutine that handles the
+	// request, so the logged stack trace points at the panic itself
+	metrics := opts.Metrics
+	if metrics == nil {
+		metrics = service.NopMetrics{}
+	}
+	chain = chain.Use(Recovery(opts.Logger, metrics))
+
+	// Bodies are limited before callbacks read them to check their signature
+	if opts.MaxBodyBytes > 0 {
+		chain = chain.Use(BodyLimit(opts.MaxBodyBytes))
+	}
+
+	// Signatures are checked first, so verified callbacks need no other
+	// credentials
+	if opts.SignatureVerifier != nil {
+		signedPaths := opts.SignedPaths
+		if signedPaths == nil {
+			signedPaths = DefaultSignedPaths
+		}
+		chain = chain.Use(Signature(opts.SignatureVerifier, opts.Logger, signedPaths))
+	}
+
+	publicPaths := opts.PublicPaths
+	if publicPaths == nil {
+		publicPaths = DefaultPublicPaths
+	}
+
+	// Credentials are checked within the timeout, as looking an API key up
+	// may wait on DynamoDB
+	if opts.Authenticator != nil {
+		chain = chain.Use(Authentication(opts.Authenticator, opts.Logger, publicPaths))
+	}
+
+	// Callers are limited once authenticated, so the limit follows their
+	// merchant rather than their address
+	if opts.RateLimiter != nil {
+		chain = chain.Use(RateLimit(opts.RateLimiter, opts.Logger, publicPaths))
+	}
+
+	return chain
+}
+
+// responseRecorder captures the status code written through a ResponseWriter
+type responseRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	wroteHeader bool
+}
+
+func newResponseRecorder(w http.ResponseWriter) *responseRecorder {
+	return &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+func (rw *responseRecorder) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.statusCode = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *responseRecorder) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	return rw.ResponseWriter.Write(b)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseRecorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
@@ -0,0 +1,485 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// recordingLogger keeps every logged message and its fields
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []service.LogEntry
+}
+
+// record merges the request identifiers of ctx like the structured logger does
+func (l *recordingLogger) record(ctx context.Context, level service.LogLevel, message string, fields []map[string]interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := service.LogEntry{Level: level, Message: message, Fields: map[string]interface{}{}}
+	for k, v := range requestctx.Fields(ctx) {
+		entry.Fields[k] = v
+	}
+	for _, f := range fields {
+		for k, v := range f {
+			entry.Fields[k] = v
+		}
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *recordingLogger) Log(ctx context.Context, entry service.LogEntry) error {
+	return l.record(ctx, entry.Level, entry.Message, []map[string]interface{}{entry.Fields})
+}
+func (l *recordingLogger) Debug(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(ctx, service.LogLevelDebug, message, fields)
+}
+func (l *recordingLogger) Info(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(ctx, service.LogLevelInfo, message, fields)
+}
+func (l *recordingLogger) Warn(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(ctx, service.LogLevelWarn, message, fields)
+}
+func (l *recordingLogger) Error(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(ctx, service.LogLevelError, message, fields)
+}
+func (l *recordingLogger) WithContext(ctx context.Context) service.Logger { return l }
+
+// find returns the first entry with the given message
+func (l *recordingLogger) find(message string) (service.LogEntry, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, entry := range l.entries {
+		if entry.Message == message {
+			return entry, true
+		}
+	}
+	return service.LogEntry{}, false
+}
+
+// countingMetrics sums the counters added, by metric name
+type countingMetrics struct {
+	service.NopMetrics
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+func (m *countingMetrics) Add(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[string]float64)
+	}
+	m.counts[metric.Name] += value
+}
+
+func (m *countingMetrics) count(name string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.counts[name]
+}
+
+func TestChain_Order(t *testing.T) {
+	var calls []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+":before")
+				next.ServeHTTP(w, r)
+				calls = append(calls, name+":after")
+			})
+		}
+	}
+
+	base := NewChain(tag("first"))
+	extended := base.Use(tag("second"), tag("third"))
+
+	handler := extended.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	want := "first:before second:before third:before handler third:after second:after first:after"
+	if got := strings.Join(calls, " "); got != want {
+		t.Errorf("Unexpected order:\n got: %s\nwant: %s", got, want)
+	}
+
+	// Use must not modify the original chain
+	calls = nil
+	base.Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).
+		ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if got := strings.Join(calls, " "); got != "first:before first:after" {
+		t.Errorf("Expected base chain to be unchanged, got: %s", got)
+	}
+}
+
+// newStandardHandler serves a router with test routes behind the standard chain
+func newStandardHandler(logger service.Logger, opts Options) http.Handler {
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"request_id":"`+requestctx.RequestID(r.Context())+`"}`)
+	})
+	rt.HandleFunc(http.MethodGet, "/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	rt.HandleFunc(http.MethodGet, "/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+	rt.HandleFunc(http.MethodPost, "/echo", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	opts.Logger = logger
+	opts.Routes = rt
+	return Standard(opts).Then(rt)
+}
+
+func decodeError(t *testing.T, recorder *httptest.ResponseRecorder) router.ErrorResponse {
+	t.Helper()
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected Content-Type 'application/json', got '%s'", contentType)
+	}
+	var response router.ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode error response %q: %v", recorder.Body.String(), err)
+	}
+	return response
+}
+
+func TestStandard_RequestID(t *testing.T) {
+	handler := newStandardHandler(&recordingLogger{}, Options{})
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		headers         map[string]string
+		wantRequest     string
+		wantCorrelation string
+	}{
+		{
+			name:            "reuses the caller's request ID",
+			headers:         map[string]string{requestctx.RequestIDHeader: "req-123"},
+			wantRequest:     "req-123",
+			wantCorrelation: "req-123",
+		},
+		{
+			name: "keeps the caller's correlation ID",
+			headers: map[string]string{
+				requestctx.RequestIDHeader:     "req-123",
+				requestctx.CorrelationIDHeader: "flow-1",
+			},
+			wantRequest:     "req-123",
+			wantCorrelation: "flow-1",
+		},
+		{
+			name:            "prefers the API Gateway request ID",
+			ctx:             requestctx.WithRequestID(context.Background(), "apigw-1"),
+			headers:         map[string]string{requestctx.RequestIDHeader: "req-123"},
+			wantRequest:     "apigw-1",
+			wantCorrelation: "req-123",
+		},
+		{
+			name:            "falls back to the Lambda request ID",
+			ctx:             lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "lambda-1"}),
+			wantRequest:     "lambda-1",
+			wantCorrelation: "lambda-1",
+		},
+		{
+			name:            "ignores unsafe header values",
+			headers:         map[string]string{requestctx.CorrelationIDHeader: "bad\nvalue"},
+			wantCorrelation: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tt.ctx != nil {
+				req = req.WithContext(tt.ctx)
+			}
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			requestID := recorder.Header().Get(requestctx.RequestIDHeader)
+			correlationID := recorder.Header().Get(requestctx.CorrelationIDHeader)
+
+			if tt.wantRequest == "" {
+				if len(requestID) != 32 {
+					t.Errorf("Expected a generated 32-character request ID, got '%s'", requestID)
+				}
+			} else if requestID != tt.wantRequest {
+				t.Errorf("Expected request ID '%s', got '%s'", tt.wantRequest, requestID)
+			}
+
+			wantCorrelation := tt.wantCorrelation
+			if wantCorrelation == "" {
+				wantCorrelation = requestID
+			}
+			if correlationID != wantCorrelation {
+				t.Errorf("Expected correlation ID '%s', got '%s'", wantCorrelation, correlationID)
+			}
+
+			if !strings.Contains(recorder.Body.String(), `"`+requestID+`"`) {
+				t.Errorf("Expected handler to see the request ID, got %s", recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestStandard_TraceID(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		header string
+		want   string
+	}{
+		{
+			name:   "reads the root of the X-Ray header",
+			header: "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			want:   "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			name: "falls back to the Lambda trace header",
+			ctx:  context.WithValue(context.Background(), lambdaTraceIDKey, "Root=1-abc-def;Sampled=0"),
+			want: "1-abc-def",
+		},
+		{
+			name:   "ignores headers without a root",
+			header: "Parent=53995c3f42cd8ad8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = requestctx.TraceID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tt.ctx != nil {
+				req = req.WithContext(tt.ctx)
+			}
+			if tt.header != "" {
+				req.Header.Set(traceIDHeader, tt.header)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("Expected trace ID '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+// stubLogLevels grants an override for the value "valid"
+type stubLogLevels struct {
+	refreshes  int
+	refreshErr error
+}
+
+type overrideKey struct{}
+
+func (l *stubLogLevels) Refresh(ctx context.Context) error {
+	l.refreshes++
+	return l.refreshErr
+}
+
+func (l *stubLogLevels) WithOverride(ctx context.Context, value string) (context.Context, error) {
+	if value != "valid" {
+		return ctx, fmt.Errorf("invalid override")
+	}
+	return context.WithValue(ctx, overrideKey{}, true), nil
+}
+
+func TestStandard_LogLevel(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		refreshErr   error
+		wantOverride bool
+		wantWarning  string
+	}{
+		{name: "no override"},
+		{name: "valid override", header: "valid", wantOverride: true},
+		{name: "invalid override", header: "forged", wantWarning: "Rejected log level override"},
+		{name: "refresh failure", refreshErr: fmt.Errorf("throttled"), wantWarning: "Failed to refresh log level"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := &recordingLogger{}
+			levels := &stubLogLevels{refreshErr: tt.refreshErr}
+
+			var overridden bool
+			handler := Standard(Options{Logger: logger, LogLevels: levels}).Then(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				overridden, _ = r.Context().Value(overrideKey{}).(bool)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+			if tt.header != "" {
+				req.Header.Set(LogLevelHeader, tt.header)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusOK {
+				t.Errorf("Expected the request to proceed, got %d", recorder.Code)
+			}
+			if levels.refreshes != 1 {
+				t.Errorf("Expected one refresh per request, got %d", levels.refreshes)
+			}
+			if overridden != tt.wantOverride {
+				t.Errorf("Expected override %v, got %v", tt.wantOverride, overridden)
+			}
+			if tt.wantWarning != "" {
+				if _, ok := logger.find(tt.wantWarning); !ok {
+					t.Errorf("Expected warning %q", tt.wantWarning)
+				}
+			}
+		})
+	}
+}
+
+func TestStandard_Logging(t *testing.T) {
+	logger := &recordingLogger{}
+	handler := newStandardHandler(logger, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	req.Header.Set(requestctx.RequestIDHeader, "req-456")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	entry, ok := logger.find("HTTP request processed")
+	if !ok {
+		t.Fatal("Expected request to be logged")
+	}
+	if entry.Fields["status_code"] != http.StatusNotFound {
+		t.Errorf("Expected status_code 404, got %v", entry.Fields["status_code"])
+	}
+	if entry.Fields["request_id"] != "req-456" {
+		t.Errorf("Expected request_id 'req-456', got %v", entry.Fields["request_id"])
+	}
+}
+
+func TestStandard_Recovery(t *testing.T) {
+	for _, opts := range []Options{{}, {RequestTimeout: time.Second}} {
+		t.Run(fmt.Sprintf("timeout %s", opts.RequestTimeout), func(t *testing.T) {
+			logger := &recordingLogger{}
+			metrics := &countingMetrics{}
+			opts.Metrics = metrics
+			handler := newStandardHandler(logger, opts)
+
+			req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+			req.Header.Set(requestctx.RequestIDHeader, "req-789")
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusInternalServerError {
+				t.Errorf("Expected status 500, got %d", recorder.Code)
+			}
+			response := decodeError(t, recorder)
+			if response.Error != "Internal Server Error" || response.ErrorID == "" {
+				t.Errorf("Expected 'Internal Server Error' with an error ID, got %+v", response)
+			}
+
+			entry, ok := logger.find("Recovered from panic")
+			if !ok {
+				t.Fatal("Expected panic to be logged")
+			}
+			if entry.Level != service.LogLevelError {
+				t.Errorf("Expected error level, got %v", entry.Level)
+			}
+			if entry.Fields["error_id"] != response.ErrorID {
+				t.Errorf("Expected logged error_id %q to match the response, got %v", response.ErrorID, entry.Fields["error_id"])
+			}
+			if entry.Fields["panic"] != "boom" || entry.Fields["request_id"] != "req-789" {
+				t.Errorf("Expected panic value and request ID to be logged, got %v", entry.Fields)
+			}
+			if stack, _ := entry.Fields["stack"].(string); !strings.Contains(stack, "chain_test.go") {
+				t.Errorf("Expected the stack trace to point at the panic, got:\n%s", stack)
+			}
+
+			if got := metrics.count(service.MetricPanicsRecovered.Name); got != 1 {
+				t.Errorf("Expected one recovered panic to be counted, got %v", got)
+			}
+
+			if entry, ok := logger.find("HTTP request processed"); !ok || entry.Fields["status_code"] != http.StatusInternalServerError {
+				t.Errorf("Expected the request to be logged with status 500, got %v", entry.Fields["status_code"])
+			}
+		})
+	}
+}
+
+func TestStandard_Timeout(t *testing.T) {
+	handler := newStandardHandler(&recordingLogger{}, Options{RequestTimeout: 20 * time.Millisecond})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/slow", nil))
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", recorder.Code)
+	}
+	if response := decodeError(t, recorder); response.Message != "Request timed out" {
+		t.Errorf("Expected 'Request timed out', got '%s'", response.Message)
+	}
+}
+
+func TestStandard_BodyLimit(t *testing.T) {
+	handler := newStandardHandler(&recordingLogger{}, Options{MaxBodyBytes: 8})
+
+	t.Run("declared length over the limit", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("0123456789")))
+
+		if recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", recorder.Code)
+		}
+		decodeError(t, recorder)
+	})
+
+	t.Run("unknown length over the limit", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("0123456789"))
+		req.ContentLength = -1
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", recorder.Code)
+		}
+	})
+
+	t.Run("within the limit", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("01234")))
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", recorder.Code)
+		}
+	})
+}
@@ -0,0 +1,228 @@
+// Package middleware holds the cross-cutting HTTP handlers shared by the local
+// server and the Lambda adapter
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSPolicy describes which cross-origin requests the API accepts
+type CORSPolicy struct {
+	// AllowedOrigins lists exact origins ("https://app.example.com"), wildcard
+	// subdomains ("https://*.example.com") or "*" for any origin. CORS is
+	// disabled when empty.
+	AllowedOrigins []string
+
+	// AllowedMethods limits the methods advertised in preflight responses.
+	// Only methods of the requested route are ever advertised.
+	AllowedMethods []string
+
+	// AllowedHeaders lists the request headers browsers may send
+	AllowedHeaders []string
+
+	// ExposedHeaders lists the response headers browsers may read
+	ExposedHeaders []string
+
+	// AllowCredentials allows cookies and Authorization headers
+	AllowCredentials bool
+
+	// MaxAge is how long browsers may cache a preflight response
+	MaxAge time.Duration
+}
+
+// Validate checks the policy for malformed or unsafe settings
+func (p CORSPolicy) Validate() error {
+	var errs []error
+
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				errs = append(errs, errors.New("origin \"*\" cannot be combined with credentials"))
+			}
+			continue
+		}
+
+		parsed, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || parsed.Path != "" {
+			errs = append(errs, fmt.Errorf("origin %q must be scheme://host[:port]", origin))
+		}
+	}
+
+	if p.MaxAge < 0 {
+		errs = append(errs, errors.New("max age must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Enabled reports whether any origin is allowed
+func (p CORSPolicy) Enabled() bool {
+	return len(p.AllowedOrigins) > 0
+}
+
+// AllowsOrigin reports whether the Origin header value matches the policy
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		scheme, host, ok := strings.Cut(allowed, "://*.")
+		if !ok {
+			continue
+		}
+		// "https://*.example.com" matches "https://a.example.com" and
+		// "https://a.b.example.com" but not "https://example.com"
+		prefix := strings.ToLower(scheme + "://")
+		suffix := strings.ToLower("." + host)
+		candidate := strings.ToLower(origin)
+		if strings.HasPrefix(candidate, prefix) && strings.HasSuffix(candidate, suffix) && len(candidate) > len(prefix)+len(suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RouteMethods reports the methods registered for a path, empty when no route matches
+type RouteMethods interface {
+	AllowedMethods(path string) []string
+}
+
+// CORS applies the policy to every request. Preflight requests are answered
+// only for paths with a registered route; other OPTIONS requests fall through
+// to next.
+func CORS(policy CORSPolicy, routes RouteMethods) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if !policy.Enabled() || origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Origin")
+
+			if isPreflight(r) {
+				methods := routes.AllowedMethods(r.URL.Path)
+				if len(methods) == 0 {
+					next.ServeHTTP(w, r)
+					return
+				}
+				policy.handlePreflight(w, r, origin, methods)
+				return
+			}
+
+			if policy.AllowsOrigin(origin) {
+				policy.setOriginHeaders(w, origin)
+				if len(policy.ExposedHeaders) > 0 {
+					w.Header().Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isPreflight reports whether the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// handlePreflight answers a preflight request for an existing route. Requests
+// the policy does not allow get 403 without CORS headers so the browser blocks
+// the actual request.
+func (p CORSPolicy) handlePreflight(w http.ResponseWriter, r *http.Request, origin string, routeMethods []string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+
+	methods := p.methodsFor(routeMethods)
+	requestedMethod := r.Header.Get("Access-Control-Request-Method")
+	requestedHeaders := parseHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+
+	if !p.AllowsOrigin(origin) || !containsFold(methods, requestedMethod) || !p.allowsHeaders(requestedHeaders) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	p.setOriginHeaders(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(requestedHeaders) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(requestedHeaders, ", "))
+	}
+	if p.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOriginHeaders allows the origin, echoing it unless any origin is allowed
+// without credentials
+func (p CORSPolicy) setOriginHeaders(w http.ResponseWriter, origin string) {
+	if !p.AllowCredentials && containsFold(p.AllowedOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// methodsFor returns the route methods allowed by the policy
+func (p CORSPolicy) methodsFor(routeMethods []string) []string {
+	if len(p.AllowedMethods) == 0 {
+		return routeMethods
+	}
+
+	var methods []string
+	for _, method := range routeMethods {
+		if containsFold(p.AllowedMethods, method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// allowsHeaders reports whether every requested header is allowed
+func (p CORSPolicy) allowsHeaders(headers []string) bool {
+	for _, header := range headers {
+		if !containsFold(p.AllowedHeaders, header) && !containsFold(p.AllowedHeaders, "*") {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList splits a comma-separated header list
+func parseHeaderList(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
+
+func containsFold(values []string, target string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
+)
+
+// newTestHandler returns a router with GET /health and POST /chargebacks behind the CORS policy
+func newTestHandler(policy CORSPolicy) http.Handler {
+	rt := router.New()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	rt.Handle(http.MethodGet, "/health", ok)
+	rt.Handle(http.MethodPost, "/chargebacks", ok)
+	rt.Handle(http.MethodGet, "/chargebacks/{id}", ok)
+	return CORS(policy, rt)(rt)
+}
+
+func TestCORSPolicy_AllowsOrigin(t *testing.T) {
+	policy := CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "https://*.partner.com"}}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.example.com", true},
+		{"http://app.example.com", false},
+		{"https://evil.example.com", false},
+		{"https://a.partner.com", true},
+		{"https://a.b.partner.com", true},
+		{"https://partner.com", false},
+		{"https://evilpartner.com", false},
+		{"http://a.partner.com", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			if got := policy.AllowsOrigin(tt.origin); got != tt.want {
+				t.Errorf("AllowsOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORSPolicy_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy CORSPolicy
+		valid  bool
+	}{
+		{"empty policy", CORSPolicy{}, true},
+		{"exact and wildcard origins", CORSPolicy{AllowedOrigins: []string{"https://a.com", "https://*.b.com", "http://localhost:3000"}}, true},
+		{"any origin", CORSPolicy{AllowedOrigins: []string{"*"}}, true},
+		{"any origin with credentials", CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}, false},
+		{"origin with path", CORSPolicy{AllowedOrigins: []string{"https://a.com/app"}}, false},
+		{"origin without scheme", CORSPolicy{AllowedOrigins: []string{"a.com"}}, false},
+		{"negative max age", CORSPolicy{MaxAge: -time.Second}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if tt.valid && err != nil {
+				t.Errorf("Expected valid policy, got error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected invalid policy, got no error")
+			}
+		})
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	policy := CORSPolicy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}
+	handler := newTestHandler(policy)
+
+	tests := []struct {
+		name        string
+		path        string
+		origin      string
+		method      string
+		headers     string
+		wantStatus  int
+		wantOrigin  string
+		wantMethods string
+	}{
+		{
+			name:        "allowed preflight advertises only route methods",
+			path:        "/chargebacks",
+			origin:      "https://app.example.com",
+			method:      "POST",
+			headers:     "content-type, authorization",
+			wantStatus:  http.StatusNoContent,
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "POST",
+		},
+		{
+			name:        "route with path parameter",
+			path:        "/chargebacks/cb-1",
+			origin:      "https://app.example.com",
+			method:      "GET",
+			wantStatus:  http.StatusNoContent,
+			wantOrigin:  "https://app.example.com",
+			wantMethods: "GET",
+		},
+		{
+			name:       "unknown route falls through to the router",
+			path:       "/unknown",
+			origin:     "https://app.example.com",
+			method:     "POST",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "origin not allowed",
+			path:       "/chargebacks",
+			origin:     "https://evil.com",
+			method:     "POST",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "method not registered for the route",
+			path:       "/chargebacks",
+			origin:     "https://app.example.com",
+			method:     "DELETE",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "header not allowed",
+			path:       "/chargebacks",
+			origin:     "https://app.example.com",
+			method:     "POST",
+			headers:    "X-Custom",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			if tt.headers != "" {
+				req.Header.Set("Access-Control-Request-Headers", tt.headers)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, recorder.Code)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Expected Access-Control-Allow-Origin '%s', got '%s'", tt.wantOrigin, got)
+			}
+			if got := recorder.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Expected Access-Control-Allow-Methods '%s', got '%s'", tt.wantMethods, got)
+			}
+			if tt.wantStatus == http.StatusNoContent {
+				if got := recorder.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+					t.Errorf("Expected credentials to be allowed, got '%s'", got)
+				}
+				if got := recorder.Header().Get("Access-Control-Max-Age"); got != "3600" {
+					t.Errorf("Expected Access-Control-Max-Age '3600', got '%s'", got)
+				}
+			}
+		})
+	}
+}
+
+func TestCORS_ActualRequest(t *testing.T) {
+	t.Run("allowed origin is echoed", func(t *testing.T) {
+		handler := newTestHandler(CORSPolicy{
+			AllowedOrigins: []string{"https://app.example.com"},
+			ExposedHeaders: []string{"X-Request-ID"},
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("Expected origin to be echoed, got '%s'", got)
+		}
+		if got := recorder.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+			t.Errorf("Expected exposed headers, got '%s'", got)
+		}
+		if got := recorder.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("Expected Vary: Origin, got '%s'", got)
+		}
+	})
+
+	t.Run("any origin without credentials", func(t *testing.T) {
+		handler := newTestHandler(CORSPolicy{AllowedOrigins: []string{"*"}})
+
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", "https://anything.com")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Expected '*', got '%s'", got)
+		}
+	})
+
+	t.Run("disallowed origin gets no CORS headers", func(t *testing.T) {
+		handler := newTestHandler(CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}})
+
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", "https://evil.com")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", recorder.Code)
+		}
+		if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("Expected no Access-Control-Allow-Origin, got '%s'", got)
+		}
+	})
+
+	t.Run("OPTIONS without preflight headers falls through", func(t *testing.T) {
+		handler := newTestHandler(CORSPolicy{AllowedOrigins: []string{"https://app.example.com"}})
+
+		req := httptest.NewRequest(http.MethodOptions, "/chargebacks", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		if recorder.Code != http.StatusMethodNotAllowed {
+			t.Errorf("Expected status 405, got %d", recorder.Code)
+		}
+	})
+}
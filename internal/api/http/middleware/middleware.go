@@ -0,0 +1,278 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// maxIDLength bounds identifiers accepted from request headers
+const maxIDLength = 128
+
+const (
+	// traceIDHeader carries the X-Ray trace header forwarded by API Gateway
+	traceIDHeader = "X-Amzn-Trace-Id"
+
+	// lambdaTraceIDKey is the context key of the trace header set by the
+	// aws-lambda-go runtime
+	lambdaTraceIDKey = "x-amzn-trace-id"
+)
+
+// RequestID populates the request and correlation IDs in the request context
+// and echoes both in the response. The request ID comes from the API Gateway
+// event when the Lambda adapter set one, then the Lambda invocation, then the
+// X-Request-ID header, and is generated otherwise. The correlation ID comes
+// from X-Correlation-ID, then X-Request-ID, and defaults to the request ID.
+// The X-Ray trace ID of the request, if any, is stored in the context as well.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			headerRequestID := headerID(r, requestctx.RequestIDHeader)
+
+			requestID := requestctx.RequestID(ctx)
+			if requestID == "" {
+				if lc, ok := lambdacontext.FromContext(ctx); ok {
+					requestID = lc.AwsRequestID
+				}
+			}
+			if requestID == "" {
+				requestID = headerRequestID
+			}
+			if requestID == "" {
+				requestID = newID()
+			}
+
+			correlationID := headerID(r, requestctx.CorrelationIDHeader)
+			if correlationID == "" {
+				correlationID = headerRequestID
+			}
+			if correlationID == "" {
+				correlationID = requestID
+			}
+
+			ctx = requestctx.WithRequestID(ctx, requestID)
+			ctx = requestctx.WithCorrelationID(ctx, correlationID)
+			if traceID := traceID(r); traceID != "" {
+				ctx = requestctx.WithTraceID(ctx, traceID)
+			}
+
+			w.Header().Set(requestctx.RequestIDHeader, requestID)
+			w.Header().Set(requestctx.CorrelationIDHeader, correlationID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// headerID returns an identifier from a request header, ignoring values that
+// are too long or contain characters that could forge log output
+func headerID(r *http.Request, name string) string {
+	id := r.Header.Get(name)
+	if id == "" || len(id) > maxIDLength {
+		return ""
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.ContainsRune("-_.:/", c)) {
+			return ""
+		}
+	}
+	return id
+}
+
+// traceID returns the root X-Ray trace ID of the request, taken from the
+// X-Amzn-Trace-Id header or, in Lambda, the trace header of the invocation
+func traceID(r *http.Request) string {
+	header := r.Header.Get(traceIDHeader)
+	if header == "" {
+		// The Lambda runtime stores the header under a bare string key
+		header, _ = r.Context().Value(lambdaTraceIDKey).(string)
+	}
+
+	for _, part := range strings.Split(header, ";") {
+		if root, ok := strings.CutPrefix(strings.TrimSpace(part), "Root="); ok {
+			if len(root) <= maxIDLength && !strings.ContainsAny(root, " \t\r\n\"") {
+				return root
+			}
+		}
+	}
+	return ""
+}
+
+// LogLevelHeader carries a signed override raising the log level of a single
+// request, see LogLevels.WithOverride
+const LogLevelHeader = "X-Debug-Log"
+
+// LogLevels controls the log level while running
+type LogLevels interface {
+	// Refresh reloads the level from its source when it is stale
+	Refresh(ctx context.Context) error
+
+	// WithOverride verifies a signed override and returns a context whose
+	// entries are logged at the granted level
+	WithOverride(ctx context.Context, value string) (context.Context, error)
+}
+
+// LogLevel keeps the dynamic log level fresh and applies per-request
+// overrides from the X-Debug-Log header. Invalid overrides are logged and
+// ignored, so they never fail the request.
+func LogLevel(levels LogLevels, logger service.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			if err := levels.Refresh(ctx); err != nil {
+				logger.Warn(ctx, "Failed to refresh log level", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+
+			if value := r.Header.Get(LogLevelHeader); value != "" {
+				overridden, err := levels.WithOverride(ctx, value)
+				if err != nil {
+					logger.Warn(ctx, "Rejected log level override", map[string]interface{}{
+						"error": err.Error(),
+					})
+				} else {
+					ctx = overridden
+				}
+			}
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Logging logs every request once the response has been written
+func Logging(logger service.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := newResponseRecorder(w)
+			start := time.Now()
+
+			next.ServeHTTP(wrapped, r)
+
+			duration := time.Since(start)
+			logger.Info(r.Context(), "HTTP request processed", map[string]interface{}{
+				"method":      r.Method,
+				"path":        r.URL.Path,
+				"status_code": wrapped.statusCode,
+				"duration_ms": float64(duration.Nanoseconds()) / 1000000,
+				"user_agent":  r.Header.Get("User-Agent"),
+				"remote_addr": r.RemoteAddr,
+			})
+		})
+	}
+}
+
+// Recovery turns a panic in a later handler into a 500 response instead of
+// crashing the server or failing the Lambda invocation. The response carries
+// an error ID that is logged together with the stack trace and request details,
+// and every recovered panic is counted in metrics.
+func Recovery(logger service.Logger, metrics service.Metrics) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wrapped := newResponseRecorder(w)
+
+			defer func() {
+				recovered := recover()
+				if recovered == nil {
+					return
+				}
+				if recovered == http.ErrAbortHandler {
+					panic(recovered)
+				}
+
+				errorID := newID()
+				metrics.Add(r.Context(), service.MetricPanicsRecovered, 1, nil)
+
+				logger.Error(r.Context(), "Recovered from panic", map[string]interface{}{
+					"error_id":    errorID,
+					"panic":       fmt.Sprint(recovered),
+					"stack":       string(debug.Stack()),
+					"method":      r.Method,
+					"path":        r.URL.Path,
+					"remote_addr": r.RemoteAddr,
+				})
+
+				if !wrapped.wroteHeader {
+					wrapped.Header().Set("Content-Type", "application/json")
+					wrapped.WriteHeader(http.StatusInternalServerError)
+					json.NewEncoder(wrapped).Encode(router.ErrorResponse{
+						Error:   "Internal Server Error",
+						Message: "An unexpected error occurred",
+						ErrorID: errorID,
+					})
+				}
+			}()
+
+			next.ServeHTTP(wrapped, r)
+		})
+	}
+}
+
+// Timeout cancels the request context after d and answers 503 if the handler
+// has not responded by then. Panics in the handler are re-raised to the caller.
+func Timeout(d time.Duration) Middleware {
+	body, _ := json.Marshal(router.ErrorResponse{
+		Error:   "Service Unavailable",
+		Message: "Request timed out",
+	})
+
+	return func(next http.Handler) http.Handler {
+		timeoutHandler := http.TimeoutHandler(next, d, string(body))
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The timeout body is JSON; a handler that responds in time
+			// overrides this with its own Content-Type
+			w.Header().Set("Content-Type", "application/json")
+			timeoutHandler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// BodyLimit rejects bodies larger than maxBytes with 413. Declared lengths are
+// checked up front; chunked bodies fail when read past the limit.
+func BodyLimit(maxBytes int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > maxBytes {
+				writeError(w, http.StatusRequestEntityTooLarge, "Payload Too Large",
+					fmt.Sprintf("Request body must not exceed %d bytes", maxBytes))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, maxBytes)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeError writes a JSON error response in the API's error format
+func writeError(w http.ResponseWriter, statusCode int, errorTitle, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(router.ErrorResponse{
+		Error:   errorTitle,
+		Message: message,
+	})
+}
+
+// newID returns a random 128-bit identifier in hex
+func newID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
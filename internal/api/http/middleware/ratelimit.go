@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"slices"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/httpx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// RateLimit limits the request rate of each caller, answering 429 with
+// Retry-After once the limit is reached. Every response outside publicPaths
+// describes the limit in RateLimit-* headers. Callers are their merchant, so
+// every integration of a merchant shares its limit, else their principal,
+// else their address. Requests are allowed when the limit cannot be checked.
+func RateLimit(limiter service.RateLimiter, logger service.Logger, publicPaths []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(publicPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			key := rateLimitKey(r)
+			result, err := limiter.Allow(ctx, key)
+			if err != nil {
+				logger.Error(ctx, "Failed to check rate limit", map[string]interface{}{
+					"error":          err.Error(),
+					"rate_limit_key": key,
+				})
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			httpx.SetRateLimitHeaders(w.Header(), result)
+			if !result.Allowed {
+				logger.Warn(ctx, "Rejected request over the rate limit", map[string]interface{}{
+					"rate_limit_key": key,
+					"limit":          result.Limit,
+				})
+				writeError(w, http.StatusTooManyRequests, "Too Many Requests", "Rate limit exceeded, retry later")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimitKey identifies the caller of a request
+func rateLimitKey(r *http.Request) string {
+	if principal := requestctx.Principal(r.Context()); principal != nil {
+		if principal.MerchantID != "" {
+			return "merchant:" + principal.MerchantID
+		}
+		return "principal:" + principal.ID
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// stubRateLimiter allows limit requests per key, remembering the keys, and
+// fails with err if set
+type stubRateLimiter struct {
+	limit int64
+	err   error
+	taken map[string]int64
+	keys  []string
+}
+
+func (l *stubRateLimiter) Allow(ctx context.Context, key string) (service.RateLimitResult, error) {
+	l.keys = append(l.keys, key)
+	if l.err != nil {
+		return service.RateLimitResult{}, l.err
+	}
+	if l.taken == nil {
+		l.taken = make(map[string]int64)
+	}
+	if l.taken[key] >= l.limit {
+		return service.RateLimitResult{Limit: l.limit, Reset: 2 * time.Second, RetryAfter: 1500 * time.Millisecond}, nil
+	}
+	l.taken[key]++
+	return service.RateLimitResult{Allowed: true, Limit: l.limit, Remaining: l.limit - l.taken[key], Reset: time.Second}, nil
+}
+
+func TestRateLimit(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	limiter := &stubRateLimiter{limit: 2}
+	handler := RateLimit(limiter, logger, DefaultPublicPaths)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	serve := func(path string, principal *entity.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = "10.0.0.1:4321"
+		if principal != nil {
+			req = req.WithContext(requestctx.WithPrincipal(req.Context(), principal))
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	merchant := &entity.Principal{ID: "key-1", MerchantID: "merchant-456"}
+
+	// Act & Assert: the limit is described on allowed requests
+	rec := serve("/chargebacks", merchant)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("RateLimit-Remaining") != "1" || rec.Header().Get("RateLimit-Reset") != "1" {
+		t.Errorf("Expected the RateLimit headers, got %v", rec.Header())
+	}
+	if rec.Header().Get("Retry-After") != "" {
+		t.Errorf("Expected no Retry-After on allowed requests, got %q", rec.Header().Get("Retry-After"))
+	}
+
+	// Another key of the same merchant shares its limit
+	serve("/chargebacks", &entity.Principal{ID: "key-2", MerchantID: "merchant-456"})
+	rec = serve("/chargebacks", merchant)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 past the limit, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "2" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected Retry-After rounded up to 2s, got %v", rec.Header())
+	}
+	if _, ok := logger.find("Rejected request over the rate limit"); !ok {
+		t.Error("Expected the rejection to be logged")
+	}
+
+	// Public paths are not limited
+	if rec := serve("/health", merchant); rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "" {
+		t.Errorf("Expected public paths to be served unlimited, got %d %v", rec.Code, rec.Header())
+	}
+
+	serve("/chargebacks", &entity.Principal{ID: "user-123"})
+	serve("/chargebacks", nil)
+	want := []string{"merchant:merchant-456", "merchant:merchant-456", "merchant:merchant-456", "principal:user-123", "ip:10.0.0.1"}
+	if len(limiter.keys) != len(want) {
+		t.Fatalf("Expected keys %v, got %v", want, limiter.keys)
+	}
+	for i, key := range want {
+		if limiter.keys[i] != key {
+			t.Errorf("Expected key %q, got %q", key, limiter.keys[i])
+		}
+	}
+}
+
+func TestRateLimit_Unavailable(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	handler := RateLimit(&stubRateLimiter{err: errors.New("throttled")}, logger, DefaultPublicPaths)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/chargebacks", nil))
+
+	// Assert
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected the request to be allowed, got %d", rec.Code)
+	}
+	if _, ok := logger.find("Failed to check rate limit"); !ok {
+		t.Error("Expected the failure to be logged")
+	}
+}
+
+func TestStandard_RateLimit(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	limiter := &stubRateLimiter{limit: 1}
+	handler := newStandardHandler(logger, Options{Authenticator: &stubAuthenticator{}, RateLimiter: limiter})
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+		req.Header.Set(APIKeyHeader, "valid")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// Act & Assert: callers are limited by the merchant they authenticated as
+	if rec := serve(); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", rec.Code)
+	}
+	rec := serve()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429, got %d", rec.Code)
+	}
+	if rec.Header().Get(requestctx.RequestIDHeader) == "" {
+		t.Error("Expected rejected requests to carry a request ID")
+	}
+	if limiter.keys[0] != "merchant:merchant-456" {
+		t.Errorf("Expected the authenticated merchant as key, got %v", limiter.keys)
+	}
+}
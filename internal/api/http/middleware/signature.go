@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// Headers of a request signed by an acquirer
+const (
+	SignatureSourceHeader    = "X-Signature-Source"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	SignatureHeader          = "X-Signature"
+)
+
+// DefaultSignedPaths are the callbacks acquirers push dispute notifications to
+var DefaultSignedPaths = []string{"/callbacks/chargebacks"}
+
+// Signature verifies the requests to signedPaths, rejecting unsigned, stale,
+// replayed or forged ones with 401. The verified source is stored in the
+// request context as the principal and as the callback source, so later
+// middlewares skip authentication and the chargeback records its channel.
+// The body is read to be verified and restored for the handler. Failures to
+// check signatures, such as an unreachable nonce store, get 503.
+func Signature(verifier service.SignatureVerifier, logger service.Logger, signedPaths []string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !slices.Contains(signedPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx := r.Context()
+			request := service.SignedRequest{
+				Source:    strings.TrimSpace(r.Header.Get(SignatureSourceHeader)),
+				Timestamp: strings.TrimSpace(r.Header.Get(SignatureTimestampHeader)),
+				Nonce:     strings.TrimSpace(r.Header.Get(SignatureNonceHeader)),
+				Signature: strings.TrimSpace(r.Header.Get(SignatureHeader)),
+			}
+			if request.Source == "" || request.Timestamp == "" || request.Nonce == "" || request.Signature == "" {
+				logger.Warn(ctx, "Rejected signature", map[string]interface{}{
+					"reason": "missing signature headers",
+				})
+				writeInvalidSignature(w)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					writeError(w, http.StatusRequestEntityTooLarge, "Payload Too Large", "Request body is too large")
+					return
+				}
+				writeError(w, http.StatusBadRequest, "Bad Request", "Failed to read request body")
+				return
+			}
+			request.Body = body
+
+			principal, err := verifier.Verify(ctx, request)
+			if err != nil {
+				if errors.Is(err, service.ErrInvalidSignature) {
+					logger.Warn(ctx, "Rejected signature", map[string]interface{}{
+						"reason":          err.Error(),
+						"callback_source": request.Source,
+					})
+					writeInvalidSignature(w)
+					return
+				}
+
+				logger.Error(ctx, "Failed to check signature", map[string]interface{}{
+					"error":           err.Error(),
+					"callback_source": request.Source,
+				})
+				writeError(w, http.StatusServiceUnavailable, "Service Unavailable", "Signature verification is temporarily unavailable")
+				return
+			}
+
+			trace.SpanFromContext(ctx).SetAttributes(
+				attribute.String("enduser.id", principal.ID),
+				attribute.String("auth.method", string(principal.Method)),
+			)
+
+			ctx = requestctx.WithCallbackSource(requestctx.WithPrincipal(ctx, principal), principal.ID)
+			r = r.WithContext(ctx)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeInvalidSignature answers 401. The reason is logged, not returned.
+func writeInvalidSignature(w http.ResponseWriter) {
+	writeError(w, http.StatusUnauthorized, "Unauthorized", "Missing or invalid signature")
+}
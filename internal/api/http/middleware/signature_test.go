@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// stubSignatureVerifier accepts the signature "valid" of acquirer-a,
+// remembering the request, and fails with err if set
+type stubSignatureVerifier struct {
+	err     error
+	request service.SignedRequest
+}
+
+func (v *stubSignatureVerifier) Verify(ctx context.Context, request service.SignedRequest) (*entity.Principal, error) {
+	v.request = request
+	switch {
+	case v.err != nil:
+		return nil, v.err
+	case request.Source == "acquirer-a" && request.Signature == "valid":
+		return &entity.Principal{ID: "acquirer-a", Method: entity.AuthMethodSignature, Roles: []entity.Role{entity.RoleAcquirer}}, nil
+	default:
+		return nil, fmt.Errorf("%w: signature does not match", service.ErrInvalidSignature)
+	}
+}
+
+// newSignedRequest returns a callback signed with signature
+func newSignedRequest(path, body, signature string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(SignatureSourceHeader, "acquirer-a")
+	req.Header.Set(SignatureTimestampHeader, "1705312800")
+	req.Header.Set(SignatureNonceHeader, "nonce-1")
+	req.Header.Set(SignatureHeader, signature)
+	return req
+}
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		name           string
+		request        func() *http.Request
+		expectedStatus int
+		expectedSource string
+		expectedLog    string
+	}{
+		{
+			name:           "valid signature",
+			request:        func() *http.Request { return newSignedRequest("/callbacks/chargebacks", `{"amount":100}`, "valid") },
+			expectedStatus: http.StatusOK,
+			expectedSource: "acquirer-a",
+		},
+		{
+			name:           "invalid signature",
+			request:        func() *http.Request { return newSignedRequest("/callbacks/chargebacks", `{"amount":100}`, "forged") },
+			expectedStatus: http.StatusUnauthorized,
+			expectedLog:    "Rejected signature",
+		},
+		{
+			name: "missing headers",
+			request: func() *http.Request {
+				req := newSignedRequest("/callbacks/chargebacks", `{"amount":100}`, "valid")
+				req.Header.Del(SignatureNonceHeader)
+				return req
+			},
+			expectedStatus: http.StatusUnauthorized,
+			expectedLog:    "Rejected signature",
+		},
+		{
+			name: "unsigned path",
+			request: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/chargebacks", strings.NewReader(`{}`))
+			},
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			logger := &recordingLogger{}
+			verifier := &stubSignatureVerifier{}
+			var source, body string
+			var principal *entity.Principal
+			handler := Signature(verifier, logger, DefaultSignedPaths)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				source = requestctx.CallbackSource(r.Context())
+				principal = requestctx.Principal(r.Context())
+				data, _ := io.ReadAll(r.Body)
+				body = string(data)
+				w.WriteHeader(http.StatusOK)
+			}))
+			rec := httptest.NewRecorder()
+			req := tt.request()
+
+			// Act
+			handler.ServeHTTP(rec, req)
+
+			// Assert
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if source != tt.expectedSource {
+				t.Errorf("Expected callback source %q, got %q", tt.expectedSource, source)
+			}
+			if tt.expectedStatus == http.StatusOK && body == "" {
+				t.Error("Expected the body to reach the handler")
+			}
+			if tt.expectedSource != "" {
+				if principal == nil || principal.ID != tt.expectedSource {
+					t.Errorf("Expected the source as principal, got %+v", principal)
+				}
+				if string(verifier.request.Body) != `{"amount":100}` || verifier.request.Timestamp != "1705312800" || verifier.request.Nonce != "nonce-1" {
+					t.Errorf("Expected the signed request to be verified, got %+v", verifier.request)
+				}
+			}
+			if tt.expectedLog != "" {
+				if _, ok := logger.find(tt.expectedLog); !ok {
+					t.Errorf("Expected %q to be logged", tt.expectedLog)
+				}
+				if response := decodeError(t, rec); response.Message != "Missing or invalid signature" {
+					t.Errorf("Expected 'Missing or invalid signature', got %q", response.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestSignature_Unavailable(t *testing.T) {
+	// Arrange
+	logger := &recordingLogger{}
+	handler := Signature(&stubSignatureVerifier{err: errors.New("throttled")}, logger, DefaultSignedPaths)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+
+	// Act
+	handler.ServeHTTP(rec, newSignedRequest("/callbacks/chargebacks", `{}`, "valid"))
+
+	// Assert
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", rec.Code)
+	}
+	if _, ok := logger.find("Failed to check signature"); !ok {
+		t.Error("Expected the failure to be logged")
+	}
+}
+
+func TestStandard_Signature(t *testing.T) {
+	// Arrange
+	handler := newStandardHandler(&recordingLogger{}, Options{
+		Authenticator:     &stubAuthenticator{},
+		SignatureVerifier: &stubSignatureVerifier{},
+		SignedPaths:       []string{"/echo"},
+		MaxBodyBytes:      8,
+	})
+	serve := func(req *http.Request) int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Act & Assert: signed callbacks need no other credentials
+	if code := serve(newSignedRequest("/echo", "01234", "valid")); code != http.StatusOK {
+		t.Errorf("Expected a signed callback to be served, got %d", code)
+	}
+	if code := serve(httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("01234"))); code != http.StatusUnauthorized {
+		t.Errorf("Expected an unsigned callback to be rejected, got %d", code)
+	}
+
+	// Bodies are limited before being read for their signature
+	req := newSignedRequest("/echo", "0123456789", "valid")
+	req.ContentLength = -1
+	if code := serve(req); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", code)
+	}
+
+	// Other paths still require credentials
+	if code := serve(httptest.NewRequest(http.MethodGet, "/ok", nil)); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", code)
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// instrumentationName identifies the spans created by the middlewares
+const instrumentationName = "github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+
+// Tracing opens a span around each request. Requests served by the local
+// server continue the trace of their W3C traceparent or X-Ray header; those
+// served by the Lambda adapter are children of its handler span. The trace ID
+// replaces the one read by RequestID, so log entries point at the span tree.
+// Only the method, path and status are recorded, never bodies or headers.
+func Tracing() Middleware {
+	tracer := otel.Tracer(instrumentationName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+
+			kind := trace.SpanKindInternal
+			if parent := trace.SpanContextFromContext(ctx); !parent.IsValid() || parent.IsRemote() {
+				ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+				kind = trace.SpanKindServer
+			}
+
+			ctx, span := tracer.Start(ctx, "ServeHTTP "+r.Method,
+				trace.WithSpanKind(kind),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			if spanContext := span.SpanContext(); spanContext.IsValid() {
+				ctx = requestctx.WithTraceID(ctx, tracing.XRayTraceID(spanContext.TraceID()))
+			}
+
+			recorder := newResponseRecorder(w)
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			span.SetAttributes(attribute.Int("http.response.status_code", recorder.statusCode))
+			if recorder.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(recorder.statusCode))
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+)
+
+// recordSpans installs a global tracer provider keeping the finished spans.
+// Tracers must be created after the call, as the middlewares keep theirs.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+// spanAttribute returns the value of an attribute of a finished span
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestStandard_Tracing(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		traceparent string
+		wantStatus  int
+		wantError   bool
+	}{
+		{
+			name:        "continues the trace of the caller",
+			path:        "/ok",
+			traceparent: "00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "starts a trace without a caller",
+			path:       "/missing",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "marks server errors",
+			path:       "/panic",
+			wantStatus: http.StatusInternalServerError,
+			wantError:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans := recordSpans(t)
+			logger := &recordingLogger{}
+			handler := newStandardHandler(logger, Options{})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			ended := spans.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(ended))
+			}
+			span := ended[0]
+
+			if span.Name() != "ServeHTTP GET" {
+				t.Errorf("Expected span 'ServeHTTP GET', got '%s'", span.Name())
+			}
+			if span.SpanKind() != trace.SpanKindServer {
+				t.Errorf("Expected a server span, got %v", span.SpanKind())
+			}
+			if tt.traceparent != "" {
+				if got := span.SpanContext().TraceID().String(); got != "5759e988bd862e3fe1be46a994272793" {
+					t.Errorf("Expected the trace of the caller, got %s", got)
+				}
+				if got := span.Parent().SpanID().String(); got != "53995c3f42cd8ad8" {
+					t.Errorf("Expected the caller as parent, got %s", got)
+				}
+			}
+			if value, _ := spanAttribute(span, "url.path"); value.AsString() != tt.path {
+				t.Errorf("Expected url.path '%s', got '%s'", tt.path, value.AsString())
+			}
+			if value, _ := spanAttribute(span, "http.response.status_code"); value.AsInt64() != int64(tt.wantStatus) {
+				t.Errorf("Expected status code %d, got %d", tt.wantStatus, value.AsInt64())
+			}
+			if got := span.Status().Code == codes.Error; got != tt.wantError {
+				t.Errorf("Expected error status %v, got %v", tt.wantError, span.Status())
+			}
+
+			entry, ok := logger.find("HTTP request processed")
+			if !ok {
+				t.Fatal("Expected request to be logged")
+			}
+			if want := tracing.XRayTraceID(span.SpanContext().TraceID()); entry.Fields["trace_id"] != want {
+				t.Errorf("Expected trace_id '%s' in the log entry, got %v", want, entry.Fields["trace_id"])
+			}
+		})
+	}
+}
+
+func TestTracing_NestsUnderLocalSpan(t *testing.T) {
+	spans := recordSpans(t)
+	handler := Tracing()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ctx, parent := otel.Tracer("test").Start(t.Context(), "lambda.handler")
+	req := httptest.NewRequest(http.MethodPost, "/chargebacks", nil).WithContext(ctx)
+	// A local span takes precedence over the headers
+	req.Header.Set("traceparent", "00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	parent.End()
+
+	ended := spans.Ended()
+	if len(ended) != 2 {
+		t.Fatalf("Expected 2 spans, got %d", len(ended))
+	}
+	child := ended[0]
+	if child.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("Expected the handler span as parent, got %s", child.Parent().SpanID())
+	}
+	if child.SpanKind() != trace.SpanKindInternal {
+		t.Errorf("Expected an internal span, got %v", child.SpanKind())
+	}
+}
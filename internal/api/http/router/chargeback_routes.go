@@ -0,0 +1,263 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/httpx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/openapi"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+// CreateChargebackUseCase interface defines the contract for creating chargebacks
+type CreateChargebackUseCase interface {
+	Execute(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error)
+}
+
+// Config holds the settings shared by every adapter serving the API
+type Config struct {
+	// ServiceName is reported by the health endpoint
+	ServiceName string
+
+	// Version is reported in the OpenAPI document
+	Version string
+
+	// MaxBodyBytes limits the size of request bodies, DefaultMaxBodyBytes if zero
+	MaxBodyBytes int64
+
+	// Metrics records rejected request fields, discarded if nil
+	Metrics service.Metrics
+
+	// Health probes the dependencies reported by the readiness endpoint;
+	// readiness reports no dependencies if nil
+	Health service.HealthChecker
+}
+
+// chargebackAPI groups the handlers of the chargeback API
+type chargebackAPI struct {
+	config             Config
+	createChargebackUC CreateChargebackUseCase
+	logger             service.Logger
+	openAPI            *openapi.Document
+}
+
+// NewChargebackRouter creates the router with every chargeback API route registered
+func NewChargebackRouter(config Config, createChargebackUC CreateChargebackUseCase, logger service.Logger) *Router {
+	if config.Version == "" {
+		config.Version = "1.0.0"
+	}
+
+	if config.MaxBodyBytes <= 0 {
+		config.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+
+	if config.Metrics == nil {
+		config.Metrics = service.NopMetrics{}
+	}
+
+	api := &chargebackAPI{
+		config:             config,
+		createChargebackUC: createChargebackUC,
+		logger:             logger,
+		openAPI:            NewOpenAPIDocument(config),
+	}
+
+	rt := New()
+
+	// Health check endpoints: liveness never touches a dependency, readiness
+	// probes them all. The bare path reports readiness.
+	rt.HandleFunc(http.MethodGet, "/health", api.handleReadiness)
+	rt.HandleFunc(http.MethodGet, "/health/live", api.handleLiveness)
+	rt.HandleFunc(http.MethodGet, "/health/ready", api.handleReadiness)
+
+	// API contract
+	rt.HandleFunc(http.MethodGet, "/openapi.json", api.handleOpenAPI)
+
+	// Chargeback endpoints
+	rt.HandleFunc(http.MethodPost, "/chargebacks", api.handleCreateChargeback)
+	rt.HandleFunc(http.MethodPost, "/api/v1/chargebacks", api.handleCreateChargeback)
+
+	// Dispute notifications pushed by acquirers, signed instead of carrying
+	// credentials
+	rt.HandleFunc(http.MethodPost, "/callbacks/chargebacks", api.handleCreateChargeback)
+
+	return rt
+}
+
+// handleLiveness reports that the process can serve requests
+func (a *chargebackAPI) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, HealthResponse{
+		Service:   a.config.ServiceName,
+		Status:    HealthStatusOK,
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+}
+
+// handleReadiness reports whether every dependency is up, with 503 if not.
+// Probe errors name tables, ARNs and endpoints, so they are logged and only
+// returned to callers presenting valid credentials, as the path is public.
+func (a *chargebackAPI) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var checks []service.DependencyHealth
+	if a.config.Health != nil {
+		checks = a.config.Health.Check(ctx)
+	}
+
+	response := HealthResponse{
+		Service:   a.config.ServiceName,
+		Status:    HealthStatusOK,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Checks:    checks,
+	}
+	statusCode := http.StatusOK
+
+	if requestctx.Principal(ctx) == nil && len(checks) > 0 {
+		response.Checks = make([]service.DependencyHealth, len(checks))
+		for i, check := range checks {
+			check.LastError = ""
+			check.LastErrorAt = nil
+			response.Checks[i] = check
+		}
+	}
+
+	var down []string
+	errs := make(map[string]interface{})
+	for _, check := range checks {
+		if check.Status != service.HealthUp {
+			down = append(down, check.Name)
+			errs[check.Name] = check.LastError
+		}
+	}
+	if len(down) > 0 {
+		a.logger.Warn(ctx, "Dependencies are down", map[string]interface{}{
+			"dependencies": down,
+			"errors":       errs,
+		})
+		response.Status = HealthStatusUnavailable
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, statusCode, response)
+}
+
+// handleOpenAPI serves the OpenAPI document describing this router
+func (a *chargebackAPI) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, a.openAPI)
+}
+
+// handleCreateChargeback handles chargeback creation requests
+func (a *chargebackAPI) handleCreateChargeback(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	// Parse and validate request body
+	var req usecase.CreateChargebackRequest
+	schema := openapi.Ref("CreateChargebackRequest")
+	if reqErr := decodeJSONBody(r, a.config.MaxBodyBytes, a.openAPI, schema, &req); reqErr != nil {
+		a.logger.Warn(ctx, "Rejected chargeback request body", map[string]interface{}{
+			"status_code": reqErr.statusCode,
+			"reason":      reqErr.message,
+			"fields":      violationPointers(reqErr.fields),
+		})
+		a.recordViolations(ctx, schema, reqErr.fields)
+		writeJSON(w, reqErr.statusCode, ErrorResponse{
+			Error:   reqErr.errorTitle,
+			Message: reqErr.message,
+			Fields:  reqErr.fields,
+		})
+		return
+	}
+
+	// Every later log entry of the request carries the merchant ID
+	ctx = requestctx.WithMerchantID(ctx, req.MerchantID)
+
+	// Execute use case
+	chargeback, err := a.createChargebackUC.Execute(ctx, req)
+	if err != nil {
+		// Denials were logged with their reason by the policy when it
+		// decided; the reason names principals and merchants, so callers
+		// only learn they were denied
+		if errors.Is(err, usecase.ErrForbidden) {
+			writeError(w, http.StatusForbidden, "Forbidden", "forbidden")
+			return
+		}
+
+		// The existing chargeback may belong to another merchant, so the
+		// response only says the transaction is taken
+		if errors.Is(err, usecase.ErrChargebackExists) {
+			a.logger.Warn(ctx, "Rejected chargeback of an already disputed transaction", map[string]interface{}{
+				"transaction_id": req.TransactionID,
+			})
+			writeError(w, http.StatusConflict, "Conflict", "chargeback already exists")
+			return
+		}
+
+		var exceeded *usecase.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			a.logger.Warn(ctx, "Rejected chargeback over the daily quota", map[string]interface{}{
+				"quota": exceeded.Result.Limit,
+			})
+			httpx.SetRateLimitHeaders(w.Header(), exceeded.Result)
+			writeError(w, http.StatusTooManyRequests, "Too Many Requests", exceeded.Error())
+			return
+		}
+
+		a.logger.Error(ctx, "Failed to create chargeback", map[string]interface{}{
+			"error": err.Error(),
+		})
+
+		// Check if it's a validation error
+		if strings.Contains(err.Error(), "validation") {
+			writeError(w, http.StatusBadRequest, "Validation Error", err.Error())
+			return
+		}
+
+		writeError(w, http.StatusInternalServerError, "Internal Server Error", "Failed to create chargeback")
+		return
+	}
+
+	ctx = requestctx.WithChargebackID(ctx, chargeback.ID)
+	a.logger.Info(ctx, "Chargeback created successfully")
+
+	writeJSON(w, http.StatusCreated, chargeback)
+}
+
+// violationPointers returns the JSON pointers of the violations, for logging
+func violationPointers(violations []openapi.Violation) []string {
+	pointers := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		pointers = append(pointers, violation.Pointer)
+	}
+	return pointers
+}
+
+// recordViolations counts the rejected fields of a request body. Fields
+// unknown to the schema are counted together, as their names are chosen by
+// the client.
+func (a *chargebackAPI) recordViolations(ctx context.Context, schema *openapi.Schema, violations []openapi.Violation) {
+	var properties map[string]*openapi.Schema
+	if resolved := a.openAPI.Resolve(schema); resolved != nil {
+		properties = resolved.Properties
+	}
+
+	for _, violation := range violations {
+		field, _, _ := strings.Cut(strings.TrimPrefix(violation.Pointer, "/"), "/")
+		switch {
+		case field == "":
+			field = "body"
+		case properties[field] == nil:
+			field = "unknown"
+		}
+		a.config.Metrics.Add(ctx, service.MetricValidationFailures, 1, service.Labels{
+			"field":  field,
+			"source": "schema",
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/openapi"
+)
+
+// DefaultMaxBodyBytes is the request body limit used when Config.MaxBodyBytes is zero
+const DefaultMaxBodyBytes int64 = 1 << 20
+
+// requestError is a decode failure ready to be written as an error response
+type requestError struct {
+	statusCode int
+	errorTitle string
+	message    string
+	fields     []openapi.Violation
+}
+
+// decodeJSONBody strictly decodes the request body into dst. The body is
+// limited to maxBytes, must hold exactly one JSON value and is validated
+// against the schema before being decoded, so unknown or wrong-case fields
+// and type mismatches are reported as field-level errors with JSON pointers.
+func decodeJSONBody(r *http.Request, maxBytes int64, doc *openapi.Document, schema *openapi.Schema, dst interface{}) *requestError {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+
+	// The body may already be limited by a middleware with a lower limit
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		maxBytes = maxBytesErr.Limit
+	}
+
+	if maxBytesErr != nil || int64(len(body)) > maxBytes {
+		return &requestError{
+			statusCode: http.StatusRequestEntityTooLarge,
+			errorTitle: "Payload Too Large",
+			message:    fmt.Sprintf("Request body must not exceed %d bytes", maxBytes),
+		}
+	}
+
+	if err != nil {
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Bad Request",
+			message:    "Failed to read request body",
+		}
+	}
+
+	// Decode into a generic value first so the schema sees exactly what was sent
+	var raw interface{}
+	if reqErr := decodeSingleValue(body, &raw); reqErr != nil {
+		return reqErr
+	}
+
+	if violations := doc.Validate(schema, raw); len(violations) > 0 {
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Validation Error",
+			message:    "Request body does not match the expected schema",
+			fields:     violations,
+		}
+	}
+
+	if reqErr := decodeSingleValue(body, dst); reqErr != nil {
+		return reqErr
+	}
+
+	return nil
+}
+
+// decodeSingleValue decodes exactly one JSON value, rejecting unknown fields
+// and trailing data
+func decodeSingleValue(body []byte, dst interface{}) *requestError {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(dst); err != nil {
+		return decodeFailure(err)
+	}
+
+	if _, err := decoder.Token(); err != io.EOF {
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Bad Request",
+			message:    "Request body must contain a single JSON object",
+		}
+	}
+
+	return nil
+}
+
+// decodeFailure converts encoding/json errors into client-facing messages
+// without echoing Go type names or body fragments
+func decodeFailure(err error) *requestError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Bad Request",
+			message:    "Request body must not be empty",
+		}
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		message := "Request body is not valid JSON"
+		if syntaxErr != nil {
+			message = fmt.Sprintf("Request body is not valid JSON (offset %d)", syntaxErr.Offset)
+		}
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Bad Request",
+			message:    message,
+		}
+	case errors.As(err, &typeErr):
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Validation Error",
+			message:    "Request body does not match the expected schema",
+			fields: []openapi.Violation{{
+				Pointer: "/" + strings.ReplaceAll(typeErr.Field, ".", "/"),
+				Message: "must be " + typeErr.Type.Kind().String(),
+			}},
+		}
+	default:
+		return &requestError{
+			statusCode: http.StatusBadRequest,
+			errorTitle: "Validation Error",
+			message:    "Request body does not match the expected schema",
+			fields: []openapi.Violation{{
+				Pointer: "",
+				Message: err.Error(),
+			}},
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+func TestCreateChargeback_StrictDecoding(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedError    string
+		expectedPointers []string
+	}{
+		{
+			name:           "empty body",
+			body:           ``,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Bad Request",
+		},
+		{
+			name:           "malformed JSON",
+			body:           `{"transaction_id":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Bad Request",
+		},
+		{
+			name:           "trailing garbage",
+			body:           validCreatePayload + `{"again":true}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Bad Request",
+		},
+		{
+			name:             "unknown field",
+			body:             strings.Replace(validCreatePayload, `"currency"`, `"coin":"x","currency"`, 1),
+			expectedStatus:   http.StatusBadRequest,
+			expectedError:    "Validation Error",
+			expectedPointers: []string{"/coin"},
+		},
+		{
+			name:             "wrong-case key",
+			body:             strings.Replace(validCreatePayload, `"merchant_id"`, `"Merchant_ID"`, 1),
+			expectedStatus:   http.StatusBadRequest,
+			expectedError:    "Validation Error",
+			expectedPointers: []string{"/merchant_id", "/Merchant_ID"},
+		},
+		{
+			name:             "wrong type",
+			body:             strings.Replace(validCreatePayload, `"amount":10`, `"amount":"10"`, 1),
+			expectedStatus:   http.StatusBadRequest,
+			expectedError:    "Validation Error",
+			expectedPointers: []string{"/amount"},
+		},
+		{
+			name:             "invalid reason",
+			body:             strings.Replace(validCreatePayload, `"fraud"`, `"bored"`, 1),
+			expectedStatus:   http.StatusBadRequest,
+			expectedError:    "Validation Error",
+			expectedPointers: []string{"/reason"},
+		},
+		{
+			name:             "invalid date",
+			body:             strings.Replace(validCreatePayload, `"2024-01-15T10:30:00Z"`, `"15/01/2024"`, 1),
+			expectedStatus:   http.StatusBadRequest,
+			expectedError:    "Validation Error",
+			expectedPointers: []string{"/transaction_date"},
+		},
+		{
+			name:           "date only",
+			body:           strings.Replace(validCreatePayload, `"2024-01-15T10:30:00Z"`, `"2024-01-15"`, 1),
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "fractional seconds",
+			body:           strings.Replace(validCreatePayload, `"2024-01-15T10:30:00Z"`, `"2024-01-15T10:30:00.123-03:00"`, 1),
+			expectedStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rt := newTestRouter(successfulUseCase())
+
+			// Act
+			req := httptest.NewRequest(http.MethodPost, "/chargebacks", bytes.NewReader([]byte(tt.body)))
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != tt.expectedStatus {
+				t.Fatalf("Expected status code %d, got %d (body: %s)", tt.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+
+			if tt.expectedError == "" {
+				return
+			}
+
+			var response ErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if response.Error != tt.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", tt.expectedError, response.Error)
+			}
+
+			if strings.Contains(response.Message, "json:") || strings.Contains(response.Message, "Go value") {
+				t.Errorf("Message should not expose Go decoder errors: %s", response.Message)
+			}
+
+			var pointers []string
+			for _, field := range response.Fields {
+				pointers = append(pointers, field.Pointer)
+			}
+
+			if strings.Join(pointers, ",") != strings.Join(tt.expectedPointers, ",") {
+				t.Errorf("Expected pointers %v, got %v", tt.expectedPointers, pointers)
+			}
+		})
+	}
+}
+
+func TestCreateChargeback_DateOnlyIsMidnightUTC(t *testing.T) {
+	// Arrange
+	var received usecase.CreateChargebackRequest
+	rt := newTestRouter(&MockCreateChargebackUseCase{
+		ExecuteFunc: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+			received = req
+			return &usecase.CreateChargebackResponse{ID: "cb-1"}, nil
+		},
+	})
+	body := strings.Replace(validCreatePayload, `"2024-01-15T10:30:00Z"`, `"2024-01-15"`, 1)
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/chargebacks", bytes.NewReader([]byte(body)))
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	expected := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	if !received.TransactionDate.Equal(expected) {
+		t.Errorf("Expected transaction date %v, got %v", expected, received.TransactionDate)
+	}
+}
+
+func TestCreateChargeback_BodyTooLarge(t *testing.T) {
+	// Arrange
+	rt := NewChargebackRouter(Config{MaxBodyBytes: 64}, successfulUseCase(), &testLogger{})
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/chargebacks", bytes.NewReader([]byte(validCreatePayload)))
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, recorder.Code)
+	}
+}
+
+func TestCreateChargeback_BodyLimitedByMiddleware(t *testing.T) {
+	// Arrange - the body is already wrapped by a lower limit than the router's
+	rt := NewChargebackRouter(Config{}, successfulUseCase(), &testLogger{})
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/chargebacks", bytes.NewReader([]byte(validCreatePayload)))
+	recorder := httptest.NewRecorder()
+	req.Body = http.MaxBytesReader(recorder, req.Body, 32)
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status code %d, got %d", http.StatusRequestEntityTooLarge, recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "must not exceed 32 bytes") {
+		t.Errorf("Expected the middleware limit in the message, got %s", recorder.Body.String())
+	}
+}
+
+func TestCreateChargeback_ValidationMetrics(t *testing.T) {
+	// Arrange
+	memory := metrics.NewMemory()
+	rt := NewChargebackRouter(Config{Metrics: memory}, successfulUseCase(), &testLogger{})
+
+	body := strings.Replace(validCreatePayload, `"amount":10`, `"amount":"10","card_4111111111111111":true`, 1)
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/chargebacks", strings.NewReader(body))
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status code %d, got %d", http.StatusBadRequest, recorder.Code)
+	}
+	if got := memory.Value("chargeback_validation_failures_total", service.Labels{"field": "amount", "source": "schema"}); got != 1 {
+		t.Errorf("Expected one amount failure, got %v", got)
+	}
+	// Field names chosen by the client are not used as label values
+	if got := memory.Value("chargeback_validation_failures_total", service.Labels{"field": "unknown", "source": "schema"}); got != 1 {
+		t.Errorf("Expected one unknown field failure, got %v", got)
+	}
+}
@@ -0,0 +1,119 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/httpx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/openapi"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+// Overall statuses reported by the health endpoints
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthResponse is the body returned by the health endpoints. Checks is
+// only set by readiness.
+type HealthResponse struct {
+	Service   string                     `json:"service"`
+	Status    string                     `json:"status"`
+	Timestamp string                     `json:"timestamp"`
+	Checks    []service.DependencyHealth `json:"checks,omitempty"`
+}
+
+// NewOpenAPIDocument describes every route registered by NewChargebackRouter.
+// Schemas are derived from the request, response and error types, so the spec
+// follows the code instead of being maintained by hand.
+func NewOpenAPIDocument(config Config) *openapi.Document {
+	generator := openapi.NewSchemaGenerator()
+	openapi.RegisterEnum(generator, entity.ValidReasons()...)
+	openapi.RegisterEnum(generator, entity.ValidStatuses()...)
+	openapi.RegisterEnum(generator, entity.ValidChannels()...)
+	openapi.RegisterEnum(generator, service.HealthUp, service.HealthDown)
+
+	doc := openapi.NewDocument("Chargeback API", config.Version)
+
+	errorSchema := doc.AddSchema("ErrorResponse", generator.Generate(ErrorResponse{}))
+	healthSchema := doc.AddSchema("HealthResponse", generator.Generate(HealthResponse{}))
+	createRequestSchema := doc.AddSchema("CreateChargebackRequest", generator.Generate(usecase.CreateChargebackRequest{}))
+	createResponseSchema := doc.AddSchema("CreateChargebackResponse", generator.Generate(usecase.CreateChargebackResponse{}))
+
+	errorResponse := func(description string) openapi.Response {
+		return openapi.Response{
+			Description: description,
+			Content:     openapi.JSONContent(errorSchema),
+		}
+	}
+
+	readiness := func(operationID string) openapi.Operation {
+		return openapi.Operation{
+			OperationID: operationID,
+			Summary:     "Report whether every dependency is up",
+			Responses: map[string]openapi.Response{
+				"200": {Description: "Every dependency is up", Content: openapi.JSONContent(healthSchema)},
+				"503": {Description: "A dependency is down", Content: openapi.JSONContent(healthSchema)},
+			},
+		}
+	}
+
+	doc.AddOperation(http.MethodGet, "/health", readiness("getHealth"))
+	doc.AddOperation(http.MethodGet, "/health/ready", readiness("getReadiness"))
+	doc.AddOperation(http.MethodGet, "/health/live", openapi.Operation{
+		OperationID: "getLiveness",
+		Summary:     "Report that the service is running, without probing dependencies",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Service is running", Content: openapi.JSONContent(healthSchema)},
+		},
+	})
+
+	doc.AddOperation(http.MethodGet, "/openapi.json", openapi.Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "Return this OpenAPI document",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "OpenAPI document", Content: openapi.JSONContent(&openapi.Schema{Type: "object"})},
+		},
+	})
+
+	rateLimited := errorResponse("Request rate or daily chargeback quota of the caller exceeded")
+	rateLimited.Headers = make(map[string]openapi.Header, len(httpx.RateLimitHeaders))
+	for _, name := range httpx.RateLimitHeaders {
+		rateLimited.Headers[name] = openapi.Header{Schema: &openapi.Schema{Type: "integer"}}
+	}
+
+	createChargeback := func(operationID string) openapi.Operation {
+		return openapi.Operation{
+			OperationID: operationID,
+			Summary:     "Create a chargeback",
+			RequestBody: &openapi.RequestBody{
+				Required: true,
+				Content:  openapi.JSONContent(createRequestSchema),
+			},
+			Responses: map[string]openapi.Response{
+				"201": {Description: "Chargeback created", Content: openapi.JSONContent(createResponseSchema)},
+				"400": errorResponse("Malformed request or validation error"),
+				"401": errorResponse("Missing or invalid credentials, when authentication is enabled"),
+				"403": errorResponse("Caller may not create chargebacks for the merchant"),
+				"409": errorResponse("Transaction already has a chargeback"),
+				"413": errorResponse("Request body too large"),
+				"429": rateLimited,
+				"500": errorResponse("Unexpected error"),
+				"503": errorResponse("Credentials could not be checked"),
+			},
+		}
+	}
+
+	doc.AddOperation(http.MethodPost, "/chargebacks", createChargeback("createChargeback"))
+	doc.AddOperation(http.MethodPost, "/api/v1/chargebacks", createChargeback("createChargebackV1"))
+
+	callback := createChargeback("createChargebackCallback")
+	callback.Summary = "Create a chargeback from a dispute notification signed by an acquirer"
+	callback.Responses["401"] = errorResponse("Missing, stale, replayed or invalid signature")
+	callback.Responses["503"] = errorResponse("Signature or credentials could not be checked")
+	doc.AddOperation(http.MethodPost, "/callbacks/chargebacks", callback)
+
+	return doc
+}
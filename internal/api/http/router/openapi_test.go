@@ -0,0 +1,200 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+const validCreatePayload = `{"transaction_id":"txn-1","merchant_id":"m-1","amount":10,"currency":"USD","card_number":"4111111111111111","reason":"fraud","description":"Unauthorized","transaction_date":"2024-01-15T10:30:00Z"}`
+
+// TestOpenAPI_DescribesEveryRoute fails when a route is added or removed
+// without updating the OpenAPI document, or the other way around
+func TestOpenAPI_DescribesEveryRoute(t *testing.T) {
+	// Arrange
+	rt := newTestRouter(&MockCreateChargebackUseCase{})
+	doc := NewOpenAPIDocument(Config{Version: "test"})
+
+	var routes []string
+	for _, route := range rt.Routes() {
+		routes = append(routes, route.Method+" "+route.Pattern)
+	}
+	sort.Strings(routes)
+
+	// Assert
+	if got, want := strings.Join(doc.Endpoints(), "\n"), strings.Join(routes, "\n"); got != want {
+		t.Errorf("OpenAPI endpoints and router routes differ\nspec:\n%s\n\nrouter:\n%s", got, want)
+	}
+}
+
+// TestOpenAPI_ResponsesMatchSchemas runs every handler outcome and checks the
+// status code is documented and the body matches the documented schema
+func TestOpenAPI_ResponsesMatchSchemas(t *testing.T) {
+	fullResponse := func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+		now := time.Now()
+		return &usecase.CreateChargebackResponse{
+			ID:              "cb-1",
+			TransactionID:   req.TransactionID,
+			MerchantID:      req.MerchantID,
+			Amount:          req.Amount,
+			Currency:        req.Currency,
+			CardNumber:      "************1111",
+			Reason:          req.Reason,
+			Status:          entity.StatusPending,
+			Description:     req.Description,
+			TransactionDate: req.TransactionDate,
+			ChargebackDate:  now,
+			CreatedAt:       now,
+			UpdatedAt:       now,
+			Channel:         entity.ChannelAPI,
+		}, nil
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		execute func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error)
+	}{
+		{name: "health", method: http.MethodGet, path: "/health"},
+		{name: "liveness", method: http.MethodGet, path: "/health/live"},
+		{name: "readiness", method: http.MethodGet, path: "/health/ready"},
+		{name: "openapi", method: http.MethodGet, path: "/openapi.json"},
+		{name: "create success", method: http.MethodPost, path: "/chargebacks", body: validCreatePayload, execute: fullResponse},
+		{name: "create v1 success", method: http.MethodPost, path: "/api/v1/chargebacks", body: validCreatePayload, execute: fullResponse},
+		{
+			name: "create callback success", method: http.MethodPost, path: "/callbacks/chargebacks", body: validCreatePayload,
+			execute: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+				response, err := fullResponse(ctx, req)
+				response.Channel, response.Source = entity.ChannelAcquirer, "acquirer-a"
+				return response, err
+			},
+		},
+		{name: "create invalid JSON", method: http.MethodPost, path: "/chargebacks", body: `{`},
+		{name: "create schema violation", method: http.MethodPost, path: "/chargebacks", body: `{"amount":"ten"}`},
+		{name: "create body too large", method: http.MethodPost, path: "/chargebacks", body: strings.Repeat(" ", int(DefaultMaxBodyBytes)+1)},
+		{
+			name: "create validation error", method: http.MethodPost, path: "/chargebacks", body: `{}`,
+			execute: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+				return nil, errors.New("validation errors: transaction ID is required")
+			},
+		},
+		{
+			name: "create forbidden", method: http.MethodPost, path: "/chargebacks", body: validCreatePayload,
+			execute: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+				return nil, &usecase.ForbiddenError{Action: usecase.ActionCreateChargeback, Reason: "no authenticated principal"}
+			},
+		},
+		{
+			name: "create over quota", method: http.MethodPost, path: "/chargebacks", body: validCreatePayload,
+			execute: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+				return nil, &usecase.QuotaExceededError{MerchantID: req.MerchantID, Result: service.RateLimitResult{Limit: 100}}
+			},
+		},
+		{
+			name: "create internal error", method: http.MethodPost, path: "/chargebacks", body: validCreatePayload,
+			execute: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+				return nil, errors.New("boom")
+			},
+		},
+	}
+
+	doc := NewOpenAPIDocument(Config{Version: "test"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rt := newTestRouter(&MockCreateChargebackUseCase{ExecuteFunc: tt.execute})
+
+			// Act
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader([]byte(tt.body)))
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			operation, ok := doc.Operation(tt.method, tt.path)
+			if !ok {
+				t.Fatalf("Operation %s %s missing from OpenAPI document", tt.method, tt.path)
+			}
+
+			response, ok := operation.Responses[strconv.Itoa(recorder.Code)]
+			if !ok {
+				t.Fatalf("Status %d of %s %s is not documented", recorder.Code, tt.method, tt.path)
+			}
+
+			var body interface{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			schema := response.Content["application/json"].Schema
+			for _, violation := range doc.Validate(schema, body) {
+				t.Errorf("Response does not match schema: %s", violation)
+			}
+		})
+	}
+}
+
+// TestOpenAPI_RequestSchemaAcceptsExample ensures the documented request
+// schema accepts the payload the handler accepts
+func TestOpenAPI_RequestSchemaAcceptsExample(t *testing.T) {
+	// Arrange
+	doc := NewOpenAPIDocument(Config{Version: "test"})
+	operation, _ := doc.Operation(http.MethodPost, "/chargebacks")
+	schema := operation.RequestBody.Content["application/json"].Schema
+
+	var payload interface{}
+	if err := json.Unmarshal([]byte(validCreatePayload), &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+
+	// Act
+	violations := doc.Validate(schema, payload)
+
+	// Assert
+	for _, violation := range violations {
+		t.Errorf("Request example does not match schema: %s", violation)
+	}
+}
+
+func TestOpenAPI_ServedDocument(t *testing.T) {
+	// Arrange
+	rt := NewChargebackRouter(Config{ServiceName: "chargeback-test", Version: "2.3.4"}, &MockCreateChargebackUseCase{}, &testLogger{})
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var document map[string]interface{}
+	if err := json.NewDecoder(recorder.Body).Decode(&document); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+
+	if document["openapi"] != "3.1.0" {
+		t.Errorf("Expected openapi '3.1.0', got '%v'", document["openapi"])
+	}
+
+	info, _ := document["info"].(map[string]interface{})
+	if info["version"] != "2.3.4" {
+		t.Errorf("Expected info.version '2.3.4', got '%v'", info["version"])
+	}
+}
@@ -0,0 +1,213 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/openapi"
+)
+
+// Router dispatches HTTP requests to handlers registered by method and path.
+// It is the single source of truth for the API routes and is shared by the
+// local HTTP server and the Lambda adapter.
+//
+// Path patterns may contain parameters such as "/chargebacks/{id}", which
+// match exactly one non-empty segment. Parameter values are exposed to
+// handlers through http.Request.PathValue.
+type Router struct {
+	routes []*route
+}
+
+// route holds the handlers registered for a single path pattern
+type route struct {
+	pattern  string
+	segments []string
+	handlers map[string]http.Handler
+}
+
+// New creates an empty router
+func New() *Router {
+	return &Router{}
+}
+
+// Handle registers a handler for the given method and path pattern
+func (rt *Router) Handle(method, pattern string, handler http.Handler) {
+	for _, existing := range rt.routes {
+		if existing.pattern == pattern {
+			existing.handlers[method] = handler
+			return
+		}
+	}
+
+	rt.routes = append(rt.routes, &route{
+		pattern:  pattern,
+		segments: splitPath(pattern),
+		handlers: map[string]http.Handler{method: handler},
+	})
+}
+
+// HandleFunc registers a handler function for the given method and path pattern
+func (rt *Router) HandleFunc(method, pattern string, handler http.HandlerFunc) {
+	rt.Handle(method, pattern, handler)
+}
+
+// Route describes a registered method and path pattern
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// Routes returns every registered route, sorted by pattern and method
+func (rt *Router) Routes() []Route {
+	var routes []Route
+	for _, r := range rt.routes {
+		for method := range r.handlers {
+			routes = append(routes, Route{Method: method, Pattern: r.pattern})
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Pattern != routes[j].Pattern {
+			return routes[i].Pattern < routes[j].Pattern
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	return routes
+}
+
+// RouteExists checks if any handler is registered for the given path
+func (rt *Router) RouteExists(path string) bool {
+	return len(rt.match(path)) > 0
+}
+
+// AllowedMethods returns the sorted methods registered for the given path
+func (rt *Router) AllowedMethods(path string) []string {
+	seen := make(map[string]bool)
+	var methods []string
+	for _, m := range rt.match(path) {
+		for method := range m.route.handlers {
+			if !seen[method] {
+				seen[method] = true
+				methods = append(methods, method)
+			}
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
+
+// ServeHTTP implements http.Handler interface
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	matches := rt.match(r.URL.Path)
+	if len(matches) == 0 {
+		writeError(w, http.StatusNotFound, "Not Found", "Route not found")
+		return
+	}
+
+	for _, m := range matches {
+		handler, ok := m.route.handlers[r.Method]
+		if !ok {
+			continue
+		}
+
+		for name, value := range m.params {
+			r.SetPathValue(name, value)
+		}
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(rt.AllowedMethods(r.URL.Path), ", "))
+	writeError(w, http.StatusMethodNotAllowed, "Method Not Allowed", "Method "+r.Method+" is not allowed for this route")
+}
+
+// routeMatch is a route matching a request path along with its parameters
+type routeMatch struct {
+	route  *route
+	params map[string]string
+}
+
+// match returns the routes matching the path, most specific first.
+// Routes with fewer parameters win, so "/chargebacks/search" takes precedence
+// over "/chargebacks/{id}".
+func (rt *Router) match(path string) []routeMatch {
+	segments := splitPath(path)
+
+	var matches []routeMatch
+	for _, candidate := range rt.routes {
+		if params, ok := candidate.matchSegments(segments); ok {
+			matches = append(matches, routeMatch{route: candidate, params: params})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return len(matches[i].params) < len(matches[j].params)
+	})
+
+	return matches
+}
+
+// matchSegments checks the path segments against the route pattern
+func (r *route) matchSegments(segments []string) (map[string]string, bool) {
+	if len(segments) != len(r.segments) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, patternSegment := range r.segments {
+		if name, ok := paramName(patternSegment); ok {
+			if segments[i] == "" {
+				return nil, false
+			}
+			params[name] = segments[i]
+			continue
+		}
+
+		if patternSegment != segments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// paramName returns the parameter name of a "{name}" pattern segment
+func paramName(segment string) (string, bool) {
+	if len(segment) > 2 && strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+		return segment[1 : len(segment)-1], true
+	}
+	return "", false
+}
+
+// splitPath splits a path into its segments, ignoring the leading slash
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+// ErrorResponse is the JSON body returned for every non-2xx response
+type ErrorResponse struct {
+	Error   string              `json:"error"`
+	Message string              `json:"message,omitempty"`
+	Fields  []openapi.Violation `json:"fields,omitempty"`
+
+	// ErrorID identifies an unexpected failure in the server logs
+	ErrorID string `json:"error_id,omitempty"`
+}
+
+// writeJSON writes a JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError writes a JSON error response with the given status code
+func writeError(w http.ResponseWriter, statusCode int, errorTitle, message string) {
+	writeJSON(w, statusCode, ErrorResponse{
+		Error:   errorTitle,
+		Message: message,
+	})
+}
@@ -0,0 +1,479 @@
+package router
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/openapi"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+// MockCreateChargebackUseCase for testing
+type MockCreateChargebackUseCase struct {
+	ExecuteFunc func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error)
+}
+
+func (m *MockCreateChargebackUseCase) Execute(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+	if m.ExecuteFunc != nil {
+		return m.ExecuteFunc(ctx, req)
+	}
+	return nil, nil
+}
+
+// testLogger is a simple logger for testing that ignores all output
+type testLogger struct{}
+
+func (t *testLogger) Log(ctx context.Context, entry service.LogEntry) error { return nil }
+func (t *testLogger) Debug(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (t *testLogger) Info(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (t *testLogger) Warn(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (t *testLogger) Error(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return nil
+}
+func (t *testLogger) WithContext(ctx context.Context) service.Logger { return t }
+
+func newTestRouter(uc CreateChargebackUseCase) *Router {
+	return NewChargebackRouter(Config{ServiceName: "chargeback-test"}, uc, &testLogger{})
+}
+
+func successfulUseCase() *MockCreateChargebackUseCase {
+	return &MockCreateChargebackUseCase{
+		ExecuteFunc: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+			return &usecase.CreateChargebackResponse{
+				ID:            "chargeback-123",
+				TransactionID: req.TransactionID,
+				Status:        entity.StatusPending,
+			}, nil
+		},
+	}
+}
+
+func TestRouter_CreateChargeback_BothPaths(t *testing.T) {
+	paths := []string{"/chargebacks", "/api/v1/chargebacks"}
+
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			// Arrange
+			rt := newTestRouter(successfulUseCase())
+			payload := []byte(`{"transaction_id":"txn-1","merchant_id":"m-1","amount":10,"currency":"USD","card_number":"4111111111111111","reason":"fraud","transaction_date":"2024-01-15T10:30:00Z"}`)
+
+			// Act
+			req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != http.StatusCreated {
+				t.Errorf("Expected status code %d, got %d", http.StatusCreated, recorder.Code)
+			}
+
+			var response usecase.CreateChargebackResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if response.TransactionID != "txn-1" {
+				t.Errorf("Expected transaction_id 'txn-1', got '%s'", response.TransactionID)
+			}
+		})
+	}
+}
+
+func TestRouter_CreateChargeback_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		useCaseErr     error
+		expectedStatus int
+		expectedError  string
+		expectedRetry  string
+	}{
+		{
+			name:           "invalid JSON",
+			body:           `{invalid`,
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Bad Request",
+		},
+		{
+			name:           "validation error",
+			body:           validCreatePayload,
+			useCaseErr:     errors.New("validation errors: transaction ID is required"),
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "Validation Error",
+		},
+		{
+			name:           "forbidden",
+			body:           validCreatePayload,
+			useCaseErr:     &usecase.ForbiddenError{Action: usecase.ActionCreateChargeback, Reason: "key-1 belongs to merchant merchant-1"},
+			expectedStatus: http.StatusForbidden,
+			expectedError:  "Forbidden",
+		},
+		{
+			name:           "duplicate transaction",
+			body:           validCreatePayload,
+			useCaseErr:     fmt.Errorf("%w for transaction txn_123", usecase.ErrChargebackExists),
+			expectedStatus: http.StatusConflict,
+			expectedError:  "Conflict",
+		},
+		{
+			name:           "daily quota exceeded",
+			body:           validCreatePayload,
+			useCaseErr:     &usecase.QuotaExceededError{MerchantID: "merchant-456", Result: service.RateLimitResult{Limit: 100, Reset: time.Hour, RetryAfter: time.Hour}},
+			expectedStatus: http.StatusTooManyRequests,
+			expectedError:  "Too Many Requests",
+			expectedRetry:  "3600",
+		},
+		{
+			name:           "unexpected error",
+			body:           validCreatePayload,
+			useCaseErr:     errors.New("dynamodb unavailable"),
+			expectedStatus: http.StatusInternalServerError,
+			expectedError:  "Internal Server Error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rt := newTestRouter(&MockCreateChargebackUseCase{
+				ExecuteFunc: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+					return nil, tt.useCaseErr
+				},
+			})
+
+			// Act
+			req := httptest.NewRequest(http.MethodPost, "/chargebacks", bytes.NewReader([]byte(tt.body)))
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, recorder.Code)
+			}
+
+			var response ErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if response.Error != tt.expectedError {
+				t.Errorf("Expected error '%s', got '%s'", tt.expectedError, response.Error)
+			}
+			if got := recorder.Header().Get("Retry-After"); got != tt.expectedRetry {
+				t.Errorf("Expected Retry-After '%s', got '%s'", tt.expectedRetry, got)
+			}
+			if tt.expectedStatus == http.StatusForbidden && response.Message != "forbidden" {
+				t.Errorf("Expected the reason of the denial to be withheld, got '%s'", response.Message)
+			}
+			if tt.expectedStatus == http.StatusConflict && response.Message != "chargeback already exists" {
+				t.Errorf("Expected a generic duplicate message, got '%s'", response.Message)
+			}
+		})
+	}
+}
+
+func TestRouter_Health(t *testing.T) {
+	// Arrange
+	rt := newTestRouter(&MockCreateChargebackUseCase{})
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if response["service"] != "chargeback-test" {
+		t.Errorf("Expected service 'chargeback-test', got '%v'", response["service"])
+	}
+}
+
+// stubHealthChecker returns fixed dependency results
+type stubHealthChecker struct {
+	results []service.DependencyHealth
+}
+
+func (s *stubHealthChecker) Check(ctx context.Context) []service.DependencyHealth {
+	return s.results
+}
+
+func TestRouter_Health_Liveness(t *testing.T) {
+	// Arrange
+	checker := &stubHealthChecker{results: []service.DependencyHealth{{Name: "dynamodb", Status: service.HealthDown}}}
+	rt := NewChargebackRouter(Config{ServiceName: "chargeback-test", Health: checker}, &MockCreateChargebackUseCase{}, &testLogger{})
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/health/live", nil)
+	recorder := httptest.NewRecorder()
+	rt.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected liveness to ignore dependencies with status %d, got %d", http.StatusOK, recorder.Code)
+	}
+
+	var response HealthResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Status != HealthStatusOK || response.Checks != nil {
+		t.Errorf("Expected status 'ok' without checks, got %+v", response)
+	}
+}
+
+func TestRouter_Health_Readiness(t *testing.T) {
+	checkedAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	up := service.DependencyHealth{Name: "card_vault", Status: service.HealthUp, LatencyMs: 12.5, CheckedAt: checkedAt}
+	down := service.DependencyHealth{Name: "dynamodb", Status: service.HealthDown, CheckedAt: checkedAt, LastError: "table chargebacks is CREATING", LastErrorAt: &checkedAt}
+
+	tests := []struct {
+		name           string
+		path           string
+		checker        service.HealthChecker
+		expectedCode   int
+		expectedStatus string
+		expectedChecks int
+	}{
+		{name: "no checker", path: "/health/ready", expectedCode: http.StatusOK, expectedStatus: HealthStatusOK},
+		{name: "every dependency up", path: "/health/ready", checker: &stubHealthChecker{results: []service.DependencyHealth{up}}, expectedCode: http.StatusOK, expectedStatus: HealthStatusOK, expectedChecks: 1},
+		{name: "dependency down", path: "/health/ready", checker: &stubHealthChecker{results: []service.DependencyHealth{up, down}}, expectedCode: http.StatusServiceUnavailable, expectedStatus: HealthStatusUnavailable, expectedChecks: 2},
+		{name: "bare path reports readiness", path: "/health", checker: &stubHealthChecker{results: []service.DependencyHealth{down}}, expectedCode: http.StatusServiceUnavailable, expectedStatus: HealthStatusUnavailable, expectedChecks: 1},
+	}
+
+	doc := NewOpenAPIDocument(Config{Version: "test"})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rt := NewChargebackRouter(Config{ServiceName: "chargeback-test", Health: tt.checker}, &MockCreateChargebackUseCase{}, &testLogger{})
+
+			// Act
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != tt.expectedCode {
+				t.Errorf("Expected status code %d, got %d", tt.expectedCode, recorder.Code)
+			}
+			if cacheControl := recorder.Header().Get("Cache-Control"); cacheControl != "no-store" {
+				t.Errorf("Expected Cache-Control 'no-store', got '%s'", cacheControl)
+			}
+
+			var body interface{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if violations := doc.Validate(openapi.Ref("HealthResponse"), body); len(violations) > 0 {
+				t.Errorf("Expected the response to match the HealthResponse schema, got %+v", violations)
+			}
+
+			var response HealthResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Service != "chargeback-test" || response.Status != tt.expectedStatus {
+				t.Errorf("Expected service 'chargeback-test' with status '%s', got %+v", tt.expectedStatus, response)
+			}
+			if len(response.Checks) != tt.expectedChecks {
+				t.Errorf("Expected %d checks, got %+v", tt.expectedChecks, response.Checks)
+			}
+		})
+	}
+}
+
+func TestRouter_Health_Readiness_ErrorDetails(t *testing.T) {
+	checkedAt := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	down := service.DependencyHealth{Name: "dynamodb", Status: service.HealthDown, CheckedAt: checkedAt, LastError: "table arn:aws:dynamodb:us-east-1:123456789012:table/chargebacks is CREATING", LastErrorAt: &checkedAt}
+	rt := NewChargebackRouter(Config{ServiceName: "chargeback-test", Health: &stubHealthChecker{results: []service.DependencyHealth{down}}}, &MockCreateChargebackUseCase{}, &testLogger{})
+
+	tests := []struct {
+		name      string
+		principal *entity.Principal
+		wantError bool
+	}{
+		{name: "anonymous caller", wantError: false},
+		{name: "authenticated caller", principal: &entity.Principal{ID: "ops", Method: entity.AuthMethodJWT}, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+			if tt.principal != nil {
+				req = req.WithContext(requestctx.WithPrincipal(req.Context(), tt.principal))
+			}
+			recorder := httptest.NewRecorder()
+
+			// Act
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			var response HealthResponse
+			if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if len(response.Checks) != 1 || response.Checks[0].Status != service.HealthDown {
+				t.Fatalf("Expected the dependency to be reported down, got %+v", response.Checks)
+			}
+			if got := strings.Contains(recorder.Body.String(), "arn:aws:dynamodb"); got != tt.wantError {
+				t.Errorf("Expected error details shown: %v, got body %s", tt.wantError, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestRouter_NotFound(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/nonexistent"},
+		{name: "sub-path of known route", method: http.MethodPost, path: "/chargebacks/anything/else"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rt := newTestRouter(successfulUseCase())
+
+			// Act
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != http.StatusNotFound {
+				t.Errorf("Expected status code %d, got %d", http.StatusNotFound, recorder.Code)
+			}
+
+			if contentType := recorder.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected Content-Type 'application/json', got '%s'", contentType)
+			}
+		})
+	}
+}
+
+func TestRouter_RouteExists(t *testing.T) {
+	rt := newTestRouter(&MockCreateChargebackUseCase{})
+
+	if !rt.RouteExists("/chargebacks") {
+		t.Error("Expected /chargebacks to exist")
+	}
+
+	if rt.RouteExists("/unknown") {
+		t.Error("Expected /unknown not to exist")
+	}
+}
+
+func TestRouter_MethodNotAllowed(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		expectedAllow string
+	}{
+		{name: "GET on create route", method: http.MethodGet, path: "/chargebacks", expectedAllow: "POST"},
+		{name: "POST on health route", method: http.MethodPost, path: "/health", expectedAllow: "GET"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			rt := newTestRouter(successfulUseCase())
+
+			// Act
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != http.StatusMethodNotAllowed {
+				t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, recorder.Code)
+			}
+
+			if allow := recorder.Header().Get("Allow"); allow != tt.expectedAllow {
+				t.Errorf("Expected Allow '%s', got '%s'", tt.expectedAllow, allow)
+			}
+		})
+	}
+}
+
+func TestRouter_PathParameters(t *testing.T) {
+	// Arrange
+	rt := New()
+	var capturedID string
+	var hitStatic bool
+	rt.HandleFunc(http.MethodGet, "/chargebacks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		capturedID = r.PathValue("id")
+	})
+	rt.HandleFunc(http.MethodDelete, "/chargebacks/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	rt.HandleFunc(http.MethodGet, "/chargebacks/search", func(w http.ResponseWriter, r *http.Request) {
+		hitStatic = true
+	})
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{name: "parameter captured", method: http.MethodGet, path: "/chargebacks/cb-123", expectedStatus: http.StatusOK},
+		{name: "static segment wins", method: http.MethodGet, path: "/chargebacks/search", expectedStatus: http.StatusOK},
+		{name: "empty parameter", method: http.MethodGet, path: "/chargebacks/", expectedStatus: http.StatusNotFound},
+		{name: "extra segments", method: http.MethodGet, path: "/chargebacks/cb-123/extra", expectedStatus: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPut, path: "/chargebacks/cb-123", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			recorder := httptest.NewRecorder()
+			rt.ServeHTTP(recorder, req)
+
+			// Assert
+			if recorder.Code != tt.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tt.expectedStatus, recorder.Code)
+			}
+		})
+	}
+
+	if capturedID != "cb-123" {
+		t.Errorf("Expected id 'cb-123', got '%s'", capturedID)
+	}
+
+	if !hitStatic {
+		t.Error("Expected static route to handle /chargebacks/search")
+	}
+
+	if methods := rt.AllowedMethods("/chargebacks/cb-123"); strings.Join(methods, ",") != "DELETE,GET" {
+		t.Errorf("Expected allowed methods [DELETE GET], got %v", methods)
+	}
+}
@@ -0,0 +1,148 @@
+package openapi
+
+import (
+	"sort"
+	"strings"
+)
+
+// Version is the OpenAPI specification version produced by this package
+const Version = "3.1.0"
+
+// Document is an OpenAPI 3.1 document
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+// Info holds the API metadata
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Components holds the reusable schemas referenced by operations
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Operation describes a single method on a path
+type Operation struct {
+	OperationID string              `json:"operationId"`
+	Summary     string              `json:"summary,omitempty"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter describes a path, query or header parameter
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes the payload accepted by an operation
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response of an operation
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Schema *Schema `json:"schema"`
+}
+
+// MediaType holds the schema of a request or response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// NewDocument creates an empty document with the given metadata
+func NewDocument(title, version string) *Document {
+	return &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:   title,
+			Version: version,
+		},
+		Paths: make(map[string]map[string]Operation),
+		Components: Components{
+			Schemas: make(map[string]*Schema),
+		},
+	}
+}
+
+// AddSchema registers a named component schema and returns a reference to it
+func (d *Document) AddSchema(name string, schema *Schema) *Schema {
+	d.Components.Schemas[name] = schema
+	return Ref(name)
+}
+
+// AddOperation registers an operation for the given method and path. Path
+// parameters written as "{name}" are declared automatically.
+func (d *Document) AddOperation(method, path string, operation Operation) {
+	if _, ok := d.Paths[path]; !ok {
+		d.Paths[path] = make(map[string]Operation)
+	}
+
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			operation.Parameters = append(operation.Parameters, Parameter{
+				Name:     strings.Trim(segment, "{}"),
+				In:       "path",
+				Required: true,
+				Schema:   &Schema{Type: "string"},
+			})
+		}
+	}
+
+	d.Paths[path][strings.ToLower(method)] = operation
+}
+
+// Operation returns the operation registered for the given method and path
+func (d *Document) Operation(method, path string) (Operation, bool) {
+	operation, ok := d.Paths[path][strings.ToLower(method)]
+	return operation, ok
+}
+
+// Endpoints returns every "METHOD path" pair described by the document, sorted
+func (d *Document) Endpoints() []string {
+	var endpoints []string
+	for path, operations := range d.Paths {
+		for method := range operations {
+			endpoints = append(endpoints, strings.ToUpper(method)+" "+path)
+		}
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// Resolve follows a component reference, returning the schema itself otherwise
+func (d *Document) Resolve(schema *Schema) *Schema {
+	if schema == nil || schema.Ref == "" {
+		return schema
+	}
+	return d.Components.Schemas[strings.TrimPrefix(schema.Ref, "#/components/schemas/")]
+}
+
+// Ref returns a reference to a named component schema
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// JSONContent wraps a schema as an application/json media type map
+func JSONContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{
+		"application/json": {Schema: schema},
+	}
+}
@@ -0,0 +1,151 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of JSON Schema (draft 2020-12) used by the API spec
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+}
+
+// SchemaGenerator derives JSON schemas from Go types using their json tags
+type SchemaGenerator struct {
+	enums map[reflect.Type][]string
+}
+
+// NewSchemaGenerator creates a new schema generator
+func NewSchemaGenerator() *SchemaGenerator {
+	return &SchemaGenerator{
+		enums: make(map[reflect.Type][]string),
+	}
+}
+
+// RegisterEnum declares the allowed values of a named string type, since they
+// cannot be discovered through reflection
+func RegisterEnum[T ~string](g *SchemaGenerator, values ...T) {
+	var zero T
+	enumValues := make([]string, len(values))
+	for i, value := range values {
+		enumValues[i] = string(value)
+	}
+	g.enums[reflect.TypeOf(zero)] = enumValues
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Generate returns the schema for the type of the given value
+func (g *SchemaGenerator) Generate(value interface{}) *Schema {
+	return g.generate(reflect.TypeOf(value))
+}
+
+func (g *SchemaGenerator) generate(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		schema := &Schema{Type: "string"}
+		if values, ok := g.enums[t]; ok {
+			schema.Enum = values
+		}
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		return g.generateStruct(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// generateStruct builds an object schema. Fields without omitempty are required.
+func (g *SchemaGenerator) generateStruct(t reflect.Type) *Schema {
+	noAdditional := false
+	schema := &Schema{
+		Type:                 "object",
+		Properties:           make(map[string]*Schema),
+		AdditionalProperties: &noAdditional,
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, omitEmpty, skip := parseJSONTag(field)
+		if skip {
+			continue
+		}
+
+		schema.Properties[name] = g.generateField(field)
+		if !omitEmpty {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// generateField returns the schema of a struct field. A `formats` tag lists
+// several accepted string formats, e.g. `formats:"date-time,date"`.
+func (g *SchemaGenerator) generateField(field reflect.StructField) *Schema {
+	formats := field.Tag.Get("formats")
+	if formats == "" {
+		return g.generate(field.Type)
+	}
+
+	schema := &Schema{}
+	for _, format := range strings.Split(formats, ",") {
+		schema.AnyOf = append(schema.AnyOf, &Schema{Type: "string", Format: strings.TrimSpace(format)})
+	}
+	return schema
+}
+
+// parseJSONTag returns the JSON name of a struct field and whether it is optional
+func parseJSONTag(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, option := range parts[1:] {
+		if option == "omitempty" || option == "omitzero" {
+			omitEmpty = true
+		}
+	}
+
+	return name, omitEmpty, false
+}
@@ -0,0 +1,130 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testColor string
+
+type testPayload struct {
+	Name      string    `json:"name"`
+	Count     int       `json:"count"`
+	Score     float64   `json:"score,omitempty"`
+	Color     testColor `json:"color"`
+	Tags      []string  `json:"tags,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Ignored   string    `json:"-"`
+	internal  string
+}
+
+func TestSchemaGenerator_Generate(t *testing.T) {
+	// Arrange
+	generator := NewSchemaGenerator()
+	RegisterEnum(generator, testColor("red"), testColor("blue"))
+
+	// Act
+	schema := generator.Generate(testPayload{})
+
+	// Assert
+	if schema.Type != "object" {
+		t.Fatalf("Expected object schema, got '%s'", schema.Type)
+	}
+
+	expectedTypes := map[string]string{
+		"name":       "string",
+		"count":      "integer",
+		"score":      "number",
+		"color":      "string",
+		"tags":       "array",
+		"created_at": "string",
+	}
+	for name, expectedType := range expectedTypes {
+		property, ok := schema.Properties[name]
+		if !ok {
+			t.Errorf("Expected property '%s'", name)
+			continue
+		}
+		if property.Type != expectedType {
+			t.Errorf("Expected property '%s' of type '%s', got '%s'", name, expectedType, property.Type)
+		}
+	}
+
+	if len(schema.Properties) != len(expectedTypes) {
+		t.Errorf("Expected %d properties, got %d", len(expectedTypes), len(schema.Properties))
+	}
+
+	if !reflect.DeepEqual(schema.Properties["color"].Enum, []string{"red", "blue"}) {
+		t.Errorf("Expected enum [red blue], got %v", schema.Properties["color"].Enum)
+	}
+
+	if schema.Properties["created_at"].Format != "date-time" {
+		t.Errorf("Expected date-time format, got '%s'", schema.Properties["created_at"].Format)
+	}
+
+	if !reflect.DeepEqual(schema.Required, []string{"name", "count", "color", "created_at"}) {
+		t.Errorf("Unexpected required fields: %v", schema.Required)
+	}
+}
+
+func TestDocument_Validate(t *testing.T) {
+	// Arrange
+	generator := NewSchemaGenerator()
+	RegisterEnum(generator, testColor("red"), testColor("blue"))
+	doc := NewDocument("Test", "1.0.0")
+	ref := doc.AddSchema("TestPayload", generator.Generate(testPayload{}))
+
+	tests := []struct {
+		name       string
+		body       string
+		violations int
+	}{
+		{name: "valid", body: `{"name":"a","count":1,"color":"red","created_at":"2024-01-15T10:30:00Z"}`, violations: 0},
+		{name: "missing required", body: `{"name":"a","color":"red","created_at":"2024-01-15T10:30:00Z"}`, violations: 1},
+		{name: "unknown property", body: `{"name":"a","count":1,"color":"red","created_at":"2024-01-15T10:30:00Z","extra":true}`, violations: 1},
+		{name: "wrong enum", body: `{"name":"a","count":1,"color":"green","created_at":"2024-01-15T10:30:00Z"}`, violations: 1},
+		{name: "bad date", body: `{"name":"a","count":1,"color":"red","created_at":"yesterday"}`, violations: 1},
+		{name: "fractional integer", body: `{"name":"a","count":1.5,"color":"red","created_at":"2024-01-15T10:30:00Z"}`, violations: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body interface{}
+			if err := json.Unmarshal([]byte(tt.body), &body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+
+			// Act
+			violations := doc.Validate(ref, body)
+
+			// Assert
+			if len(violations) != tt.violations {
+				t.Errorf("Expected %d violations, got %v", tt.violations, violations)
+			}
+		})
+	}
+}
+
+func TestDocument_AddOperation_PathParameters(t *testing.T) {
+	// Arrange
+	doc := NewDocument("Test", "1.0.0")
+
+	// Act
+	doc.AddOperation("GET", "/chargebacks/{id}", Operation{OperationID: "getChargeback"})
+
+	// Assert
+	operation, ok := doc.Operation("GET", "/chargebacks/{id}")
+	if !ok {
+		t.Fatal("Expected operation to be registered")
+	}
+
+	if len(operation.Parameters) != 1 || operation.Parameters[0].Name != "id" || operation.Parameters[0].In != "path" {
+		t.Errorf("Expected path parameter 'id', got %+v", operation.Parameters)
+	}
+
+	if endpoints := doc.Endpoints(); len(endpoints) != 1 || endpoints[0] != "GET /chargebacks/{id}" {
+		t.Errorf("Unexpected endpoints: %v", endpoints)
+	}
+}
@@ -0,0 +1,199 @@
+package openapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Violation describes a value that does not match its schema
+type Violation struct {
+	// Pointer is the JSON pointer (RFC 6901) to the offending value
+	Pointer string `json:"pointer"`
+
+	// Message explains why the value was rejected
+	Message string `json:"message"`
+}
+
+// String returns the violation in "pointer: message" form
+func (v Violation) String() string {
+	pointer := v.Pointer
+	if pointer == "" {
+		pointer = "/"
+	}
+	return pointer + ": " + v.Message
+}
+
+// Validate checks a decoded JSON value (as produced by encoding/json into an
+// interface{}) against a schema and returns every violation found
+func (d *Document) Validate(schema *Schema, value interface{}) []Violation {
+	return d.validate(schema, value, "")
+}
+
+func (d *Document) validate(schema *Schema, value interface{}, pointer string) []Violation {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return []Violation{{Pointer: pointer, Message: "unresolved schema"}}
+	}
+
+	if len(schema.AnyOf) > 0 {
+		return d.validateAnyOf(schema, value, pointer)
+	}
+
+	switch schema.Type {
+	case "object":
+		return d.validateObject(schema, value, pointer)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []Violation{typeViolation(pointer, "array", value)}
+		}
+		var violations []Violation
+		for i, item := range items {
+			violations = append(violations, d.validate(schema.Items, item, fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+		return violations
+	case "string":
+		return validateString(schema, value, pointer)
+	case "number", "integer":
+		number, ok := value.(float64)
+		if !ok {
+			return []Violation{typeViolation(pointer, schema.Type, value)}
+		}
+		if schema.Type == "integer" && number != float64(int64(number)) {
+			return []Violation{typeViolation(pointer, "integer", value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []Violation{typeViolation(pointer, "boolean", value)}
+		}
+	}
+
+	return nil
+}
+
+// validateAnyOf accepts the value when at least one alternative matches
+func (d *Document) validateAnyOf(schema *Schema, value interface{}, pointer string) []Violation {
+	var expected []string
+	for _, alternative := range schema.AnyOf {
+		if len(d.validate(alternative, value, pointer)) == 0 {
+			return nil
+		}
+		alternative = d.Resolve(alternative)
+		if alternative.Format != "" {
+			expected = append(expected, alternative.Format)
+		} else {
+			expected = append(expected, alternative.Type)
+		}
+	}
+
+	return []Violation{{Pointer: pointer, Message: "must be one of: " + strings.Join(expected, ", ")}}
+}
+
+func (d *Document) validateObject(schema *Schema, value interface{}, pointer string) []Violation {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return []Violation{typeViolation(pointer, "object", value)}
+	}
+
+	var violations []Violation
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			violations = append(violations, Violation{Pointer: pointer + "/" + escapePointer(name), Message: "is required"})
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		property, ok := schema.Properties[key]
+		if !ok {
+			if schema.AdditionalProperties != nil && !*schema.AdditionalProperties {
+				violations = append(violations, Violation{Pointer: pointer + "/" + escapePointer(key), Message: "is not a known field"})
+			}
+			continue
+		}
+		violations = append(violations, d.validate(property, object[key], pointer+"/"+escapePointer(key))...)
+	}
+
+	return violations
+}
+
+// dateLayouts maps the supported string formats to their time layout
+var dateLayouts = map[string]string{
+	"date-time": time.RFC3339Nano,
+	"date":      time.DateOnly,
+}
+
+func validateString(schema *Schema, value interface{}, pointer string) []Violation {
+	text, ok := value.(string)
+	if !ok {
+		return []Violation{typeViolation(pointer, "string", value)}
+	}
+
+	if len(schema.Enum) > 0 {
+		allowed := false
+		for _, option := range schema.Enum {
+			if text == option {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return []Violation{{Pointer: pointer, Message: "must be one of: " + strings.Join(schema.Enum, ", ")}}
+		}
+	}
+
+	if layout, ok := dateLayouts[schema.Format]; ok {
+		if _, err := time.Parse(layout, text); err != nil {
+			return []Violation{{Pointer: pointer, Message: "must be a valid " + schema.Format}}
+		}
+	}
+
+	return nil
+}
+
+// typeViolation reports a value of the wrong JSON type
+func typeViolation(pointer, expected string, value interface{}) Violation {
+	return Violation{Pointer: pointer, Message: fmt.Sprintf("must be %s %s, got %s", article(expected), expected, jsonType(value))}
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func article(word string) string {
+	if strings.ContainsRune("aeiou", rune(word[0])) {
+		return "an"
+	}
+	return "a"
+}
+
+// escapePointer escapes a property name for use in a JSON pointer
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
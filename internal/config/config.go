@@ -0,0 +1,1082 @@
+// Package config loads the typed configuration shared by every entrypoint.
+//
+// Values are layered: built-in defaults, then an optional YAML file (CONFIG_FILE),
+// then environment variables. Any string value may be a reference to AWS SSM
+// Parameter Store ("ssm:/path") or Secrets Manager ("secret:name" or
+// "secret:name#json-key"), resolved after layering. CONFIG_SECRETS_FILE points
+// to a local YAML file that stands in for both services during development.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/authorizer"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/httpx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/repository"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/auth"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/db"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/encryption"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/health"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/ratelimit"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/signature"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/server"
+)
+
+// redactedValue replaces sensitive values in the configuration dump
+const redactedValue = "[REDACTED]"
+
+// Config is the effective configuration of the service
+type Config struct {
+	Service    ServiceConfig    `yaml:"service"`
+	Log        LogConfig        `yaml:"log"`
+	DynamoDB   DynamoDBConfig   `yaml:"dynamodb"`
+	HTTP       HTTPConfig       `yaml:"http"`
+	CORS       CORSConfig       `yaml:"cors"`
+	Tracing    TracingConfig    `yaml:"tracing"`
+	Metrics    MetricsConfig    `yaml:"metrics"`
+	Health     HealthConfig     `yaml:"health"`
+	Auth       AuthConfig       `yaml:"auth"`
+	RateLimit  RateLimitConfig  `yaml:"rate_limit"`
+	Signature  SignatureConfig  `yaml:"signature"`
+	Encryption EncryptionConfig `yaml:"encryption"`
+
+	// references holds the paths of values resolved from Parameter Store or
+	// Secrets Manager
+	references map[string]bool
+
+	// deprecations describes the deprecated settings in use
+	deprecations []string
+}
+
+// ServiceConfig identifies the running service
+type ServiceConfig struct {
+	// Name is reported in logs and by the health endpoint
+	Name string `yaml:"name" env:"SERVICE_NAME"`
+
+	// Version is reported in logs and in the OpenAPI document
+	Version string `yaml:"version" env:"APP_VERSION"`
+}
+
+// LogConfig configures the structured logger
+type LogConfig struct {
+	// Level is one of debug, info, warn or error
+	Level string `yaml:"level" env:"LOG_LEVEL"`
+
+	// Format is json, text or emf (CloudWatch Embedded Metric Format)
+	Format string `yaml:"format" env:"LOG_FORMAT"`
+
+	// ContextFields lists the request context identifiers added to every
+	// entry, such as request_id, merchant_id or trace_id
+	ContextFields []string `yaml:"context_fields" env:"LOG_CONTEXT_FIELDS"`
+
+	// RedactKeys lists field names whose values are never logged, on top of
+	// the built-in card, credential and personal data names
+	RedactKeys []string `yaml:"redact_keys" env:"LOG_REDACT_KEYS"`
+
+	// Sampling limits repeated entries below the error level
+	Sampling LogSamplingConfig `yaml:"sampling"`
+
+	// LevelParameter names an SSM parameter holding the level, reloaded
+	// while running. The value is a level name or a JSON document with
+	// per-merchant levels.
+	LevelParameter string `yaml:"level_parameter" env:"LOG_LEVEL_PARAMETER"`
+
+	// LevelFile is a local file holding the level, reloaded while running
+	LevelFile string `yaml:"level_file" env:"LOG_LEVEL_FILE"`
+
+	// LevelTTL is how long a reloaded level is used before loading it again
+	LevelTTL time.Duration `yaml:"level_ttl" env:"LOG_LEVEL_TTL"`
+
+	// LevelOverrideKey verifies the signed X-Debug-Log header that raises
+	// the level of a single request. Overrides are rejected when empty.
+	LevelOverrideKey string `yaml:"level_override_key" env:"LOG_LEVEL_OVERRIDE_KEY" log:"redact"`
+
+	// File also writes entries to a rotating local file when its path is set
+	File LogFileConfig `yaml:"file"`
+
+	// OTLP also exports entries to an OpenTelemetry collector when its
+	// endpoint is set
+	OTLP LogOTLPConfig `yaml:"otlp"`
+}
+
+// LogFileConfig writes entries to a file rotated by size and age
+type LogFileConfig struct {
+	Path        string        `yaml:"path" env:"LOG_FILE_PATH"`
+	Format      string        `yaml:"format" env:"LOG_FILE_FORMAT"`
+	MinLevel    string        `yaml:"min_level" env:"LOG_FILE_MIN_LEVEL"`
+	MaxBytes    int64         `yaml:"max_bytes" env:"LOG_FILE_MAX_BYTES"`
+	RotateEvery time.Duration `yaml:"rotate_every" env:"LOG_FILE_ROTATE_EVERY"`
+	MaxBackups  int           `yaml:"max_backups" env:"LOG_FILE_MAX_BACKUPS"`
+}
+
+// LogOTLPConfig exports entries over OTLP/HTTP. Headers is a comma-separated
+// list of Name=Value pairs, such as the API key of a hosted collector.
+type LogOTLPConfig struct {
+	Endpoint      string        `yaml:"endpoint" env:"LOG_OTLP_ENDPOINT"`
+	MinLevel      string        `yaml:"min_level" env:"LOG_OTLP_MIN_LEVEL"`
+	Headers       string        `yaml:"headers" env:"LOG_OTLP_HEADERS" log:"redact"`
+	BatchSize     int           `yaml:"batch_size" env:"LOG_OTLP_BATCH_SIZE"`
+	FlushInterval time.Duration `yaml:"flush_interval" env:"LOG_OTLP_FLUSH_INTERVAL"`
+	Timeout       time.Duration `yaml:"timeout" env:"LOG_OTLP_TIMEOUT"`
+}
+
+// LogSamplingConfig logs the first Initial entries of each message per
+// Interval, then one in every Thereafter. Disabled when Initial is zero.
+type LogSamplingConfig struct {
+	Initial        int           `yaml:"initial" env:"LOG_SAMPLING_INITIAL"`
+	Thereafter     int           `yaml:"thereafter" env:"LOG_SAMPLING_THEREAFTER"`
+	Interval       time.Duration `yaml:"interval" env:"LOG_SAMPLING_INTERVAL"`
+	ReportInterval time.Duration `yaml:"report_interval" env:"LOG_SAMPLING_REPORT_INTERVAL"`
+}
+
+// DynamoDBConfig configures the DynamoDB client and table
+type DynamoDBConfig struct {
+	// Endpoint overrides the AWS endpoint, e.g. for DynamoDB Local
+	Endpoint string `yaml:"endpoint" env:"DYNAMODB_ENDPOINT"`
+
+	// Region is the AWS region of the table
+	Region string `yaml:"region" env:"AWS_REGION"`
+
+	// TableName is the chargebacks table. CHARGEBACK_TABLE_NAME is still
+	// read when DYNAMODB_TABLE is unset.
+	TableName string `yaml:"table_name" env:"DYNAMODB_TABLE" deprecatedEnv:"CHARGEBACK_TABLE_NAME"`
+}
+
+// HTTPConfig configures request handling
+type HTTPConfig struct {
+	// Port is the port the local HTTP server listens on
+	Port int `yaml:"port" env:"PORT"`
+
+	// MaxBodyBytes limits the size of request bodies
+	MaxBodyBytes int64 `yaml:"max_body_bytes" env:"MAX_BODY_BYTES"`
+
+	// ReadTimeout bounds reading a whole request on the local server
+	ReadTimeout time.Duration `yaml:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+
+	// WriteTimeout bounds writing a response on the local server
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+
+	// IdleTimeout bounds idle keep-alive connections on the local server
+	IdleTimeout time.Duration `yaml:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+
+	// ShutdownTimeout bounds how long the local server drains in-flight requests
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+
+	// RequestTimeout bounds handling of a single request in both adapters.
+	// Keep it below the Lambda timeout so callers get a JSON 503.
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"HTTP_REQUEST_TIMEOUT"`
+}
+
+// CORSConfig configures the cross-origin policy applied by both adapters
+type CORSConfig struct {
+	// AllowedOrigins lists exact or wildcard-subdomain origins; empty disables CORS
+	AllowedOrigins []string `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+
+	// AllowedMethods limits the methods advertised to browsers
+	AllowedMethods []string `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+
+	// AllowedHeaders lists the request headers browsers may send
+	AllowedHeaders []string `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+
+	// ExposedHeaders lists the response headers browsers may read
+	ExposedHeaders []string `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+
+	// AllowCredentials allows cookies and Authorization headers
+	AllowCredentials bool `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+
+	// MaxAge is how long browsers may cache preflight responses
+	MaxAge time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// TracingConfig configures OpenTelemetry tracing
+type TracingConfig struct {
+	// Exporter is none, otlp, xray or stdout
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER"`
+
+	// Endpoint is the base URL of the OTLP collector; the OTEL_EXPORTER_OTLP_*
+	// variables apply when empty
+	Endpoint string `yaml:"endpoint" env:"TRACING_OTLP_ENDPOINT"`
+
+	// Headers is a comma-separated list of Name=Value pairs sent to the
+	// OTLP collector
+	Headers string `yaml:"headers" env:"TRACING_OTLP_HEADERS" log:"redact"`
+
+	// XRayDaemonAddress is set by Lambda when active tracing is enabled
+	XRayDaemonAddress string `yaml:"xray_daemon_address" env:"AWS_XRAY_DAEMON_ADDRESS"`
+
+	// SampleRatio is the fraction of new traces recorded, from 0 to 1
+	SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+}
+
+// MetricsConfig configures business and technical metrics
+type MetricsConfig struct {
+	// Exporter is none, emf or prometheus
+	Exporter string `yaml:"exporter" env:"METRICS_EXPORTER"`
+
+	// Path serves the Prometheus exposition on the local server
+	Path string `yaml:"path" env:"METRICS_PATH"`
+
+	// Namespace of the EMF metrics, the service name if empty
+	Namespace string `yaml:"namespace" env:"METRICS_NAMESPACE"`
+
+	// EMFDimensions lists the labels used as EMF dimensions; every label
+	// but merchant_id if empty. Each combination of dimension values is a
+	// CloudWatch metric.
+	EMFDimensions []string `yaml:"emf_dimensions" env:"METRICS_EMF_DIMENSIONS"`
+
+	// PrometheusLabels opts high-cardinality labels such as merchant_id into
+	// the Prometheus series, which leave them out by default
+	PrometheusLabels []string `yaml:"prometheus_labels" env:"METRICS_PROMETHEUS_LABELS"`
+
+	// FlushInterval writes buffered EMF values periodically on the local
+	// server; Lambda flushes after each invocation
+	FlushInterval time.Duration `yaml:"flush_interval" env:"METRICS_FLUSH_INTERVAL"`
+}
+
+// HealthConfig configures the dependency probes of the readiness endpoint
+type HealthConfig struct {
+	// CacheTTL is how long a probe result is reused, so frequent readiness
+	// checks do not load the dependencies
+	CacheTTL time.Duration `yaml:"cache_ttl" env:"HEALTH_CACHE_TTL"`
+
+	// Timeout bounds a single probe
+	Timeout time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT"`
+
+	// CardVaultURL is the health URL of the card vault, not probed if empty
+	CardVaultURL string `yaml:"card_vault_url" env:"HEALTH_CARD_VAULT_URL"`
+
+	// EventPublisherURL is the health URL of the event publisher, not
+	// probed if empty
+	EventPublisherURL string `yaml:"event_publisher_url" env:"HEALTH_EVENT_PUBLISHER_URL"`
+}
+
+// AuthConfig configures the authentication of API callers
+type AuthConfig struct {
+	// Enabled rejects requests outside PublicPaths without valid credentials
+	Enabled bool `yaml:"enabled" env:"AUTH_ENABLED"`
+
+	// PublicPaths are served without credentials
+	PublicPaths []string `yaml:"public_paths" env:"AUTH_PUBLIC_PATHS"`
+
+	// JWKSURL is fetched for the keys verifying bearer tokens
+	JWKSURL string `yaml:"jwks_url" env:"AUTH_JWKS_URL"`
+
+	// JWKSFile is read for the keys verifying bearer tokens, instead of JWKSURL
+	JWKSFile string `yaml:"jwks_file" env:"AUTH_JWKS_FILE"`
+
+	// JWKSRefresh is how long fetched keys are used before fetching them again
+	JWKSRefresh time.Duration `yaml:"jwks_refresh" env:"AUTH_JWKS_REFRESH"`
+
+	// Issuer must match the "iss" claim of bearer tokens
+	Issuer string `yaml:"issuer" env:"AUTH_JWT_ISSUER"`
+
+	// Audience must be among the "aud" claim values of bearer tokens
+	Audience string `yaml:"audience" env:"AUTH_JWT_AUDIENCE"`
+
+	// Leeway is the clock skew tolerated on token expiry
+	Leeway time.Duration `yaml:"leeway" env:"AUTH_JWT_LEEWAY"`
+
+	// APIKeysTable is the DynamoDB table of hashed API keys; API keys are
+	// rejected when empty
+	APIKeysTable string `yaml:"api_keys_table" env:"AUTH_API_KEYS_TABLE"`
+
+	// APIKeyCacheTTL is how long a looked up API key is reused
+	APIKeyCacheTTL time.Duration `yaml:"api_key_cache_ttl" env:"AUTH_API_KEY_CACHE_TTL"`
+
+	// AuthorizerCacheTTL is how long the Lambda authorizer reuses the caller
+	// of the same credentials; negative disables its cache
+	AuthorizerCacheTTL time.Duration `yaml:"authorizer_cache_ttl" env:"AUTH_AUTHORIZER_CACHE_TTL"`
+
+	// MerchantClaim, RolesClaim and ScopesClaim name the claims describing
+	// a caller in tokens and authorizer contexts
+	MerchantClaim string `yaml:"merchant_claim" env:"AUTH_MERCHANT_CLAIM"`
+	RolesClaim    string `yaml:"roles_claim" env:"AUTH_ROLES_CLAIM"`
+	ScopesClaim   string `yaml:"scopes_claim" env:"AUTH_SCOPES_CLAIM"`
+}
+
+// RateLimitConfig configures the request rate limit of each caller and the
+// daily chargeback quota of each merchant
+type RateLimitConfig struct {
+	// Rate is the sustained requests a second allowed to each caller; zero
+	// disables rate limiting
+	Rate float64 `yaml:"rate" env:"RATE_LIMIT_RATE"`
+
+	// Burst is how many requests a caller may send at once, the rate
+	// rounded up if zero
+	Burst int64 `yaml:"burst" env:"RATE_LIMIT_BURST"`
+
+	// DailyQuota is how many chargebacks each merchant may open a UTC day;
+	// zero disables quotas
+	DailyQuota int64 `yaml:"daily_quota" env:"RATE_LIMIT_DAILY_QUOTA"`
+
+	// MerchantQuotas is a comma-separated list of MerchantID=Quota pairs
+	// overriding DailyQuota; a quota of 0 exempts the merchant
+	MerchantQuotas string `yaml:"merchant_quotas" env:"RATE_LIMIT_MERCHANT_QUOTAS"`
+
+	// Backend keeps limits in "memory" or in a "dynamodb" table shared by
+	// every instance
+	Backend string `yaml:"backend" env:"RATE_LIMIT_BACKEND"`
+
+	// Table is the DynamoDB table of the dynamodb backend
+	Table string `yaml:"table" env:"RATE_LIMIT_TABLE"`
+}
+
+// SignatureConfig configures the verification of callbacks signed by
+// acquirers
+type SignatureConfig struct {
+	// Sources is a JSON array of the acquirers allowed to push callbacks,
+	// each with an "id", an "algorithm" (hmac-sha256 or rsa-pss-sha256) and
+	// a "secret" or PEM "public_key"; empty disables signed callbacks
+	Sources string `yaml:"sources" env:"SIGNATURE_SOURCES" log:"redact"`
+
+	// Paths are the callbacks whose requests must be signed
+	Paths []string `yaml:"paths" env:"SIGNATURE_PATHS"`
+
+	// Window is how far the timestamp of a callback may be from now
+	Window time.Duration `yaml:"window" env:"SIGNATURE_WINDOW"`
+
+	// NonceTable is the DynamoDB table remembering nonces across instances;
+	// nonces are kept in memory when empty
+	NonceTable string `yaml:"nonce_table" env:"SIGNATURE_NONCE_TABLE"`
+}
+
+// EncryptionConfig configures the encryption of chargeback attributes at
+// rest
+type EncryptionConfig struct {
+	// Fields are the attributes encrypted before they are stored, among
+	// description and card_number; empty disables encryption
+	Fields []string `yaml:"fields" env:"ENCRYPTION_FIELDS"`
+
+	// Provider wraps data keys with a local "keyring" file or with "kms"
+	Provider string `yaml:"provider" env:"ENCRYPTION_PROVIDER"`
+
+	// KeyringFile is the JSON keyring of the keyring provider
+	KeyringFile string `yaml:"keyring_file" env:"ENCRYPTION_KEYRING_FILE"`
+
+	// KMSKeyID is the ID, ARN or alias of the KMS key of the kms provider
+	KMSKeyID string `yaml:"kms_key_id" env:"ENCRYPTION_KMS_KEY_ID"`
+}
+
+// Defaults returns the configuration used when nothing else is set
+func Defaults() Config {
+	return Config{
+		Service: ServiceConfig{
+			Name:    "chargeback-api",
+			Version: "1.0.0",
+		},
+		Log: LogConfig{
+			Level:         "info",
+			Format:        "json",
+			ContextFields: slices.Clone(requestctx.DefaultFields),
+			LevelTTL:      logging.DefaultLevelTTL,
+			File: LogFileConfig{
+				Format:      "json",
+				MinLevel:    "debug",
+				MaxBytes:    100 << 20,
+				RotateEvery: 24 * time.Hour,
+				MaxBackups:  7,
+			},
+			OTLP: LogOTLPConfig{
+				MinLevel:      "debug",
+				BatchSize:     logging.DefaultOTLPBatchSize,
+				FlushInterval: 5 * time.Second,
+				Timeout:       logging.DefaultOTLPTimeout,
+			},
+		},
+		DynamoDB: DynamoDBConfig{
+			Region:    "us-east-1",
+			TableName: "chargebacks",
+		},
+		HTTP: HTTPConfig{
+			Port:            8080,
+			MaxBodyBytes:    router.DefaultMaxBodyBytes,
+			ReadTimeout:     server.DefaultReadTimeout,
+			WriteTimeout:    server.DefaultWriteTimeout,
+			IdleTimeout:     server.DefaultIdleTimeout,
+			ShutdownTimeout: server.DefaultShutdownTimeout,
+			RequestTimeout:  25 * time.Second,
+		},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", requestctx.RequestIDHeader, requestctx.CorrelationIDHeader, middleware.APIKeyHeader},
+			ExposedHeaders: append([]string{requestctx.RequestIDHeader, requestctx.CorrelationIDHeader}, httpx.RateLimitHeaders...),
+			MaxAge:         10 * time.Minute,
+		},
+		Tracing: TracingConfig{
+			Exporter:    string(tracing.ExporterNone),
+			SampleRatio: 1,
+		},
+		Metrics: MetricsConfig{
+			Exporter:      string(metrics.ExporterNone),
+			Path:          "/metrics",
+			FlushInterval: time.Minute,
+		},
+		Health: HealthConfig{
+			CacheTTL: health.DefaultCacheTTL,
+			Timeout:  health.DefaultTimeout,
+		},
+		Auth: AuthConfig{
+			PublicPaths:        slices.Clone(middleware.DefaultPublicPaths),
+			JWKSRefresh:        auth.DefaultJWKSRefresh,
+			Leeway:             auth.DefaultLeeway,
+			APIKeyCacheTTL:     auth.DefaultAPIKeyCacheTTL,
+			AuthorizerCacheTTL: authorizer.DefaultCacheTTL,
+			MerchantClaim:      auth.DefaultClaimNames.Merchant,
+			RolesClaim:         auth.DefaultClaimNames.Roles,
+			ScopesClaim:        auth.DefaultClaimNames.Scopes,
+		},
+		RateLimit: RateLimitConfig{
+			Backend: string(ratelimit.BackendMemory),
+		},
+		Signature: SignatureConfig{
+			Paths:  slices.Clone(middleware.DefaultSignedPaths),
+			Window: signature.DefaultWindow,
+		},
+		Encryption: EncryptionConfig{
+			Provider: string(encryption.ProviderKeyring),
+		},
+	}
+}
+
+// Validate checks every setting and reports all problems at once
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Service.Name == "" {
+		errs = append(errs, errors.New("service.name is required"))
+	}
+	if c.Service.Version == "" {
+		errs = append(errs, errors.New("service.version is required"))
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	if _, err := parseLogFormat(c.Log.Format); err != nil {
+		errs = append(errs, fmt.Errorf("log.format: %w", err))
+	}
+	for _, field := range c.Log.ContextFields {
+		if !requestctx.IsField(field) {
+			errs = append(errs, fmt.Errorf("log.context_fields: unknown field %q", field))
+		}
+	}
+	if err := c.LoggerConfig().Sampling.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("log.sampling: %w", err))
+	}
+	if c.Log.LevelParameter != "" && c.Log.LevelFile != "" {
+		errs = append(errs, errors.New("log: level_parameter and level_file are mutually exclusive"))
+	}
+	if c.Log.LevelTTL < 0 {
+		errs = append(errs, errors.New("log.level_ttl must not be negative"))
+	}
+	if c.Log.File.Path != "" {
+		if sink, err := c.logFileSink(); err != nil {
+			errs = append(errs, fmt.Errorf("log.file: %w", err))
+		} else if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("log.file: %w", err))
+		}
+	}
+	if c.Log.OTLP.Endpoint != "" {
+		if sink, err := c.logOTLPSink(); err != nil {
+			errs = append(errs, fmt.Errorf("log.otlp: %w", err))
+		} else if err := sink.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("log.otlp: %w", err))
+		}
+	}
+
+	if c.DynamoDB.Region == "" {
+		errs = append(errs, errors.New("dynamodb.region is required"))
+	}
+	if c.DynamoDB.TableName == "" {
+		errs = append(errs, errors.New("dynamodb.table_name is required"))
+	}
+	if c.DynamoDB.Endpoint != "" {
+		if endpoint, err := url.Parse(c.DynamoDB.Endpoint); err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			errs = append(errs, fmt.Errorf("dynamodb.endpoint must be an http(s) URL, got %q", c.DynamoDB.Endpoint))
+		}
+	}
+
+	if c.HTTP.Port < 1 || c.HTTP.Port > 65535 {
+		errs = append(errs, fmt.Errorf("http.port must be between 1 and 65535, got %d", c.HTTP.Port))
+	}
+	if c.HTTP.MaxBodyBytes <= 0 {
+		errs = append(errs, fmt.Errorf("http.max_body_bytes must be positive, got %d", c.HTTP.MaxBodyBytes))
+	}
+	for _, timeout := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"http.read_timeout", c.HTTP.ReadTimeout},
+		{"http.write_timeout", c.HTTP.WriteTimeout},
+		{"http.idle_timeout", c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", c.HTTP.ShutdownTimeout},
+		{"http.request_timeout", c.HTTP.RequestTimeout},
+	} {
+		if timeout.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", timeout.name, timeout.value))
+		}
+	}
+
+	if err := c.CORSPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+
+	if _, err := tracing.ParseExporter(c.Tracing.Exporter); err != nil {
+		errs = append(errs, fmt.Errorf("tracing.exporter: %w", err))
+	}
+	if _, err := parseHeaders(c.Tracing.Headers); err != nil {
+		errs = append(errs, fmt.Errorf("tracing.headers: %w", err))
+	}
+	if err := c.TracingConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tracing: %w", err))
+	}
+
+	if _, err := metrics.ParseExporter(c.Metrics.Exporter); err != nil {
+		errs = append(errs, fmt.Errorf("metrics.exporter: %w", err))
+	}
+	if !strings.HasPrefix(c.Metrics.Path, "/") {
+		errs = append(errs, fmt.Errorf("metrics.path must start with /, got %q", c.Metrics.Path))
+	}
+	if err := c.MetricsConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("metrics: %w", err))
+	}
+
+	if c.Health.CacheTTL <= 0 {
+		errs = append(errs, fmt.Errorf("health.cache_ttl must be positive, got %s", c.Health.CacheTTL))
+	}
+	if c.Health.Timeout <= 0 {
+		errs = append(errs, fmt.Errorf("health.timeout must be positive, got %s", c.Health.Timeout))
+	}
+	if err := c.HealthConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("health: %w", err))
+	}
+
+	if c.Auth.Enabled {
+		if err := c.AuthConfig().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("auth: %w", err))
+		}
+	}
+	for _, path := range c.Auth.PublicPaths {
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("auth.public_paths must start with /, got %q", path))
+		}
+	}
+
+	if _, err := parseQuotas(c.RateLimit.MerchantQuotas); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limit.merchant_quotas: %w", err))
+	} else if err := c.RateLimitConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limit: %w", err))
+	}
+
+	if sources, err := signature.ParseSources(c.Signature.Sources); err != nil {
+		errs = append(errs, fmt.Errorf("signature.sources: %w", err))
+	} else if len(sources) > 0 {
+		if err := c.SignatureConfig().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("signature: %w", err))
+		}
+	}
+	for _, path := range c.Signature.Paths {
+		if !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf("signature.paths must start with /, got %q", path))
+		}
+	}
+
+	if len(c.Encryption.Fields) > 0 {
+		if err := c.EncryptionConfig().Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("encryption: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// LoggerConfig returns the structured logger settings. The configuration must
+// have been validated.
+func (c *Config) LoggerConfig() logging.LoggerConfig {
+	level, _ := logging.ParseLevel(c.Log.Level)
+	format, _ := parseLogFormat(c.Log.Format)
+
+	// The defaults list every field, so an empty list means none
+	contextKeys := c.Log.ContextFields
+	if contextKeys == nil {
+		contextKeys = []string{}
+	}
+
+	return logging.LoggerConfig{
+		Level:       level,
+		Format:      format,
+		ServiceName: c.Service.Name,
+		Version:     c.Service.Version,
+		ContextKeys: contextKeys,
+		RedactKeys:  c.Log.RedactKeys,
+		Sampling: logging.SamplingConfig{
+			Initial:        c.Log.Sampling.Initial,
+			Thereafter:     c.Log.Sampling.Thereafter,
+			Interval:       c.Log.Sampling.Interval,
+			ReportInterval: c.Log.Sampling.ReportInterval,
+		},
+		LevelTTL:         c.Log.LevelTTL,
+		LevelOverrideKey: []byte(c.Log.LevelOverrideKey),
+		Sinks:            c.logSinks(format),
+	}
+}
+
+// logSinks returns the outputs of the logger, or nil when entries are only
+// written to stdout
+func (c *Config) logSinks(format logging.LogFormat) []logging.SinkConfig {
+	if c.Log.File.Path == "" && c.Log.OTLP.Endpoint == "" {
+		return nil
+	}
+
+	sinks := []logging.SinkConfig{{Format: format}}
+	if c.Log.File.Path != "" {
+		sink, _ := c.logFileSink()
+		sinks = append(sinks, sink)
+	}
+	if c.Log.OTLP.Endpoint != "" {
+		sink, _ := c.logOTLPSink()
+		sinks = append(sinks, sink)
+	}
+	return sinks
+}
+
+// logFileSink returns the rotating file sink
+func (c *Config) logFileSink() (logging.SinkConfig, error) {
+	format, err := parseLogFormat(c.Log.File.Format)
+	if err != nil {
+		return logging.SinkConfig{}, fmt.Errorf("format: %w", err)
+	}
+	minLevel, err := logging.ParseLevel(c.Log.File.MinLevel)
+	if err != nil {
+		return logging.SinkConfig{}, fmt.Errorf("min_level: %w", err)
+	}
+
+	return logging.SinkConfig{
+		Format:   format,
+		MinLevel: minLevel,
+		File: &logging.FileConfig{
+			Path:        c.Log.File.Path,
+			MaxBytes:    c.Log.File.MaxBytes,
+			RotateEvery: c.Log.File.RotateEvery,
+			MaxBackups:  c.Log.File.MaxBackups,
+		},
+	}, nil
+}
+
+// logOTLPSink returns the OpenTelemetry collector sink
+func (c *Config) logOTLPSink() (logging.SinkConfig, error) {
+	minLevel, err := logging.ParseLevel(c.Log.OTLP.MinLevel)
+	if err != nil {
+		return logging.SinkConfig{}, fmt.Errorf("min_level: %w", err)
+	}
+	headers, err := parseHeaders(c.Log.OTLP.Headers)
+	if err != nil {
+		return logging.SinkConfig{}, fmt.Errorf("headers: %w", err)
+	}
+
+	return logging.SinkConfig{
+		Format:   logging.FormatOTLP,
+		MinLevel: minLevel,
+		OTLP: logging.OTLPConfig{
+			Endpoint:      c.Log.OTLP.Endpoint,
+			Headers:       headers,
+			BatchSize:     c.Log.OTLP.BatchSize,
+			FlushInterval: c.Log.OTLP.FlushInterval,
+			Timeout:       c.Log.OTLP.Timeout,
+		},
+	}, nil
+}
+
+// LogLevelSource returns the source the log level is reloaded from, or nil
+// when the level is fixed
+func (c *Config) LogLevelSource(ctx context.Context) (logging.LevelSource, error) {
+	switch {
+	case c.Log.LevelFile != "":
+		return logging.FileLevelSource{Path: c.Log.LevelFile}, nil
+	case c.Log.LevelParameter != "":
+		parameters, _, err := newAWSStores(ctx, c.DynamoDB.Region)
+		if err != nil {
+			return nil, err
+		}
+		return logging.ParameterLevelSource{Store: parameters, Name: c.Log.LevelParameter}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// TracingConfig returns the tracing settings. The configuration must have
+// been validated.
+func (c *Config) TracingConfig() tracing.Config {
+	exporter, _ := tracing.ParseExporter(c.Tracing.Exporter)
+	headers, _ := parseHeaders(c.Tracing.Headers)
+
+	return tracing.Config{
+		Exporter:          exporter,
+		ServiceName:       c.Service.Name,
+		Version:           c.Service.Version,
+		Endpoint:          c.Tracing.Endpoint,
+		Headers:           headers,
+		XRayDaemonAddress: c.Tracing.XRayDaemonAddress,
+		SampleRatio:       c.Tracing.SampleRatio,
+	}
+}
+
+// MetricsConfig returns the metrics settings. The configuration must have
+// been validated.
+func (c *Config) MetricsConfig() metrics.Config {
+	exporter, _ := metrics.ParseExporter(c.Metrics.Exporter)
+
+	var dimensions []string
+	if len(c.Metrics.EMFDimensions) > 0 {
+		dimensions = c.Metrics.EMFDimensions
+	}
+
+	return metrics.Config{
+		Exporter:    exporter,
+		ServiceName: c.Service.Name,
+		EMF: metrics.EMFConfig{
+			Namespace:     c.Metrics.Namespace,
+			Dimensions:    dimensions,
+			FlushInterval: c.Metrics.FlushInterval,
+		},
+		PrometheusLabels: c.Metrics.PrometheusLabels,
+	}
+}
+
+// HealthConfig returns the settings of the dependency probes
+func (c *Config) HealthConfig() health.Config {
+	endpoints := make(map[string]string)
+	if c.Health.CardVaultURL != "" {
+		endpoints["card_vault"] = c.Health.CardVaultURL
+	}
+	if c.Health.EventPublisherURL != "" {
+		endpoints["event_publisher"] = c.Health.EventPublisherURL
+	}
+
+	return health.Config{
+		CacheTTL:  c.Health.CacheTTL,
+		Timeout:   c.Health.Timeout,
+		Endpoints: endpoints,
+	}
+}
+
+// AuthConfig returns the authentication settings
+func (c *Config) AuthConfig() auth.Config {
+	return auth.Config{
+		JWKSURL:     c.Auth.JWKSURL,
+		JWKSFile:    c.Auth.JWKSFile,
+		JWKSRefresh: c.Auth.JWKSRefresh,
+		JWT: auth.JWTConfig{
+			Issuer:   c.Auth.Issuer,
+			Audience: c.Auth.Audience,
+			Leeway:   c.Auth.Leeway,
+		},
+		APIKeysTable:   c.Auth.APIKeysTable,
+		APIKeyCacheTTL: c.Auth.APIKeyCacheTTL,
+		Claims: auth.ClaimNames{
+			Merchant: c.Auth.MerchantClaim,
+			Roles:    c.Auth.RolesClaim,
+			Scopes:   c.Auth.ScopesClaim,
+		},
+	}
+}
+
+// AuthorizerConfig returns the settings of the Lambda authorizer, whose
+// context carries the claims read by the API function
+func (c *Config) AuthorizerConfig() authorizer.Config {
+	return authorizer.Config{
+		CacheTTL:    c.Auth.AuthorizerCacheTTL,
+		MerchantKey: c.Auth.MerchantClaim,
+		RolesKey:    c.Auth.RolesClaim,
+		ScopesKey:   c.Auth.ScopesClaim,
+	}
+}
+
+// Authenticator returns the authenticator of API callers, reading API keys
+// through the given DynamoDB client, or nil if authentication is disabled
+func (c *Config) Authenticator(dynamo auth.GetItemAPI) (service.Authenticator, error) {
+	if !c.Auth.Enabled {
+		return nil, nil
+	}
+	authenticator, err := auth.Setup(c.AuthConfig(), dynamo)
+	if err != nil {
+		return nil, err
+	}
+	return authenticator, nil
+}
+
+// RateLimitConfig returns the rate limit and quota settings. The
+// configuration must have been validated.
+func (c *Config) RateLimitConfig() ratelimit.Config {
+	quotas, _ := parseQuotas(c.RateLimit.MerchantQuotas)
+	return ratelimit.Config{
+		Rate:           c.RateLimit.Rate,
+		Burst:          c.RateLimit.Burst,
+		DailyQuota:     c.RateLimit.DailyQuota,
+		MerchantQuotas: quotas,
+		Backend:        ratelimit.Backend(c.RateLimit.Backend),
+		Table:          c.RateLimit.Table,
+	}
+}
+
+// RateLimits returns the request rate limiter of callers and the daily
+// chargeback quota of merchants, each nil if disabled. Limits shared between
+// instances are kept through the given DynamoDB client.
+func (c *Config) RateLimits(dynamo ratelimit.DynamoDBAPI) (service.RateLimiter, service.Quota, error) {
+	config := c.RateLimitConfig()
+	if !config.RateLimited() && !config.QuotaLimited() {
+		return nil, nil, nil
+	}
+
+	limiter, err := ratelimit.Setup(config, dynamo)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rateLimiter service.RateLimiter
+	if config.RateLimited() {
+		rateLimiter = limiter
+	}
+	var quota service.Quota
+	if config.QuotaLimited() {
+		quota = limiter
+	}
+	return rateLimiter, quota, nil
+}
+
+// SignatureConfig returns the settings verifying signed callbacks. The
+// configuration must have been validated.
+func (c *Config) SignatureConfig() signature.Config {
+	sources, _ := signature.ParseSources(c.Signature.Sources)
+	return signature.Config{
+		Sources:    sources,
+		Window:     c.Signature.Window,
+		NonceTable: c.Signature.NonceTable,
+	}
+}
+
+// SignatureVerifier returns the verifier of signed callbacks, remembering
+// nonces through the given DynamoDB client, or nil if no source is
+// configured
+func (c *Config) SignatureVerifier(dynamo signature.PutItemAPI) (service.SignatureVerifier, error) {
+	config := c.SignatureConfig()
+	if len(config.Sources) == 0 {
+		return nil, nil
+	}
+	verifier, err := signature.Setup(config, dynamo)
+	if err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+// EncryptionConfig returns the settings encrypting chargeback attributes
+func (c *Config) EncryptionConfig() encryption.Config {
+	return encryption.Config{
+		Fields:      c.Encryption.Fields,
+		Provider:    encryption.Provider(c.Encryption.Provider),
+		KeyringFile: c.Encryption.KeyringFile,
+		KMSKeyID:    c.Encryption.KMSKeyID,
+		Region:      c.DynamoDB.Region,
+	}
+}
+
+// EncryptChargebacks wraps the repository storing chargebacks so the
+// configured attributes are encrypted at rest, or returns it as it is if
+// encryption is disabled
+func (c *Config) EncryptChargebacks(ctx context.Context, repo repository.ChargebackRepository) (repository.ChargebackRepository, error) {
+	config := c.EncryptionConfig()
+	if len(config.Fields) == 0 {
+		return repo, nil
+	}
+	cipher, err := encryption.Setup(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	return encryption.NewChargebackRepository(repo, cipher, config.Fields), nil
+}
+
+// DynamoDBClientConfig returns the DynamoDB client settings
+func (c *Config) DynamoDBClientConfig() db.DynamoDBConfig {
+	return db.DynamoDBConfig{
+		Endpoint:  c.DynamoDB.Endpoint,
+		Region:    c.DynamoDB.Region,
+		TableName: c.DynamoDB.TableName,
+	}
+}
+
+// RouterConfig returns the settings of the chargeback API router
+func (c *Config) RouterConfig() router.Config {
+	return router.Config{
+		ServiceName:  c.Service.Name,
+		Version:      c.Service.Version,
+		MaxBodyBytes: c.HTTP.MaxBodyBytes,
+	}
+}
+
+// ServerConfig returns the settings of the local HTTP server
+func (c *Config) ServerConfig() server.ServerConfig {
+	return server.ServerConfig{
+		Port:            strconv.Itoa(c.HTTP.Port),
+		ReadTimeout:     c.HTTP.ReadTimeout,
+		WriteTimeout:    c.HTTP.WriteTimeout,
+		IdleTimeout:     c.HTTP.IdleTimeout,
+		ShutdownTimeout: c.HTTP.ShutdownTimeout,
+		RequestTimeout:  c.HTTP.RequestTimeout,
+		API:             c.RouterConfig(),
+		CORS:            c.CORSPolicy(),
+		MetricsPath:     c.Metrics.Path,
+		PublicPaths:     c.Auth.PublicPaths,
+		SignedPaths:     c.Signature.Paths,
+	}
+}
+
+// MiddlewareOptions returns the settings of the middleware chain shared by
+// both adapters
+func (c *Config) MiddlewareOptions(logger service.Logger, routes middleware.RouteMethods) middleware.Options {
+	return middleware.Options{
+		Logger:         logger,
+		CORS:           c.CORSPolicy(),
+		Routes:         routes,
+		RequestTimeout: c.HTTP.RequestTimeout,
+		MaxBodyBytes:   c.HTTP.MaxBodyBytes,
+		PublicPaths:    c.Auth.PublicPaths,
+		SignedPaths:    c.Signature.Paths,
+	}
+}
+
+// CORSPolicy returns the cross-origin policy shared by both adapters
+func (c *Config) CORSPolicy() middleware.CORSPolicy {
+	return middleware.CORSPolicy{
+		AllowedOrigins:   c.CORS.AllowedOrigins,
+		AllowedMethods:   c.CORS.AllowedMethods,
+		AllowedHeaders:   c.CORS.AllowedHeaders,
+		ExposedHeaders:   c.CORS.ExposedHeaders,
+		AllowCredentials: c.CORS.AllowCredentials,
+		MaxAge:           c.CORS.MaxAge,
+	}
+}
+
+// Deprecations describes the deprecated settings the configuration was
+// loaded from, to be logged as warnings at startup
+func (c *Config) Deprecations() []string {
+	return c.deprecations
+}
+
+// Redacted returns the effective configuration keyed by setting path, e.g.
+// "dynamodb.table_name". Values resolved from Parameter Store or Secrets
+// Manager and fields tagged `log:"redact"` are replaced so the dump is safe
+// to log.
+func (c *Config) Redacted() map[string]interface{} {
+	dump := make(map[string]interface{})
+	walkFields(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		if (c.references[path] || field.Tag.Get("log") == "redact") && !value.IsZero() {
+			dump[path] = redactedValue
+			return
+		}
+		if value.Type() == durationType {
+			dump[path] = value.Interface().(time.Duration).String()
+			return
+		}
+		dump[path] = value.Interface()
+	})
+	return dump
+}
+
+// walkFields calls fn for every leaf field of a configuration struct, passing
+// its dotted YAML path
+func walkFields(v reflect.Value, prefix string, fn func(path string, field reflect.StructField, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			walkFields(v.Field(i), path, fn)
+			continue
+		}
+		fn(path, field, v.Field(i))
+	}
+}
+
+// parseLogFormat converts a format name into a logging format
+func parseLogFormat(format string) (logging.LogFormat, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return logging.FormatJSON, nil
+	case "text":
+		return logging.FormatText, nil
+	case "emf":
+		return logging.FormatEMF, nil
+	default:
+		return logging.FormatJSON, fmt.Errorf("unknown log format %q (want json, text or emf)", format)
+	}
+}
+
+// parseHeaders parses a comma-separated list of Name=Value pairs
+func parseHeaders(raw string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, errors.New("want comma-separated Name=Value pairs")
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+// parseQuotas parses a comma-separated list of MerchantID=Quota pairs
+func parseQuotas(raw string) (map[string]int64, error) {
+	quotas := make(map[string]int64)
+	for _, pair := range strings.Split(raw, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		merchantID, value, ok := strings.Cut(pair, "=")
+		merchantID = strings.TrimSpace(merchantID)
+		if !ok || merchantID == "" {
+			return nil, errors.New("want comma-separated MerchantID=Quota pairs")
+		}
+		quota, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota %q of merchant %s", value, merchantID)
+		}
+		quotas[merchantID] = quota
+	}
+	return quotas, nil
+}
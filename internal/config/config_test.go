@@ -0,0 +1,682 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/authorizer"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/auth"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/encryption"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/health"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/ratelimit"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+)
+
+// envMap returns a LookupEnv function backed by a map
+func envMap(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+// writeFile writes content to a temporary file and returns its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestLoader_Defaults(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(nil)}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.DynamoDB.TableName != "chargebacks" {
+		t.Errorf("Expected table name 'chargebacks', got %s", cfg.DynamoDB.TableName)
+	}
+	if cfg.DynamoDB.Region != "us-east-1" {
+		t.Errorf("Expected region 'us-east-1', got %s", cfg.DynamoDB.Region)
+	}
+	if cfg.DynamoDB.Endpoint != "" {
+		t.Errorf("Expected empty endpoint, got %s", cfg.DynamoDB.Endpoint)
+	}
+	if cfg.HTTP.Port != 8080 {
+		t.Errorf("Expected port 8080, got %d", cfg.HTTP.Port)
+	}
+}
+
+func TestLoader_Environment(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"DYNAMODB_ENDPOINT":     "http://localhost:8000",
+		"AWS_REGION":            "us-west-2",
+		"DYNAMODB_TABLE":        "test-chargebacks",
+		"LOG_LEVEL":             "DEBUG",
+		"LOG_FORMAT":            "text",
+		"LOG_CONTEXT_FIELDS":    "request_id, merchant_id",
+		"LOG_SAMPLING_INITIAL":  "50",
+		"LOG_SAMPLING_INTERVAL": "2s",
+		"SERVICE_NAME":          "chargeback-lambda",
+		"PORT":                  "3000",
+		"MAX_BODY_BYTES":        "2048",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	dynamo := cfg.DynamoDBClientConfig()
+	if dynamo.Endpoint != "http://localhost:8000" || dynamo.Region != "us-west-2" || dynamo.TableName != "test-chargebacks" {
+		t.Errorf("Unexpected DynamoDB config: %+v", dynamo)
+	}
+
+	loggerConfig := cfg.LoggerConfig()
+	if loggerConfig.Level != service.LogLevelDebug {
+		t.Errorf("Expected debug level, got %v", loggerConfig.Level)
+	}
+	if loggerConfig.Format != logging.FormatText {
+		t.Errorf("Expected text format, got %v", loggerConfig.Format)
+	}
+	if loggerConfig.ServiceName != "chargeback-lambda" {
+		t.Errorf("Expected service name 'chargeback-lambda', got %s", loggerConfig.ServiceName)
+	}
+	if strings.Join(loggerConfig.ContextKeys, ",") != "request_id,merchant_id" {
+		t.Errorf("Expected context keys [request_id merchant_id], got %v", loggerConfig.ContextKeys)
+	}
+	if loggerConfig.Sampling.Initial != 50 || loggerConfig.Sampling.Interval != 2*time.Second {
+		t.Errorf("Expected sampling of 50 per 2s, got %+v", loggerConfig.Sampling)
+	}
+
+	if cfg.HTTP.Port != 3000 {
+		t.Errorf("Expected port 3000, got %d", cfg.HTTP.Port)
+	}
+	if cfg.RouterConfig().MaxBodyBytes != 2048 {
+		t.Errorf("Expected max body bytes 2048, got %d", cfg.RouterConfig().MaxBodyBytes)
+	}
+}
+
+func TestLoader_FileThenEnvironment(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+service:
+  name: from-file
+log:
+  level: warn
+dynamodb:
+  table_name: file-table
+`)
+
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		EnvConfigFile:    path,
+		"DYNAMODB_TABLE": "env-table",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Service.Name != "from-file" {
+		t.Errorf("Expected service name from file, got %s", cfg.Service.Name)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("Expected log level from file, got %s", cfg.Log.Level)
+	}
+	if cfg.DynamoDB.TableName != "env-table" {
+		t.Errorf("Expected environment to override file, got %s", cfg.DynamoDB.TableName)
+	}
+	if cfg.Log.Format != "json" {
+		t.Errorf("Expected default format to be kept, got %s", cfg.Log.Format)
+	}
+}
+
+func TestLoader_FileRejectsUnknownKeys(t *testing.T) {
+	path := writeFile(t, "config.yaml", "dynamodb:\n  table: typo\n")
+	loader := &Loader{LookupEnv: envMap(map[string]string{EnvConfigFile: path})}
+
+	if _, err := loader.Load(context.Background(), Defaults()); err == nil {
+		t.Error("Expected unknown key to be rejected")
+	}
+}
+
+func TestLoader_References(t *testing.T) {
+	path := writeFile(t, "secrets.yaml", `
+parameters:
+  /chargeback/test/table: ssm-table
+secrets:
+  chargeback/test/dynamodb: '{"endpoint": "http://secret-host:8000"}'
+`)
+
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		EnvSecretsFile:      path,
+		"DYNAMODB_TABLE":    "ssm:/chargeback/test/table",
+		"DYNAMODB_ENDPOINT": "secret:chargeback/test/dynamodb#endpoint",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.DynamoDB.TableName != "ssm-table" {
+		t.Errorf("Expected parameter to be resolved, got %s", cfg.DynamoDB.TableName)
+	}
+	if cfg.DynamoDB.Endpoint != "http://secret-host:8000" {
+		t.Errorf("Expected secret to be resolved, got %s", cfg.DynamoDB.Endpoint)
+	}
+
+	dump := cfg.Redacted()
+	if dump["dynamodb.endpoint"] != redactedValue {
+		t.Errorf("Expected secret to be redacted, got %v", dump["dynamodb.endpoint"])
+	}
+	if dump["dynamodb.table_name"] != redactedValue {
+		t.Errorf("Expected parameter to be redacted, since it may be a SecureString, got %v", dump["dynamodb.table_name"])
+	}
+	if dump["dynamodb.region"] != "us-east-1" {
+		t.Errorf("Expected plain settings to be shown, got %v", dump["dynamodb.region"])
+	}
+}
+
+func TestLoader_MissingReference(t *testing.T) {
+	path := writeFile(t, "secrets.yaml", "parameters: {}\n")
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		EnvSecretsFile:   path,
+		"DYNAMODB_TABLE": "ssm:/missing",
+	})}
+
+	_, err := loader.Load(context.Background(), Defaults())
+	if err == nil || !strings.Contains(err.Error(), "parameter /missing not found") {
+		t.Errorf("Expected missing parameter error, got %v", err)
+	}
+}
+
+func TestLoader_InvalidEnvironment(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{"PORT": "eighty"})}
+
+	_, err := loader.Load(context.Background(), Defaults())
+	if err == nil || !strings.Contains(err.Error(), "PORT") {
+		t.Errorf("Expected PORT parse error, got %v", err)
+	}
+}
+
+func TestLoader_DeprecatedTableName(t *testing.T) {
+	tests := []struct {
+		name           string
+		env            map[string]string
+		wantTable      string
+		wantDeprecated bool
+		wantErr        string
+	}{
+		{
+			name:      "default",
+			env:       map[string]string{},
+			wantTable: "chargebacks",
+		},
+		{
+			name:           "deprecated variable only",
+			env:            map[string]string{"CHARGEBACK_TABLE_NAME": "legacy-chargebacks"},
+			wantTable:      "legacy-chargebacks",
+			wantDeprecated: true,
+		},
+		{
+			name:           "both set to the same table",
+			env:            map[string]string{"CHARGEBACK_TABLE_NAME": "test-chargebacks", "DYNAMODB_TABLE": "test-chargebacks"},
+			wantTable:      "test-chargebacks",
+			wantDeprecated: true,
+		},
+		{
+			name:    "both set to different tables",
+			env:     map[string]string{"CHARGEBACK_TABLE_NAME": "legacy-chargebacks", "DYNAMODB_TABLE": "test-chargebacks"},
+			wantErr: "DYNAMODB_TABLE: conflicts with deprecated CHARGEBACK_TABLE_NAME",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := (&Loader{LookupEnv: envMap(tt.env)}).Load(context.Background(), Defaults())
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if cfg.DynamoDB.TableName != tt.wantTable {
+				t.Errorf("Expected table name %q, got %q", tt.wantTable, cfg.DynamoDB.TableName)
+			}
+			deprecations := strings.Join(cfg.Deprecations(), "; ")
+			if strings.Contains(deprecations, "CHARGEBACK_TABLE_NAME is deprecated") != tt.wantDeprecated {
+				t.Errorf("Expected deprecation notice %v, got %q", tt.wantDeprecated, deprecations)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	cfg := Defaults()
+	cfg.Log.Level = "verbose"
+	cfg.Log.Format = "xml"
+	cfg.Log.ContextFields = []string{"request_id", "card_number"}
+	cfg.Log.LevelParameter = "/chargeback/log-level"
+	cfg.Log.LevelFile = "log-level.json"
+	cfg.DynamoDB.TableName = ""
+	cfg.DynamoDB.Endpoint = "localhost:8000"
+	cfg.Log.OTLP.Endpoint = "collector:4318"
+	cfg.Log.OTLP.Headers = "no-separator"
+	cfg.HTTP.Port = 0
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.Tracing.SampleRatio = 2
+	cfg.Metrics.Exporter = "statsd"
+	cfg.Metrics.Path = "metrics"
+	cfg.Health.Timeout = 0
+	cfg.Health.CardVaultURL = "vault:8081"
+	cfg.Auth.Enabled = true
+	cfg.Auth.JWKSURL = "https://issuer.example.com/jwks.json"
+	cfg.Auth.PublicPaths = []string{"health"}
+	cfg.RateLimit.Rate = -1
+	cfg.Signature.Sources = `[{"id":"acquirer-a","algorithm":"md5"}]`
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+
+	for _, want := range []string{"log.level", "log.format", "log.context_fields", "level_parameter", "log.otlp", "dynamodb.table_name", "dynamodb.endpoint", "http.port", "tracing.exporter", "sample ratio", "metrics.exporter", "metrics.path", "health.timeout", "card_vault", "auth: jwt", "auth.public_paths", "rate_limit: rate", "signature: source acquirer-a"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+
+	valid := Defaults()
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected defaults to be valid, got %v", err)
+	}
+}
+
+func TestConfig_LogLevelSource(t *testing.T) {
+	cfg := Defaults()
+
+	source, err := cfg.LogLevelSource(context.Background())
+	if err != nil || source != nil {
+		t.Errorf("Expected no source by default, got %v (err: %v)", source, err)
+	}
+
+	cfg.Log.LevelFile = "log-level.json"
+	source, err = cfg.LogLevelSource(context.Background())
+	if err != nil {
+		t.Fatalf("LogLevelSource() error = %v", err)
+	}
+	if file, ok := source.(logging.FileLevelSource); !ok || file.Path != "log-level.json" {
+		t.Errorf("Expected a file source, got %#v", source)
+	}
+}
+
+func TestConfig_LogSinks(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"LOG_FORMAT":          "emf",
+		"LOG_FILE_PATH":       "/var/log/chargeback/api.log",
+		"LOG_FILE_MIN_LEVEL":  "warn",
+		"LOG_FILE_MAX_BYTES":  "1048576",
+		"LOG_OTLP_ENDPOINT":   "http://localhost:4318",
+		"LOG_OTLP_MIN_LEVEL":  "info",
+		"LOG_OTLP_HEADERS":    "X-Api-Key=collector-key, X-Team=payments",
+		"LOG_OTLP_BATCH_SIZE": "20",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	loggerConfig := cfg.LoggerConfig()
+	if len(loggerConfig.Sinks) != 3 {
+		t.Fatalf("Expected stdout, file and OTLP sinks, got %+v", loggerConfig.Sinks)
+	}
+
+	stdout, file, otlp := loggerConfig.Sinks[0], loggerConfig.Sinks[1], loggerConfig.Sinks[2]
+	if stdout.Format != logging.FormatEMF || stdout.Writer != nil {
+		t.Errorf("Expected EMF entries on the default writer, got %+v", stdout)
+	}
+	if file.File == nil || file.File.Path != "/var/log/chargeback/api.log" || file.File.MaxBytes != 1<<20 || file.File.MaxBackups != 7 {
+		t.Errorf("Expected the rotating file sink, got %+v", file.File)
+	}
+	if file.Format != logging.FormatJSON || file.MinLevel != service.LogLevelWarn {
+		t.Errorf("Expected JSON file entries from warn, got %v from %v", file.Format, file.MinLevel)
+	}
+	if otlp.Format != logging.FormatOTLP || otlp.MinLevel != service.LogLevelInfo || otlp.OTLP.BatchSize != 20 {
+		t.Errorf("Expected the OTLP sink from info, got %+v", otlp)
+	}
+	if otlp.OTLP.Headers["X-Api-Key"] != "collector-key" || otlp.OTLP.Headers["X-Team"] != "payments" {
+		t.Errorf("Expected the parsed headers, got %v", otlp.OTLP.Headers)
+	}
+
+	if cfg.Redacted()["log.otlp.headers"] == "X-Api-Key=collector-key, X-Team=payments" {
+		t.Error("Expected the collector headers to be redacted")
+	}
+
+	defaults := Defaults()
+	if sinks := defaults.LoggerConfig().Sinks; sinks != nil {
+		t.Errorf("Expected no extra sinks by default, got %+v", sinks)
+	}
+}
+
+func TestConfig_Tracing(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"SERVICE_NAME":          "chargeback-api",
+		"TRACING_EXPORTER":      "OTLP",
+		"TRACING_OTLP_ENDPOINT": "http://localhost:4318",
+		"TRACING_OTLP_HEADERS":  "X-Api-Key=collector-key",
+		"TRACING_SAMPLE_RATIO":  "0.25",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tracingConfig := cfg.TracingConfig()
+	if tracingConfig.Exporter != tracing.ExporterOTLP || tracingConfig.Endpoint != "http://localhost:4318" {
+		t.Errorf("Expected the OTLP exporter, got %+v", tracingConfig)
+	}
+	if tracingConfig.SampleRatio != 0.25 {
+		t.Errorf("Expected sample ratio 0.25, got %v", tracingConfig.SampleRatio)
+	}
+	if tracingConfig.ServiceName != "chargeback-api" {
+		t.Errorf("Expected service name 'chargeback-api', got '%s'", tracingConfig.ServiceName)
+	}
+	if tracingConfig.Headers["X-Api-Key"] != "collector-key" {
+		t.Errorf("Expected the parsed headers, got %v", tracingConfig.Headers)
+	}
+	if cfg.Redacted()["tracing.headers"] == "X-Api-Key=collector-key" {
+		t.Error("Expected the collector headers to be redacted")
+	}
+
+	invalid := &Loader{LookupEnv: envMap(map[string]string{"TRACING_SAMPLE_RATIO": "half"})}
+	if _, err := invalid.Load(context.Background(), Defaults()); err == nil {
+		t.Error("Expected an error for a non-numeric sample ratio")
+	}
+}
+
+func TestConfig_Metrics(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"SERVICE_NAME":              "chargeback-lambda",
+		"METRICS_EXPORTER":          "EMF",
+		"METRICS_NAMESPACE":         "Chargebacks",
+		"METRICS_EMF_DIMENSIONS":    "reason,currency",
+		"METRICS_PROMETHEUS_LABELS": "merchant_id",
+		"METRICS_PATH":              "/internal/metrics",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	metricsConfig := cfg.MetricsConfig()
+	if metricsConfig.Exporter != metrics.ExporterEMF || metricsConfig.ServiceName != "chargeback-lambda" {
+		t.Errorf("Expected the EMF exporter of the service, got %+v", metricsConfig)
+	}
+	if metricsConfig.EMF.Namespace != "Chargebacks" || strings.Join(metricsConfig.EMF.Dimensions, ",") != "reason,currency" {
+		t.Errorf("Expected the namespace and dimensions, got %+v", metricsConfig.EMF)
+	}
+	if strings.Join(metricsConfig.PrometheusLabels, ",") != "merchant_id" {
+		t.Errorf("Expected merchant_id in Prometheus series, got %v", metricsConfig.PrometheusLabels)
+	}
+	if metricsConfig.EMF.FlushInterval != time.Minute {
+		t.Errorf("Expected the default flush interval, got %s", metricsConfig.EMF.FlushInterval)
+	}
+	if serverConfig := cfg.ServerConfig(); serverConfig.MetricsPath != "/internal/metrics" {
+		t.Errorf("Expected the metrics path, got '%s'", serverConfig.MetricsPath)
+	}
+
+	defaults := Defaults()
+	if dimensions := defaults.MetricsConfig().EMF.Dimensions; dimensions != nil {
+		t.Errorf("Expected every label as a dimension by default, got %v", dimensions)
+	}
+
+	invalid := &Loader{LookupEnv: envMap(map[string]string{"METRICS_PROMETHEUS_LABELS": "transaction_id"})}
+	if _, err := invalid.Load(context.Background(), Defaults()); err == nil || !strings.Contains(err.Error(), "unknown Prometheus label") {
+		t.Errorf("Expected an unknown label error, got %v", err)
+	}
+}
+
+func TestConfig_Health(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"HEALTH_CACHE_TTL":           "10s",
+		"HEALTH_CARD_VAULT_URL":      "https://vault.internal/health",
+		"HEALTH_EVENT_PUBLISHER_URL": "https://events.internal/health",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	healthConfig := cfg.HealthConfig()
+	if healthConfig.CacheTTL != 10*time.Second || healthConfig.Timeout != health.DefaultTimeout {
+		t.Errorf("Expected the cache TTL and the default timeout, got %+v", healthConfig)
+	}
+	if healthConfig.Endpoints["card_vault"] != "https://vault.internal/health" || healthConfig.Endpoints["event_publisher"] != "https://events.internal/health" {
+		t.Errorf("Expected both endpoints, got %v", healthConfig.Endpoints)
+	}
+
+	defaults := Defaults()
+	if endpoints := defaults.HealthConfig().Endpoints; len(endpoints) != 0 {
+		t.Errorf("Expected no endpoints by default, got %v", endpoints)
+	}
+}
+
+func TestConfig_Auth(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"AUTH_ENABLED":        "true",
+		"AUTH_JWKS_URL":       "https://issuer.example.com/jwks.json",
+		"AUTH_JWT_ISSUER":     "https://issuer.example.com/",
+		"AUTH_JWT_AUDIENCE":   "chargeback-api",
+		"AUTH_API_KEYS_TABLE": "api-keys",
+		"AUTH_ROLES_CLAIM":    "groups",
+		"AUTH_PUBLIC_PATHS":   "/health/live",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	authConfig := cfg.AuthConfig()
+	if authConfig.JWT.Issuer != "https://issuer.example.com/" || authConfig.JWT.Audience != "chargeback-api" || authConfig.JWT.Leeway != auth.DefaultLeeway {
+		t.Errorf("Expected the token settings, got %+v", authConfig.JWT)
+	}
+	if authConfig.APIKeysTable != "api-keys" || authConfig.APIKeyCacheTTL != auth.DefaultAPIKeyCacheTTL {
+		t.Errorf("Expected the API key settings, got %+v", authConfig)
+	}
+	if authConfig.Claims.Roles != "groups" || authConfig.Claims.Merchant != auth.DefaultClaimNames.Merchant {
+		t.Errorf("Expected the roles claim and the default merchant claim, got %+v", authConfig.Claims)
+	}
+	if authorizerConfig := cfg.AuthorizerConfig(); authorizerConfig.RolesKey != "groups" || authorizerConfig.CacheTTL != authorizer.DefaultCacheTTL {
+		t.Errorf("Expected the authorizer to pass the roles claim, got %+v", authorizerConfig)
+	}
+	if paths := cfg.ServerConfig().PublicPaths; len(paths) != 1 || paths[0] != "/health/live" {
+		t.Errorf("Expected the public paths on the server, got %v", paths)
+	}
+
+	authenticator, err := cfg.Authenticator(nil)
+	if err != nil || authenticator == nil {
+		t.Errorf("Expected an authenticator, got %v, %v", authenticator, err)
+	}
+
+	defaults := Defaults()
+	if authenticator, err := defaults.Authenticator(nil); err != nil || authenticator != nil {
+		t.Errorf("Expected authentication to be disabled by default, got %v, %v", authenticator, err)
+	}
+}
+
+func TestConfig_RateLimit(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"RATE_LIMIT_RATE":            "5",
+		"RATE_LIMIT_DAILY_QUOTA":     "1000",
+		"RATE_LIMIT_MERCHANT_QUOTAS": "merchant-big=50000, merchant-exempt=0",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	rateLimitConfig := cfg.RateLimitConfig()
+	if rateLimitConfig.Rate != 5 || rateLimitConfig.Backend != ratelimit.BackendMemory {
+		t.Errorf("Expected a rate kept in memory, got %+v", rateLimitConfig)
+	}
+	if rateLimitConfig.QuotaFor("merchant-1") != 1000 || rateLimitConfig.QuotaFor("merchant-big") != 50000 || rateLimitConfig.QuotaFor("merchant-exempt") != 0 {
+		t.Errorf("Expected the merchant quotas, got %+v", rateLimitConfig)
+	}
+
+	rateLimiter, quota, err := cfg.RateLimits(nil)
+	if err != nil || rateLimiter == nil || quota == nil {
+		t.Errorf("Expected a rate limiter and a quota, got %v, %v, %v", rateLimiter, quota, err)
+	}
+
+	defaults := Defaults()
+	if rateLimiter, quota, err := defaults.RateLimits(nil); err != nil || rateLimiter != nil || quota != nil {
+		t.Errorf("Expected limits to be disabled by default, got %v, %v, %v", rateLimiter, quota, err)
+	}
+
+	// Quotas alone leave the rate unlimited
+	defaults.RateLimit.DailyQuota = 10
+	if rateLimiter, quota, err := defaults.RateLimits(nil); err != nil || rateLimiter != nil || quota == nil {
+		t.Errorf("Expected a quota only, got %v, %v, %v", rateLimiter, quota, err)
+	}
+
+	for _, raw := range []string{"merchant-1", "merchant-1=many", "=10"} {
+		invalid := Defaults()
+		invalid.RateLimit.MerchantQuotas = raw
+		if err := invalid.Validate(); err == nil || !strings.Contains(err.Error(), "rate_limit.merchant_quotas") {
+			t.Errorf("Expected %q to be rejected, got %v", raw, err)
+		}
+	}
+
+	shared := Defaults()
+	shared.RateLimit.Rate = 5
+	shared.RateLimit.Backend = string(ratelimit.BackendDynamoDB)
+	if err := shared.Validate(); err == nil || !strings.Contains(err.Error(), "table is required") {
+		t.Errorf("Expected the dynamodb backend to require a table, got %v", err)
+	}
+}
+
+func TestConfig_Signature(t *testing.T) {
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"SIGNATURE_SOURCES":     `[{"id":"acquirer-a","algorithm":"hmac-sha256","secret":"s3cret"}]`,
+		"SIGNATURE_WINDOW":      "2m",
+		"SIGNATURE_NONCE_TABLE": "chargeback-nonces",
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	signatureConfig := cfg.SignatureConfig()
+	if len(signatureConfig.Sources) != 1 || signatureConfig.Sources[0].ID != "acquirer-a" || signatureConfig.Window != 2*time.Minute || signatureConfig.NonceTable != "chargeback-nonces" {
+		t.Errorf("Unexpected signature config %+v", signatureConfig)
+	}
+	if paths := cfg.MiddlewareOptions(nil, nil).SignedPaths; len(paths) != 1 || paths[0] != "/callbacks/chargebacks" {
+		t.Errorf("Expected the default signed paths, got %v", paths)
+	}
+	if cfg.Redacted()["signature.sources"] != redactedValue {
+		t.Errorf("Expected the sources to be redacted, got %v", cfg.Redacted()["signature.sources"])
+	}
+	if verifier, err := cfg.SignatureVerifier(nil); err != nil || verifier == nil {
+		t.Errorf("Expected a verifier, got %v, %v", verifier, err)
+	}
+
+	defaults := Defaults()
+	if verifier, err := defaults.SignatureVerifier(nil); err != nil || verifier != nil {
+		t.Errorf("Expected signatures to be disabled by default, got %v, %v", verifier, err)
+	}
+
+	invalid := Defaults()
+	invalid.Signature.Sources = `{"id":"acquirer-a"}`
+	invalid.Signature.Paths = []string{"callbacks"}
+	err = invalid.Validate()
+	for _, want := range []string{"signature.sources", "signature.paths"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestConfig_Encryption(t *testing.T) {
+	keyring := writeFile(t, "keyring.json", `{"current":"2024-06","keys":{"2024-06":"AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}}`)
+	loader := &Loader{LookupEnv: envMap(map[string]string{
+		"ENCRYPTION_FIELDS":       "description,card_number",
+		"ENCRYPTION_KEYRING_FILE": keyring,
+	})}
+
+	cfg, err := loader.Load(context.Background(), Defaults())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	encryptionConfig := cfg.EncryptionConfig()
+	if len(encryptionConfig.Fields) != 2 || encryptionConfig.Provider != encryption.ProviderKeyring || encryptionConfig.KeyringFile != keyring || encryptionConfig.Region != "us-east-1" {
+		t.Errorf("Unexpected encryption config %+v", encryptionConfig)
+	}
+	repo, err := cfg.EncryptChargebacks(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("EncryptChargebacks() error = %v", err)
+	}
+	if _, ok := repo.(*encryption.ChargebackRepository); !ok {
+		t.Errorf("Expected an encrypting repository, got %T", repo)
+	}
+
+	defaults := Defaults()
+	if repo, err := defaults.EncryptChargebacks(context.Background(), nil); err != nil || repo != nil {
+		t.Errorf("Expected encryption to be disabled by default, got %v, %v", repo, err)
+	}
+
+	invalid := Defaults()
+	invalid.Encryption.Fields = []string{"amount"}
+	invalid.Encryption.Provider = "kms"
+	err = invalid.Validate()
+	for _, want := range []string{`encryption: unknown field "amount"`, "KMS key ID is required"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestGetSecretValue(t *testing.T) {
+	store := &FileStore{Secrets: map[string]string{
+		"plain": "value",
+		"json":  `{"port": 8080}`,
+	}}
+	ctx := context.Background()
+
+	if value, err := getSecretValue(ctx, store, "plain"); err != nil || value != "value" {
+		t.Errorf("Expected plain secret, got %q, %v", value, err)
+	}
+	if value, err := getSecretValue(ctx, store, "json#port"); err != nil || value != "8080" {
+		t.Errorf("Expected JSON field, got %q, %v", value, err)
+	}
+	if _, err := getSecretValue(ctx, store, "json#missing"); err == nil {
+		t.Error("Expected missing key error")
+	}
+	if _, err := getSecretValue(ctx, store, "plain#key"); err == nil {
+		t.Error("Expected non-JSON secret error")
+	}
+}
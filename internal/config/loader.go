@@ -0,0 +1,269 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// EnvConfigFile names the optional YAML configuration file
+	EnvConfigFile = "CONFIG_FILE"
+
+	// EnvSecretsFile names the local file standing in for SSM and Secrets Manager
+	EnvSecretsFile = "CONFIG_SECRETS_FILE"
+
+	// parameterPrefix marks a value stored in SSM Parameter Store
+	parameterPrefix = "ssm:"
+
+	// secretPrefix marks a value stored in Secrets Manager
+	secretPrefix = "secret:"
+)
+
+// Loader builds a Config from its sources
+type Loader struct {
+	// LookupEnv reads environment variables, os.LookupEnv if nil
+	LookupEnv func(key string) (string, bool)
+
+	// Parameters resolves "ssm:" references. When nil it is created on first
+	// use from CONFIG_SECRETS_FILE or, if unset, from the AWS SDK.
+	Parameters ParameterStore
+
+	// Secrets resolves "secret:" references, created like Parameters when nil
+	Secrets SecretStore
+}
+
+// Load reads the configuration from the process environment on top of the
+// given defaults and validates it
+func Load(ctx context.Context, defaults Config) (*Config, error) {
+	return (&Loader{}).Load(ctx, defaults)
+}
+
+// Load layers the YAML file and environment variables over the defaults,
+// resolves parameter and secret references and validates the result
+func (l *Loader) Load(ctx context.Context, defaults Config) (*Config, error) {
+	cfg := defaults
+	cfg.references = make(map[string]bool)
+
+	if path := l.getenv(EnvConfigFile); path != "" {
+		if err := loadFile(path, &cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := l.applyEnv(&cfg); err != nil {
+		return nil, err
+	}
+
+	if err := l.resolveReferences(ctx, &cfg); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func (l *Loader) getenv(key string) string {
+	lookup := l.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	value, _ := lookup(key)
+	return value
+}
+
+// loadFile decodes a YAML file over cfg. Unknown keys are rejected so typos
+// do not silently fall back to defaults.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// applyEnv overrides every field carrying an env tag whose variable is set.
+// A variable named by a deprecatedEnv tag is read when the env one is unset,
+// and rejected when both are set to different values.
+func (l *Loader) applyEnv(cfg *Config) error {
+	var errs []error
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		key := field.Tag.Get("env")
+		if key == "" {
+			return
+		}
+
+		raw := l.getenv(key)
+		if deprecated := field.Tag.Get("deprecatedEnv"); deprecated != "" && l.getenv(deprecated) != "" {
+			old := l.getenv(deprecated)
+			switch {
+			case raw == "":
+				raw = old
+				cfg.deprecations = append(cfg.deprecations, fmt.Sprintf("%s is deprecated, set %s instead", deprecated, key))
+			case raw != old:
+				errs = append(errs, fmt.Errorf("%s: conflicts with deprecated %s", key, deprecated))
+				return
+			default:
+				cfg.deprecations = append(cfg.deprecations, fmt.Sprintf("%s is deprecated and can be removed, %s is set", deprecated, key))
+			}
+		}
+		if raw == "" {
+			return
+		}
+
+		if err := setValue(value, raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid environment: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue parses raw into a field of a supported kind
+func setValue(value reflect.Value, raw string) error {
+	if value.Type() == durationType {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		number, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		value.SetInt(number)
+	case reflect.Float64:
+		number, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		value.SetFloat(number)
+	case reflect.Bool:
+		flag, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		value.SetBool(flag)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", value.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+
+	return nil
+}
+
+// resolveReferences replaces "ssm:" and "secret:" string values with the
+// stored value and remembers which paths were resolved. Parameters may be
+// SecureStrings, so both kinds are treated as secrets.
+func (l *Loader) resolveReferences(ctx context.Context, cfg *Config) error {
+	var errs []error
+	walkFields(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.StructField, value reflect.Value) {
+		if value.Kind() != reflect.String {
+			return
+		}
+
+		raw := value.String()
+		var resolved string
+		var err error
+
+		switch {
+		case strings.HasPrefix(raw, parameterPrefix):
+			if err = l.ensureStores(ctx, cfg.DynamoDB.Region); err == nil {
+				resolved, err = l.Parameters.GetParameter(ctx, strings.TrimPrefix(raw, parameterPrefix))
+			}
+		case strings.HasPrefix(raw, secretPrefix):
+			if err = l.ensureStores(ctx, cfg.DynamoDB.Region); err == nil {
+				resolved, err = getSecretValue(ctx, l.Secrets, strings.TrimPrefix(raw, secretPrefix))
+			}
+		default:
+			return
+		}
+		cfg.references[path] = true
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		value.SetString(resolved)
+	})
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to resolve configuration references: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// ensureStores creates the missing stores, preferring the local file when set
+func (l *Loader) ensureStores(ctx context.Context, region string) error {
+	if l.Parameters != nil && l.Secrets != nil {
+		return nil
+	}
+
+	if path := l.getenv(EnvSecretsFile); path != "" {
+		store, err := NewFileStore(path)
+		if err != nil {
+			return err
+		}
+		l.setStores(store, store)
+		return nil
+	}
+
+	parameters, secrets, err := newAWSStores(ctx, region)
+	if err != nil {
+		return err
+	}
+	l.setStores(parameters, secrets)
+	return nil
+}
+
+func (l *Loader) setStores(parameters ParameterStore, secrets SecretStore) {
+	if l.Parameters == nil {
+		l.Parameters = parameters
+	}
+	if l.Secrets == nil {
+		l.Secrets = secrets
+	}
+}
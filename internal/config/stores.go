@@ -0,0 +1,154 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"gopkg.in/yaml.v3"
+)
+
+// ParameterStore reads values from a parameter store such as SSM
+type ParameterStore interface {
+	// GetParameter returns the decrypted value of the named parameter
+	GetParameter(ctx context.Context, name string) (string, error)
+}
+
+// SecretStore reads values from a secret store such as Secrets Manager
+type SecretStore interface {
+	// GetSecret returns the secret string of the named secret
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// getSecretValue resolves "name" or "name#key", where key selects a field of
+// a secret stored as a JSON object
+func getSecretValue(ctx context.Context, store SecretStore, reference string) (string, error) {
+	name, key, hasKey := strings.Cut(reference, "#")
+
+	value, err := store.GetSecret(ctx, name)
+	if err != nil || !hasKey {
+		return value, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object", name)
+	}
+
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", name, key)
+	}
+
+	if text, ok := field.(string); ok {
+		return text, nil
+	}
+	return fmt.Sprint(field), nil
+}
+
+// FileStore serves parameters and secrets from a local YAML file, standing in
+// for SSM and Secrets Manager during development and tests:
+//
+//	parameters:
+//	  /chargeback/dev/table: chargebacks-dev
+//	secrets:
+//	  chargeback/dev/api: '{"key": "value"}'
+type FileStore struct {
+	Parameters map[string]string `yaml:"parameters"`
+	Secrets    map[string]string `yaml:"secrets"`
+}
+
+// NewFileStore loads a FileStore from a YAML file
+func NewFileStore(path string) (*FileStore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+
+	var store FileStore
+	if err := yaml.Unmarshal(data, &store); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file %s: %w", path, err)
+	}
+
+	return &store, nil
+}
+
+// GetParameter returns the named parameter from the file
+func (s *FileStore) GetParameter(ctx context.Context, name string) (string, error) {
+	value, ok := s.Parameters[name]
+	if !ok {
+		return "", fmt.Errorf("parameter %s not found", name)
+	}
+	return value, nil
+}
+
+// GetSecret returns the named secret from the file
+func (s *FileStore) GetSecret(ctx context.Context, name string) (string, error) {
+	value, ok := s.Secrets[name]
+	if !ok {
+		return "", fmt.Errorf("secret %s not found", name)
+	}
+	return value, nil
+}
+
+// SSMParameterStore reads parameters from AWS Systems Manager Parameter Store
+type SSMParameterStore struct {
+	client *ssm.Client
+}
+
+// NewSSMParameterStore creates a parameter store backed by SSM
+func NewSSMParameterStore(client *ssm.Client) *SSMParameterStore {
+	return &SSMParameterStore{client: client}
+}
+
+// GetParameter returns the parameter value, decrypting SecureString parameters
+func (s *SSMParameterStore) GetParameter(ctx context.Context, name string) (string, error) {
+	output, err := s.client.GetParameter(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(name),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get parameter %s: %w", name, err)
+	}
+
+	return aws.ToString(output.Parameter.Value), nil
+}
+
+// SecretsManagerStore reads secrets from AWS Secrets Manager
+type SecretsManagerStore struct {
+	client *secretsmanager.Client
+}
+
+// NewSecretsManagerStore creates a secret store backed by Secrets Manager
+func NewSecretsManagerStore(client *secretsmanager.Client) *SecretsManagerStore {
+	return &SecretsManagerStore{client: client}
+}
+
+// GetSecret returns the current secret string
+func (s *SecretsManagerStore) GetSecret(ctx context.Context, name string) (string, error) {
+	output, err := s.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+
+	return aws.ToString(output.SecretString), nil
+}
+
+// newAWSStores creates SSM and Secrets Manager stores using the default
+// credential chain
+func newAWSStores(ctx context.Context, region string) (*SSMParameterStore, *SecretsManagerStore, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	return NewSSMParameterStore(ssm.NewFromConfig(awsCfg)), NewSecretsManagerStore(secretsmanager.NewFromConfig(awsCfg)), nil
+}
@@ -1,6 +1,8 @@
 package entity
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
@@ -26,6 +28,17 @@ const (
 	ReasonConsumerDispute    ChargebackReason = "consumer_dispute"
 )
 
+// ChargebackChannel is how a chargeback reached us
+type ChargebackChannel string
+
+const (
+	// ChannelAPI is a chargeback opened by a caller of the API
+	ChannelAPI ChargebackChannel = "api"
+
+	// ChannelAcquirer is a dispute notification pushed by an acquirer
+	ChannelAcquirer ChargebackChannel = "acquirer"
+)
+
 // Chargeback represents a chargeback entity in the domain
 type Chargeback struct {
 	ID              string           `json:"id"`
@@ -41,6 +54,11 @@ type Chargeback struct {
 	ChargebackDate  time.Time        `json:"chargeback_date"`
 	CreatedAt       time.Time        `json:"created_at"`
 	UpdatedAt       time.Time        `json:"updated_at"`
+
+	// Channel is how the chargeback reached us, and Source the verified
+	// sender of an acquirer notification
+	Channel ChargebackChannel `json:"channel"`
+	Source  string            `json:"source,omitempty"`
 }
 
 // CreateChargebackRequest represents the data needed to create a new chargeback
@@ -49,52 +67,83 @@ type CreateChargebackRequest struct {
 	MerchantID      string           `json:"merchant_id"`
 	Amount          float64          `json:"amount"`
 	Currency        string           `json:"currency"`
-	CardNumber      string           `json:"card_number"`
+	CardNumber      string           `json:"card_number" log:"redact"`
 	Reason          ChargebackReason `json:"reason"`
 	Description     string           `json:"description,omitempty"`
 	TransactionDate time.Time        `json:"transaction_date"`
 }
 
-// Validate validates the create chargeback request
+// ValidationError reports the invalid fields of a create chargeback request
+type ValidationError struct {
+	// Fields holds the JSON names of the invalid fields, in request order
+	Fields []string
+
+	problems []string
+}
+
+// Error lists every problem of the request
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validation errors: %s", strings.Join(e.problems, "; "))
+}
+
+// add records a problem of the named field
+func (e *ValidationError) add(field, problem string) {
+	e.Fields = append(e.Fields, field)
+	e.problems = append(e.problems, problem)
+}
+
+// Validate validates the create chargeback request. The returned error is a
+// *ValidationError.
 func (req *CreateChargebackRequest) Validate() error {
-	var errors []string
+	validationErr := &ValidationError{}
 
 	if strings.TrimSpace(req.TransactionID) == "" {
-		errors = append(errors, "transaction ID is required")
+		validationErr.add("transaction_id", "transaction ID is required")
 	}
 
 	if strings.TrimSpace(req.MerchantID) == "" {
-		errors = append(errors, "merchant ID is required")
+		validationErr.add("merchant_id", "merchant ID is required")
 	}
 
 	if req.Amount <= 0 {
-		errors = append(errors, "amount must be greater than zero")
+		validationErr.add("amount", "amount must be greater than zero")
 	}
 
 	if strings.TrimSpace(req.Currency) == "" {
-		errors = append(errors, "currency is required")
+		validationErr.add("currency", "currency is required")
+	} else if !IsValidCurrency(req.Currency) {
+		validationErr.add("currency", "currency must be an ISO 4217 code such as USD")
 	}
 
 	if strings.TrimSpace(req.CardNumber) == "" {
-		errors = append(errors, "card number is required")
+		validationErr.add("card_number", "card number is required")
 	}
 
 	if !isValidReason(req.Reason) {
-		errors = append(errors, "invalid chargeback reason")
+		validationErr.add("reason", "invalid chargeback reason")
 	}
 
 	if req.TransactionDate.IsZero() {
-		errors = append(errors, "transaction date is required")
+		validationErr.add("transaction_date", "transaction date is required")
 	}
 
-	if len(errors) > 0 {
-		return fmt.Errorf("validation errors: %s", strings.Join(errors, "; "))
+	if len(validationErr.Fields) > 0 {
+		return validationErr
 	}
 
 	return nil
 }
 
-// NewChargeback creates a new chargeback from a request
+// NewChargebackID returns a new chargeback ID, "cb_" followed by 32 random
+// hexadecimal digits
+func NewChargebackID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return "cb_" + hex.EncodeToString(b[:])
+}
+
+// NewChargeback creates a new chargeback from a request with a new ID, so the
+// ID is known before the chargeback is stored
 func NewChargeback(req CreateChargebackRequest) (*Chargeback, error) {
 	if err := req.Validate(); err != nil {
 		return nil, err
@@ -103,6 +152,7 @@ func NewChargeback(req CreateChargebackRequest) (*Chargeback, error) {
 	now := time.Now()
 
 	return &Chargeback{
+		ID:              NewChargebackID(),
 		TransactionID:   req.TransactionID,
 		MerchantID:      req.MerchantID,
 		Amount:          req.Amount,
@@ -115,9 +165,17 @@ func NewChargeback(req CreateChargebackRequest) (*Chargeback, error) {
 		ChargebackDate:  now,
 		CreatedAt:       now,
 		UpdatedAt:       now,
+		Channel:         ChannelAPI,
 	}, nil
 }
 
+// ReceivedFrom records that the chargeback was pushed by the verified
+// acquirer source
+func (c *Chargeback) ReceivedFrom(source string) {
+	c.Channel = ChannelAcquirer
+	c.Source = source
+}
+
 // Approve changes the chargeback status to approved
 func (c *Chargeback) Approve() error {
 	if c.Status != StatusPending {
@@ -152,16 +210,36 @@ func (c *Chargeback) IsValid() bool {
 		!c.ChargebackDate.IsZero()
 }
 
-// isValidReason checks if the provided reason is valid
-func isValidReason(reason ChargebackReason) bool {
-	validReasons := []ChargebackReason{
+// ValidReasons returns every accepted chargeback reason
+func ValidReasons() []ChargebackReason {
+	return []ChargebackReason{
 		ReasonFraud,
 		ReasonAuthorizationError,
 		ReasonProcessingError,
 		ReasonConsumerDispute,
 	}
+}
+
+// ValidStatuses returns every chargeback status
+func ValidStatuses() []ChargebackStatus {
+	return []ChargebackStatus{
+		StatusPending,
+		StatusApproved,
+		StatusRejected,
+	}
+}
 
-	for _, validReason := range validReasons {
+// ValidChannels returns every chargeback channel
+func ValidChannels() []ChargebackChannel {
+	return []ChargebackChannel{
+		ChannelAPI,
+		ChannelAcquirer,
+	}
+}
+
+// isValidReason checks if the provided reason is valid
+func isValidReason(reason ChargebackReason) bool {
+	for _, validReason := range ValidReasons() {
 		if reason == validReason {
 			return true
 		}
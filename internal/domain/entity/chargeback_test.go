@@ -1,6 +1,7 @@
 package entity
 
 import (
+	"errors"
 	"strings"
 	"testing"
 	"time"
@@ -113,6 +114,20 @@ func TestCreateChargebackRequest_Validate(t *testing.T) {
 			shouldErr: true,
 			errMsg:    "currency is required",
 		},
+		{
+			name: "unknown currency",
+			request: CreateChargebackRequest{
+				TransactionID:   "txn-12345",
+				MerchantID:      "merchant-67890",
+				Amount:          99.99,
+				Currency:        "usd",
+				CardNumber:      "1234567890123456",
+				Reason:          ReasonFraud,
+				TransactionDate: time.Now().Add(-24 * time.Hour),
+			},
+			shouldErr: true,
+			errMsg:    "currency must be an ISO 4217 code such as USD",
+		},
 		{
 			name: "empty card number",
 			request: CreateChargebackRequest{
@@ -190,6 +205,30 @@ func TestCreateChargebackRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestCreateChargebackRequest_Validate_Fields(t *testing.T) {
+	request := CreateChargebackRequest{
+		TransactionID:   "txn-12345",
+		MerchantID:      "merchant-67890",
+		Amount:          -1,
+		CardNumber:      "1234567890123456",
+		Reason:          ReasonFraud,
+		TransactionDate: time.Now(),
+	}
+
+	err := request.Validate()
+
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, got %T", err)
+	}
+	if got := strings.Join(validationErr.Fields, ","); got != "amount,currency" {
+		t.Errorf("Expected fields amount,currency, got %s", got)
+	}
+	if want := "validation errors: amount must be greater than zero; currency is required"; err.Error() != want {
+		t.Errorf("Expected '%s', got '%s'", want, err.Error())
+	}
+}
+
 func TestNewChargeback(t *testing.T) {
 	validRequest := CreateChargebackRequest{
 		TransactionID:   "txn-12345",
@@ -243,6 +282,10 @@ func TestNewChargeback(t *testing.T) {
 			t.Errorf("Expected Status %s, got %s", StatusPending, chargeback.Status)
 		}
 
+		if chargeback.Channel != ChannelAPI || chargeback.Source != "" {
+			t.Errorf("Expected the API channel without source, got %s %q", chargeback.Channel, chargeback.Source)
+		}
+
 		// Verify card number is masked
 		if !strings.Contains(chargeback.CardNumber, "*") {
 			t.Error("Expected card number to be masked")
@@ -265,9 +308,14 @@ func TestNewChargeback(t *testing.T) {
 			t.Error("Expected ChargebackDate to be set")
 		}
 
-		// Verify ID is not set (should be set by repository)
-		if chargeback.ID != "" {
-			t.Error("Expected ID to be empty")
+		// Verify a new ID is assigned
+		if !strings.HasPrefix(chargeback.ID, "cb_") || len(chargeback.ID) != len("cb_")+32 {
+			t.Errorf("Expected a cb_ ID with 32 hex digits, got %q", chargeback.ID)
+		}
+
+		other, _ := NewChargeback(validRequest)
+		if other.ID == chargeback.ID {
+			t.Errorf("Expected distinct IDs, got %q twice", chargeback.ID)
 		}
 	})
 
@@ -388,6 +436,19 @@ func TestChargeback_Reject(t *testing.T) {
 	})
 }
 
+func TestChargeback_ReceivedFrom(t *testing.T) {
+	chargeback := &Chargeback{Channel: ChannelAPI}
+
+	chargeback.ReceivedFrom("acquirer-a")
+
+	if chargeback.Channel != ChannelAcquirer {
+		t.Errorf("Expected Channel %s, got %s", ChannelAcquirer, chargeback.Channel)
+	}
+	if chargeback.Source != "acquirer-a" {
+		t.Errorf("Expected Source acquirer-a, got %s", chargeback.Source)
+	}
+}
+
 func TestChargeback_IsValid(t *testing.T) {
 	validChargeback := &Chargeback{
 		TransactionID:   "txn-12345",
@@ -577,6 +638,19 @@ func TestIsValidReason(t *testing.T) {
 	}
 }
 
+func TestIsValidCurrency(t *testing.T) {
+	for _, code := range []string{"USD", "EUR", "BRL", "JPY", "XOF"} {
+		if !IsValidCurrency(code) {
+			t.Errorf("Expected %s to be valid", code)
+		}
+	}
+	for _, code := range []string{"", "usd", "US", "USDT", "XAU", "XXX", "ABC"} {
+		if IsValidCurrency(code) {
+			t.Errorf("Expected %q to be invalid", code)
+		}
+	}
+}
+
 func TestMaskCardNumber(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -0,0 +1,75 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// AuthMethod identifies how a caller was authenticated
+type AuthMethod string
+
+const (
+	// AuthMethodJWT is a bearer token signed by a trusted issuer
+	AuthMethodJWT AuthMethod = "jwt"
+
+	// AuthMethodAPIKey is an API key issued to a merchant integration
+	AuthMethodAPIKey AuthMethod = "api_key"
+
+	// AuthMethodAuthorizer is a caller already authenticated by an API
+	// Gateway authorizer in front of the function
+	AuthMethodAuthorizer AuthMethod = "authorizer"
+
+	// AuthMethodSignature is an acquirer whose request was signed with the
+	// key configured for it
+	AuthMethodSignature AuthMethod = "signature"
+)
+
+// Role is a role granted to a caller
+type Role string
+
+const (
+	// RoleMerchant is a merchant integration, limited to its own chargebacks
+	RoleMerchant Role = "merchant"
+
+	// RoleAnalyst reviews chargebacks: approves or rejects them
+	RoleAnalyst Role = "analyst"
+
+	// RoleAdmin may perform every action on every merchant
+	RoleAdmin Role = "admin"
+
+	// RoleAcquirer is an acquirer pushing dispute notifications of any of
+	// its merchants
+	RoleAcquirer Role = "acquirer"
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	// ID identifies the caller: the token subject or the API key ID
+	ID string `json:"id"`
+
+	// Method is how the caller was authenticated
+	Method AuthMethod `json:"method"`
+
+	// MerchantID is the merchant the caller belongs to, empty for staff
+	MerchantID string `json:"merchant_id,omitempty"`
+
+	// Roles granted to the caller
+	Roles []Role `json:"roles,omitempty"`
+
+	// Scopes granted to the caller, e.g. "chargebacks:write"
+	Scopes []string `json:"scopes,omitempty"`
+
+	// ExpiresAt is when the credentials expire, e.g. the "exp" claim of a
+	// token, or zero if they do not
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+}
+
+// HasRole reports whether the caller was granted role
+func (p *Principal) HasRole(role Role) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// HasScope reports whether the caller was granted scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+)
+
+// ErrUnauthenticated is returned when credentials are missing or invalid.
+// Other errors mean the credentials could not be checked.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Credentials are the credentials presented with a request. At most one is
+// used, in the order of the fields.
+type Credentials struct {
+	// Claims were established by an API Gateway authorizer, which already
+	// verified the caller
+	Claims map[string]string
+
+	// BearerToken is the JWT of the Authorization header
+	BearerToken string
+
+	// APIKey is the value of the X-API-Key header
+	APIKey string
+}
+
+// Empty reports whether no credentials were presented
+func (c Credentials) Empty() bool {
+	return len(c.Claims) == 0 && c.BearerToken == "" && c.APIKey == ""
+}
+
+// Authenticator turns credentials into the calling principal
+type Authenticator interface {
+	// Authenticate returns the principal of the credentials, or an error
+	// wrapping ErrUnauthenticated if they are missing or invalid
+	Authenticate(ctx context.Context, credentials Credentials) (*entity.Principal, error)
+}
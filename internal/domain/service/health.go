@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// HealthStatus is the state of a dependency
+type HealthStatus string
+
+const (
+	// HealthUp means the last probe of the dependency succeeded
+	HealthUp HealthStatus = "up"
+
+	// HealthDown means the last probe of the dependency failed
+	HealthDown HealthStatus = "down"
+)
+
+// DependencyHealth is the result of the last probe of a dependency
+type DependencyHealth struct {
+	// Name identifies the dependency, e.g. "dynamodb"
+	Name string `json:"name"`
+
+	// Status of the dependency at CheckedAt
+	Status HealthStatus `json:"status"`
+
+	// LatencyMs is how long the last probe took, in milliseconds
+	LatencyMs float64 `json:"latency_ms"`
+
+	// CheckedAt is when the last probe ran. Results are cached briefly, so
+	// it may be earlier than the request.
+	CheckedAt time.Time `json:"checked_at"`
+
+	// LastError is the error of the most recent failed probe, kept after the
+	// dependency recovers. It may name internal resources, so it is only
+	// shown to authenticated callers.
+	LastError string `json:"last_error,omitempty"`
+
+	// LastErrorAt is when the most recent failed probe ran
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// HealthChecker reports whether the dependencies of the service can serve
+// requests
+type HealthChecker interface {
+	// Check returns the state of every dependency, in a stable order
+	Check(ctx context.Context) []DependencyHealth
+}
@@ -44,6 +44,11 @@ func (l LogLevel) IsValid() bool {
 	return l >= LogLevelDebug && l <= LogLevelError
 }
 
+// FieldAudit marks an entry as part of the audit trail when set to true in
+// its fields. Audit entries are written whatever the configured level and are
+// never sampled.
+const FieldAudit = "audit"
+
 // LogEntry represents a single log entry with structured data
 type LogEntry struct {
 	// Level is the severity level of the log entry
@@ -69,6 +74,12 @@ func (e LogEntry) Validate() error {
 	return nil
 }
 
+// IsAudit reports whether the entry belongs to the audit trail, see FieldAudit
+func (e LogEntry) IsAudit() bool {
+	audit, _ := e.Fields[FieldAudit].(bool)
+	return audit
+}
+
 // WithField creates a new LogEntry with an additional field
 // This method creates a copy to maintain immutability
 func (e LogEntry) WithField(key string, value interface{}) LogEntry {
@@ -173,6 +173,29 @@ func TestLogEntry_WithField(t *testing.T) {
 	}
 }
 
+// TestLogEntry_IsAudit tests recognizing audit entries
+func TestLogEntry_IsAudit(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   bool
+	}{
+		{"marked", map[string]interface{}{FieldAudit: true}, true},
+		{"marked false", map[string]interface{}{FieldAudit: false}, false},
+		{"not a bool", map[string]interface{}{FieldAudit: "true"}, false},
+		{"unmarked", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := LogEntry{Level: LogLevelInfo, Message: "Test message", Fields: tt.fields}
+			if got := entry.IsAudit(); got != tt.want {
+				t.Errorf("IsAudit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestLogEntry_WithFields tests adding multiple fields to log entry
 func TestLogEntry_WithFields(t *testing.T) {
 	entry := LogEntry{
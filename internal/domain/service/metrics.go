@@ -0,0 +1,164 @@
+package service
+
+import "context"
+
+// MetricKind identifies how the values of a metric are aggregated
+type MetricKind int
+
+const (
+	// MetricCounter is a cumulative count that only increases
+	MetricCounter MetricKind = iota
+
+	// MetricHistogram is a distribution of observed values
+	MetricHistogram
+
+	// MetricGauge is a value that may go up and down
+	MetricGauge
+)
+
+// String returns the string representation of the metric kind
+func (k MetricKind) String() string {
+	switch k {
+	case MetricCounter:
+		return "counter"
+	case MetricHistogram:
+		return "histogram"
+	case MetricGauge:
+		return "gauge"
+	default:
+		return "unknown"
+	}
+}
+
+// MetricUnit is the unit of a metric, named as CloudWatch names it
+type MetricUnit string
+
+const (
+	// UnitCount is used by counters of events
+	UnitCount MetricUnit = "Count"
+
+	// UnitMilliseconds is used by latencies
+	UnitMilliseconds MetricUnit = "Milliseconds"
+
+	// UnitNone is used by values without a unit, such as amounts
+	UnitNone MetricUnit = "None"
+)
+
+// Metric describes a metric. Implementations need no prior registration;
+// the description travels with every recorded value.
+type Metric struct {
+	// Name is the metric name, in snake_case
+	Name string
+
+	// Kind selects how values are aggregated
+	Kind MetricKind
+
+	// Unit of the recorded values
+	Unit MetricUnit
+
+	// Help describes the metric in a sentence
+	Help string
+
+	// Buckets are the upper bounds of histogram buckets, in ascending
+	// order. Implementations use their defaults if empty.
+	Buckets []float64
+}
+
+// Labels qualify a recorded value, such as the reason of a chargeback. Label
+// values must come from small fixed sets, as every distinct value makes a new
+// series: never card numbers or free text. The only exceptions are the
+// HighCardinalityLabels, which exporters leave out unless configured to keep
+// them.
+type Labels map[string]string
+
+// LabelMerchantID names the merchant of a recorded value
+const LabelMerchantID = "merchant_id"
+
+// HighCardinalityLabels may take an unbounded number of values, one per
+// merchant. Exporters only make series or dimensions of them when configured
+// to.
+var HighCardinalityLabels = []string{LabelMerchantID}
+
+// Business metrics recorded by the chargeback use cases and API
+var (
+	// MetricChargebacksCreated counts created chargebacks by reason,
+	// currency and merchant
+	MetricChargebacksCreated = Metric{
+		Name: "chargebacks_created_total",
+		Kind: MetricCounter,
+		Unit: UnitCount,
+		Help: "Chargebacks created, by reason, currency and merchant.",
+	}
+
+	// MetricChargebackAmount is the distribution of created chargeback
+	// amounts by currency and reason
+	MetricChargebackAmount = Metric{
+		Name:    "chargeback_amount",
+		Kind:    MetricHistogram,
+		Unit:    UnitNone,
+		Help:    "Amounts of created chargebacks, by currency and reason.",
+		Buckets: []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 50000},
+	}
+
+	// MetricValidationFailures counts rejected request fields by field
+	// and by the source of the rejection: "schema" or "entity"
+	MetricValidationFailures = Metric{
+		Name: "chargeback_validation_failures_total",
+		Kind: MetricCounter,
+		Unit: UnitCount,
+		Help: "Invalid chargeback request fields, by field and source.",
+	}
+
+	// MetricDuplicateRejections counts chargebacks rejected because their
+	// transaction already has one
+	MetricDuplicateRejections = Metric{
+		Name: "chargeback_duplicates_total",
+		Kind: MetricCounter,
+		Unit: UnitCount,
+		Help: "Chargebacks rejected for an already disputed transaction, by merchant.",
+	}
+
+	// MetricQuotaRejections counts chargebacks rejected because their
+	// merchant used up its daily quota
+	MetricQuotaRejections = Metric{
+		Name: "chargeback_quota_rejections_total",
+		Kind: MetricCounter,
+		Unit: UnitCount,
+		Help: "Chargebacks rejected for exceeding the daily quota, by merchant.",
+	}
+
+	// MetricPanicsRecovered counts the panics of request handlers turned
+	// into 500 responses
+	MetricPanicsRecovered = Metric{
+		Name: "http_panics_recovered_total",
+		Kind: MetricCounter,
+		Unit: UnitCount,
+		Help: "Panics of request handlers recovered as 500 responses.",
+	}
+)
+
+// Metrics defines the contract for recording metrics in the domain layer.
+// Recording never fails the operation being measured, so the methods
+// return nothing; implementations report their own export errors.
+type Metrics interface {
+	// Add increments a counter by value
+	Add(ctx context.Context, metric Metric, value float64, labels Labels)
+
+	// Observe records a value in a histogram
+	Observe(ctx context.Context, metric Metric, value float64, labels Labels)
+
+	// Set updates the value of a gauge
+	Set(ctx context.Context, metric Metric, value float64, labels Labels)
+}
+
+// NopMetrics discards every value. It stands in when metrics are disabled.
+type NopMetrics struct{}
+
+// Add discards the value
+func (NopMetrics) Add(ctx context.Context, metric Metric, value float64, labels Labels) {}
+
+// Observe discards the value
+func (NopMetrics) Observe(ctx context.Context, metric Metric, value float64, labels Labels) {}
+
+// Set discards the value
+func (NopMetrics) Set(ctx context.Context, metric Metric, value float64, labels Labels) {}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitResult is the outcome of taking from a limit
+type RateLimitResult struct {
+	// Allowed reports whether the request fits the limit
+	Allowed bool
+
+	// Limit is the size of the limit, such as the burst of a rate or the
+	// daily quota
+	Limit int64
+
+	// Remaining is how many more requests fit the limit now
+	Remaining int64
+
+	// Reset is how long until the limit is fully restored
+	Reset time.Duration
+
+	// RetryAfter is how long until a rejected request may be retried, zero
+	// if allowed
+	RetryAfter time.Duration
+}
+
+// RateLimiter limits the request rate of callers
+type RateLimiter interface {
+	// Allow takes one request from the limit of key
+	Allow(ctx context.Context, key string) (RateLimitResult, error)
+}
+
+// Quota limits how many chargebacks merchants open a day
+type Quota interface {
+	// Consume counts one chargeback against the daily quota of the merchant
+	Consume(ctx context.Context, merchantID string) (RateLimitResult, error)
+}
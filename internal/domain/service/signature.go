@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+)
+
+// ErrInvalidSignature is returned when a signed request comes from an
+// unknown source, is stale or replayed, or its signature does not match.
+// Other errors mean the signature could not be checked.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignedRequest is a request pushed by an external source, such as an
+// acquirer, with the signature of its sender
+type SignedRequest struct {
+	// Source identifies the sender, and so the key verifying it
+	Source string
+
+	// Timestamp is when the request was signed, in Unix seconds
+	Timestamp string
+
+	// Nonce is unique to the request, so it cannot be replayed
+	Nonce string
+
+	// Signature is the base64 signature of the timestamp, nonce and body
+	Signature string
+
+	// Body is the raw request body
+	Body []byte
+}
+
+// SignatureVerifier verifies the requests pushed by external sources
+type SignatureVerifier interface {
+	// Verify returns the principal of the source that signed the request,
+	// or an error wrapping ErrInvalidSignature if the request must be
+	// rejected
+	Verify(ctx context.Context, request SignedRequest) (*entity.Principal, error)
+}
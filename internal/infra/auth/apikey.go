@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// APIKey is an API key issued to a merchant integration. Only the hash of
+// the key is stored.
+type APIKey struct {
+	// Hash is the hex SHA-256 of the key, see HashAPIKey
+	Hash string `dynamodbav:"key_hash"`
+
+	// ID identifies the key in logs and audits without revealing it
+	ID string `dynamodbav:"key_id"`
+
+	// MerchantID is the merchant the key was issued to
+	MerchantID string `dynamodbav:"merchant_id"`
+
+	// Roles granted to the key
+	Roles []string `dynamodbav:"roles,omitempty"`
+
+	// Scopes granted to the key
+	Scopes []string `dynamodbav:"scopes,omitempty"`
+
+	// Disabled keys are rejected
+	Disabled bool `dynamodbav:"disabled,omitempty"`
+
+	// ExpiresAt is the expiry in Unix seconds, never if zero. It may double
+	// as the table's TTL attribute.
+	ExpiresAt int64 `dynamodbav:"expires_at,omitempty"`
+}
+
+// Active reports whether the key may be used at now
+func (k *APIKey) Active(now time.Time) bool {
+	return !k.Disabled && (k.ExpiresAt == 0 || now.Unix() < k.ExpiresAt)
+}
+
+// HashAPIKey returns the hex SHA-256 of an API key. Keys are random, so a
+// fast hash is enough to keep them out of the table.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore looks API keys up by hash
+type APIKeyStore interface {
+	// Lookup returns the key with the given hash, or nil if there is none
+	Lookup(ctx context.Context, hash string) (*APIKey, error)
+}
+
+// GetItemAPI is the part of the DynamoDB client used by DynamoDBAPIKeyStore
+type GetItemAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+}
+
+// DynamoDBAPIKeyStore reads API keys from a table keyed by "key_hash"
+type DynamoDBAPIKeyStore struct {
+	client    GetItemAPI
+	tableName string
+}
+
+// NewDynamoDBAPIKeyStore creates a store reading the given table
+func NewDynamoDBAPIKeyStore(client GetItemAPI, tableName string) *DynamoDBAPIKeyStore {
+	return &DynamoDBAPIKeyStore{client: client, tableName: tableName}
+}
+
+// Lookup returns the key with the given hash, or nil if there is none
+func (s *DynamoDBAPIKeyStore) Lookup(ctx context.Context, hash string) (*APIKey, error) {
+	output, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.tableName),
+		Key: map[string]types.AttributeValue{
+			"key_hash": &types.AttributeValueMemberS{Value: hash},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+	if len(output.Item) == 0 {
+		return nil, nil
+	}
+
+	var key APIKey
+	if err := attributevalue.UnmarshalMap(output.Item, &key); err != nil {
+		return nil, fmt.Errorf("failed to decode API key: %w", err)
+	}
+	return &key, nil
+}
@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// stubGetItem returns items keyed by hash and counts its calls
+type stubGetItem struct {
+	items map[string]map[string]types.AttributeValue
+	err   error
+	calls int
+	input *dynamodb.GetItemInput
+}
+
+func (s *stubGetItem) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	s.calls++
+	s.input = params
+	if s.err != nil {
+		return nil, s.err
+	}
+	hash := params.Key["key_hash"].(*types.AttributeValueMemberS).Value
+	return &dynamodb.GetItemOutput{Item: s.items[hash]}, nil
+}
+
+// apiKeyItem returns the stored form of an API key
+func apiKeyItem(rawKey, id, merchantID string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"key_hash":    &types.AttributeValueMemberS{Value: HashAPIKey(rawKey)},
+		"key_id":      &types.AttributeValueMemberS{Value: id},
+		"merchant_id": &types.AttributeValueMemberS{Value: merchantID},
+		"roles":       &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "merchant"}}},
+		"scopes":      &types.AttributeValueMemberL{Value: []types.AttributeValue{&types.AttributeValueMemberS{Value: "chargebacks:write"}}},
+	}
+}
+
+func TestHashAPIKey(t *testing.T) {
+	// SHA-256 of "secret"
+	want := "2bb80d537b1da3e38bd30361aa855686bde0eacd7162fef6a25fe97bf527a25b"
+	if got := HashAPIKey("secret"); got != want {
+		t.Errorf("HashAPIKey() = %s, want %s", got, want)
+	}
+}
+
+func TestAPIKey_Active(t *testing.T) {
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{name: "no expiry", key: APIKey{}, want: true},
+		{name: "not expired", key: APIKey{ExpiresAt: testNow.Add(time.Hour).Unix()}, want: true},
+		{name: "expired", key: APIKey{ExpiresAt: testNow.Unix()}, want: false},
+		{name: "disabled", key: APIKey{Disabled: true}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key.Active(testNow); got != tt.want {
+				t.Errorf("Active() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDynamoDBAPIKeyStore_Lookup(t *testing.T) {
+	// Arrange
+	client := &stubGetItem{items: map[string]map[string]types.AttributeValue{
+		HashAPIKey("sk_live_123"): apiKeyItem("sk_live_123", "key-1", "merchant-456"),
+	}}
+	store := NewDynamoDBAPIKeyStore(client, "api-keys")
+	ctx := context.Background()
+
+	// Act
+	key, err := store.Lookup(ctx, HashAPIKey("sk_live_123"))
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if key.ID != "key-1" || key.MerchantID != "merchant-456" || len(key.Roles) != 1 || key.Scopes[0] != "chargebacks:write" {
+		t.Errorf("Unexpected key %+v", key)
+	}
+	if aws.ToString(client.input.TableName) != "api-keys" || !aws.ToBool(client.input.ConsistentRead) {
+		t.Errorf("Expected a consistent read of api-keys, got %+v", client.input)
+	}
+
+	if key, err := store.Lookup(ctx, HashAPIKey("unknown")); err != nil || key != nil {
+		t.Errorf("Expected no key for an unknown hash, got %+v, %v", key, err)
+	}
+
+	client.err = errors.New("throttled")
+	if _, err := store.Lookup(ctx, HashAPIKey("sk_live_123")); err == nil {
+		t.Error("Expected the DynamoDB error")
+	}
+}
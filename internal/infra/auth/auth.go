@@ -0,0 +1,315 @@
+// Package auth implements service.Authenticator: JWTs verified against a
+// JWKS fetched from a URL or read from a file, hashed API keys stored in
+// DynamoDB, and the claims of an API Gateway authorizer
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+const (
+	// DefaultAPIKeyCacheTTL is how long a looked up API key is reused
+	DefaultAPIKeyCacheTTL = time.Minute
+
+	// maxCachedAPIKeys bounds the API key cache, which also remembers
+	// unknown keys
+	maxCachedAPIKeys = 10000
+)
+
+// ClaimNames names the claims holding the merchant, roles and scopes of a
+// caller, in tokens and authorizer contexts
+type ClaimNames struct {
+	Merchant string
+	Roles    string
+	Scopes   string
+}
+
+// DefaultClaimNames are used for the names left empty
+var DefaultClaimNames = ClaimNames{
+	Merchant: "merchant_id",
+	Roles:    "roles",
+	Scopes:   "scope",
+}
+
+// Config configures authentication
+type Config struct {
+	// JWKSURL is fetched for the keys verifying bearer tokens
+	JWKSURL string
+
+	// JWKSFile is read for the keys verifying bearer tokens, e.g. in tests
+	// and local development. Bearer tokens are rejected if neither is set.
+	JWKSFile string
+
+	// JWKSRefresh is how long fetched keys are used, DefaultJWKSRefresh if zero
+	JWKSRefresh time.Duration
+
+	// JWT configures the checks of bearer tokens
+	JWT JWTConfig
+
+	// APIKeysTable is the DynamoDB table of hashed API keys. API keys are
+	// rejected if empty.
+	APIKeysTable string
+
+	// APIKeyCacheTTL is how long a looked up key is reused,
+	// DefaultAPIKeyCacheTTL if zero
+	APIKeyCacheTTL time.Duration
+
+	// Claims names the claims describing a caller
+	Claims ClaimNames
+}
+
+// Validate checks the authentication configuration
+func (c Config) Validate() error {
+	var errs []error
+	if c.JWKSURL != "" && c.JWKSFile != "" {
+		errs = append(errs, errors.New("JWKS URL and file are mutually exclusive"))
+	}
+	if c.JWKSURL != "" {
+		if u, err := url.Parse(c.JWKSURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("JWKS URL must be an http(s) URL, got %q", c.JWKSURL))
+		}
+	}
+	if c.JWKSURL != "" || c.JWKSFile != "" {
+		if err := c.JWT.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("jwt: %w", err))
+		}
+	}
+	if c.JWKSRefresh < 0 {
+		errs = append(errs, fmt.Errorf("JWKS refresh must not be negative, got %s", c.JWKSRefresh))
+	}
+	if c.APIKeyCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("API key cache TTL must not be negative, got %s", c.APIKeyCacheTTL))
+	}
+	return errors.Join(errs...)
+}
+
+// Authenticator is a service.Authenticator accepting authorizer claims,
+// bearer tokens and API keys
+type Authenticator struct {
+	jwt     *JWTVerifier
+	apiKeys APIKeyStore
+	claims  ClaimNames
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]cachedAPIKey
+}
+
+// cachedAPIKey is a looked up API key, nil if the key is unknown
+type cachedAPIKey struct {
+	key     *APIKey
+	expires time.Time
+}
+
+// NewAuthenticator creates an authenticator. Bearer tokens are rejected if
+// jwt is nil and API keys if apiKeys is nil.
+func NewAuthenticator(jwt *JWTVerifier, apiKeys APIKeyStore, config Config) *Authenticator {
+	if config.APIKeyCacheTTL == 0 {
+		config.APIKeyCacheTTL = DefaultAPIKeyCacheTTL
+	}
+	if config.Claims.Merchant == "" {
+		config.Claims.Merchant = DefaultClaimNames.Merchant
+	}
+	if config.Claims.Roles == "" {
+		config.Claims.Roles = DefaultClaimNames.Roles
+	}
+	if config.Claims.Scopes == "" {
+		config.Claims.Scopes = DefaultClaimNames.Scopes
+	}
+
+	return &Authenticator{
+		jwt:     jwt,
+		apiKeys: apiKeys,
+		claims:  config.Claims,
+		ttl:     config.APIKeyCacheTTL,
+		now:     time.Now,
+		cache:   make(map[string]cachedAPIKey),
+	}
+}
+
+// Setup creates the configured authenticator, reading API keys through the
+// given DynamoDB client
+func Setup(config Config, dynamo GetItemAPI) (*Authenticator, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	var keys KeySet
+	switch {
+	case config.JWKSURL != "":
+		keys = NewRemoteKeySet(config.JWKSURL, nil, config.JWKSRefresh)
+	case config.JWKSFile != "":
+		fileKeys, err := LoadKeySetFile(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = fileKeys
+	}
+
+	var verifier *JWTVerifier
+	if keys != nil {
+		verifier = NewJWTVerifier(keys, config.JWT)
+	}
+
+	var apiKeys APIKeyStore
+	if config.APIKeysTable != "" {
+		apiKeys = NewDynamoDBAPIKeyStore(dynamo, config.APIKeysTable)
+	}
+
+	return NewAuthenticator(verifier, apiKeys, config), nil
+}
+
+// Authenticate returns the principal of the first credentials presented
+func (a *Authenticator) Authenticate(ctx context.Context, credentials service.Credentials) (*entity.Principal, error) {
+	switch {
+	case len(credentials.Claims) > 0:
+		return a.authorizerPrincipal(credentials.Claims)
+	case credentials.BearerToken != "":
+		return a.tokenPrincipal(ctx, credentials.BearerToken)
+	case credentials.APIKey != "":
+		return a.apiKeyPrincipal(ctx, credentials.APIKey)
+	default:
+		return nil, unauthenticated("no credentials")
+	}
+}
+
+// authorizerPrincipal trusts the claims of an API Gateway authorizer
+func (a *Authenticator) authorizerPrincipal(claims map[string]string) (*entity.Principal, error) {
+	id := firstNonEmpty(claims["principal_id"], claims["principalId"], claims["sub"])
+	if id == "" {
+		return nil, unauthenticated("authorizer claims name no principal")
+	}
+
+	return &entity.Principal{
+		ID:         id,
+		Method:     entity.AuthMethodAuthorizer,
+		MerchantID: claims[a.claims.Merchant],
+		Roles:      roles(splitList(claims[a.claims.Roles])),
+		Scopes:     splitList(claims[a.claims.Scopes]),
+	}, nil
+}
+
+// tokenPrincipal verifies a bearer token
+func (a *Authenticator) tokenPrincipal(ctx context.Context, token string) (*entity.Principal, error) {
+	if a.jwt == nil {
+		return nil, unauthenticated("bearer tokens are not accepted")
+	}
+
+	claims, err := a.jwt.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, unauthenticated("token has no subject")
+	}
+	merchantID, _ := claims[a.claims.Merchant].(string)
+	expires, _ := claims.time("exp")
+
+	return &entity.Principal{
+		ID:         subject,
+		Method:     entity.AuthMethodJWT,
+		MerchantID: merchantID,
+		Roles:      roles(claims.Strings(a.claims.Roles)),
+		Scopes:     claims.Strings(a.claims.Scopes),
+		ExpiresAt:  expires,
+	}, nil
+}
+
+// apiKeyPrincipal looks an API key up by hash
+func (a *Authenticator) apiKeyPrincipal(ctx context.Context, rawKey string) (*entity.Principal, error) {
+	if a.apiKeys == nil {
+		return nil, unauthenticated("API keys are not accepted")
+	}
+
+	key, err := a.lookupAPIKey(ctx, HashAPIKey(rawKey))
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, unauthenticated("unknown API key")
+	}
+	if !key.Active(a.now()) {
+		return nil, unauthenticated(fmt.Sprintf("API key %s is disabled or expired", key.ID))
+	}
+
+	principal := &entity.Principal{
+		ID:         key.ID,
+		Method:     entity.AuthMethodAPIKey,
+		MerchantID: key.MerchantID,
+		Roles:      roles(key.Roles),
+		Scopes:     key.Scopes,
+	}
+	if key.ExpiresAt != 0 {
+		principal.ExpiresAt = time.Unix(key.ExpiresAt, 0)
+	}
+	return principal, nil
+}
+
+// lookupAPIKey returns a cached key, looking it up first if the cached entry
+// expired. Failed lookups are not cached.
+func (a *Authenticator) lookupAPIKey(ctx context.Context, hash string) (*APIKey, error) {
+	now := a.now()
+
+	a.mu.Lock()
+	cached, ok := a.cache[hash]
+	a.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.key, nil
+	}
+
+	key, err := a.apiKeys.Lookup(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.cache) >= maxCachedAPIKeys {
+		a.cache = make(map[string]cachedAPIKey)
+	}
+	a.cache[hash] = cachedAPIKey{key: key, expires: now.Add(a.ttl)}
+	return key, nil
+}
+
+// roles converts role names
+func roles(names []string) []entity.Role {
+	if len(names) == 0 {
+		return nil
+	}
+	converted := make([]entity.Role, len(names))
+	for i, name := range names {
+		converted[i] = entity.Role(name)
+	}
+	return converted
+}
+
+// splitList splits an authorizer value holding several values. API Gateway
+// flattens JWT lists to "[a b]"; Lambda authorizers may also use commas.
+func splitList(value string) []string {
+	value = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(value), "["), "]")
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ' ' || r == ','
+	})
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
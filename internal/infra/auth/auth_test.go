@@ -0,0 +1,292 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	jwt := JWTConfig{Issuer: "https://issuer.example.com/", Audience: "chargeback-api"}
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "zero values", config: Config{}},
+		{name: "JWKS URL", config: Config{JWKSURL: "https://issuer.example.com/jwks.json", JWT: jwt}},
+		{name: "API keys only", config: Config{APIKeysTable: "api-keys"}},
+		{name: "URL and file", config: Config{JWKSURL: "https://issuer.example.com/jwks.json", JWKSFile: "jwks.json", JWT: jwt}, wantErr: "mutually exclusive"},
+		{name: "relative URL", config: Config{JWKSURL: "/jwks.json", JWT: jwt}, wantErr: "http(s) URL"},
+		{name: "no issuer", config: Config{JWKSFile: "jwks.json", JWT: JWTConfig{Audience: "chargeback-api"}}, wantErr: "issuer"},
+		{name: "negative refresh", config: Config{JWKSRefresh: -time.Second}, wantErr: "JWKS refresh"},
+		{name: "negative cache TTL", config: Config{APIKeyCacheTTL: -time.Second}, wantErr: "cache TTL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func newTestAuthenticator(client *stubGetItem) *Authenticator {
+	authenticator := NewAuthenticator(newTestVerifier(), NewDynamoDBAPIKeyStore(client, "api-keys"), Config{})
+	authenticator.now = func() time.Time { return testNow }
+	return authenticator
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	client := &stubGetItem{items: map[string]map[string]types.AttributeValue{
+		HashAPIKey("sk_live_123"): apiKeyItem("sk_live_123", "key-1", "merchant-456"),
+	}}
+
+	tokenClaims := validClaims()
+	tokenClaims["roles"] = []string{"analyst"}
+
+	tests := []struct {
+		name        string
+		credentials func(t *testing.T) service.Credentials
+		want        entity.Principal
+	}{
+		{
+			name: "bearer token",
+			credentials: func(t *testing.T) service.Credentials {
+				return service.Credentials{BearerToken: signToken(t, "RS256", "rsa-1", testRSAKey(), tokenClaims)}
+			},
+			want: entity.Principal{
+				ID:         "user-123",
+				Method:     entity.AuthMethodJWT,
+				MerchantID: "merchant-456",
+				Roles:      []entity.Role{"analyst"},
+				Scopes:     []string{"chargebacks:read", "chargebacks:write"},
+				ExpiresAt:  time.Unix(testNow.Add(time.Hour).Unix(), 0),
+			},
+		},
+		{
+			name: "API key",
+			credentials: func(t *testing.T) service.Credentials {
+				return service.Credentials{APIKey: "sk_live_123"}
+			},
+			want: entity.Principal{
+				ID:         "key-1",
+				Method:     entity.AuthMethodAPIKey,
+				MerchantID: "merchant-456",
+				Roles:      []entity.Role{"merchant"},
+				Scopes:     []string{"chargebacks:write"},
+			},
+		},
+		{
+			name: "authorizer claims take precedence",
+			credentials: func(t *testing.T) service.Credentials {
+				return service.Credentials{
+					Claims: map[string]string{
+						"sub":         "user-789",
+						"merchant_id": "merchant-456",
+						"roles":       "[analyst admin]",
+						"scope":       "chargebacks:read",
+					},
+					APIKey: "sk_live_123",
+				}
+			},
+			want: entity.Principal{
+				ID:         "user-789",
+				Method:     entity.AuthMethodAuthorizer,
+				MerchantID: "merchant-456",
+				Roles:      []entity.Role{"analyst", "admin"},
+				Scopes:     []string{"chargebacks:read"},
+			},
+		},
+		{
+			name: "Lambda authorizer principal",
+			credentials: func(t *testing.T) service.Credentials {
+				return service.Credentials{Claims: map[string]string{"principalId": "key-1", "roles": "merchant,analyst"}}
+			},
+			want: entity.Principal{
+				ID:     "key-1",
+				Method: entity.AuthMethodAuthorizer,
+				Roles:  []entity.Role{"merchant", "analyst"},
+			},
+		},
+	}
+
+	authenticator := newTestAuthenticator(client)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			principal, err := authenticator.Authenticate(context.Background(), tt.credentials(t))
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if principal.ID != tt.want.ID || principal.Method != tt.want.Method || principal.MerchantID != tt.want.MerchantID {
+				t.Errorf("Expected %+v, got %+v", tt.want, principal)
+			}
+			if !principal.ExpiresAt.Equal(tt.want.ExpiresAt) {
+				t.Errorf("Expected expiry %v, got %v", tt.want.ExpiresAt, principal.ExpiresAt)
+			}
+			if strings.Join(principal.Scopes, " ") != strings.Join(tt.want.Scopes, " ") {
+				t.Errorf("Expected scopes %v, got %v", tt.want.Scopes, principal.Scopes)
+			}
+			if len(principal.Roles) != len(tt.want.Roles) {
+				t.Fatalf("Expected roles %v, got %v", tt.want.Roles, principal.Roles)
+			}
+			for _, role := range tt.want.Roles {
+				if !principal.HasRole(role) {
+					t.Errorf("Expected role %s, got %v", role, principal.Roles)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticator_Authenticate_Rejected(t *testing.T) {
+	disabled := apiKeyItem("sk_disabled", "key-2", "merchant-456")
+	disabled["disabled"] = &types.AttributeValueMemberBOOL{Value: true}
+	client := &stubGetItem{items: map[string]map[string]types.AttributeValue{
+		HashAPIKey("sk_disabled"): disabled,
+	}}
+	noSubject := validClaims()
+	delete(noSubject, "sub")
+
+	tests := []struct {
+		name          string
+		authenticator *Authenticator
+		credentials   func(t *testing.T) service.Credentials
+		wantErr       string
+	}{
+		{
+			name:          "no credentials",
+			authenticator: newTestAuthenticator(client),
+			credentials:   func(t *testing.T) service.Credentials { return service.Credentials{} },
+			wantErr:       "no credentials",
+		},
+		{
+			name:          "unknown API key",
+			authenticator: newTestAuthenticator(client),
+			credentials:   func(t *testing.T) service.Credentials { return service.Credentials{APIKey: "sk_unknown"} },
+			wantErr:       "unknown API key",
+		},
+		{
+			name:          "disabled API key",
+			authenticator: newTestAuthenticator(client),
+			credentials:   func(t *testing.T) service.Credentials { return service.Credentials{APIKey: "sk_disabled"} },
+			wantErr:       "key-2 is disabled",
+		},
+		{
+			name:          "token without subject",
+			authenticator: newTestAuthenticator(client),
+			credentials: func(t *testing.T) service.Credentials {
+				return service.Credentials{BearerToken: signToken(t, "RS256", "rsa-1", testRSAKey(), noSubject)}
+			},
+			wantErr: "no subject",
+		},
+		{
+			name:          "authorizer claims without principal",
+			authenticator: newTestAuthenticator(client),
+			credentials: func(t *testing.T) service.Credentials {
+				return service.Credentials{Claims: map[string]string{"merchant_id": "merchant-456"}}
+			},
+			wantErr: "no principal",
+		},
+		{
+			name:          "bearer tokens not configured",
+			authenticator: NewAuthenticator(nil, nil, Config{}),
+			credentials:   func(t *testing.T) service.Credentials { return service.Credentials{BearerToken: "a.b.c"} },
+			wantErr:       "bearer tokens are not accepted",
+		},
+		{
+			name:          "API keys not configured",
+			authenticator: NewAuthenticator(nil, nil, Config{}),
+			credentials:   func(t *testing.T) service.Credentials { return service.Credentials{APIKey: "sk_live_123"} },
+			wantErr:       "API keys are not accepted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.authenticator.Authenticate(context.Background(), tt.credentials(t))
+			if !errors.Is(err, service.ErrUnauthenticated) {
+				t.Fatalf("Expected an unauthenticated error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticator_APIKeyCache(t *testing.T) {
+	// Arrange
+	client := &stubGetItem{items: map[string]map[string]types.AttributeValue{
+		HashAPIKey("sk_live_123"): apiKeyItem("sk_live_123", "key-1", "merchant-456"),
+	}}
+	authenticator := newTestAuthenticator(client)
+	now := testNow
+	authenticator.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Act & Assert: found and unknown keys are both cached for the TTL
+	for i := 0; i < 3; i++ {
+		if _, err := authenticator.Authenticate(ctx, service.Credentials{APIKey: "sk_live_123"}); err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		authenticator.Authenticate(ctx, service.Credentials{APIKey: "sk_unknown"})
+	}
+	if client.calls != 2 {
+		t.Errorf("Expected 2 lookups, got %d", client.calls)
+	}
+
+	now = now.Add(DefaultAPIKeyCacheTTL)
+	authenticator.Authenticate(ctx, service.Credentials{APIKey: "sk_live_123"})
+	if client.calls != 3 {
+		t.Errorf("Expected an expired entry to be looked up again, got %d lookups", client.calls)
+	}
+
+	// Failed lookups are neither cached nor reported as bad credentials
+	client.err = errors.New("throttled")
+	for i := 0; i < 2; i++ {
+		_, err := authenticator.Authenticate(ctx, service.Credentials{APIKey: "sk_other"})
+		if err == nil || errors.Is(err, service.ErrUnauthenticated) {
+			t.Errorf("Expected the lookup error, got %v", err)
+		}
+	}
+	if client.calls != 5 {
+		t.Errorf("Expected failed lookups to be retried, got %d lookups", client.calls)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := Setup(Config{JWKSURL: "/jwks.json"}, nil); err == nil {
+		t.Error("Expected an invalid config error")
+	}
+	if _, err := Setup(Config{JWKSFile: "missing.json", JWT: JWTConfig{Issuer: "i", Audience: "a"}}, nil); err == nil {
+		t.Error("Expected an error for a missing JWKS file")
+	}
+
+	authenticator, err := Setup(Config{APIKeysTable: "api-keys"}, &stubGetItem{})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if _, err := authenticator.Authenticate(context.Background(), service.Credentials{BearerToken: "a.b.c"}); !errors.Is(err, service.ErrUnauthenticated) {
+		t.Errorf("Expected bearer tokens to be rejected without a JWKS, got %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+const (
+	// DefaultJWKSRefresh is how long a fetched key set is used before it is
+	// fetched again
+	DefaultJWKSRefresh = time.Hour
+
+	// DefaultJWKSTimeout bounds a key set fetch when no client is given
+	DefaultJWKSTimeout = 10 * time.Second
+
+	// minJWKSRefresh bounds how often an unknown key ID triggers a fetch, so
+	// forged key IDs cannot hammer the issuer
+	minJWKSRefresh = time.Minute
+
+	// jwksRetryInterval bounds how often a key set that never loaded is
+	// fetched again after a failure
+	jwksRetryInterval = 5 * time.Second
+
+	// maxJWKSBytes bounds the size of a key set document
+	maxJWKSBytes = 1 << 20
+)
+
+// KeySet returns the public keys verifying token signatures
+type KeySet interface {
+	// Key returns the key with the given ID, or an error wrapping
+	// service.ErrUnauthenticated if the set has no such key
+	Key(ctx context.Context, kid string) (PublicKey, error)
+}
+
+// PublicKey is a signature verification key of a key set
+type PublicKey struct {
+	// Key is an *rsa.PublicKey or an *ecdsa.PublicKey
+	Key crypto.PublicKey
+
+	// Algorithm restricts the key to one JWS algorithm, any matching the key
+	// type if empty
+	Algorithm string
+}
+
+// jwk is a JSON Web Key as published in a key set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS parses a JSON Web Key Set, keyed by key ID. Keys used for
+// encryption and key types other than RSA and EC are skipped.
+func ParseJWKS(data []byte) (map[string]PublicKey, error) {
+	var document struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]PublicKey, len(document.Keys))
+	for _, key := range document.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+
+		var publicKey crypto.PublicKey
+		var err error
+		switch key.Kty {
+		case "RSA":
+			publicKey, err = parseRSAKey(key)
+		case "EC":
+			publicKey, err = parseECKey(key)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", key.Kid, err)
+		}
+		keys[key.Kid] = PublicKey{Key: publicKey, Algorithm: key.Alg}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS holds no signature keys")
+	}
+	return keys, nil
+}
+
+// parseRSAKey decodes the modulus and exponent of an RSA key
+func parseRSAKey(key jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(key.N)
+	if err != nil || len(n) == 0 {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(key.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	publicKey := &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}
+	if publicKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("RSA keys must have at least 2048 bits, got %d", publicKey.N.BitLen())
+	}
+	return publicKey, nil
+}
+
+// parseECKey decodes the curve point of an EC key
+func parseECKey(key jwk) (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch key.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", key.Crv)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+	x, err := base64.RawURLEncoding.DecodeString(key.X)
+	if err != nil || len(x) != size {
+		return nil, errors.New("invalid x coordinate")
+	}
+	y, err := base64.RawURLEncoding.DecodeString(key.Y)
+	if err != nil || len(y) != size {
+		return nil, errors.New("invalid y coordinate")
+	}
+
+	// The uncompressed point encoding also checks the point is on the curve
+	point := append(append([]byte{4}, x...), y...)
+	return ecdsa.ParseUncompressedPublicKey(curve, point)
+}
+
+// lookupKey returns a key by ID. Tokens without a key ID may only be
+// verified by a set holding a single key.
+func lookupKey(keys map[string]PublicKey, kid string) (PublicKey, bool) {
+	if key, ok := keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	return PublicKey{}, false
+}
+
+// StaticKeySet is a key set loaded once, e.g. from a local file
+type StaticKeySet struct {
+	keys map[string]PublicKey
+}
+
+// NewStaticKeySet creates a key set holding the given keys
+func NewStaticKeySet(keys map[string]PublicKey) *StaticKeySet {
+	return &StaticKeySet{keys: keys}
+}
+
+// LoadKeySetFile reads a JWKS document from a local file
+func LoadKeySetFile(path string) (*StaticKeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWKS file %s: %w", path, err)
+	}
+	return NewStaticKeySet(keys), nil
+}
+
+// Key returns the key with the given ID
+func (s *StaticKeySet) Key(ctx context.Context, kid string) (PublicKey, error) {
+	if key, ok := lookupKey(s.keys, kid); ok {
+		return key, nil
+	}
+	return PublicKey{}, fmt.Errorf("%w: unknown key ID %q", service.ErrUnauthenticated, kid)
+}
+
+// RemoteKeySet is a key set fetched from the JWKS URL of an issuer. It is
+// fetched again once stale, or when a token names an unknown key, so keys
+// rotated by the issuer are picked up. Concurrent callers share one fetch,
+// and failed fetches are retried at most every jwksRetryInterval.
+type RemoteKeySet struct {
+	url     string
+	client  *http.Client
+	refresh time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]PublicKey
+	err       error
+	fetchedAt time.Time
+	attempted time.Time
+	fetching  chan struct{}
+}
+
+// NewRemoteKeySet creates a key set fetched from url, using a client with
+// DefaultJWKSTimeout if client is nil and DefaultJWKSRefresh if refresh is
+// zero. Nothing is fetched until the first token is verified.
+func NewRemoteKeySet(url string, client *http.Client, refresh time.Duration) *RemoteKeySet {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultJWKSTimeout}
+	}
+	if refresh <= 0 {
+		refresh = DefaultJWKSRefresh
+	}
+	return &RemoteKeySet{url: url, client: client, refresh: refresh, now: time.Now}
+}
+
+// Key returns the key with the given ID, fetching the set first if it is
+// stale or lacks the key. A failed fetch keeps serving the previous keys.
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (PublicKey, error) {
+	s.mu.Lock()
+	now := s.now()
+	key, found := lookupKey(s.keys, kid)
+	loaded, err := s.keys != nil, s.err
+	stale := !loaded || now.Sub(s.fetchedAt) >= s.refresh
+	done := s.fetching
+	fetch := (stale || !found) && done == nil && s.mayFetch(now)
+	if fetch {
+		done = make(chan struct{})
+		s.fetching, s.attempted = done, now
+	}
+	s.mu.Unlock()
+
+	// The fetch runs outside the lock and outlives the caller that started
+	// it, as others wait on its result; the client timeout bounds it
+	if fetch {
+		keys, err := s.fetch(context.WithoutCancel(ctx))
+		s.mu.Lock()
+		if err == nil {
+			s.keys = keys
+			s.fetchedAt = now
+		}
+		s.err = err
+		s.fetching = nil
+		s.mu.Unlock()
+		close(done)
+	}
+
+	// A missing key may come with the fetch in progress, while a stale set
+	// holding the key is served meanwhile
+	if done != nil && (fetch || !found) {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return PublicKey{}, ctx.Err()
+		}
+		s.mu.Lock()
+		key, found = lookupKey(s.keys, kid)
+		loaded, err = s.keys != nil, s.err
+		s.mu.Unlock()
+	}
+
+	if !loaded {
+		return PublicKey{}, err
+	}
+	if !found {
+		return PublicKey{}, fmt.Errorf("%w: unknown key ID %q", service.ErrUnauthenticated, kid)
+	}
+	return key, nil
+}
+
+// mayFetch reports whether enough time passed since the last fetch to start
+// another. The caller holds s.mu.
+func (s *RemoteKeySet) mayFetch(now time.Time) bool {
+	interval := minJWKSRefresh
+	if s.keys == nil {
+		interval = jwksRetryInterval
+	}
+	return s.attempted.IsZero() || now.Sub(s.attempted) >= interval
+}
+
+// fetch downloads and parses the key set
+func (s *RemoteKeySet) fetch(ctx context.Context) (map[string]PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxJWKSBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+	return ParseJWKS(data)
+}
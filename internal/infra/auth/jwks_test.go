@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// testRSAKey and testECKey are generated once; RSA key generation is slow
+var (
+	testRSAKey = sync.OnceValue(func() *rsa.PrivateKey {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			panic(err)
+		}
+		return key
+	})
+	testECKey = sync.OnceValue(func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			panic(err)
+		}
+		return key
+	})
+)
+
+// rsaJWK returns the public JWK of an RSA key
+func rsaJWK(kid, alg string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"alg": alg,
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// ecJWK returns the public JWK of a P-256 key
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	point, err := key.PublicKey.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(point[1:33]),
+		"y":   base64.RawURLEncoding.EncodeToString(point[33:]),
+	}
+}
+
+// jwksDocument encodes keys as a JWKS document
+func jwksDocument(keys ...map[string]string) []byte {
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey := &testRSAKey().PublicKey
+	smallKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		document []byte
+		wantKids []string
+		wantErr  string
+	}{
+		{
+			name:     "RSA and EC keys",
+			document: jwksDocument(rsaJWK("rsa-1", "RS256", rsaKey), ecJWK("ec-1", testECKey())),
+			wantKids: []string{"rsa-1", "ec-1"},
+		},
+		{
+			name: "encryption and symmetric keys skipped",
+			document: jwksDocument(
+				rsaJWK("rsa-1", "RS256", rsaKey),
+				map[string]string{"kty": "RSA", "kid": "enc", "use": "enc"},
+				map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"},
+			),
+			wantKids: []string{"rsa-1"},
+		},
+		{name: "not JSON", document: []byte("keys"), wantErr: "decode"},
+		{name: "no signature keys", document: jwksDocument(), wantErr: "no signature keys"},
+		{name: "short RSA key", document: jwksDocument(rsaJWK("small", "RS256", &smallKey.PublicKey)), wantErr: "2048 bits"},
+		{
+			name:     "unsupported curve",
+			document: jwksDocument(map[string]string{"kty": "EC", "kid": "ec", "crv": "secp256k1"}),
+			wantErr:  "unsupported curve",
+		},
+		{
+			name: "point off the curve",
+			document: jwksDocument(func() map[string]string {
+				key := ecJWK("ec", testECKey())
+				key["y"] = key["x"]
+				return key
+			}()),
+			wantErr: `invalid key "ec"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseJWKS(tt.document)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error mentioning %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseJWKS() error = %v", err)
+			}
+			if len(keys) != len(tt.wantKids) {
+				t.Errorf("Expected keys %v, got %d keys", tt.wantKids, len(keys))
+			}
+			for _, kid := range tt.wantKids {
+				if _, ok := keys[kid]; !ok {
+					t.Errorf("Expected key %q", kid)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticKeySet_Key(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksDocument(rsaJWK("rsa-1", "RS256", &testRSAKey().PublicKey)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := LoadKeySetFile(path)
+	if err != nil {
+		t.Fatalf("LoadKeySetFile() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// Act & Assert
+	if key, err := keys.Key(ctx, "rsa-1"); err != nil || key.Algorithm != "RS256" {
+		t.Errorf("Expected the RS256 key, got %+v, %v", key, err)
+	}
+	if _, err := keys.Key(ctx, ""); err != nil {
+		t.Errorf("Expected a token without key ID to use the only key, got %v", err)
+	}
+	if _, err := keys.Key(ctx, "rsa-2"); !errors.Is(err, service.ErrUnauthenticated) {
+		t.Errorf("Expected an unknown key ID to be unauthenticated, got %v", err)
+	}
+
+	if _, err := LoadKeySetFile(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestRemoteKeySet_Key(t *testing.T) {
+	// Arrange
+	rsaKey := &testRSAKey().PublicKey
+	var fetches atomic.Int32
+	var document atomic.Value
+	document.Store(jwksDocument(rsaJWK("rsa-1", "RS256", rsaKey)))
+	var failing atomic.Bool
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(document.Load().([]byte))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	keys := NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+	keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Act & Assert: the first token fetches the set, later ones reuse it
+	for i := 0; i < 3; i++ {
+		if _, err := keys.Key(ctx, "rsa-1"); err != nil {
+			t.Fatalf("Key() error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch, got %d", got)
+	}
+
+	// A rotated key is fetched on first use, but unknown key IDs do not
+	// refetch more than once per minute
+	document.Store(jwksDocument(rsaJWK("rsa-1", "RS256", rsaKey), rsaJWK("rsa-2", "RS256", rsaKey)))
+	now = now.Add(2 * time.Minute)
+	if _, err := keys.Key(ctx, "rsa-2"); err != nil {
+		t.Errorf("Expected the rotated key, got %v", err)
+	}
+	if _, err := keys.Key(ctx, "forged"); !errors.Is(err, service.ErrUnauthenticated) {
+		t.Errorf("Expected an unknown key ID to be unauthenticated, got %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected 2 fetches, got %d", got)
+	}
+
+	// A stale set that fails to refresh keeps serving the previous keys
+	failing.Store(true)
+	now = now.Add(2 * time.Hour)
+	if _, err := keys.Key(ctx, "rsa-2"); err != nil {
+		t.Errorf("Expected the previous keys after a failed refresh, got %v", err)
+	}
+	if got := fetches.Load(); got != 3 {
+		t.Errorf("Expected 3 fetches, got %d", got)
+	}
+}
+
+func TestRemoteKeySet_FetchFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, server.Client(), 0)
+	_, err := keys.Key(context.Background(), "rsa-1")
+	if err == nil || errors.Is(err, service.ErrUnauthenticated) {
+		t.Errorf("Expected an unavailable key set not to be reported as bad credentials, got %v", err)
+	}
+}
+
+func TestRemoteKeySet_RetryAfterFailure(t *testing.T) {
+	// Arrange
+	var fetches atomic.Int32
+	var failing atomic.Bool
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(jwksDocument(rsaJWK("rsa-1", "RS256", &testRSAKey().PublicKey)))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	keys := NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+	keys.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Act & Assert: failures are not retried on every token
+	for i := 0; i < 3; i++ {
+		if _, err := keys.Key(ctx, "rsa-1"); err == nil || !strings.Contains(err.Error(), "status 503") {
+			t.Errorf("Expected the fetch error, got %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch, got %d", got)
+	}
+
+	failing.Store(false)
+	now = now.Add(jwksRetryInterval)
+	if _, err := keys.Key(ctx, "rsa-1"); err != nil {
+		t.Errorf("Expected the key once the issuer recovers, got %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("Expected 2 fetches, got %d", got)
+	}
+}
+
+func TestRemoteKeySet_ConcurrentFetch(t *testing.T) {
+	// Arrange
+	var fetches atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write(jwksDocument(rsaJWK("rsa-1", "RS256", &testRSAKey().PublicKey)))
+	}))
+	defer server.Close()
+
+	keys := NewRemoteKeySet(server.URL, server.Client(), time.Hour)
+	ctx := context.Background()
+
+	// Act: callers arriving during the fetch wait for it
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	key := func() {
+		defer wg.Done()
+		_, err := keys.Key(ctx, "rsa-1")
+		errs <- err
+	}
+	wg.Add(1)
+	go key()
+	<-started
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go key()
+	}
+
+	// A caller whose context ends stops waiting
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := keys.Key(cancelled, "rsa-1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancelled caller to stop waiting, got %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	// Assert
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Key() error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch, got %d", got)
+	}
+}
+
+func TestNewRemoteKeySet_DefaultClient(t *testing.T) {
+	keys := NewRemoteKeySet("https://issuer.example.com/.well-known/jwks.json", nil, 0)
+	if keys.client == http.DefaultClient || keys.client.Timeout != DefaultJWKSTimeout {
+		t.Errorf("Expected a client with a %s timeout, got %+v", DefaultJWKSTimeout, keys.client)
+	}
+}
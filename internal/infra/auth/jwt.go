@@ -0,0 +1,241 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// DefaultLeeway is the clock skew tolerated on token times
+const DefaultLeeway = time.Minute
+
+// JWTConfig configures token verification
+type JWTConfig struct {
+	// Issuer must equal the "iss" claim
+	Issuer string
+
+	// Audience must be one of the "aud" claim values
+	Audience string
+
+	// Leeway is the clock skew tolerated on "exp" and "nbf", DefaultLeeway
+	// if zero
+	Leeway time.Duration
+}
+
+// Validate checks the token verification settings
+func (c JWTConfig) Validate() error {
+	var errs []error
+	if c.Issuer == "" {
+		errs = append(errs, errors.New("issuer is required"))
+	}
+	if c.Audience == "" {
+		errs = append(errs, errors.New("audience is required"))
+	}
+	if c.Leeway < 0 {
+		errs = append(errs, fmt.Errorf("leeway must not be negative, got %s", c.Leeway))
+	}
+	return errors.Join(errs...)
+}
+
+// Claims are the claims of a verified token
+type Claims map[string]interface{}
+
+// algorithm describes a supported JWS signature algorithm
+type algorithm struct {
+	hash crypto.Hash
+	kind string // "rsa", "pss" or "ecdsa"
+}
+
+// algorithms lists the accepted JWS algorithms. Symmetric algorithms and
+// "none" are rejected: the keys come from a public key set.
+var algorithms = map[string]algorithm{
+	"RS256": {crypto.SHA256, "rsa"},
+	"RS384": {crypto.SHA384, "rsa"},
+	"RS512": {crypto.SHA512, "rsa"},
+	"PS256": {crypto.SHA256, "pss"},
+	"PS384": {crypto.SHA384, "pss"},
+	"PS512": {crypto.SHA512, "pss"},
+	"ES256": {crypto.SHA256, "ecdsa"},
+	"ES384": {crypto.SHA384, "ecdsa"},
+	"ES512": {crypto.SHA512, "ecdsa"},
+}
+
+// JWTVerifier verifies signed JWTs against a key set and checks their issuer,
+// audience and validity period
+type JWTVerifier struct {
+	keys   KeySet
+	config JWTConfig
+	now    func() time.Time
+}
+
+// NewJWTVerifier creates a verifier of tokens signed by keys of the set
+func NewJWTVerifier(keys KeySet, config JWTConfig) *JWTVerifier {
+	if config.Leeway == 0 {
+		config.Leeway = DefaultLeeway
+	}
+	return &JWTVerifier{keys: keys, config: config, now: time.Now}
+}
+
+// Verify checks a compact JWS token and returns its claims. Invalid tokens
+// return an error wrapping service.ErrUnauthenticated.
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, unauthenticated("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+		Typ string `json:"typ"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, unauthenticated("malformed token header")
+	}
+
+	alg, ok := algorithms[header.Alg]
+	if !ok {
+		return nil, unauthenticated(fmt.Sprintf("unsupported algorithm %q", header.Alg))
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if key.Algorithm != "" && key.Algorithm != header.Alg {
+		return nil, unauthenticated(fmt.Sprintf("key %q is not used with %s", header.Kid, header.Alg))
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, unauthenticated("malformed token signature")
+	}
+	if err := verifySignature(alg, key.Key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, unauthenticated(err.Error())
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, unauthenticated("malformed token claims")
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// checkClaims checks the issuer, audience and validity period of a token
+func (v *JWTVerifier) checkClaims(claims Claims) error {
+	if issuer, _ := claims["iss"].(string); issuer != v.config.Issuer {
+		return unauthenticated(fmt.Sprintf("unexpected issuer %q", issuer))
+	}
+	if !slices.Contains(claims.Strings("aud"), v.config.Audience) {
+		return unauthenticated("token is not intended for this audience")
+	}
+
+	now := v.now()
+	expires, ok := claims.time("exp")
+	if !ok {
+		return unauthenticated("token has no expiry")
+	}
+	if !now.Before(expires.Add(v.config.Leeway)) {
+		return unauthenticated("token expired")
+	}
+	if notBefore, ok := claims.time("nbf"); ok && now.Add(v.config.Leeway).Before(notBefore) {
+		return unauthenticated("token is not valid yet")
+	}
+	return nil
+}
+
+// verifySignature checks a signature with a key matching the algorithm
+func verifySignature(alg algorithm, key crypto.PublicKey, signed, signature []byte) error {
+	hasher := alg.hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	switch alg.kind {
+	case "rsa", "pss":
+		publicKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key type does not match the algorithm")
+		}
+		var err error
+		if alg.kind == "rsa" {
+			err = rsa.VerifyPKCS1v15(publicKey, alg.hash, digest, signature)
+		} else {
+			err = rsa.VerifyPSS(publicKey, alg.hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		if err != nil {
+			return errors.New("invalid signature")
+		}
+	case "ecdsa":
+		publicKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key type does not match the algorithm")
+		}
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(publicKey, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+// Strings returns a claim holding a string or a list of strings. A single
+// string holding several values, such as "scope", is split on spaces.
+func (c Claims) Strings(name string) []string {
+	switch value := c[name].(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// time returns a NumericDate claim
+func (c Claims) time(name string) (time.Time, bool) {
+	seconds, ok := c[name].(float64)
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
+
+// decodeSegment decodes a base64url JSON segment of a token
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unauthenticated returns an error wrapping service.ErrUnauthenticated
+func unauthenticated(reason string) error {
+	return fmt.Errorf("%w: %s", service.ErrUnauthenticated, reason)
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// testNow is the time tokens are verified at
+var testNow = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// signToken signs claims with the given algorithm and private key
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hash := algorithms[alg].hash
+	hasher := hash.New()
+	hasher.Write([]byte(signed))
+	digest := hasher.Sum(nil)
+
+	var signature []byte
+	switch algorithms[alg].kind {
+	case "rsa":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key.(*rsa.PrivateKey), hash, digest)
+	case "pss":
+		signature, err = rsa.SignPSS(rand.Reader, key.(*rsa.PrivateKey), hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ecdsa":
+		privateKey := key.(*ecdsa.PrivateKey)
+		r, s, signErr := ecdsa.Sign(rand.Reader, privateKey, digest)
+		if signErr != nil {
+			t.Fatal(signErr)
+		}
+		size := (privateKey.Curve.Params().BitSize + 7) / 8
+		signature = make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		s.FillBytes(signature[size:])
+	default:
+		t.Fatalf("unsupported algorithm %s", alg)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims returns claims accepted by newTestVerifier
+func validClaims() map[string]interface{} {
+	return map[string]interface{}{
+		"iss":         "https://issuer.example.com/",
+		"aud":         []string{"other-api", "chargeback-api"},
+		"sub":         "user-123",
+		"exp":         testNow.Add(time.Hour).Unix(),
+		"merchant_id": "merchant-456",
+		"scope":       "chargebacks:read chargebacks:write",
+	}
+}
+
+func newTestVerifier() *JWTVerifier {
+	keys := NewStaticKeySet(map[string]PublicKey{
+		"rsa-1": {Key: &testRSAKey().PublicKey},
+		"rs256": {Key: &testRSAKey().PublicKey, Algorithm: "RS256"},
+		"ec-1":  {Key: &testECKey().PublicKey, Algorithm: "ES256"},
+	})
+	verifier := NewJWTVerifier(keys, JWTConfig{Issuer: "https://issuer.example.com/", Audience: "chargeback-api"})
+	verifier.now = func() time.Time { return testNow }
+	return verifier
+}
+
+func TestJWTConfig_Validate(t *testing.T) {
+	if err := (JWTConfig{Issuer: "https://issuer.example.com/", Audience: "chargeback-api"}).Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	err := JWTConfig{Leeway: -time.Second}.Validate()
+	for _, want := range []string{"issuer", "audience", "leeway"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got: %v", want, err)
+		}
+	}
+}
+
+func TestJWTVerifier_Verify_Algorithms(t *testing.T) {
+	tests := []struct {
+		alg string
+		kid string
+		key crypto.Signer
+	}{
+		{alg: "RS256", kid: "rsa-1", key: testRSAKey()},
+		{alg: "RS512", kid: "rsa-1", key: testRSAKey()},
+		{alg: "PS256", kid: "rsa-1", key: testRSAKey()},
+		{alg: "ES256", kid: "ec-1", key: testECKey()},
+	}
+
+	verifier := newTestVerifier()
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			// Arrange
+			token := signToken(t, tt.alg, tt.kid, tt.key, validClaims())
+
+			// Act
+			claims, err := verifier.Verify(context.Background(), token)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims["sub"] != "user-123" {
+				t.Errorf("Expected subject user-123, got %v", claims["sub"])
+			}
+			if scopes := claims.Strings("scope"); len(scopes) != 2 || scopes[1] != "chargebacks:write" {
+				t.Errorf("Expected both scopes, got %v", scopes)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_Verify_Rejected(t *testing.T) {
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims()
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name    string
+		token   func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name:    "malformed",
+			token:   func(t *testing.T) string { return "not-a-token" },
+			wantErr: "malformed token",
+		},
+		{
+			name: "unsigned",
+			token: func(t *testing.T) string {
+				token := signToken(t, "RS256", "rsa-1", testRSAKey(), validClaims())
+				header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa-1"}`))
+				return header + token[strings.Index(token, "."):]
+			},
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name: "tampered claims",
+			token: func(t *testing.T) string {
+				token := signToken(t, "RS256", "rsa-1", testRSAKey(), validClaims())
+				parts := strings.Split(token, ".")
+				other := strings.Split(signToken(t, "RS256", "rsa-1", testRSAKey(), withClaim("merchant_id", "merchant-999")), ".")
+				return parts[0] + "." + other[1] + "." + parts[2]
+			},
+			wantErr: "invalid signature",
+		},
+		{
+			name:    "algorithm not allowed for the key",
+			token:   func(t *testing.T) string { return signToken(t, "PS256", "rs256", testRSAKey(), validClaims()) },
+			wantErr: "not used with PS256",
+		},
+		{
+			name:    "key type mismatch",
+			token:   func(t *testing.T) string { return signToken(t, "ES256", "rsa-1", testECKey(), validClaims()) },
+			wantErr: "key type",
+		},
+		{
+			name:    "unknown key",
+			token:   func(t *testing.T) string { return signToken(t, "RS256", "rsa-9", testRSAKey(), validClaims()) },
+			wantErr: "unknown key ID",
+		},
+		{
+			name: "wrong issuer",
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", "rsa-1", testRSAKey(), withClaim("iss", "https://evil.example.com/"))
+			},
+			wantErr: "unexpected issuer",
+		},
+		{
+			name: "wrong audience",
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", "rsa-1", testRSAKey(), withClaim("aud", "other-api"))
+			},
+			wantErr: "audience",
+		},
+		{
+			name:    "no expiry",
+			token:   func(t *testing.T) string { return signToken(t, "RS256", "rsa-1", testRSAKey(), withClaim("exp", nil)) },
+			wantErr: "no expiry",
+		},
+		{
+			name: "expired beyond the leeway",
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", "rsa-1", testRSAKey(), withClaim("exp", testNow.Add(-2*time.Minute).Unix()))
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "not valid yet",
+			token: func(t *testing.T) string {
+				return signToken(t, "RS256", "rsa-1", testRSAKey(), withClaim("nbf", testNow.Add(10*time.Minute).Unix()))
+			},
+			wantErr: "not valid yet",
+		},
+	}
+
+	verifier := newTestVerifier()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(context.Background(), tt.token(t))
+			if !errors.Is(err, service.ErrUnauthenticated) {
+				t.Fatalf("Expected an unauthenticated error, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error mentioning %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_Verify_Leeway(t *testing.T) {
+	claims := validClaims()
+	claims["exp"] = testNow.Add(-30 * time.Second).Unix()
+	token := signToken(t, "RS256", "rsa-1", testRSAKey(), claims)
+
+	if _, err := newTestVerifier().Verify(context.Background(), token); err != nil {
+		t.Errorf("Expected a token expired within the leeway to be accepted, got %v", err)
+	}
+}
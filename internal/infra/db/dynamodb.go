@@ -84,20 +84,3 @@ func NewDynamoDBClient(ctx context.Context, cfg DynamoDBConfig) (*dynamodb.Clien
 	log.Printf("✅ DynamoDB client initialized successfully for table: %s", cfg.TableName)
 	return client, nil
 }
-
-// LoadDynamoDBConfigFromEnv loads DynamoDB configuration from environment variables
-func LoadDynamoDBConfigFromEnv() DynamoDBConfig {
-	return DynamoDBConfig{
-		Endpoint:  os.Getenv("DYNAMODB_ENDPOINT"),
-		Region:    getEnvWithDefault("AWS_REGION", "us-east-1"),
-		TableName: getEnvWithDefault("CHARGEBACK_TABLE_NAME", "chargebacks"),
-	}
-}
-
-// getEnvWithDefault returns environment variable value or default if not set
-func getEnvWithDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DescribeTableAPI is the part of the DynamoDB client used by TableProbe
+type DescribeTableAPI interface {
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// TableProbe checks that a table exists and accepts reads and writes
+type TableProbe struct {
+	client    DescribeTableAPI
+	tableName string
+}
+
+// NewTableProbe creates a probe of the given table
+func NewTableProbe(client DescribeTableAPI, tableName string) *TableProbe {
+	return &TableProbe{client: client, tableName: tableName}
+}
+
+// Probe describes the table. Tables being updated still serve requests, so
+// only ACTIVE and UPDATING count as up.
+func (p *TableProbe) Probe(ctx context.Context) error {
+	output, err := p.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+		TableName: aws.String(p.tableName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe table %s: %w", p.tableName, err)
+	}
+	if output.Table == nil {
+		return fmt.Errorf("table %s has no description", p.tableName)
+	}
+
+	switch status := output.Table.TableStatus; status {
+	case types.TableStatusActive, types.TableStatusUpdating:
+		return nil
+	default:
+		return fmt.Errorf("table %s is %s", p.tableName, status)
+	}
+}
@@ -0,0 +1,62 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// stubDescribeTable returns a fixed table description or error
+type stubDescribeTable struct {
+	table     *types.TableDescription
+	err       error
+	tableName string
+}
+
+func (s *stubDescribeTable) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	s.tableName = aws.ToString(params.TableName)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &dynamodb.DescribeTableOutput{Table: s.table}, nil
+}
+
+func TestTableProbe_Probe(t *testing.T) {
+	tests := []struct {
+		name    string
+		client  *stubDescribeTable
+		wantErr string
+	}{
+		{name: "active", client: &stubDescribeTable{table: &types.TableDescription{TableStatus: types.TableStatusActive}}},
+		{name: "updating", client: &stubDescribeTable{table: &types.TableDescription{TableStatus: types.TableStatusUpdating}}},
+		{name: "creating", client: &stubDescribeTable{table: &types.TableDescription{TableStatus: types.TableStatusCreating}}, wantErr: "table chargebacks is CREATING"},
+		{name: "no description", client: &stubDescribeTable{}, wantErr: "no description"},
+		{name: "client error", client: &stubDescribeTable{err: errors.New("ResourceNotFoundException")}, wantErr: "failed to describe table chargebacks: ResourceNotFoundException"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			err := NewTableProbe(tt.client, "chargebacks").Probe(context.Background())
+
+			// Assert
+			if tt.client.tableName != "chargebacks" {
+				t.Errorf("Expected the chargebacks table to be described, got '%s'", tt.client.tableName)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+// Package encryption encrypts sensitive chargeback attributes at rest with
+// AES-GCM envelope encryption: every write seals its values under a new data
+// key, itself wrapped by a key encryption key from a pluggable provider, a
+// local keyring file or AWS KMS
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+)
+
+const (
+	// prefix marks an encrypted value, so values stored before encryption
+	// was enabled are read as they are
+	prefix = "enc:v1:"
+
+	// dataKeySize is the size of AES-256 data keys
+	dataKeySize = 32
+
+	// maxCachedDataKeys bounds the cache of unwrapped data keys
+	maxCachedDataKeys = 1000
+)
+
+// Provider selects where key encryption keys come from
+type Provider string
+
+const (
+	// ProviderKeyring reads keys from a local keyring file, for tests and
+	// local development
+	ProviderKeyring Provider = "keyring"
+
+	// ProviderKMS generates and unwraps data keys with AWS KMS
+	ProviderKMS Provider = "kms"
+)
+
+// ParseProvider converts a provider name, case-insensitively
+func ParseProvider(name string) (Provider, error) {
+	switch provider := Provider(strings.ToLower(strings.TrimSpace(name))); provider {
+	case ProviderKeyring, ProviderKMS:
+		return provider, nil
+	case "":
+		return ProviderKeyring, nil
+	default:
+		return ProviderKeyring, fmt.Errorf("unknown key provider %q (want keyring or kms)", name)
+	}
+}
+
+// DataKey is a key sealing values, in plain and wrapped by a key encryption
+// key
+type DataKey struct {
+	// KeyID identifies the key encryption key that wrapped the data key
+	KeyID string
+
+	// Plaintext is the AES-256 key itself, never stored
+	Plaintext []byte
+
+	// Wrapped is the data key encrypted under KeyID, stored with the values
+	Wrapped []byte
+}
+
+// KeyProvider wraps and unwraps data keys. Rotating the key encryption key
+// only changes the key new data keys are wrapped with; previous keys must
+// still unwrap the data keys they wrapped.
+type KeyProvider interface {
+	// GenerateDataKey returns a new data key wrapped under the current key
+	GenerateDataKey(ctx context.Context) (DataKey, error)
+
+	// DecryptDataKey unwraps a data key wrapped under the key keyID
+	DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Config configures the encryption of chargeback attributes
+type Config struct {
+	// Fields names the attributes encrypted at rest, among Fields()
+	Fields []string
+
+	// Provider selects where keys come from, ProviderKeyring if empty
+	Provider Provider
+
+	// KeyringFile is the keyring of ProviderKeyring, see LoadKeyringFile
+	KeyringFile string
+
+	// KMSKeyID is the KMS key wrapping the data keys of ProviderKMS
+	KMSKeyID string
+
+	// Region is the AWS region of the KMS key
+	Region string
+}
+
+// Validate checks the encryption configuration
+func (c Config) Validate() error {
+	var errs []error
+	for _, field := range c.Fields {
+		if _, ok := fieldValues[field]; !ok {
+			errs = append(errs, fmt.Errorf("unknown field %q (want one of %s)", field, strings.Join(Fields(), ", ")))
+		}
+	}
+	provider, err := ParseProvider(string(c.Provider))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if provider == ProviderKeyring && c.KeyringFile == "" {
+		errs = append(errs, errors.New("keyring file is required by the keyring provider"))
+	}
+	if provider == ProviderKMS && c.KMSKeyID == "" {
+		errs = append(errs, errors.New("KMS key ID is required by the kms provider"))
+	}
+	return errors.Join(errs...)
+}
+
+// Setup creates the cipher of the configured provider
+func Setup(ctx context.Context, config Config) (*Cipher, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid encryption config: %w", err)
+	}
+
+	provider, _ := ParseProvider(string(config.Provider))
+	if provider == ProviderKMS {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(config.Region))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+		}
+		return NewCipher(NewKMSKeyProvider(kms.NewFromConfig(awsCfg), config.KMSKeyID)), nil
+	}
+
+	keyring, err := LoadKeyringFile(config.KeyringFile)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(keyring), nil
+}
+
+// Cipher seals and opens attribute values. Each sealed value carries the ID
+// of the key that wrapped its data key, the wrapped data key and the AES-GCM
+// nonce and ciphertext, and is bound to its record and attribute name, so it
+// does not open once copied to another record or attribute.
+type Cipher struct {
+	keys KeyProvider
+
+	mu       sync.Mutex
+	dataKeys map[string][]byte
+}
+
+// NewCipher creates a cipher whose data keys come from keys
+func NewCipher(keys KeyProvider) *Cipher {
+	return &Cipher{keys: keys, dataKeys: make(map[string][]byte)}
+}
+
+// Seal encrypts the values of a record under one new data key, keyed by
+// attribute name. Empty values are left empty.
+func (c *Cipher) Seal(ctx context.Context, record string, values map[string]string) (map[string]string, error) {
+	sealed := make(map[string]string, len(values))
+	var dataKey *DataKey
+	for name, value := range values {
+		if value == "" {
+			sealed[name] = ""
+			continue
+		}
+
+		if dataKey == nil {
+			generated, err := c.keys.GenerateDataKey(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate data key: %w", err)
+			}
+			dataKey = &generated
+		}
+
+		ciphertext, err := sealAESGCM(dataKey.Plaintext, []byte(value), additionalData(record, name))
+		if err != nil {
+			return nil, err
+		}
+		sealed[name] = prefix + strings.Join([]string{
+			encode([]byte(dataKey.KeyID)),
+			encode(dataKey.Wrapped),
+			encode(ciphertext),
+		}, ":")
+	}
+	return sealed, nil
+}
+
+// Open decrypts the value of the named attribute of a record. Values without
+// the encryption prefix were stored in plain and are returned as they are.
+func (c *Cipher) Open(ctx context.Context, record, name, value string) (string, error) {
+	if !IsSealed(value) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed encrypted value")
+	}
+	var decoded [3][]byte
+	for i, part := range parts {
+		bytes, err := base64.RawURLEncoding.DecodeString(part)
+		if err != nil {
+			return "", errors.New("malformed encrypted value")
+		}
+		decoded[i] = bytes
+	}
+	keyID, wrapped, ciphertext := string(decoded[0]), decoded[1], decoded[2]
+
+	dataKey, err := c.dataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openAESGCM(dataKey, ciphertext, additionalData(record, name))
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// additionalData binds a value to its attribute and record
+func additionalData(record, name string) []byte {
+	return []byte(name + "\x00" + record)
+}
+
+// KeyID returns the ID of the key that wrapped the data key of a sealed
+// value, or "" if the value is not sealed
+func KeyID(value string) string {
+	if !IsSealed(value) {
+		return ""
+	}
+	encoded, _, _ := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	keyID, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ""
+	}
+	return string(keyID)
+}
+
+// IsSealed reports whether a value was sealed by a Cipher
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// dataKey unwraps a data key, reusing keys unwrapped before. The values of a
+// record share their data key, so a read unwraps it once.
+func (c *Cipher) dataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + ":" + string(wrapped)
+
+	c.mu.Lock()
+	dataKey, ok := c.dataKeys[cacheKey]
+	c.mu.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	dataKey, err := c.keys.DecryptDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key: %w", err)
+	}
+	if len(dataKey) != dataKeySize {
+		return nil, fmt.Errorf("data key must have %d bytes, got %d", dataKeySize, len(dataKey))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.dataKeys) >= maxCachedDataKeys {
+		c.dataKeys = make(map[string][]byte)
+	}
+	c.dataKeys[cacheKey] = dataKey
+	return dataKey, nil
+}
+
+// sealAESGCM encrypts plaintext under key, returning the random nonce
+// followed by the ciphertext
+func sealAESGCM(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// openAESGCM decrypts the output of sealAESGCM
+func openAESGCM(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, errors.New("ciphertext does not match its key, record or attribute")
+	}
+	return plaintext, nil
+}
+
+// newGCM returns AES-GCM under key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// encode encodes bytes in unpadded base64url, which has no ":"
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Fields returns the chargeback attributes that may be encrypted, sorted
+func Fields() []string {
+	fields := make([]string, 0, len(fieldValues))
+	for field := range fieldValues {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	return fields
+}
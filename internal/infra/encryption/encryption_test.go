@@ -0,0 +1,274 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a 32-byte key filled with b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, dataKeySize)
+}
+
+// newTestKeyring returns a keyring of the keys "2024-01" and "2024-06",
+// wrapping under current
+func newTestKeyring(t *testing.T, current string) *Keyring {
+	t.Helper()
+	keyring, err := NewKeyring(current, map[string][]byte{
+		"2024-01": testKey(1),
+		"2024-06": testKey(6),
+	})
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return keyring
+}
+
+// countingProvider counts the data keys unwrapped by the wrapped provider
+type countingProvider struct {
+	KeyProvider
+	generated, decrypted int
+	err                  error
+}
+
+func (p *countingProvider) GenerateDataKey(ctx context.Context) (DataKey, error) {
+	p.generated++
+	if p.err != nil {
+		return DataKey{}, p.err
+	}
+	return p.KeyProvider.GenerateDataKey(ctx)
+}
+
+func (p *countingProvider) DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	p.decrypted++
+	return p.KeyProvider.DecryptDataKey(ctx, keyID, wrapped)
+}
+
+func TestParseProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Provider
+		wantErr  bool
+	}{
+		{name: "", expected: ProviderKeyring},
+		{name: "keyring", expected: ProviderKeyring},
+		{name: " KMS ", expected: ProviderKMS},
+		{name: "vault", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := ParseProvider(tt.name)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil || provider != tt.expected {
+				t.Errorf("ParseProvider(%q) = %q, %v, want %q", tt.name, provider, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "keyring", config: Config{Fields: []string{"description"}, KeyringFile: "keyring.json"}},
+		{name: "kms", config: Config{Fields: []string{"description", "card_number"}, Provider: ProviderKMS, KMSKeyID: "alias/chargeback-data"}},
+		{name: "unknown field", config: Config{Fields: []string{"amount"}, KeyringFile: "keyring.json"}, wantErr: `unknown field "amount" (want one of card_number, description)`},
+		{name: "unknown provider", config: Config{Provider: "vault"}, wantErr: "unknown key provider"},
+		{name: "no keyring file", config: Config{Provider: ProviderKeyring}, wantErr: "keyring file is required"},
+		{name: "no KMS key", config: Config{Provider: ProviderKMS}, wantErr: "KMS key ID is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	path := writeKeyring(t, `{"current":"2024-06","keys":{"2024-06":"`+encodeStd(testKey(6))+`"}}`)
+
+	cipher, err := Setup(context.Background(), Config{Fields: []string{"description"}, KeyringFile: path})
+	if err != nil || cipher == nil {
+		t.Fatalf("Setup() = %v, %v", cipher, err)
+	}
+
+	if _, err := Setup(context.Background(), Config{Provider: ProviderKMS}); err == nil || !strings.Contains(err.Error(), "invalid encryption config") {
+		t.Errorf("Expected an invalid config error, got %v", err)
+	}
+}
+
+func TestCipher_SealOpen(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	provider := &countingProvider{KeyProvider: newTestKeyring(t, "2024-06")}
+	cipher := NewCipher(provider)
+
+	// Act
+	sealed, err := cipher.Seal(ctx, "cb_123", map[string]string{
+		"description": "Customer says the card was stolen",
+		"card_number": "****1234",
+		"empty":       "",
+	})
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if provider.generated != 1 {
+		t.Errorf("Expected one data key per write, got %d", provider.generated)
+	}
+	if sealed["empty"] != "" {
+		t.Errorf("Expected empty values to stay empty, got %q", sealed["empty"])
+	}
+	for name, plaintext := range map[string]string{"description": "Customer says the card was stolen", "card_number": "****1234"} {
+		if !IsSealed(sealed[name]) || strings.Contains(sealed[name], plaintext) {
+			t.Errorf("Expected %s to be encrypted, got %q", name, sealed[name])
+		}
+		if keyID := KeyID(sealed[name]); keyID != "2024-06" {
+			t.Errorf("Expected %s under the current key, got %q", name, keyID)
+		}
+		opened, err := cipher.Open(ctx, "cb_123", name, sealed[name])
+		if err != nil || opened != plaintext {
+			t.Errorf("Open(%s) = %q, %v, want %q", name, opened, err, plaintext)
+		}
+	}
+	if provider.decrypted != 1 {
+		t.Errorf("Expected the shared data key to be unwrapped once, got %d", provider.decrypted)
+	}
+}
+
+func TestCipher_Open(t *testing.T) {
+	ctx := context.Background()
+	cipher := NewCipher(newTestKeyring(t, "2024-06"))
+	sealed, err := cipher.Seal(ctx, "cb_123", map[string]string{"description": "stolen card"})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	value := sealed["description"]
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+
+	tests := []struct {
+		name     string
+		record   string
+		field    string
+		value    string
+		cipher   *Cipher
+		expected string
+		wantErr  string
+	}{
+		{name: "plaintext stored before encryption", field: "description", value: "stolen card", expected: "stolen card"},
+		{name: "value of another attribute", field: "card_number", value: value, wantErr: "does not match its key, record or attribute"},
+		{name: "value of another record", record: "cb_456", field: "description", value: value, wantErr: "does not match its key, record or attribute"},
+		{name: "tampered ciphertext", field: "description", value: prefix + parts[0] + ":" + parts[1] + ":" + encode([]byte("0123456789abcdefghijklmnopqrstuvwxyz")), wantErr: "does not match its key, record or attribute"},
+		{name: "missing part", field: "description", value: prefix + parts[0] + ":" + parts[1], wantErr: "malformed encrypted value"},
+		{name: "invalid base64", field: "description", value: prefix + "!:" + parts[1] + ":" + parts[2], wantErr: "malformed encrypted value"},
+		{name: "unknown key", field: "description", value: prefix + encode([]byte("2023-01")) + ":" + parts[1] + ":" + parts[2], wantErr: `unknown key "2023-01"`},
+		{
+			name:  "key removed from the keyring",
+			field: "description",
+			value: value,
+			cipher: func() *Cipher {
+				keyring, _ := NewKeyring("2024-01", map[string][]byte{"2024-01": testKey(1)})
+				return NewCipher(keyring)
+			}(),
+			wantErr: `unknown key "2024-06"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := cipher
+			if tt.cipher != nil {
+				c = tt.cipher
+			}
+			record := "cb_123"
+			if tt.record != "" {
+				record = tt.record
+			}
+			opened, err := c.Open(ctx, record, tt.field, tt.value)
+			if tt.wantErr == "" {
+				if err != nil || opened != tt.expected {
+					t.Errorf("Open() = %q, %v, want %q", opened, err, tt.expected)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCipher_Seal_ProviderError(t *testing.T) {
+	cipher := NewCipher(&countingProvider{KeyProvider: newTestKeyring(t, "2024-06"), err: errors.New("throttled")})
+
+	if _, err := cipher.Seal(context.Background(), "cb_123", map[string]string{"description": "stolen card"}); err == nil || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("Expected the provider error, got %v", err)
+	}
+	if sealed, err := cipher.Seal(context.Background(), "cb_123", map[string]string{"description": ""}); err != nil || sealed["description"] != "" {
+		t.Errorf("Expected no data key for empty values, got %v, %v", sealed, err)
+	}
+}
+
+func TestCipher_DataKeyCache(t *testing.T) {
+	ctx := context.Background()
+	provider := &countingProvider{KeyProvider: newTestKeyring(t, "2024-06")}
+	cipher := NewCipher(provider)
+	values := make([]string, 0, maxCachedDataKeys+1)
+	for range maxCachedDataKeys + 1 {
+		sealed, err := cipher.Seal(ctx, "cb_123", map[string]string{"description": "stolen card"})
+		if err != nil {
+			t.Fatalf("Seal() error = %v", err)
+		}
+		values = append(values, sealed["description"])
+	}
+
+	for _, value := range values {
+		if _, err := cipher.Open(ctx, "cb_123", "description", value); err != nil {
+			t.Fatalf("Open() error = %v", err)
+		}
+	}
+
+	if len(cipher.dataKeys) > maxCachedDataKeys {
+		t.Errorf("Expected at most %d cached data keys, got %d", maxCachedDataKeys, len(cipher.dataKeys))
+	}
+	if provider.decrypted != maxCachedDataKeys+1 {
+		t.Errorf("Expected every data key to be unwrapped once, got %d", provider.decrypted)
+	}
+}
+
+func TestKeyID(t *testing.T) {
+	if keyID := KeyID("stolen card"); keyID != "" {
+		t.Errorf("Expected no key for plaintext, got %q", keyID)
+	}
+	if keyID := KeyID(prefix + "!"); keyID != "" {
+		t.Errorf("Expected no key for malformed values, got %q", keyID)
+	}
+}
+
+func TestFields(t *testing.T) {
+	if fields := Fields(); strings.Join(fields, ",") != "card_number,description" {
+		t.Errorf("Unexpected fields %v", fields)
+	}
+}
@@ -0,0 +1,132 @@
+package encryption
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// encodeStd encodes a key as in keyring files
+func encodeStd(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// writeKeyring writes a keyring file, returning its path
+func writeKeyring(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write keyring file: %v", err)
+	}
+	return path
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		current string
+		keys    map[string][]byte
+		wantErr string
+	}{
+		{name: "valid", current: "2024-06", keys: map[string][]byte{"2024-01": testKey(1), "2024-06": testKey(6)}},
+		{name: "no current key", current: "2024-12", keys: map[string][]byte{"2024-06": testKey(6)}, wantErr: `current key "2024-12" is not in the keyring`},
+		{name: "key without ID", current: "2024-06", keys: map[string][]byte{"": testKey(1), "2024-06": testKey(6)}, wantErr: "without ID"},
+		{name: "short key", current: "2024-06", keys: map[string][]byte{"2024-06": testKey(6)[:16]}, wantErr: "key 2024-06 must have 32 bytes, got 16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.current, tt.keys)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("NewKeyring() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadKeyringFile(t *testing.T) {
+	// Arrange
+	path := writeKeyring(t, `{"current":"2024-06","keys":{"2024-01":"`+encodeStd(testKey(1))+`","2024-06":"`+encodeStd(testKey(6))+`"}}`)
+
+	// Act
+	keyring, err := LoadKeyringFile(path)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("LoadKeyringFile() error = %v", err)
+	}
+	dataKey, err := keyring.GenerateDataKey(context.Background())
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+	if dataKey.KeyID != "2024-06" || len(dataKey.Plaintext) != dataKeySize {
+		t.Errorf("Unexpected data key %+v", dataKey)
+	}
+}
+
+func TestLoadKeyringFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    func(t *testing.T) string
+		wantErr string
+	}{
+		{
+			name:    "missing file",
+			path:    func(t *testing.T) string { return filepath.Join(t.TempDir(), "missing.json") },
+			wantErr: "failed to read keyring file",
+		},
+		{
+			name:    "invalid JSON",
+			path:    func(t *testing.T) string { return writeKeyring(t, `{"current":`) },
+			wantErr: "failed to parse keyring file",
+		},
+		{
+			name: "invalid base64",
+			path: func(t *testing.T) string {
+				return writeKeyring(t, `{"current":"2024-06","keys":{"2024-06":"not base64!"}}`)
+			},
+			wantErr: "key 2024-06 is not base64",
+		},
+		{
+			name:    "no current key",
+			path:    func(t *testing.T) string { return writeKeyring(t, `{"keys":{"2024-06":"`+encodeStd(testKey(6))+`"}}`) },
+			wantErr: "is not in the keyring",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadKeyringFile(tt.path(t)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKeyring_DecryptDataKey(t *testing.T) {
+	ctx := context.Background()
+	keyring := newTestKeyring(t, "2024-06")
+	dataKey, err := keyring.GenerateDataKey(ctx)
+	if err != nil {
+		t.Fatalf("GenerateDataKey() error = %v", err)
+	}
+
+	plaintext, err := keyring.DecryptDataKey(ctx, "2024-06", dataKey.Wrapped)
+	if err != nil || string(plaintext) != string(dataKey.Plaintext) {
+		t.Errorf("DecryptDataKey() = %x, %v, want %x", plaintext, err, dataKey.Plaintext)
+	}
+
+	// A data key only unwraps under the key that wrapped it
+	if _, err := keyring.DecryptDataKey(ctx, "2024-01", dataKey.Wrapped); err == nil {
+		t.Error("Expected a data key wrapped by another key to be rejected")
+	}
+}
@@ -0,0 +1,59 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// KMSAPI is the part of the KMS client used by KMSKeyProvider
+type KMSAPI interface {
+	GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error)
+	Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error)
+}
+
+// KMSKeyProvider is a KeyProvider generating data keys under a KMS key.
+// Rotating the key material in KMS, or pointing at another key, needs no
+// change here: KMS unwraps data keys under the key that wrapped them.
+type KMSKeyProvider struct {
+	client KMSAPI
+	keyID  string
+}
+
+// NewKMSKeyProvider creates a provider wrapping data keys under keyID, a key
+// ID, ARN or alias
+func NewKMSKeyProvider(client KMSAPI, keyID string) *KMSKeyProvider {
+	return &KMSKeyProvider{client: client, keyID: keyID}
+}
+
+// GenerateDataKey returns a new AES-256 data key wrapped by KMS
+func (p *KMSKeyProvider) GenerateDataKey(ctx context.Context) (DataKey, error) {
+	output, err := p.client.GenerateDataKey(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(p.keyID),
+		KeySpec: types.DataKeySpecAes256,
+	})
+	if err != nil {
+		return DataKey{}, fmt.Errorf("failed to generate data key with KMS: %w", err)
+	}
+	return DataKey{
+		KeyID:     aws.ToString(output.KeyId),
+		Plaintext: output.Plaintext,
+		Wrapped:   output.CiphertextBlob,
+	}, nil
+}
+
+// DecryptDataKey unwraps a data key with KMS, requiring it was wrapped by
+// keyID
+func (p *KMSKeyProvider) DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	output, err := p.client.Decrypt(ctx, &kms.DecryptInput{
+		CiphertextBlob: wrapped,
+		KeyId:          aws.String(keyID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key with KMS: %w", err)
+	}
+	return output.Plaintext, nil
+}
@@ -0,0 +1,91 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+)
+
+// stubKMS wraps data keys under a local keyring, reporting the key ARN of
+// the alias, and records its last inputs
+type stubKMS struct {
+	keyring  *Keyring
+	err      error
+	generate *kms.GenerateDataKeyInput
+	decrypt  *kms.DecryptInput
+}
+
+func (s *stubKMS) GenerateDataKey(ctx context.Context, params *kms.GenerateDataKeyInput, optFns ...func(*kms.Options)) (*kms.GenerateDataKeyOutput, error) {
+	s.generate = params
+	if s.err != nil {
+		return nil, s.err
+	}
+	dataKey, err := s.keyring.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{
+		KeyId:          aws.String("arn:aws:kms:us-east-1:123456789012:key/" + dataKey.KeyID),
+		Plaintext:      dataKey.Plaintext,
+		CiphertextBlob: dataKey.Wrapped,
+	}, nil
+}
+
+func (s *stubKMS) Decrypt(ctx context.Context, params *kms.DecryptInput, optFns ...func(*kms.Options)) (*kms.DecryptOutput, error) {
+	s.decrypt = params
+	if s.err != nil {
+		return nil, s.err
+	}
+	keyID := strings.TrimPrefix(aws.ToString(params.KeyId), "arn:aws:kms:us-east-1:123456789012:key/")
+	plaintext, err := s.keyring.DecryptDataKey(ctx, keyID, params.CiphertextBlob)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.DecryptOutput{Plaintext: plaintext}, nil
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	client := &stubKMS{keyring: newTestKeyring(t, "2024-06")}
+	cipher := NewCipher(NewKMSKeyProvider(client, "alias/chargeback-data"))
+
+	// Act
+	sealed, err := cipher.Seal(ctx, "cb_123", map[string]string{"description": "stolen card"})
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	opened, err := cipher.Open(ctx, "cb_123", "description", sealed["description"])
+
+	// Assert
+	if err != nil || opened != "stolen card" {
+		t.Fatalf("Open() = %q, %v", opened, err)
+	}
+	if aws.ToString(client.generate.KeyId) != "alias/chargeback-data" || client.generate.KeySpec != types.DataKeySpecAes256 {
+		t.Errorf("Unexpected GenerateDataKey input %+v", client.generate)
+	}
+	// Values record the key ARN, not the alias, which may move to another key
+	if keyID := KeyID(sealed["description"]); keyID != "arn:aws:kms:us-east-1:123456789012:key/2024-06" {
+		t.Errorf("Expected the key ARN, got %q", keyID)
+	}
+	if aws.ToString(client.decrypt.KeyId) != "arn:aws:kms:us-east-1:123456789012:key/2024-06" {
+		t.Errorf("Expected Decrypt to require the wrapping key, got %+v", client.decrypt)
+	}
+}
+
+func TestKMSKeyProvider_Error(t *testing.T) {
+	ctx := context.Background()
+	provider := NewKMSKeyProvider(&stubKMS{err: errors.New("AccessDeniedException")}, "alias/chargeback-data")
+
+	if _, err := provider.GenerateDataKey(ctx); err == nil || !strings.Contains(err.Error(), "failed to generate data key with KMS") {
+		t.Errorf("Expected a KMS error, got %v", err)
+	}
+	if _, err := provider.DecryptDataKey(ctx, "key", []byte("wrapped")); err == nil || !strings.Contains(err.Error(), "failed to decrypt data key with KMS") {
+		t.Errorf("Expected a KMS error, got %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/repository"
+)
+
+// fieldValues maps the attributes that may be encrypted to their value in a
+// chargeback
+var fieldValues = map[string]func(*entity.Chargeback) *string{
+	"description": func(c *entity.Chargeback) *string { return &c.Description },
+	"card_number": func(c *entity.Chargeback) *string { return &c.CardNumber },
+}
+
+// ChargebackRepository is a repository.ChargebackRepository encrypting the
+// configured attributes before they reach the wrapped repository and
+// decrypting them on read. Values are bound to the ID of their chargeback,
+// so chargebacks must have their ID, see entity.NewChargeback, before they
+// are written. Every write seals under the current key, so records are
+// re-encrypted lazily after a rotation as they are updated.
+type ChargebackRepository struct {
+	next   repository.ChargebackRepository
+	cipher *Cipher
+	fields []string
+}
+
+// NewChargebackRepository wraps a repository, encrypting fields with cipher
+func NewChargebackRepository(next repository.ChargebackRepository, cipher *Cipher, fields []string) *ChargebackRepository {
+	return &ChargebackRepository{
+		next:   next,
+		cipher: cipher,
+		fields: fields,
+	}
+}
+
+// seal returns a copy of a chargeback with its fields encrypted
+func (r *ChargebackRepository) seal(ctx context.Context, chargeback *entity.Chargeback) (*entity.Chargeback, error) {
+	values := make(map[string]string, len(r.fields))
+	for _, field := range r.fields {
+		values[field] = *fieldValues[field](chargeback)
+	}
+	sealed, err := r.cipher.Seal(ctx, chargeback.ID, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt chargeback: %w", err)
+	}
+
+	encrypted := *chargeback
+	for field, value := range sealed {
+		*fieldValues[field](&encrypted) = value
+	}
+	return &encrypted, nil
+}
+
+// write stores a chargeback with store, keeping the caller's plaintexts and
+// any change the wrapped repository made
+func (r *ChargebackRepository) write(ctx context.Context, chargeback *entity.Chargeback, store func(context.Context, *entity.Chargeback) error) error {
+	if chargeback.ID == "" {
+		return errors.New("failed to encrypt chargeback: no ID to bind the values to")
+	}
+	encrypted, err := r.seal(ctx, chargeback)
+	if err != nil {
+		return err
+	}
+	if err := store(ctx, encrypted); err != nil {
+		return err
+	}
+
+	for _, field := range r.fields {
+		*fieldValues[field](encrypted) = *fieldValues[field](chargeback)
+	}
+	*chargeback = *encrypted
+	return nil
+}
+
+// open decrypts the fields of a chargeback in place
+func (r *ChargebackRepository) open(ctx context.Context, chargeback *entity.Chargeback) error {
+	if chargeback == nil {
+		return nil
+	}
+	for _, field := range r.fields {
+		value := fieldValues[field](chargeback)
+		plaintext, err := r.cipher.Open(ctx, chargeback.ID, field, *value)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s of chargeback %s: %w", field, chargeback.ID, err)
+		}
+		*value = plaintext
+	}
+	return nil
+}
+
+// openAll decrypts the fields of chargebacks in place
+func (r *ChargebackRepository) openAll(ctx context.Context, chargebacks []*entity.Chargeback) ([]*entity.Chargeback, error) {
+	for _, chargeback := range chargebacks {
+		if err := r.open(ctx, chargeback); err != nil {
+			return nil, err
+		}
+	}
+	return chargebacks, nil
+}
+
+// Save stores a new chargeback
+func (r *ChargebackRepository) Save(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.write(ctx, chargeback, r.next.Save)
+}
+
+// FindByID returns the chargeback with the given ID
+func (r *ChargebackRepository) FindByID(ctx context.Context, id string) (*entity.Chargeback, error) {
+	chargeback, err := r.next.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.open(ctx, chargeback); err != nil {
+		return nil, err
+	}
+	return chargeback, nil
+}
+
+// FindByTransactionID returns the chargeback of a transaction
+func (r *ChargebackRepository) FindByTransactionID(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+	chargeback, err := r.next.FindByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.open(ctx, chargeback); err != nil {
+		return nil, err
+	}
+	return chargeback, nil
+}
+
+// FindByMerchantID returns the chargebacks of a merchant
+func (r *ChargebackRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]*entity.Chargeback, error) {
+	chargebacks, err := r.next.FindByMerchantID(ctx, merchantID)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, chargebacks)
+}
+
+// Update replaces a stored chargeback, re-encrypting it under the current key
+func (r *ChargebackRepository) Update(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.write(ctx, chargeback, r.next.Update)
+}
+
+// Delete removes the chargeback with the given ID
+func (r *ChargebackRepository) Delete(ctx context.Context, id string) error {
+	return r.next.Delete(ctx, id)
+}
+
+// FindByStatus returns the chargebacks in a status
+func (r *ChargebackRepository) FindByStatus(ctx context.Context, status entity.ChargebackStatus) ([]*entity.Chargeback, error) {
+	chargebacks, err := r.next.FindByStatus(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, chargebacks)
+}
+
+// List returns a page of chargebacks
+func (r *ChargebackRepository) List(ctx context.Context, offset, limit int) ([]*entity.Chargeback, error) {
+	chargebacks, err := r.next.List(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.openAll(ctx, chargebacks)
+}
@@ -0,0 +1,304 @@
+package encryption
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+)
+
+// memoryRepository stores copies of chargebacks as they reach the database,
+// and fails every call with err if set
+type memoryRepository struct {
+	stored map[string]entity.Chargeback
+	err    error
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{stored: make(map[string]entity.Chargeback)}
+}
+
+func (r *memoryRepository) Save(ctx context.Context, chargeback *entity.Chargeback) error {
+	if r.err != nil {
+		return r.err
+	}
+	r.stored[chargeback.ID] = *chargeback
+	return nil
+}
+func (r *memoryRepository) FindByID(ctx context.Context, id string) (*entity.Chargeback, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	chargeback, ok := r.stored[id]
+	if !ok {
+		return nil, nil
+	}
+	return &chargeback, nil
+}
+func (r *memoryRepository) FindByTransactionID(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+	chargebacks, err := r.List(ctx, 0, 0)
+	for _, chargeback := range chargebacks {
+		if chargeback.TransactionID == transactionID {
+			return chargeback, nil
+		}
+	}
+	return nil, err
+}
+func (r *memoryRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]*entity.Chargeback, error) {
+	return r.List(ctx, 0, 0)
+}
+func (r *memoryRepository) Update(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.Save(ctx, chargeback)
+}
+func (r *memoryRepository) Delete(ctx context.Context, id string) error {
+	delete(r.stored, id)
+	return r.err
+}
+func (r *memoryRepository) FindByStatus(ctx context.Context, status entity.ChargebackStatus) ([]*entity.Chargeback, error) {
+	return r.List(ctx, 0, 0)
+}
+func (r *memoryRepository) List(ctx context.Context, offset, limit int) ([]*entity.Chargeback, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	chargebacks := make([]*entity.Chargeback, 0, len(r.stored))
+	for _, chargeback := range r.stored {
+		chargebacks = append(chargebacks, &chargeback)
+	}
+	return chargebacks, nil
+}
+
+// newChargeback returns a chargeback with personal data in its description
+func newChargeback() *entity.Chargeback {
+	return &entity.Chargeback{
+		ID:            "cb_123",
+		TransactionID: "txn_123",
+		MerchantID:    "merchant_456",
+		CardNumber:    "****1234",
+		Description:   "Customer Jane Doe, jane@example.com, says the card was stolen",
+		Status:        entity.StatusPending,
+	}
+}
+
+func TestChargebackRepository_Save(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description"})
+	chargeback := newChargeback()
+
+	// Act
+	err := repo.Save(ctx, chargeback)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if chargeback.ID != "cb_123" {
+		t.Errorf("Expected the ID the values are bound to, got %q", chargeback.ID)
+	}
+	if chargeback.Description != newChargeback().Description {
+		t.Errorf("Expected the caller to keep the plaintext, got %q", chargeback.Description)
+	}
+	stored := inner.stored["cb_123"]
+	if !IsSealed(stored.Description) || strings.Contains(stored.Description, "jane@example.com") {
+		t.Errorf("Expected the description to be stored encrypted, got %q", stored.Description)
+	}
+	if stored.CardNumber != "****1234" {
+		t.Errorf("Expected unconfigured fields to be stored in plain, got %q", stored.CardNumber)
+	}
+}
+
+func TestChargebackRepository_Read(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description", "card_number"})
+	if err := repo.Save(ctx, newChargeback()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	want := newChargeback()
+
+	check := func(name string, chargeback *entity.Chargeback, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s() error = %v", name, err)
+		}
+		if chargeback.Description != want.Description || chargeback.CardNumber != want.CardNumber {
+			t.Errorf("%s: expected decrypted fields, got %q and %q", name, chargeback.Description, chargeback.CardNumber)
+		}
+	}
+	checkAll := func(name string, chargebacks []*entity.Chargeback, err error) {
+		t.Helper()
+		if len(chargebacks) != 1 {
+			t.Fatalf("%s: expected one chargeback, got %d (%v)", name, len(chargebacks), err)
+		}
+		check(name, chargebacks[0], err)
+	}
+
+	chargeback, err := repo.FindByID(ctx, "cb_123")
+	check("FindByID", chargeback, err)
+	chargeback, err = repo.FindByTransactionID(ctx, "txn_123")
+	check("FindByTransactionID", chargeback, err)
+	chargebacks, err := repo.FindByMerchantID(ctx, "merchant_456")
+	checkAll("FindByMerchantID", chargebacks, err)
+	chargebacks, err = repo.FindByStatus(ctx, entity.StatusPending)
+	checkAll("FindByStatus", chargebacks, err)
+	chargebacks, err = repo.List(ctx, 0, 10)
+	checkAll("List", chargebacks, err)
+
+	if chargeback, err := repo.FindByID(ctx, "cb_missing"); chargeback != nil || err != nil {
+		t.Errorf("Expected no chargeback, got %v, %v", chargeback, err)
+	}
+	if err := repo.Delete(ctx, "cb_123"); err != nil || len(inner.stored) != 0 {
+		t.Errorf("Expected the chargeback to be deleted, got %v", err)
+	}
+}
+
+func TestChargebackRepository_Plaintext(t *testing.T) {
+	// Arrange: a chargeback stored before encryption was enabled
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	if err := inner.Save(ctx, newChargeback()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description"})
+
+	// Act
+	chargeback, err := repo.FindByID(ctx, "cb_123")
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	chargeback.Status = entity.StatusApproved
+	err = repo.Update(ctx, chargeback)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if chargeback.Description != newChargeback().Description {
+		t.Errorf("Expected the plaintext to be read as it is, got %q", chargeback.Description)
+	}
+	if stored := inner.stored["cb_123"]; !IsSealed(stored.Description) {
+		t.Errorf("Expected the update to encrypt the description, got %q", stored.Description)
+	}
+}
+
+func TestChargebackRepository_Rotation(t *testing.T) {
+	// Arrange: a chargeback stored under the previous key
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	previous := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-01")), []string{"description"})
+	if err := previous.Save(ctx, newChargeback()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description"})
+
+	// Act
+	chargeback, err := repo.FindByID(ctx, "cb_123")
+	if err != nil {
+		t.Fatalf("FindByID() error = %v", err)
+	}
+	before := KeyID(inner.stored["cb_123"].Description)
+	chargeback.Status = entity.StatusApproved
+	err = repo.Update(ctx, chargeback)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if chargeback.Description != newChargeback().Description {
+		t.Errorf("Expected the record under the previous key to be read, got %q", chargeback.Description)
+	}
+	if before != "2024-01" {
+		t.Errorf("Expected reads to leave the record under the previous key, got %q", before)
+	}
+	if after := KeyID(inner.stored["cb_123"].Description); after != "2024-06" {
+		t.Errorf("Expected the update to re-encrypt under the current key, got %q", after)
+	}
+}
+
+func TestChargebackRepository_SwappedValues(t *testing.T) {
+	// Arrange: the description of one chargeback copied into another
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description"})
+	first, second := newChargeback(), newChargeback()
+	first.ID, second.ID = "cb_1", "cb_2"
+	second.Description = "Merchant error"
+	for _, chargeback := range []*entity.Chargeback{first, second} {
+		if err := repo.Save(ctx, chargeback); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+	swapped := inner.stored["cb_2"]
+	swapped.Description = inner.stored["cb_1"].Description
+	inner.stored["cb_2"] = swapped
+
+	// Act
+	_, err := repo.FindByID(ctx, "cb_2")
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "failed to decrypt description of chargeback cb_2") {
+		t.Errorf("Expected a value moved between records not to open, got %v", err)
+	}
+	if chargeback, err := repo.FindByID(ctx, "cb_1"); err != nil || chargeback.Description != first.Description {
+		t.Errorf("Expected the original record to open, got %v", err)
+	}
+}
+
+func TestChargebackRepository_RequiresID(t *testing.T) {
+	ctx := context.Background()
+	inner := newMemoryRepository()
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description"})
+
+	chargeback := newChargeback()
+	chargeback.ID = ""
+	if err := repo.Save(ctx, chargeback); err == nil || !strings.Contains(err.Error(), "no ID") {
+		t.Errorf("Expected a chargeback without ID to be rejected, got %v", err)
+	}
+	if err := repo.Update(ctx, chargeback); err == nil {
+		t.Error("Expected an update without ID to be rejected")
+	}
+	if len(inner.stored) != 0 || chargeback.ID != "" {
+		t.Errorf("Expected nothing stored and no ID assigned, got %v and %q", inner.stored, chargeback.ID)
+	}
+}
+
+func TestChargebackRepository_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	// Writes fail without reaching the database when no data key is available
+	inner := newMemoryRepository()
+	failing := NewChargebackRepository(inner, NewCipher(&countingProvider{KeyProvider: newTestKeyring(t, "2024-06"), err: errors.New("throttled")}), []string{"description"})
+	if err := failing.Save(ctx, newChargeback()); err == nil || !strings.Contains(err.Error(), "failed to encrypt chargeback: failed to generate data key: throttled") {
+		t.Errorf("Expected an encryption error, got %v", err)
+	}
+	if len(inner.stored) != 0 {
+		t.Errorf("Expected nothing to be stored, got %v", inner.stored)
+	}
+
+	// Reads fail when the key is gone
+	repo := NewChargebackRepository(inner, NewCipher(newTestKeyring(t, "2024-06")), []string{"description"})
+	if err := repo.Save(ctx, newChargeback()); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	keyring, _ := NewKeyring("2024-01", map[string][]byte{"2024-01": testKey(1)})
+	withoutKey := NewChargebackRepository(inner, NewCipher(keyring), []string{"description"})
+	if _, err := withoutKey.FindByID(ctx, "cb_123"); err == nil || !strings.Contains(err.Error(), "failed to decrypt description of chargeback cb_123") {
+		t.Errorf("Expected a decryption error, got %v", err)
+	}
+	if _, err := withoutKey.List(ctx, 0, 10); err == nil {
+		t.Error("Expected a decryption error")
+	}
+
+	// Errors of the wrapped repository are returned as they are
+	inner.err = errors.New("unavailable")
+	if err := repo.Update(ctx, newChargeback()); !errors.Is(err, inner.err) {
+		t.Errorf("Expected the repository error, got %v", err)
+	}
+	if _, err := repo.FindByTransactionID(ctx, "txn_123"); !errors.Is(err, inner.err) {
+		t.Errorf("Expected the repository error, got %v", err)
+	}
+}
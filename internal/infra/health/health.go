@@ -0,0 +1,189 @@
+// Package health implements service.HealthChecker: a registry of dependency
+// probes run concurrently under a timeout, whose results are cached briefly so
+// frequent readiness checks do not load the dependencies
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+const (
+	// DefaultCacheTTL is how long a probe result is reused when the
+	// configuration sets none
+	DefaultCacheTTL = 5 * time.Second
+
+	// DefaultTimeout bounds a single probe when the configuration sets none
+	DefaultTimeout = 2 * time.Second
+
+	// maxErrorLength bounds the probe errors reported to callers
+	maxErrorLength = 256
+)
+
+// Probe checks that a dependency can serve requests
+type Probe interface {
+	Probe(ctx context.Context) error
+}
+
+// ProbeFunc adapts a function to a Probe
+type ProbeFunc func(ctx context.Context) error
+
+// Probe calls f
+func (f ProbeFunc) Probe(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Config configures the registry
+type Config struct {
+	// CacheTTL is how long a probe result is reused, DefaultCacheTTL if zero
+	CacheTTL time.Duration
+
+	// Timeout bounds a single probe, DefaultTimeout if zero
+	Timeout time.Duration
+
+	// Endpoints maps dependency names to health URLs probed over HTTP, for
+	// dependencies without a client in this service
+	Endpoints map[string]string
+}
+
+// Validate checks the health configuration
+func (c Config) Validate() error {
+	var errs []error
+	if c.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("cache TTL must not be negative, got %s", c.CacheTTL))
+	}
+	if c.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("timeout must not be negative, got %s", c.Timeout))
+	}
+	for name, endpoint := range c.Endpoints {
+		if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("endpoint %s must be an http(s) URL, got %q", name, endpoint))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Registry is a service.HealthChecker probing every registered dependency
+type Registry struct {
+	config Config
+	now    func() time.Time
+
+	mu           sync.Mutex
+	dependencies []*dependency
+}
+
+// dependency holds a probe and its cached result. Its lock is held while
+// probing, so concurrent checks share a single probe.
+type dependency struct {
+	name  string
+	probe Probe
+
+	mu      sync.Mutex
+	result  service.DependencyHealth
+	expires time.Time
+}
+
+// NewRegistry creates a registry probing the configured endpoints over HTTP
+func NewRegistry(config Config) *Registry {
+	if config.CacheTTL == 0 {
+		config.CacheTTL = DefaultCacheTTL
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	r := &Registry{config: config, now: time.Now}
+	for name, endpoint := range config.Endpoints {
+		r.Register(name, NewHTTPProbe(endpoint, nil))
+	}
+	return r
+}
+
+// Register adds a dependency, replacing any registered under the same name
+func (r *Registry) Register(name string, probe Probe) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d := &dependency{name: name, probe: probe}
+	for i, existing := range r.dependencies {
+		if existing.name == name {
+			r.dependencies[i] = d
+			return
+		}
+	}
+	r.dependencies = append(r.dependencies, d)
+	sort.Slice(r.dependencies, func(i, j int) bool { return r.dependencies[i].name < r.dependencies[j].name })
+}
+
+// Check probes every dependency whose cached result expired, concurrently,
+// and returns the results sorted by name
+func (r *Registry) Check(ctx context.Context) []service.DependencyHealth {
+	r.mu.Lock()
+	dependencies := append([]*dependency(nil), r.dependencies...)
+	r.mu.Unlock()
+
+	results := make([]service.DependencyHealth, len(dependencies))
+	var wg sync.WaitGroup
+	for i, d := range dependencies {
+		wg.Add(1)
+		go func(i int, d *dependency) {
+			defer wg.Done()
+			results[i] = r.check(ctx, d)
+		}(i, d)
+	}
+	wg.Wait()
+	return results
+}
+
+// check returns the cached result of a dependency, probing it first if the
+// result expired
+func (r *Registry) check(ctx context.Context, d *dependency) service.DependencyHealth {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if r.now().Before(d.expires) {
+		return d.result
+	}
+
+	// The result is shared with other callers, so a caller giving up must
+	// not be recorded as a failed dependency
+	probeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.config.Timeout)
+	defer cancel()
+
+	start := r.now()
+	err := d.probe.Probe(probeCtx)
+	end := r.now()
+
+	d.result.Name = d.name
+	d.result.Status = service.HealthUp
+	d.result.LatencyMs = float64(end.Sub(start)) / float64(time.Millisecond)
+	d.result.CheckedAt = start
+	if err != nil {
+		failedAt := start
+		d.result.Status = service.HealthDown
+		d.result.LastError = truncate(err.Error(), maxErrorLength)
+		d.result.LastErrorAt = &failedAt
+	}
+	d.expires = end.Add(r.config.CacheTTL)
+	return d.result
+}
+
+// truncate shortens s to at most n bytes without splitting a character,
+// marking the cut
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "..."
+}
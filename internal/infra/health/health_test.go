@@ -0,0 +1,299 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// fakeClock returns a time moved forward by tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// countingProbe counts its calls and returns err
+type countingProbe struct {
+	calls atomic.Int32
+
+	mu  sync.Mutex
+	err error
+}
+
+func (p *countingProbe) Probe(ctx context.Context) error {
+	p.calls.Add(1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+func (p *countingProbe) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+func newTestRegistry(config Config) (*Registry, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+	registry := NewRegistry(config)
+	registry.now = clock.Now
+	return registry, clock
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "zero values", config: Config{}},
+		{name: "endpoints", config: Config{Endpoints: map[string]string{"card_vault": "https://vault.internal/health"}}},
+		{name: "negative TTL", config: Config{CacheTTL: -time.Second}, wantErr: "cache TTL"},
+		{name: "negative timeout", config: Config{Timeout: -time.Second}, wantErr: "timeout"},
+		{name: "relative endpoint", config: Config{Endpoints: map[string]string{"card_vault": "/health"}}, wantErr: "card_vault"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegistry_Check(t *testing.T) {
+	// Arrange
+	registry, _ := newTestRegistry(Config{})
+	vault := &countingProbe{}
+	dynamo := &countingProbe{}
+	dynamo.fail(errors.New("table chargebacks is CREATING"))
+	registry.Register("dynamodb", dynamo)
+	registry.Register("card_vault", vault)
+
+	// Act
+	results := registry.Check(context.Background())
+
+	// Assert
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "card_vault" || results[1].Name != "dynamodb" {
+		t.Errorf("Expected results sorted by name, got %s, %s", results[0].Name, results[1].Name)
+	}
+	if results[0].Status != service.HealthUp || results[0].LastError != "" || results[0].LastErrorAt != nil {
+		t.Errorf("Expected card_vault up without error, got %+v", results[0])
+	}
+	if results[1].Status != service.HealthDown || results[1].LastError != "table chargebacks is CREATING" {
+		t.Errorf("Expected dynamodb down with its error, got %+v", results[1])
+	}
+	if results[1].CheckedAt.IsZero() || results[1].LastErrorAt == nil {
+		t.Errorf("Expected check and error times, got %+v", results[1])
+	}
+}
+
+func TestRegistry_Check_Cached(t *testing.T) {
+	// Arrange
+	registry, clock := newTestRegistry(Config{CacheTTL: 10 * time.Second})
+	probe := &countingProbe{}
+	registry.Register("dynamodb", probe)
+
+	// Act
+	first := registry.Check(context.Background())
+	clock.Advance(9 * time.Second)
+	cached := registry.Check(context.Background())
+	clock.Advance(2 * time.Second)
+	registry.Check(context.Background())
+
+	// Assert
+	if calls := probe.calls.Load(); calls != 2 {
+		t.Errorf("Expected 2 probes over 11 seconds with a 10s TTL, got %d", calls)
+	}
+	if !cached[0].CheckedAt.Equal(first[0].CheckedAt) {
+		t.Errorf("Expected the cached result, got checked at %s instead of %s", cached[0].CheckedAt, first[0].CheckedAt)
+	}
+}
+
+func TestRegistry_Check_KeepsLastError(t *testing.T) {
+	// Arrange
+	registry, clock := newTestRegistry(Config{CacheTTL: time.Second})
+	probe := &countingProbe{}
+	probe.fail(errors.New("connection refused"))
+	registry.Register("card_vault", probe)
+
+	// Act
+	failed := registry.Check(context.Background())
+	clock.Advance(2 * time.Second)
+	probe.fail(nil)
+	recovered := registry.Check(context.Background())
+
+	// Assert
+	if failed[0].Status != service.HealthDown {
+		t.Fatalf("Expected the first probe to fail, got %+v", failed[0])
+	}
+	if recovered[0].Status != service.HealthUp {
+		t.Errorf("Expected the dependency to recover, got %+v", recovered[0])
+	}
+	if recovered[0].LastError != "connection refused" || !recovered[0].LastErrorAt.Equal(failed[0].CheckedAt) {
+		t.Errorf("Expected the last error to be kept, got %+v", recovered[0])
+	}
+}
+
+func TestRegistry_Check_Timeout(t *testing.T) {
+	// Arrange
+	registry := NewRegistry(Config{Timeout: 20 * time.Millisecond})
+	registry.Register("event_publisher", ProbeFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+
+	// Act
+	start := time.Now()
+	results := registry.Check(context.Background())
+
+	// Assert
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the probe to be cut at the timeout, took %s", elapsed)
+	}
+	if results[0].Status != service.HealthDown || !strings.Contains(results[0].LastError, "deadline exceeded") {
+		t.Errorf("Expected a timed out probe, got %+v", results[0])
+	}
+	if results[0].LatencyMs < 20 {
+		t.Errorf("Expected the latency to cover the timeout, got %vms", results[0].LatencyMs)
+	}
+}
+
+func TestRegistry_Check_IgnoresCallerCancellation(t *testing.T) {
+	// Arrange
+	registry := NewRegistry(Config{})
+	registry.Register("dynamodb", ProbeFunc(func(ctx context.Context) error {
+		return ctx.Err()
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	results := registry.Check(ctx)
+
+	// Assert
+	if results[0].Status != service.HealthUp {
+		t.Errorf("Expected a cancelled caller not to fail the shared probe, got %+v", results[0])
+	}
+}
+
+func TestRegistry_Check_SharesConcurrentProbes(t *testing.T) {
+	// Arrange
+	registry := NewRegistry(Config{CacheTTL: time.Minute})
+	var calls atomic.Int32
+	registry.Register("dynamodb", ProbeFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}))
+
+	// Act
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Check(context.Background())
+		}()
+	}
+	wg.Wait()
+
+	// Assert
+	if got := calls.Load(); got != 1 {
+		t.Errorf("Expected concurrent checks to share one probe, got %d probes", got)
+	}
+}
+
+func TestRegistry_Register_Replaces(t *testing.T) {
+	registry := NewRegistry(Config{})
+	registry.Register("dynamodb", ProbeFunc(func(ctx context.Context) error { return errors.New("old") }))
+	registry.Register("dynamodb", ProbeFunc(func(ctx context.Context) error { return nil }))
+
+	results := registry.Check(context.Background())
+	if len(results) != 1 || results[0].Status != service.HealthUp {
+		t.Errorf("Expected the replacing probe only, got %+v", results)
+	}
+}
+
+func TestNewRegistry_Endpoints(t *testing.T) {
+	// Arrange
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer up.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	registry := NewRegistry(Config{Endpoints: map[string]string{
+		"card_vault":      up.URL,
+		"event_publisher": down.URL,
+	}})
+
+	// Act
+	results := registry.Check(context.Background())
+
+	// Assert
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Name != "card_vault" || results[0].Status != service.HealthUp {
+		t.Errorf("Expected card_vault up, got %+v", results[0])
+	}
+	if results[1].Name != "event_publisher" || results[1].Status != service.HealthDown || !strings.Contains(results[1].LastError, "503") {
+		t.Errorf("Expected event_publisher down with its status, got %+v", results[1])
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		limit int
+		want  string
+	}{
+		{name: "short", value: "abc", limit: 5, want: "abc"},
+		{name: "cut", value: "abcdef", limit: 3, want: "abc..."},
+		{name: "multi-byte character", value: "aéb", limit: 2, want: "a..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.value, tt.limit); got != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPProbe checks a dependency by requesting its health URL. Any 2xx status
+// means the dependency is up.
+type HTTPProbe struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPProbe creates a probe of url, using http.DefaultClient if client is nil
+func NewHTTPProbe(url string, client *http.Client) *HTTPProbe {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPProbe{url: url, client: client}
+}
+
+// Probe requests the health URL
+func (p *HTTPProbe) Probe(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("health request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Drain a bounded amount so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("health endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
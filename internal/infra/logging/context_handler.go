@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// ContextHandler is a slog.Handler that adds the registered identifiers
+// carried by the context of each record, such as the request, merchant,
+// chargeback and trace IDs. Attributes set on the record or bound with
+// WithAttrs take precedence, so no key is ever written twice.
+type ContextHandler struct {
+	next  slog.Handler
+	keys  []string
+	bound []string
+}
+
+// NewContextHandler wraps next so every record gets the context identifiers
+// named by keys, in that order. Keys must be requestctx field names; nil
+// selects requestctx.DefaultFields.
+func NewContextHandler(next slog.Handler, keys []string) (*ContextHandler, error) {
+	if err := validateContextKeys(keys); err != nil {
+		return nil, err
+	}
+
+	if keys == nil {
+		keys = requestctx.DefaultFields
+	}
+
+	return &ContextHandler{next: next, keys: slices.Clone(keys)}, nil
+}
+
+// validateContextKeys rejects keys that do not name a context identifier
+func validateContextKeys(keys []string) error {
+	for _, key := range keys {
+		if !requestctx.IsField(key) {
+			return fmt.Errorf("unknown context key: %q", key)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether the wrapped handler handles records at level
+func (h *ContextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle adds the context identifiers missing from the record and passes it on
+func (h *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := h.contextAttrs(ctx, record)
+	if len(attrs) > 0 {
+		// The record may share its attribute storage with the caller
+		record = record.Clone()
+		record.AddAttrs(attrs...)
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler that also skips the bound keys when extracting
+// context identifiers
+func (h *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	bound := slices.Clone(h.bound)
+	for _, attr := range attrs {
+		bound = append(bound, attr.Key)
+	}
+	return &ContextHandler{next: h.next.WithAttrs(attrs), keys: h.keys, bound: bound}
+}
+
+// WithGroup returns a handler whose record and context attributes are nested
+// in the named group. Keys bound before the group still skip their context
+// identifiers, so the group does not repeat them.
+func (h *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{next: h.next.WithGroup(name), keys: h.keys, bound: h.bound}
+}
+
+// contextAttrs returns the identifiers carried by ctx that are neither bound
+// to the handler nor set on the record
+func (h *ContextHandler) contextAttrs(ctx context.Context, record slog.Record) []slog.Attr {
+	var attrs []slog.Attr
+	for _, key := range h.keys {
+		if slices.Contains(h.bound, key) {
+			continue
+		}
+		if value := requestctx.Lookup(ctx, key); value != "" {
+			attrs = append(attrs, slog.String(key, value))
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = slices.DeleteFunc(attrs, func(a slog.Attr) bool { return a.Key == attr.Key })
+		return len(attrs) > 0
+	})
+	return attrs
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+func newTestContext() context.Context {
+	ctx := requestctx.WithRequestID(context.Background(), "req-1")
+	ctx = requestctx.WithMerchantID(ctx, "merchant-1")
+	ctx = requestctx.WithChargebackID(ctx, "cb-1")
+	return requestctx.WithTraceID(ctx, "1-abc-def")
+}
+
+func TestContextHandler_ExtractsRegisteredKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		keys    []string
+		want    map[string]string
+		notWant []string
+	}{
+		{
+			name: "defaults extract every identifier",
+			keys: nil,
+			want: map[string]string{
+				"request_id":    "req-1",
+				"merchant_id":   "merchant-1",
+				"chargeback_id": "cb-1",
+				"trace_id":      "1-abc-def",
+			},
+		},
+		{
+			name:    "configured keys only",
+			keys:    []string{requestctx.FieldMerchantID, requestctx.FieldTraceID},
+			want:    map[string]string{"merchant_id": "merchant-1", "trace_id": "1-abc-def"},
+			notWant: []string{"request_id", "chargeback_id"},
+		},
+		{
+			name:    "empty keys disable extraction",
+			keys:    []string{},
+			notWant: []string{"request_id", "merchant_id", "chargeback_id", "trace_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler, err := NewContextHandler(slog.NewJSONHandler(&buf, nil), tt.keys)
+			if err != nil {
+				t.Fatalf("NewContextHandler() error = %v", err)
+			}
+
+			slog.New(handler).InfoContext(newTestContext(), "message")
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("Failed to parse log entry: %v", err)
+			}
+			for key, value := range tt.want {
+				if entry[key] != value {
+					t.Errorf("Expected %s '%s', got %v", key, value, entry[key])
+				}
+			}
+			for _, key := range tt.notWant {
+				if _, ok := entry[key]; ok {
+					t.Errorf("Expected no %s, got %v", key, entry[key])
+				}
+			}
+		})
+	}
+}
+
+func TestContextHandler_ExplicitAttributesWin(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewContextHandler(slog.NewJSONHandler(&buf, nil), nil)
+	if err != nil {
+		t.Fatalf("NewContextHandler() error = %v", err)
+	}
+
+	logger := slog.New(handler).With("merchant_id", "bound")
+	logger.InfoContext(newTestContext(), "message", "chargeback_id", "explicit")
+
+	line := buf.String()
+	for _, key := range []string{"merchant_id", "chargeback_id", "request_id"} {
+		if count := strings.Count(line, `"`+key+`"`); count != 1 {
+			t.Errorf("Expected %s once, found %d times in %s", key, count, line)
+		}
+	}
+	if !strings.Contains(line, `"merchant_id":"bound"`) || !strings.Contains(line, `"chargeback_id":"explicit"`) {
+		t.Errorf("Expected bound and explicit values to win, got %s", line)
+	}
+}
+
+func TestContextHandler_WithAttrsThenGroup(t *testing.T) {
+	var buf bytes.Buffer
+	handler, err := NewContextHandler(slog.NewJSONHandler(&buf, nil), nil)
+	if err != nil {
+		t.Fatalf("NewContextHandler() error = %v", err)
+	}
+
+	logger := slog.New(handler).With("merchant_id", "bound").WithGroup("payment")
+	logger.InfoContext(newTestContext(), "message", "amount", 100)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log entry: %v", err)
+	}
+	if entry["merchant_id"] != "bound" {
+		t.Errorf("Expected the bound merchant_id to be kept, got %v", entry["merchant_id"])
+	}
+	group, _ := entry["payment"].(map[string]interface{})
+	if _, ok := group["merchant_id"]; ok {
+		t.Errorf("Expected the bound merchant_id not to be repeated in the group, got %v", group)
+	}
+	if group["amount"] != float64(100) || group["request_id"] != "req-1" {
+		t.Errorf("Expected record and context attributes in the group, got %v", group)
+	}
+}
+
+func TestNewContextHandler_UnknownKey(t *testing.T) {
+	if _, err := NewContextHandler(slog.NewJSONHandler(&bytes.Buffer{}, nil), []string{"request_id", "card_number"}); err == nil {
+		t.Error("Expected an error for an unknown context key")
+	}
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// DefaultEMFMetrics maps the numeric fields published as CloudWatch metrics
+// by default to their units
+var DefaultEMFMetrics = map[string]string{
+	"duration_ms": "Milliseconds",
+}
+
+// EMFConfig configures CloudWatch Embedded Metric Format entries. Entries
+// carrying any of the metric fields are annotated so CloudWatch extracts the
+// fields as metrics; other entries are written as plain JSON.
+type EMFConfig struct {
+	// Namespace of the metrics, the service name if empty
+	Namespace string
+
+	// Dimensions lists top-level fields used as metric dimensions,
+	// ["service"] if nil and the service name is set
+	Dimensions []string
+
+	// Metrics maps numeric field names to CloudWatch units,
+	// DefaultEMFMetrics if nil
+	Metrics map[string]string
+}
+
+// withDefaults fills unset fields for a logger of the given service
+func (c EMFConfig) withDefaults(serviceName string) EMFConfig {
+	if c.Namespace == "" {
+		c.Namespace = serviceName
+	}
+	if c.Namespace == "" {
+		c.Namespace = "chargeback-api"
+	}
+	if c.Dimensions == nil && serviceName != "" {
+		c.Dimensions = []string{"service"}
+	}
+	if c.Metrics == nil {
+		c.Metrics = DefaultEMFMetrics
+	}
+	return c
+}
+
+// EMFHandler is a slog.Handler adding the CloudWatch "_aws" metadata to
+// records with metric fields before passing them to a JSON handler. Metric
+// fields must be logged on the record itself, outside any group.
+type EMFHandler struct {
+	next   slog.Handler
+	config EMFConfig
+}
+
+// NewEMFHandler wraps a JSON handler so metric fields become CloudWatch metrics
+func NewEMFHandler(next slog.Handler, config EMFConfig) *EMFHandler {
+	return &EMFHandler{next: next, config: config}
+}
+
+// Enabled reports whether the wrapped handler handles records at level
+func (h *EMFHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle adds the metric metadata when the record carries metric fields
+func (h *EMFHandler) Handle(ctx context.Context, record slog.Record) error {
+	var metrics []map[string]string
+	record.Attrs(func(attr slog.Attr) bool {
+		unit, ok := h.config.Metrics[attr.Key]
+		if !ok {
+			return true
+		}
+		switch attr.Value.Kind() {
+		case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+			metrics = append(metrics, map[string]string{"Name": attr.Key, "Unit": unit})
+		}
+		return true
+	})
+
+	if len(metrics) > 0 {
+		sort.Slice(metrics, func(i, j int) bool { return metrics[i]["Name"] < metrics[j]["Name"] })
+
+		dimensions := h.config.Dimensions
+		if dimensions == nil {
+			dimensions = []string{}
+		}
+
+		record = record.Clone()
+		record.AddAttrs(slog.Any("_aws", map[string]interface{}{
+			"Timestamp": record.Time.UnixMilli(),
+			"CloudWatchMetrics": []map[string]interface{}{{
+				"Namespace":  h.config.Namespace,
+				"Dimensions": [][]string{dimensions},
+				"Metrics":    metrics,
+			}},
+		}))
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+// WithAttrs returns a handler with the attributes bound
+func (h *EMFHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &EMFHandler{next: h.next.WithAttrs(attrs), config: h.config}
+}
+
+// WithGroup returns a handler with the group opened
+func (h *EMFHandler) WithGroup(name string) slog.Handler {
+	return &EMFHandler{next: h.next.WithGroup(name), config: h.config}
+}
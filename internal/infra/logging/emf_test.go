@@ -0,0 +1,95 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+func TestStructuredLogger_EMF(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level:       service.LogLevelInfo,
+		Format:      FormatEMF,
+		ServiceName: "chargeback-api",
+	}, &buf)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+	ctx := context.Background()
+
+	logger.Info(ctx, "HTTP request processed", map[string]interface{}{"duration_ms": int64(42), "status": 201})
+	logger.Info(ctx, "Chargeback created successfully")
+
+	entries := logEntries(t, &buf)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+
+	metric := entries[0]
+	if metric["duration_ms"] != float64(42) || metric["service"] != "chargeback-api" {
+		t.Errorf("Expected the metric and dimension fields at the top level, got %v", metric)
+	}
+
+	aws, ok := metric["_aws"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected _aws metadata, got %v", metric)
+	}
+	if _, ok := aws["Timestamp"].(float64); !ok {
+		t.Errorf("Expected a numeric timestamp, got %v", aws["Timestamp"])
+	}
+
+	directives := aws["CloudWatchMetrics"].([]interface{})
+	directive := directives[0].(map[string]interface{})
+	if directive["Namespace"] != "chargeback-api" {
+		t.Errorf("Expected the service name as namespace, got %v", directive["Namespace"])
+	}
+	dimensions := directive["Dimensions"].([]interface{})[0].([]interface{})
+	if len(dimensions) != 1 || dimensions[0] != "service" {
+		t.Errorf("Expected the service dimension, got %v", dimensions)
+	}
+	metrics := directive["Metrics"].([]interface{})
+	if len(metrics) != 1 {
+		t.Fatalf("Expected 1 metric, got %v", metrics)
+	}
+	if m := metrics[0].(map[string]interface{}); m["Name"] != "duration_ms" || m["Unit"] != "Milliseconds" {
+		t.Errorf("Expected duration_ms in milliseconds, got %v", m)
+	}
+
+	if _, ok := entries[1]["_aws"]; ok {
+		t.Errorf("Expected entries without metric fields to be plain JSON, got %v", entries[1])
+	}
+}
+
+func TestEMFHandler_IgnoresNonNumericMetricFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level: service.LogLevelInfo,
+		Sinks: []SinkConfig{{
+			Format: FormatEMF,
+			Writer: &buf,
+			EMF:    EMFConfig{Namespace: "Chargebacks", Metrics: map[string]string{"amount": "None"}},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	logger.Info(context.Background(), "text amount", map[string]interface{}{"amount": "100.50"})
+	logger.Info(context.Background(), "numeric amount", map[string]interface{}{"amount": 100.5})
+
+	entries := logEntries(t, &buf)
+	if _, ok := entries[0]["_aws"]; ok {
+		t.Errorf("Expected a string field not to be a metric, got %v", entries[0])
+	}
+	aws, ok := entries[1]["_aws"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected _aws metadata for the numeric field, got %v", entries[1])
+	}
+	directive := aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+	if directive["Namespace"] != "Chargebacks" {
+		t.Errorf("Expected the configured namespace, got %v", directive["Namespace"])
+	}
+}
@@ -0,0 +1,321 @@
+package logging
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+const (
+	// DefaultLevelTTL is how long a level loaded from a LevelSource is used
+	// before it is loaded again
+	DefaultLevelTTL = 30 * time.Second
+
+	// MaxLevelOverrideTTL bounds how far in the future a signed level
+	// override may expire, so leaked header values stop working quickly
+	MaxLevelOverrideTTL = time.Hour
+)
+
+// ErrLevelOverridesDisabled is returned when a level override is presented
+// but no signing key is configured
+var ErrLevelOverridesDisabled = errors.New("log level overrides are disabled")
+
+// LevelSource loads the log verbosity. The value is either a level name such
+// as "debug", or a JSON document that can also raise the level per merchant:
+//
+//	{"level": "info", "merchants": {"merchant-123": "debug"}}
+type LevelSource interface {
+	LoadLevel(ctx context.Context) (string, error)
+}
+
+// ParameterGetter reads a parameter by name, such as an SSM parameter
+type ParameterGetter interface {
+	GetParameter(ctx context.Context, name string) (string, error)
+}
+
+// ParameterLevelSource loads the level from a parameter store
+type ParameterLevelSource struct {
+	Store ParameterGetter
+	Name  string
+}
+
+// LoadLevel returns the value of the parameter
+func (s ParameterLevelSource) LoadLevel(ctx context.Context) (string, error) {
+	return s.Store.GetParameter(ctx, s.Name)
+}
+
+// FileLevelSource loads the level from a local file
+type FileLevelSource struct {
+	Path string
+}
+
+// LoadLevel returns the contents of the file
+func (s FileLevelSource) LoadLevel(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LevelSettings is the verbosity loaded from a LevelSource
+type LevelSettings struct {
+	// Level is the minimum level of every entry
+	Level service.LogLevel
+
+	// Merchants lowers the minimum level for entries of requests acting on
+	// the given merchants
+	Merchants map[string]service.LogLevel
+}
+
+// ParseLevel converts a level name into a domain log level
+func ParseLevel(name string) (service.LogLevel, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return service.LogLevelDebug, nil
+	case "info":
+		return service.LogLevelInfo, nil
+	case "warn", "warning":
+		return service.LogLevelWarn, nil
+	case "error":
+		return service.LogLevelError, nil
+	default:
+		return service.LogLevelInfo, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", name)
+	}
+}
+
+// ParseLevelSettings parses the value loaded from a LevelSource
+func ParseLevelSettings(raw string) (LevelSettings, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "{") {
+		level, err := ParseLevel(raw)
+		return LevelSettings{Level: level}, err
+	}
+
+	var document struct {
+		Level     string            `json:"level"`
+		Merchants map[string]string `json:"merchants"`
+	}
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&document); err != nil {
+		return LevelSettings{}, fmt.Errorf("invalid level document: %w", err)
+	}
+
+	level, err := ParseLevel(document.Level)
+	if err != nil {
+		return LevelSettings{}, err
+	}
+
+	settings := LevelSettings{Level: level, Merchants: make(map[string]service.LogLevel, len(document.Merchants))}
+	for merchantID, name := range document.Merchants {
+		if settings.Merchants[merchantID], err = ParseLevel(name); err != nil {
+			return LevelSettings{}, fmt.Errorf("merchant %s: %w", merchantID, err)
+		}
+	}
+	return settings, nil
+}
+
+// DynamicLevel decides which entries are logged. The level can be changed
+// while running, reloaded from a LevelSource once its TTL expires, raised for
+// selected merchants and raised for single requests carrying a signed
+// override.
+type DynamicLevel struct {
+	base      slog.LevelVar
+	merchants atomic.Pointer[map[string]slog.Level]
+
+	source      LevelSource
+	ttl         time.Duration
+	overrideKey []byte
+	now         func() time.Time
+
+	// refreshAt is when the source is next loaded, in Unix nanoseconds
+	refreshAt atomic.Int64
+	mu        sync.Mutex
+}
+
+// NewDynamicLevel creates a level starting at level. The source may be nil,
+// and overrides are rejected when overrideKey is empty.
+func NewDynamicLevel(level service.LogLevel, source LevelSource, ttl time.Duration, overrideKey []byte) *DynamicLevel {
+	if ttl <= 0 {
+		ttl = DefaultLevelTTL
+	}
+
+	d := &DynamicLevel{
+		source:      source,
+		ttl:         ttl,
+		overrideKey: overrideKey,
+		now:         time.Now,
+	}
+	d.base.Set(convertLogLevel(level))
+	return d
+}
+
+// Set replaces the level and merchant levels
+func (d *DynamicLevel) Set(settings LevelSettings) {
+	d.base.Set(convertLogLevel(settings.Level))
+
+	merchants := make(map[string]slog.Level, len(settings.Merchants))
+	for merchantID, level := range settings.Merchants {
+		merchants[merchantID] = convertLogLevel(level)
+	}
+	d.merchants.Store(&merchants)
+}
+
+// Level returns the minimum level of entries without overrides
+func (d *DynamicLevel) Level() slog.Level {
+	return d.base.Level()
+}
+
+// Refresh loads the level from the source once the TTL has expired. On
+// failure the current level is kept and the source is retried after the TTL.
+func (d *DynamicLevel) Refresh(ctx context.Context) error {
+	if d.source == nil {
+		return nil
+	}
+
+	now := d.now()
+	if now.UnixNano() < d.refreshAt.Load() {
+		return nil
+	}
+
+	// A single caller loads the source; the others keep the current level
+	if !d.mu.TryLock() {
+		return nil
+	}
+	defer d.mu.Unlock()
+
+	if now.UnixNano() < d.refreshAt.Load() {
+		return nil
+	}
+	d.refreshAt.Store(now.Add(d.ttl).UnixNano())
+
+	raw, err := d.source.LoadLevel(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load log level: %w", err)
+	}
+
+	settings, err := ParseLevelSettings(raw)
+	if err != nil {
+		return fmt.Errorf("failed to load log level: %w", err)
+	}
+
+	d.Set(settings)
+	return nil
+}
+
+// Enabled reports whether an entry at level is logged for the request of ctx
+func (d *DynamicLevel) Enabled(ctx context.Context, level slog.Level) bool {
+	minimum := d.base.Level()
+	if level >= minimum {
+		return true
+	}
+
+	if ctx == nil {
+		return false
+	}
+
+	if override, ok := ctx.Value(levelOverrideKey{}).(slog.Level); ok && level >= override {
+		return true
+	}
+
+	if merchants := d.merchants.Load(); merchants != nil {
+		if merchantLevel, ok := (*merchants)[requestctx.MerchantID(ctx)]; ok && level >= merchantLevel {
+			return true
+		}
+	}
+
+	return false
+}
+
+// levelOverrideKey is the context key of a per-request level override
+type levelOverrideKey struct{}
+
+// WithOverride verifies a signed override created by SignLevelOverride and
+// returns a context whose entries are logged from the granted level
+func (d *DynamicLevel) WithOverride(ctx context.Context, value string) (context.Context, error) {
+	if len(d.overrideKey) == 0 {
+		return ctx, ErrLevelOverridesDisabled
+	}
+
+	name, rest, ok := strings.Cut(value, ".")
+	expiresRaw, signature, ok2 := strings.Cut(rest, ".")
+	if !ok || !ok2 {
+		return ctx, errors.New("malformed log level override")
+	}
+
+	expected := signLevelOverride(d.overrideKey, name, expiresRaw)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return ctx, errors.New("invalid log level override signature")
+	}
+
+	expiresUnix, err := strconv.ParseInt(expiresRaw, 10, 64)
+	if err != nil {
+		return ctx, errors.New("malformed log level override expiry")
+	}
+	expires := time.Unix(expiresUnix, 0)
+	now := d.now()
+	if !expires.After(now) {
+		return ctx, errors.New("log level override has expired")
+	}
+	if expires.Sub(now) > MaxLevelOverrideTTL {
+		return ctx, fmt.Errorf("log level override must expire within %s", MaxLevelOverrideTTL)
+	}
+
+	level, err := ParseLevel(name)
+	if err != nil {
+		return ctx, err
+	}
+
+	return context.WithValue(ctx, levelOverrideKey{}, convertLogLevel(level)), nil
+}
+
+// SignLevelOverride returns a header value granting level to the requests
+// carrying it until expires, in the form "<level>.<unix expiry>.<signature>"
+func SignLevelOverride(key []byte, level service.LogLevel, expires time.Time) string {
+	expiresRaw := strconv.FormatInt(expires.Unix(), 10)
+	return level.String() + "." + expiresRaw + "." + signLevelOverride(key, level.String(), expiresRaw)
+}
+
+// signLevelOverride returns the hex HMAC-SHA256 of the level and expiry
+func signLevelOverride(key []byte, level, expires string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(level + "." + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// levelHandler is a slog.Handler that filters records with a DynamicLevel
+type levelHandler struct {
+	next   slog.Handler
+	levels *DynamicLevel
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.levels.Enabled(ctx, level) && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.next.Handle(ctx, record)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), levels: h.levels}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), levels: h.levels}
+}
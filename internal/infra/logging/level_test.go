@@ -0,0 +1,210 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// stubLevelSource returns a fixed value and counts loads
+type stubLevelSource struct {
+	value string
+	err   error
+	loads int
+}
+
+func (s *stubLevelSource) LoadLevel(ctx context.Context) (string, error) {
+	s.loads++
+	return s.value, s.err
+}
+
+func TestParseLevelSettings(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		wantLevel     service.LogLevel
+		wantMerchants map[string]service.LogLevel
+		wantErr       bool
+	}{
+		{name: "level name", raw: "DEBUG\n", wantLevel: service.LogLevelDebug},
+		{
+			name:          "document with merchants",
+			raw:           `{"level": "warn", "merchants": {"merchant-1": "debug"}}`,
+			wantLevel:     service.LogLevelWarn,
+			wantMerchants: map[string]service.LogLevel{"merchant-1": service.LogLevelDebug},
+		},
+		{name: "unknown level", raw: "verbose", wantErr: true},
+		{name: "unknown merchant level", raw: `{"level": "info", "merchants": {"m": "loud"}}`, wantErr: true},
+		{name: "unknown field", raw: `{"level": "info", "users": {}}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			settings, err := ParseLevelSettings(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevelSettings() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if settings.Level != tt.wantLevel {
+				t.Errorf("Expected level %v, got %v", tt.wantLevel, settings.Level)
+			}
+			for merchantID, level := range tt.wantMerchants {
+				if settings.Merchants[merchantID] != level {
+					t.Errorf("Expected merchant %s at %v, got %v", merchantID, level, settings.Merchants[merchantID])
+				}
+			}
+		})
+	}
+}
+
+func TestDynamicLevel_Refresh(t *testing.T) {
+	source := &stubLevelSource{value: "debug"}
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+	levels := NewDynamicLevel(service.LogLevelInfo, source, time.Minute, nil)
+	levels.now = clock.Now
+	ctx := context.Background()
+
+	if err := levels.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if levels.Level() != slog.LevelDebug {
+		t.Errorf("Expected debug level after refresh, got %v", levels.Level())
+	}
+
+	// The loaded level is used until the TTL expires
+	source.value = "error"
+	clock.Advance(30 * time.Second)
+	levels.Refresh(ctx)
+	if source.loads != 1 || levels.Level() != slog.LevelDebug {
+		t.Errorf("Expected the cached level within the TTL, got %v after %d loads", levels.Level(), source.loads)
+	}
+
+	clock.Advance(31 * time.Second)
+	levels.Refresh(ctx)
+	if levels.Level() != slog.LevelError {
+		t.Errorf("Expected error level after the TTL, got %v", levels.Level())
+	}
+
+	// A failing source keeps the current level
+	source.err = errors.New("throttled")
+	clock.Advance(time.Minute)
+	if err := levels.Refresh(ctx); err == nil {
+		t.Error("Expected the load error to be returned")
+	}
+	if levels.Level() != slog.LevelError {
+		t.Errorf("Expected the level to be kept on failure, got %v", levels.Level())
+	}
+}
+
+func TestFileLevelSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level.json")
+	if err := os.WriteFile(path, []byte(`{"level":"warn"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	levels := NewDynamicLevel(service.LogLevelInfo, FileLevelSource{Path: path}, 0, nil)
+	if err := levels.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+	if levels.Level() != slog.LevelWarn {
+		t.Errorf("Expected warn level from the file, got %v", levels.Level())
+	}
+}
+
+func TestDynamicLevel_MerchantLevels(t *testing.T) {
+	levels := NewDynamicLevel(service.LogLevelInfo, nil, 0, nil)
+	levels.Set(LevelSettings{
+		Level:     service.LogLevelInfo,
+		Merchants: map[string]service.LogLevel{"merchant-1": service.LogLevelDebug},
+	})
+
+	debugged := requestctx.WithMerchantID(context.Background(), "merchant-1")
+	other := requestctx.WithMerchantID(context.Background(), "merchant-2")
+
+	if !levels.Enabled(debugged, slog.LevelDebug) {
+		t.Error("Expected debug entries for the selected merchant")
+	}
+	if levels.Enabled(other, slog.LevelDebug) || levels.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Expected debug entries to stay disabled for other requests")
+	}
+}
+
+func TestDynamicLevel_WithOverride(t *testing.T) {
+	key := []byte("override-key")
+	now := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	levels := NewDynamicLevel(service.LogLevelInfo, nil, 0, key)
+	levels.now = func() time.Time { return now }
+
+	valid := SignLevelOverride(key, service.LogLevelDebug, now.Add(10*time.Minute))
+
+	tests := []struct {
+		name    string
+		levels  *DynamicLevel
+		value   string
+		wantErr bool
+	}{
+		{name: "valid", levels: levels, value: valid},
+		{name: "tampered level", levels: levels, value: "error" + strings.TrimPrefix(valid, "debug"), wantErr: true},
+		{name: "wrong key", levels: levels, value: SignLevelOverride([]byte("other"), service.LogLevelDebug, now.Add(time.Minute)), wantErr: true},
+		{name: "expired", levels: levels, value: SignLevelOverride(key, service.LogLevelDebug, now.Add(-time.Second)), wantErr: true},
+		{name: "expires too late", levels: levels, value: SignLevelOverride(key, service.LogLevelDebug, now.Add(2*time.Hour)), wantErr: true},
+		{name: "malformed", levels: levels, value: "debug", wantErr: true},
+		{name: "disabled", levels: NewDynamicLevel(service.LogLevelInfo, nil, 0, nil), value: valid, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, err := tt.levels.WithOverride(context.Background(), tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithOverride() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := tt.levels.Enabled(ctx, slog.LevelDebug); got == tt.wantErr {
+				t.Errorf("Expected debug enabled = %v, got %v", !tt.wantErr, got)
+			}
+		})
+	}
+}
+
+func TestStructuredLogger_DynamicLevel(t *testing.T) {
+	var buf bytes.Buffer
+	key := []byte("override-key")
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level:            service.LogLevelInfo,
+		Format:           FormatJSON,
+		LevelOverrideKey: key,
+	}, &buf)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+	ctx := context.Background()
+
+	logger.Debug(ctx, "hidden")
+
+	override, err := logger.Levels().WithOverride(ctx, SignLevelOverride(key, service.LogLevelDebug, time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatalf("WithOverride() error = %v", err)
+	}
+	logger.Debug(override, "overridden request")
+
+	logger.Levels().Set(LevelSettings{Level: service.LogLevelDebug})
+	logger.Debug(ctx, "raised level")
+
+	output := buf.String()
+	if strings.Contains(output, "hidden") {
+		t.Error("Expected debug entries to be dropped at info level")
+	}
+	if !strings.Contains(output, "overridden request") || !strings.Contains(output, "raised level") {
+		t.Errorf("Expected overridden and raised entries, got:\n%s", output)
+	}
+}
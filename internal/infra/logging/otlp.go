@@ -0,0 +1,446 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+const (
+	// DefaultOTLPBatchSize is how many entries are buffered before they are
+	// exported
+	DefaultOTLPBatchSize = 100
+
+	// DefaultOTLPTimeout bounds each export request
+	DefaultOTLPTimeout = 5 * time.Second
+
+	// otlpLogsPath is the OTLP/HTTP logs path appended to the endpoint
+	otlpLogsPath = "/v1/logs"
+
+	// otlpScopeName identifies the logger in exported entries
+	otlpScopeName = "github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+)
+
+// OTLPConfig configures the export of entries to an OpenTelemetry collector
+// over OTLP/HTTP with JSON encoding
+type OTLPConfig struct {
+	// Endpoint is the base URL of the collector, such as
+	// http://localhost:4318; entries are posted to Endpoint + "/v1/logs"
+	Endpoint string
+
+	// Headers are added to every export request, such as API keys
+	Headers map[string]string
+
+	// BatchSize is how many entries are buffered before they are exported,
+	// DefaultOTLPBatchSize if zero
+	BatchSize int
+
+	// FlushInterval exports buffered entries periodically when positive.
+	// Otherwise entries wait for a full batch or an explicit Flush.
+	FlushInterval time.Duration
+
+	// Timeout bounds each export request, DefaultOTLPTimeout if zero
+	Timeout time.Duration
+
+	// Client sends the export requests, a client with Timeout if nil
+	Client *http.Client
+
+	// ErrorLog receives failed exports, os.Stderr if nil. Failures cannot
+	// be logged through the logger itself.
+	ErrorLog io.Writer
+}
+
+// Validate checks the exporter configuration
+func (c OTLPConfig) Validate() error {
+	if c.Endpoint == "" {
+		return errors.New("endpoint is required")
+	}
+	if !strings.HasPrefix(c.Endpoint, "http://") && !strings.HasPrefix(c.Endpoint, "https://") {
+		return fmt.Errorf("endpoint %q must be an http or https URL", c.Endpoint)
+	}
+	if c.BatchSize < 0 || c.FlushInterval < 0 || c.Timeout < 0 {
+		return errors.New("batch size, flush interval and timeout must not be negative")
+	}
+	return nil
+}
+
+// OTLPExporter buffers entries and posts them to a collector. It is safe for
+// concurrent use.
+type OTLPExporter struct {
+	config   OTLPConfig
+	url      string
+	client   *http.Client
+	resource otlpResource
+
+	mu      sync.Mutex
+	batch   []otlpLogRecord
+	sending sync.Mutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewOTLPExporter creates an exporter describing entries as coming from the
+// given service. With a FlushInterval it exports in the background until
+// closed.
+func NewOTLPExporter(config OTLPConfig, serviceName, version string) *OTLPExporter {
+	if config.BatchSize == 0 {
+		config.BatchSize = DefaultOTLPBatchSize
+	}
+	if config.Timeout == 0 {
+		config.Timeout = DefaultOTLPTimeout
+	}
+	if config.ErrorLog == nil {
+		config.ErrorLog = os.Stderr
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{Timeout: config.Timeout}
+	}
+
+	resource := otlpResource{}
+	if serviceName != "" {
+		resource.Attributes = append(resource.Attributes, otlpString("service.name", serviceName))
+	}
+	if version != "" {
+		resource.Attributes = append(resource.Attributes, otlpString("service.version", version))
+	}
+
+	e := &OTLPExporter{
+		config:   config,
+		url:      strings.TrimSuffix(config.Endpoint, "/") + otlpLogsPath,
+		client:   client,
+		resource: resource,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if config.FlushInterval > 0 {
+		go e.run()
+	} else {
+		close(e.done)
+	}
+	return e
+}
+
+// run exports buffered entries every FlushInterval until the exporter is closed
+func (e *OTLPExporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.reportError(e.Flush(context.Background()))
+		case <-e.stop:
+			return
+		}
+	}
+}
+
+// add buffers an entry, exporting the batch once it is full
+func (e *OTLPExporter) add(ctx context.Context, record otlpLogRecord) error {
+	e.mu.Lock()
+	e.batch = append(e.batch, record)
+	full := len(e.batch) >= e.config.BatchSize
+	e.mu.Unlock()
+
+	if !full {
+		return nil
+	}
+
+	err := e.Flush(context.WithoutCancel(ctx))
+	e.reportError(err)
+	return err
+}
+
+// Flush exports the buffered entries. Entries of a failed export are dropped
+// so a collector outage cannot exhaust memory.
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	// Exports are serialized so entries reach the collector in order
+	e.sending.Lock()
+	defer e.sending.Unlock()
+
+	e.mu.Lock()
+	batch := e.batch
+	e.batch = nil
+	e.mu.Unlock()
+
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if err := e.export(ctx, batch); err != nil {
+		return fmt.Errorf("failed to export %d log entries: %w", len(batch), err)
+	}
+	return nil
+}
+
+// Close stops the background export and flushes the buffered entries
+func (e *OTLPExporter) Close(ctx context.Context) error {
+	e.stopOnce.Do(func() { close(e.stop) })
+	<-e.done
+	return e.Flush(ctx)
+}
+
+// export posts a batch to the collector
+func (e *OTLPExporter) export(ctx context.Context, batch []otlpLogRecord) error {
+	body, err := json.Marshal(otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: e.resource,
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: otlpScopeName},
+				LogRecords: batch,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// reportError writes export failures to the error log
+func (e *OTLPExporter) reportError(err error) {
+	if err != nil {
+		fmt.Fprintf(e.config.ErrorLog, "otlp log exporter: %v\n", err)
+	}
+}
+
+// OTLPHandler is a slog.Handler converting records to OTLP log records.
+// Attributes in groups are flattened into dotted keys.
+type OTLPHandler struct {
+	exporter *OTLPExporter
+	attrs    []otlpKeyValue
+	prefix   string
+}
+
+// NewOTLPHandler creates a handler exporting records with exporter
+func NewOTLPHandler(exporter *OTLPExporter) *OTLPHandler {
+	return &OTLPHandler{exporter: exporter}
+}
+
+// Enabled accepts every level; levels are filtered by the logger
+func (h *OTLPHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+// Handle buffers the record for export
+func (h *OTLPHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make([]otlpKeyValue, len(h.attrs), len(h.attrs)+record.NumAttrs())
+	copy(attrs, h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = appendOTLPAttr(attrs, h.prefix, attr)
+		return true
+	})
+
+	severityNumber, severityText := otlpSeverity(record.Level)
+	return h.exporter.add(ctx, otlpLogRecord{
+		TimeUnixNano:   strconv.FormatInt(record.Time.UnixNano(), 10),
+		SeverityNumber: severityNumber,
+		SeverityText:   severityText,
+		Body:           otlpAnyValue{StringValue: &record.Message},
+		Attributes:     attrs,
+		TraceID:        otlpTraceID(requestctx.TraceID(ctx)),
+	})
+}
+
+// WithAttrs returns a handler with the attributes bound
+func (h *OTLPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	bound := make([]otlpKeyValue, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(bound, h.attrs)
+	for _, attr := range attrs {
+		bound = appendOTLPAttr(bound, h.prefix, attr)
+	}
+	return &OTLPHandler{exporter: h.exporter, attrs: bound, prefix: h.prefix}
+}
+
+// WithGroup returns a handler prefixing later attribute keys with name
+func (h *OTLPHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &OTLPHandler{exporter: h.exporter, attrs: h.attrs, prefix: h.prefix + name + "."}
+}
+
+// appendOTLPAttr converts attr, flattening groups into dotted keys
+func appendOTLPAttr(attrs []otlpKeyValue, prefix string, attr slog.Attr) []otlpKeyValue {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return attrs
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, member := range attr.Value.Group() {
+			attrs = appendOTLPAttr(attrs, prefix, member)
+		}
+		return attrs
+	}
+
+	return append(attrs, otlpKeyValue{Key: prefix + attr.Key, Value: otlpValue(attr.Value)})
+}
+
+// otlpValue converts a slog value to an OTLP AnyValue
+func otlpValue(value slog.Value) otlpAnyValue {
+	switch value.Kind() {
+	case slog.KindString:
+		s := value.String()
+		return otlpAnyValue{StringValue: &s}
+	case slog.KindInt64:
+		// 64-bit integers are strings in the protobuf JSON mapping
+		s := strconv.FormatInt(value.Int64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindUint64:
+		s := strconv.FormatUint(value.Uint64(), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindFloat64:
+		f := value.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	case slog.KindBool:
+		b := value.Bool()
+		return otlpAnyValue{BoolValue: &b}
+	case slog.KindDuration:
+		s := strconv.FormatInt(int64(value.Duration()), 10)
+		return otlpAnyValue{IntValue: &s}
+	case slog.KindTime:
+		s := value.Time().Format(time.RFC3339Nano)
+		return otlpAnyValue{StringValue: &s}
+	}
+
+	// Other values are encoded as they are in JSON entries
+	any := value.Any()
+	if err, ok := any.(error); ok {
+		s := err.Error()
+		return otlpAnyValue{StringValue: &s}
+	}
+	if data, err := json.Marshal(any); err == nil {
+		s := string(data)
+		return otlpAnyValue{StringValue: &s}
+	}
+	s := fmt.Sprint(any)
+	return otlpAnyValue{StringValue: &s}
+}
+
+// otlpSeverity maps a slog level to the OTLP severity number and text
+func otlpSeverity(level slog.Level) (int, string) {
+	switch {
+	case level >= slog.LevelError:
+		return 17, "ERROR"
+	case level >= slog.LevelWarn:
+		return 13, "WARN"
+	case level >= slog.LevelInfo:
+		return 9, "INFO"
+	default:
+		return 5, "DEBUG"
+	}
+}
+
+// otlpTraceID converts an X-Ray trace ID such as
+// "1-5759e988-bd862e3fe1be46a994272793" to the 32 hex digits of an
+// OpenTelemetry trace ID, or returns "" if it is not one
+func otlpTraceID(traceID string) string {
+	if !strings.HasPrefix(traceID, "1-") {
+		return ""
+	}
+	hex := strings.ReplaceAll(strings.TrimPrefix(traceID, "1-"), "-", "")
+	if len(hex) != 32 {
+		return ""
+	}
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return ""
+		}
+	}
+	return hex
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: &value}}
+}
+
+// The types below follow the JSON encoding of the OTLP logs protocol
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string         `json:"timeUnixNano"`
+	SeverityNumber int            `json:"severityNumber"`
+	SeverityText   string         `json:"severityText"`
+	Body           otlpAnyValue   `json:"body"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID        string         `json:"traceId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
@@ -0,0 +1,259 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// collectorStub is a local OTLP/HTTP collector recording the exported entries
+type collectorStub struct {
+	mu       sync.Mutex
+	requests []otlpLogsRequest
+	headers  []http.Header
+	status   int
+}
+
+func newCollectorStub(t *testing.T) (*collectorStub, *httptest.Server) {
+	t.Helper()
+	stub := &collectorStub{status: http.StatusOK}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/logs" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+
+		var request otlpLogsRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		stub.mu.Lock()
+		defer stub.mu.Unlock()
+		stub.requests = append(stub.requests, request)
+		stub.headers = append(stub.headers, r.Header.Clone())
+		w.WriteHeader(stub.status)
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+// records returns the exported log records in order
+func (s *collectorStub) records() []otlpLogRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var records []otlpLogRecord
+	for _, request := range s.requests {
+		for _, resourceLogs := range request.ResourceLogs {
+			for _, scopeLogs := range resourceLogs.ScopeLogs {
+				records = append(records, scopeLogs.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+func newTestRecord(message string) slog.Record {
+	return slog.NewRecord(time.Now(), slog.LevelInfo, message, 0)
+}
+
+func attribute(record otlpLogRecord, key string) *otlpAnyValue {
+	for _, attr := range record.Attributes {
+		if attr.Key == key {
+			return &attr.Value
+		}
+	}
+	return nil
+}
+
+func TestStructuredLogger_OTLP(t *testing.T) {
+	stub, server := newCollectorStub(t)
+
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level:       service.LogLevelInfo,
+		ServiceName: "chargeback-api",
+		Version:     "1.2.3",
+		Sinks: []SinkConfig{{
+			Format: FormatOTLP,
+			OTLP: OTLPConfig{
+				Endpoint: server.URL,
+				Headers:  map[string]string{"X-Api-Key": "collector-key"},
+			},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	ctx := requestctx.WithRequestID(context.Background(), "req-123")
+	ctx = requestctx.WithTraceID(ctx, "1-5759e988-bd862e3fe1be46a994272793")
+	logger.Warn(ctx, "Chargeback rejected", map[string]interface{}{
+		"amount":      100.5,
+		"attempts":    3,
+		"card_number": testPANs[0],
+	})
+
+	if len(stub.records()) != 0 {
+		t.Fatal("Expected entries to be buffered until flushed")
+	}
+	if err := logger.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	records := stub.records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 exported record, got %d", len(records))
+	}
+	record := records[0]
+
+	if record.SeverityNumber != 13 || record.SeverityText != "WARN" {
+		t.Errorf("Expected WARN severity 13, got %s %d", record.SeverityText, record.SeverityNumber)
+	}
+	if record.Body.StringValue == nil || *record.Body.StringValue != "Chargeback rejected" {
+		t.Errorf("Expected the message as body, got %+v", record.Body)
+	}
+	if record.TraceID != "5759e988bd862e3fe1be46a994272793" {
+		t.Errorf("Expected the X-Ray trace ID in OTLP form, got %q", record.TraceID)
+	}
+	if record.TimeUnixNano == "" {
+		t.Error("Expected a timestamp")
+	}
+
+	if v := attribute(record, "request_id"); v == nil || *v.StringValue != "req-123" {
+		t.Errorf("Expected the request ID attribute, got %+v", v)
+	}
+	if v := attribute(record, "attempts"); v == nil || v.IntValue == nil || *v.IntValue != "3" {
+		t.Errorf("Expected an int attribute, got %+v", v)
+	}
+	if v := attribute(record, "amount"); v == nil || v.DoubleValue == nil || *v.DoubleValue != 100.5 {
+		t.Errorf("Expected a double attribute, got %+v", v)
+	}
+	if v := attribute(record, "card_number"); v == nil || *v.StringValue != RedactedValue {
+		t.Errorf("Expected the card number to be redacted, got %+v", v)
+	}
+
+	resource := stub.requests[0].ResourceLogs[0].Resource
+	if len(resource.Attributes) != 2 || *resource.Attributes[0].Value.StringValue != "chargeback-api" {
+		t.Errorf("Expected service resource attributes, got %+v", resource.Attributes)
+	}
+	if stub.headers[0].Get("X-Api-Key") != "collector-key" {
+		t.Errorf("Expected the configured headers, got %v", stub.headers[0])
+	}
+}
+
+func TestOTLPExporter_Batching(t *testing.T) {
+	stub, server := newCollectorStub(t)
+	exporter := NewOTLPExporter(OTLPConfig{Endpoint: server.URL, BatchSize: 3}, "", "")
+	handler := NewOTLPHandler(exporter)
+	ctx := context.Background()
+
+	for i := 0; i < 7; i++ {
+		record := newTestRecord("entry")
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatalf("Handle() error = %v", err)
+		}
+	}
+
+	stub.mu.Lock()
+	requests := len(stub.requests)
+	stub.mu.Unlock()
+	if requests != 2 {
+		t.Errorf("Expected 2 full batches exported, got %d", requests)
+	}
+
+	if err := exporter.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := len(stub.records()); got != 7 {
+		t.Errorf("Expected all 7 entries after close, got %d", got)
+	}
+}
+
+func TestOTLPExporter_FlushInterval(t *testing.T) {
+	stub, server := newCollectorStub(t)
+	exporter := NewOTLPExporter(OTLPConfig{Endpoint: server.URL, FlushInterval: 10 * time.Millisecond}, "", "")
+	defer exporter.Close(context.Background())
+
+	NewOTLPHandler(exporter).Handle(context.Background(), newTestRecord("background"))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(stub.records()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the entry to be exported in the background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestOTLPExporter_CollectorFailure(t *testing.T) {
+	stub, server := newCollectorStub(t)
+	stub.status = http.StatusServiceUnavailable
+
+	var errorLog bytes.Buffer
+	exporter := NewOTLPExporter(OTLPConfig{Endpoint: server.URL, BatchSize: 1, ErrorLog: &errorLog}, "", "")
+
+	if err := NewOTLPHandler(exporter).Handle(context.Background(), newTestRecord("lost")); err == nil {
+		t.Error("Expected the failed export to be returned")
+	}
+	if !strings.Contains(errorLog.String(), "status 503") {
+		t.Errorf("Expected the failure in the error log, got %q", errorLog.String())
+	}
+
+	// The failed batch is dropped rather than retried forever
+	stub.status = http.StatusOK
+	exporter.Flush(context.Background())
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if len(stub.requests) != 1 {
+		t.Errorf("Expected no retry of the dropped batch, got %d requests", len(stub.requests))
+	}
+}
+
+func TestOTLPHandler_Groups(t *testing.T) {
+	stub, server := newCollectorStub(t)
+	exporter := NewOTLPExporter(OTLPConfig{Endpoint: server.URL}, "", "")
+	handler := NewOTLPHandler(exporter).WithAttrs(nil).WithGroup("http")
+
+	record := newTestRecord("grouped")
+	record.AddAttrs(slog.Group("request", "method", "POST"))
+	handler.Handle(context.Background(), record)
+	exporter.Flush(context.Background())
+
+	records := stub.records()
+	if len(records) != 1 {
+		t.Fatalf("Expected 1 record, got %d", len(records))
+	}
+	if v := attribute(records[0], "http.request.method"); v == nil || *v.StringValue != "POST" {
+		t.Errorf("Expected groups flattened into dotted keys, got %+v", records[0].Attributes)
+	}
+}
+
+func TestOTLPTraceID(t *testing.T) {
+	tests := map[string]string{
+		"1-5759e988-bd862e3fe1be46a994272793": "5759e988bd862e3fe1be46a994272793",
+		"":                                    "",
+		"Root=1-5759e988-bd862e3fe1be46a994272793": "",
+		"1-5759e988-bd862e3fe1be46a99427279Z":      "",
+		"1-5759e988-bd862e":                        "",
+	}
+	for input, want := range tests {
+		if got := otlpTraceID(input); got != want {
+			t.Errorf("otlpTraceID(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// RedactedValue replaces every value removed from a log entry
+const RedactedValue = "[REDACTED]"
+
+// maxRedactDepth bounds the traversal of nested values, guarding against cycles
+const maxRedactDepth = 16
+
+// DefaultRedactKeys lists the field names whose values are never logged.
+// Names are compared ignoring case, "_", "-" and ".", and also match the
+// trailing words of a name, so "card_number" covers "cardNumber" and
+// "masked_card_number", while "pan" does not cover "span_id".
+var DefaultRedactKeys = []string{
+	"card_number",
+	"pan",
+	"cvv",
+	"cvc",
+	"security_code",
+	"password",
+	"secret",
+	"token",
+	"api_key",
+	"authorization",
+	"cookie",
+	"email",
+	"cpf",
+	"ssn",
+}
+
+var (
+	// digitRunPattern matches digit sequences, optionally split by single
+	// spaces or dashes as card numbers are often written
+	digitRunPattern = regexp.MustCompile(`\d(?:[ -]?\d)*`)
+
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+
+	// cpfPattern matches Brazilian taxpayer IDs such as 123.456.789-09
+	cpfPattern = regexp.MustCompile(`\b\d{3}\.\d{3}\.\d{3}-\d{2}\b`)
+
+	// ssnPattern matches US social security numbers such as 123-45-6789
+	ssnPattern = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+)
+
+// Redactor removes sensitive data from log values: values of denylisted keys,
+// struct fields tagged `log:"redact"`, and card numbers that pass the Luhn
+// check, emails, CPFs and SSNs found anywhere in strings
+type Redactor struct {
+	keys []string
+}
+
+// NewRedactor creates a redactor denying DefaultRedactKeys and the given keys
+func NewRedactor(keys []string) *Redactor {
+	normalized := make([]string, 0, len(DefaultRedactKeys)+len(keys))
+	for _, key := range append(append([]string(nil), DefaultRedactKeys...), keys...) {
+		if key = normalizeKey(key); key != "" {
+			normalized = append(normalized, key)
+		}
+	}
+	return &Redactor{keys: normalized}
+}
+
+// normalizeKey lowercases a field name and drops separators
+func normalizeKey(key string) string {
+	return strings.Join(keyWords(key), "")
+}
+
+// keyWords splits a field name into lowercase words at "_", "-", ".", spaces
+// and camelCase boundaries, so "maskedCardNumber" and "APIKey" give
+// masked, card, number and api, key
+func keyWords(key string) []string {
+	var words []string
+	var word []rune
+	runes := []rune(key)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' || r == ' ' {
+			if len(word) > 0 {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(word) > 0 {
+			previous := runes[i-1]
+			acronymEnd := unicode.IsUpper(previous) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(previous) || acronymEnd {
+				words = append(words, string(word))
+				word = word[:0]
+			}
+		}
+		word = append(word, unicode.ToLower(r))
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	return words
+}
+
+// DeniesKey reports whether values logged under key are always redacted: a
+// denylisted name matches the whole key or its trailing words
+func (r *Redactor) DeniesKey(key string) bool {
+	words := keyWords(key)
+	for i := range words {
+		if slices.Contains(r.keys, strings.Join(words[i:], "")) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString replaces card numbers, emails, CPFs and SSNs in s
+func (r *Redactor) RedactString(s string) string {
+	s = emailPattern.ReplaceAllString(s, RedactedValue)
+	s = cpfPattern.ReplaceAllString(s, RedactedValue)
+	s = ssnPattern.ReplaceAllString(s, RedactedValue)
+	return digitRunPattern.ReplaceAllStringFunc(s, func(run string) string {
+		if containsPAN(run) {
+			return RedactedValue
+		}
+		return run
+	})
+}
+
+// containsPAN reports whether a digit run holds a card number: 13 to 19
+// digits passing the Luhn check, made of whole groups of the run. Groups too
+// long to be a card number are searched for one at every offset.
+func containsPAN(run string) bool {
+	groups := strings.FieldsFunc(run, func(r rune) bool { return r == ' ' || r == '-' })
+	for i := range groups {
+		if len(groups[i]) > 19 && containsLuhnWindow(groups[i]) {
+			return true
+		}
+
+		digits := ""
+		for _, group := range groups[i:] {
+			digits += group
+			if len(digits) > 19 {
+				break
+			}
+			if len(digits) >= 13 && luhnValid(digits) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// containsLuhnWindow reports whether any 13 to 19 consecutive digits pass the
+// Luhn check
+func containsLuhnWindow(digits string) bool {
+	for start := 0; start+13 <= len(digits); start++ {
+		for end := start + 13; end <= len(digits) && end-start <= 19; end++ {
+			if luhnValid(digits[start:end]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the digits pass the Luhn checksum
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// RedactAttr returns the attribute with sensitive data removed
+func (r *Redactor) RedactAttr(attr slog.Attr) slog.Attr {
+	attr.Value = attr.Value.Resolve()
+	if r.DeniesKey(attr.Key) {
+		return slog.String(attr.Key, RedactedValue)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, r.RedactString(attr.Value.String()))
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+		if r.redactValue(attr.Value.Any(), 0) == RedactedValue {
+			return slog.String(attr.Key, RedactedValue)
+		}
+	case slog.KindGroup:
+		group := attr.Value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = r.RedactAttr(member)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		return slog.Any(attr.Key, r.Redact(attr.Value.Any()))
+	}
+	return attr
+}
+
+// Redact returns a copy of value safe to log. Structs, maps and slices are
+// converted to generic maps and slices so they serialize like the original.
+func (r *Redactor) Redact(value interface{}) interface{} {
+	return r.redactValue(value, 0)
+}
+
+func (r *Redactor) redactValue(value interface{}, depth int) interface{} {
+	if depth > maxRedactDepth {
+		return RedactedValue
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.RedactString(v)
+	case []byte:
+		return r.RedactString(string(v))
+	case time.Time, time.Duration:
+		return v
+	case error:
+		return r.RedactString(v.Error())
+	case json.Marshaler:
+		// Redact what the value would have been encoded as
+		data, err := v.MarshalJSON()
+		if err != nil {
+			return RedactedValue
+		}
+		var decoded interface{}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return RedactedValue
+		}
+		return r.redactValue(decoded, depth+1)
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return r.redactValue(rv.Elem().Interface(), depth+1)
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		r.redactStruct(rv, fields, depth)
+		return fields
+	case reflect.Map:
+		fields := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			if r.DeniesKey(key) {
+				fields[key] = RedactedValue
+				continue
+			}
+			fields[key] = r.redactValue(iter.Value().Interface(), depth+1)
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, rv.Len())
+		for i := range items {
+			items[i] = r.redactValue(rv.Index(i).Interface(), depth+1)
+		}
+		return items
+	case reflect.String:
+		return r.RedactString(rv.String())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if containsPAN(strconv.FormatInt(rv.Int(), 10)) {
+			return RedactedValue
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if containsPAN(strconv.FormatUint(rv.Uint(), 10)) {
+			return RedactedValue
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == float64(int64(f)) && containsPAN(strconv.FormatInt(int64(f), 10)) {
+			return RedactedValue
+		}
+	}
+	return value
+}
+
+// redactStruct adds the exported fields of a struct to fields under their
+// JSON names, flattening embedded structs as encoding/json does
+func (r *Redactor) redactStruct(rv reflect.Value, fields map[string]interface{}, depth int) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+
+		value := rv.Field(i)
+		if field.Anonymous && name == "" {
+			if value.Kind() == reflect.Pointer {
+				if value.IsNil() {
+					continue
+				}
+				value = value.Elem()
+			}
+			if value.Kind() == reflect.Struct {
+				r.redactStruct(value, fields, depth)
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+		if field.Tag.Get("log") == "redact" || r.DeniesKey(name) {
+			fields[name] = RedactedValue
+			continue
+		}
+		fields[name] = r.redactValue(value.Interface(), depth+1)
+	}
+}
+
+// RedactingHandler is a slog.Handler that removes sensitive data from the
+// message and every attribute before passing records on
+type RedactingHandler struct {
+	next     slog.Handler
+	redactor *Redactor
+}
+
+// NewRedactingHandler wraps next so nothing it writes carries sensitive data
+func NewRedactingHandler(next slog.Handler, redactor *Redactor) *RedactingHandler {
+	return &RedactingHandler{next: next, redactor: redactor}
+}
+
+// Enabled reports whether the wrapped handler handles records at level
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle redacts the record and passes it on
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.RedactString(record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactor.RedactAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs redacts the attributes before binding them to the wrapped handler
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactor.RedactAttr(attr)
+	}
+	return &RedactingHandler{next: h.next.WithAttrs(redacted), redactor: h.redactor}
+}
+
+// WithGroup returns a handler whose attributes are nested in the named group
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{next: h.next.WithGroup(name), redactor: h.redactor}
+}
@@ -0,0 +1,206 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+// testPANs are well-known test card numbers of every common length and brand
+var testPANs = []string{
+	"4111111111111111",    // Visa
+	"4222222222222",       // Visa, 13 digits
+	"5555555555554444",    // Mastercard
+	"2223003122003222",    // Mastercard 2-series
+	"378282246310005",     // American Express
+	"6011111111111117",    // Discover
+	"30569309025904",      // Diners Club
+	"3530111333300000",    // JCB
+	"6759649826438453",    // Maestro
+	"6011000990139424884", // 19 digits
+}
+
+// assertNoPAN fails if the output holds the PAN, even with separators removed
+func assertNoPAN(t *testing.T, output, pan string) {
+	t.Helper()
+	compact := strings.NewReplacer(" ", "", "-", "").Replace(output)
+	if strings.Contains(output, pan) || strings.Contains(compact, pan) {
+		t.Errorf("PAN %s leaked into output: %s", pan, output)
+	}
+}
+
+// formatPAN writes the PAN with the given separator every four digits
+func formatPAN(pan, sep string) string {
+	var groups []string
+	for i := 0; i < len(pan); i += 4 {
+		groups = append(groups, pan[i:min(i+4, len(pan))])
+	}
+	return strings.Join(groups, sep)
+}
+
+func TestRedactor_RedactString(t *testing.T) {
+	redactor := NewRedactor(nil)
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"plain PAN", "card 4111111111111111 declined", "card [REDACTED] declined"},
+		{"spaced PAN", "card 4111 1111 1111 1111", "card [REDACTED]"},
+		{"dashed PAN", "4111-1111-1111-1111", "[REDACTED]"},
+		{"PAN inside a word", "pan=4111111111111111;", "pan=[REDACTED];"},
+		{"PAN inside a longer number", "ref 0004111111111111111000", "ref [REDACTED]"},
+		{"number failing Luhn", "order 4111111111111112", "order 4111111111111112"},
+		{"short numbers", "amount 150.75 on 2024-01-15", "amount 150.75 on 2024-01-15"},
+		{"masked PAN", "****-****-****-1111", "****-****-****-1111"},
+		{"email", "contact john.doe@example.com now", "contact [REDACTED] now"},
+		{"CPF", "cpf 123.456.789-09", "cpf [REDACTED]"},
+		{"SSN", "ssn 123-45-6789", "ssn [REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactor.RedactString(tt.input); got != tt.want {
+				t.Errorf("RedactString(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactor_DeniesKey(t *testing.T) {
+	redactor := NewRedactor([]string{"iban"})
+
+	for _, key := range []string{"card_number", "cardNumber", "cardnumber", "CARD-NUMBER", "masked_card_number", "password", "client_secret", "Authorization", "email", "iban", "pan", "PAN", "card.pan", "APIKey", "x-api-key", "accessToken"} {
+		if !redactor.DeniesKey(key) {
+			t.Errorf("Expected key %q to be denied", key)
+		}
+	}
+	for _, key := range []string{"merchant_id", "amount", "status_code", "transaction_id", "span_id", "spanID", "company", "japan", "secretary", "token_expires_at"} {
+		if redactor.DeniesKey(key) {
+			t.Errorf("Expected key %q to be allowed", key)
+		}
+	}
+}
+
+func TestRedactor_StructTags(t *testing.T) {
+	type Account struct {
+		Holder string `json:"holder" log:"redact"`
+		Notes  string `json:"notes"`
+		Limit  int    `json:"limit"`
+	}
+	type wrapper struct {
+		Account
+		Accounts []*Account `json:"accounts"`
+	}
+
+	value := wrapper{
+		Account:  Account{Holder: "Jane Roe", Notes: "paid with 5555555555554444", Limit: 100},
+		Accounts: []*Account{{Holder: "John Roe", Limit: 50}},
+	}
+
+	got := fmt.Sprint(NewRedactor(nil).Redact(value))
+
+	if strings.Contains(got, "Roe") {
+		t.Errorf("Expected tagged fields to be redacted, got %s", got)
+	}
+	assertNoPAN(t, got, "5555555555554444")
+	if !strings.Contains(got, "limit:100") || !strings.Contains(got, "limit:50") {
+		t.Errorf("Expected untagged fields to be kept, got %s", got)
+	}
+}
+
+// TestStructuredLogger_NeverLogsPAN logs test card numbers through every path
+// a value can reach the output and checks none of them is written
+func TestStructuredLogger_NeverLogsPAN(t *testing.T) {
+	type nested struct {
+		Detail string
+		Values []interface{}
+	}
+
+	for _, format := range []LogFormat{FormatJSON, FormatText} {
+		for _, pan := range testPANs {
+			t.Run(format.String()+"/"+pan, func(t *testing.T) {
+				var buf bytes.Buffer
+				logger, err := NewStructuredLogger(LoggerConfig{Level: service.LogLevelDebug, Format: format}, &buf)
+				if err != nil {
+					t.Fatalf("NewStructuredLogger() error = %v", err)
+				}
+				ctx := context.Background()
+
+				var panNumber int64
+				fmt.Sscan(pan, &panNumber)
+
+				logger.Info(ctx, "Charge for "+pan+" failed")
+				logger.Info(ctx, "Spaced", map[string]interface{}{"detail": formatPAN(pan, " ")})
+				logger.Info(ctx, "Dashed", map[string]interface{}{"detail": formatPAN(pan, "-")})
+				logger.Info(ctx, "Denied key", map[string]interface{}{"card_number": "x" + pan})
+				logger.Warn(ctx, "Number", map[string]interface{}{"value": panNumber, "float": float64(panNumber)})
+				logger.Error(ctx, "Error", map[string]interface{}{"error": errors.New("declined card " + pan)})
+				logger.Debug(ctx, "Nested", map[string]interface{}{
+					"payload": map[string]interface{}{"inner": []string{"a", pan}},
+					"struct":  &nested{Detail: pan, Values: []interface{}{pan, map[int]string{1: pan}}},
+					"bytes":   []byte(pan),
+				})
+				logger.Info(ctx, "Request", map[string]interface{}{
+					"request": usecase.CreateChargebackRequest{CardNumber: pan, Description: "card " + pan},
+				})
+				logger.WithContext(ctx).Info(ctx, "Bound", map[string]interface{}{"detail": "ref:" + pan + ":end"})
+
+				slogger := slog.New(logger.logger.Handler())
+				slogger.With("bound", pan).WithGroup("group").Info("Group", "detail", pan, slog.Group("inner", "value", pan))
+
+				output := buf.String()
+				if strings.Count(output, "\n") != 10 {
+					t.Fatalf("Expected 10 log lines, got:\n%s", output)
+				}
+				assertNoPAN(t, output, pan)
+			})
+		}
+	}
+}
+
+// TestRedactor_RandomPANs checks generated Luhn-valid card numbers of every
+// length are redacted wherever they appear in a string
+func TestRedactor_RandomPANs(t *testing.T) {
+	redactor := NewRedactor(nil)
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 2000; i++ {
+		pan := randomPAN(rng, 13+rng.Intn(7))
+		formatted := pan
+		switch rng.Intn(3) {
+		case 1:
+			formatted = formatPAN(pan, " ")
+		case 2:
+			formatted = formatPAN(pan, "-")
+		}
+
+		input := fmt.Sprintf("prefix-%d %s|suffix", rng.Intn(1000), formatted)
+		assertNoPAN(t, redactor.RedactString(input), pan)
+	}
+}
+
+// randomPAN returns a random Luhn-valid number of the given length
+func randomPAN(rng *rand.Rand, length int) string {
+	digits := make([]byte, length)
+	digits[0] = byte('1' + rng.Intn(9))
+	for i := 1; i < length-1; i++ {
+		digits[i] = byte('0' + rng.Intn(10))
+	}
+	for check := byte('0'); check <= '9'; check++ {
+		digits[length-1] = check
+		if luhnValid(string(digits)) {
+			break
+		}
+	}
+	return string(digits)
+}
@@ -0,0 +1,282 @@
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat names rotated files, sorting them chronologically
+const rotatedTimeFormat = "20060102T150405.000"
+
+// FileConfig configures a RotatingFile
+type FileConfig struct {
+	// Path of the active log file; rotated files are written next to it as
+	// "<name>-<timestamp><ext>"
+	Path string
+
+	// MaxBytes rotates the file before it would grow beyond this size,
+	// disabled when zero
+	MaxBytes int64
+
+	// RotateEvery rotates the file once it has been written for this long,
+	// disabled when zero
+	RotateEvery time.Duration
+
+	// MaxBackups is how many rotated files are kept, all when zero
+	MaxBackups int
+
+	// ErrorLog receives failed rotations, os.Stderr if nil. They do not fail
+	// the write, which lands in the active file, and cannot be logged
+	// through the logger itself.
+	ErrorLog io.Writer
+}
+
+// Validate checks the file configuration
+func (c FileConfig) Validate() error {
+	if c.Path == "" {
+		return errors.New("path is required")
+	}
+	if c.MaxBytes < 0 || c.RotateEvery < 0 || c.MaxBackups < 0 {
+		return errors.New("max bytes, rotation interval and max backups must not be negative")
+	}
+	return nil
+}
+
+// RotatingFile is an io.WriteCloser appending to a file that is rotated by
+// size and age. Each Write lands in a single file, so entries are never
+// split. It is safe for concurrent use.
+type RotatingFile struct {
+	config FileConfig
+	now    func() time.Time
+	rename func(oldpath, newpath string) error
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// OpenRotatingFile opens or creates the log file, appending to it
+func OpenRotatingFile(config FileConfig) (*RotatingFile, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.ErrorLog == nil {
+		config.ErrorLog = os.Stderr
+	}
+
+	f := &RotatingFile{config: config, now: time.Now, rename: os.Rename}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the active file, creating its directory if needed
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.config.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+// Write appends p, rotating the file first when p would exceed MaxBytes or
+// the file is older than RotateEvery. A failed rotation is reported to
+// ErrorLog and p written to whichever file is open.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if f.shouldRotate(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return 0, err
+			}
+			fmt.Fprintf(f.config.ErrorLog, "rotating log file: %v\n", err)
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// shouldRotate reports whether writing n bytes requires a new file. An empty
+// file is never rotated, so entries larger than MaxBytes are still written.
+func (f *RotatingFile) shouldRotate(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.config.MaxBytes > 0 && f.size+n > f.config.MaxBytes {
+		return true
+	}
+	return f.config.RotateEvery > 0 && f.now().Sub(f.openedAt) >= f.config.RotateEvery
+}
+
+// rotate renames the active file with a timestamp, opens a new one and
+// removes backups beyond MaxBackups. An error leaves an open file to write
+// to, unless reopening failed.
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to close log file: %w", err), f.open())
+	}
+
+	if err := f.rename(f.config.Path, f.backupName()); err != nil {
+		// Keep appending to the active file, rotating again on a later write
+		return errors.Join(fmt.Errorf("failed to rotate log file: %w", err), f.open())
+	}
+
+	if err := f.open(); err != nil {
+		return err
+	}
+
+	return f.prune()
+}
+
+// backupName returns an unused name for the rotated file
+func (f *RotatingFile) backupName() string {
+	ext := filepath.Ext(f.config.Path)
+	base := strings.TrimSuffix(f.config.Path, ext)
+	stamp := f.now().UTC().Format(rotatedTimeFormat)
+
+	name := base + "-" + stamp + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+			return name
+		}
+		name = fmt.Sprintf("%s-%s.%d%s", base, stamp, i, ext)
+	}
+}
+
+// backups returns the rotated files, oldest first. Only names written by
+// backupName are listed, so other files sharing the prefix, such as
+// api-audit.log next to api.log, are never pruned.
+func (f *RotatingFile) backups() ([]string, error) {
+	ext := filepath.Ext(f.config.Path)
+	base := strings.TrimSuffix(f.config.Path, ext)
+
+	matches, err := filepath.Glob(escapeGlob(base) + "-*" + escapeGlob(ext))
+	if err != nil {
+		return nil, err
+	}
+
+	type backup struct {
+		name    string
+		rotated time.Time
+		n       int
+	}
+	backups := make([]backup, 0, len(matches))
+	for _, match := range matches {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(match, base+"-"), ext)
+		if rotated, n, ok := parseBackupSuffix(suffix); ok {
+			backups = append(backups, backup{name: match, rotated: rotated, n: n})
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if !backups[i].rotated.Equal(backups[j].rotated) {
+			return backups[i].rotated.Before(backups[j].rotated)
+		}
+		return backups[i].n < backups[j].n
+	})
+
+	names := make([]string, len(backups))
+	for i, backup := range backups {
+		names[i] = backup.name
+	}
+	return names, nil
+}
+
+// parseBackupSuffix parses the "<timestamp>[.N]" part of a rotated file
+// name, N numbering the files rotated within the same millisecond
+func parseBackupSuffix(suffix string) (time.Time, int, bool) {
+	if len(suffix) < len(rotatedTimeFormat) {
+		return time.Time{}, 0, false
+	}
+	rotated, err := time.Parse(rotatedTimeFormat, suffix[:len(rotatedTimeFormat)])
+	if err != nil {
+		return time.Time{}, 0, false
+	}
+
+	rest := suffix[len(rotatedTimeFormat):]
+	if rest == "" {
+		return rotated, 0, true
+	}
+	if !strings.HasPrefix(rest, ".") {
+		return time.Time{}, 0, false
+	}
+	n, err := strconv.Atoi(rest[1:])
+	if err != nil || n < 1 || strconv.Itoa(n) != rest[1:] {
+		return time.Time{}, 0, false
+	}
+	return rotated, n, true
+}
+
+// prune removes the oldest rotated files beyond MaxBackups
+func (f *RotatingFile) prune() error {
+	if f.config.MaxBackups == 0 {
+		return nil
+	}
+
+	backups, err := f.backups()
+	if err != nil {
+		return fmt.Errorf("failed to list rotated log files: %w", err)
+	}
+
+	var errs []error
+	for len(backups) > f.config.MaxBackups {
+		errs = append(errs, os.Remove(backups[0]))
+		backups = backups[1:]
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to remove rotated log files: %w", err)
+	}
+	return nil
+}
+
+// Close closes the active file
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// escapeGlob escapes the pattern characters of a path for filepath.Glob
+func escapeGlob(path string) string {
+	return strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`).Replace(path)
+}
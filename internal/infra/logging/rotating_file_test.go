@@ -0,0 +1,264 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestFile(t *testing.T, config FileConfig) (*RotatingFile, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+
+	file, err := OpenRotatingFile(config)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile() error = %v", err)
+	}
+	file.now = clock.Now
+	file.openedAt = clock.Now()
+	t.Cleanup(func() { file.Close() })
+	return file, clock
+}
+
+func rotatedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "api-*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestRotatingFile_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	file, clock := openTestFile(t, FileConfig{Path: path, MaxBytes: 20})
+
+	file.Write([]byte("first entry 1234\n"))
+	clock.Advance(time.Millisecond)
+	file.Write([]byte("second entry 123\n"))
+
+	backups := rotatedFiles(t, dir)
+	if len(backups) != 1 {
+		t.Fatalf("Expected 1 rotated file, got %v", backups)
+	}
+	if filepath.Base(backups[0]) != "api-20240115T100000.001.log" {
+		t.Errorf("Expected a timestamped backup name, got %s", filepath.Base(backups[0]))
+	}
+
+	rotated, _ := os.ReadFile(backups[0])
+	current, _ := os.ReadFile(path)
+	if string(rotated) != "first entry 1234\n" || string(current) != "second entry 123\n" {
+		t.Errorf("Expected each entry in its own file, got %q and %q", rotated, current)
+	}
+}
+
+func TestRotatingFile_RotatesByAge(t *testing.T) {
+	dir := t.TempDir()
+	file, clock := openTestFile(t, FileConfig{Path: filepath.Join(dir, "api.log"), RotateEvery: time.Hour})
+
+	file.Write([]byte("morning\n"))
+	clock.Advance(30 * time.Minute)
+	file.Write([]byte("still morning\n"))
+	if len(rotatedFiles(t, dir)) != 0 {
+		t.Fatal("Expected no rotation before the interval")
+	}
+
+	clock.Advance(31 * time.Minute)
+	file.Write([]byte("afternoon\n"))
+	if len(rotatedFiles(t, dir)) != 1 {
+		t.Error("Expected a rotation after the interval")
+	}
+}
+
+func TestRotatingFile_MaxBackups(t *testing.T) {
+	dir := t.TempDir()
+	file, clock := openTestFile(t, FileConfig{Path: filepath.Join(dir, "api.log"), MaxBytes: 1, MaxBackups: 2})
+
+	for _, entry := range []string{"a\n", "b\n", "c\n", "d\n", "e\n"} {
+		file.Write([]byte(entry))
+		clock.Advance(time.Second)
+	}
+
+	backups := rotatedFiles(t, dir)
+	if len(backups) != 2 {
+		t.Fatalf("Expected 2 backups to be kept, got %v", backups)
+	}
+	newest, _ := os.ReadFile(backups[1])
+	if string(newest) != "d\n" {
+		t.Errorf("Expected the newest backups to be kept, got %q", newest)
+	}
+}
+
+func TestRotatingFile_MaxBackupsIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	audit := filepath.Join(dir, "api-audit.log")
+	if err := os.WriteFile(audit, []byte("audit\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	file, clock := openTestFile(t, FileConfig{Path: filepath.Join(dir, "api.log"), MaxBytes: 1, MaxBackups: 1})
+
+	for _, entry := range []string{"a\n", "b\n", "c\n"} {
+		file.Write([]byte(entry))
+		clock.Advance(time.Second)
+	}
+
+	if data, err := os.ReadFile(audit); err != nil || string(data) != "audit\n" {
+		t.Errorf("Expected the sibling file to be left alone, got %q, %v", data, err)
+	}
+	backups, err := file.backups()
+	if err != nil || len(backups) != 1 {
+		t.Fatalf("Expected 1 backup to be kept, got %v, %v", backups, err)
+	}
+	if newest, _ := os.ReadFile(backups[0]); string(newest) != "b\n" {
+		t.Errorf("Expected the newest backup to be kept, got %q", newest)
+	}
+}
+
+func TestRotatingFile_BackupsWithinAMillisecond(t *testing.T) {
+	dir := t.TempDir()
+	file, _ := openTestFile(t, FileConfig{Path: filepath.Join(dir, "api.log"), MaxBytes: 1})
+
+	for _, entry := range []string{"a\n", "b\n", "c\n"} {
+		file.Write([]byte(entry))
+	}
+
+	backups, err := file.backups()
+	if err != nil || len(backups) != 2 {
+		t.Fatalf("Expected 2 backups, got %v, %v", backups, err)
+	}
+	oldest, _ := os.ReadFile(backups[0])
+	newest, _ := os.ReadFile(backups[1])
+	if string(oldest) != "a\n" || string(newest) != "b\n" {
+		t.Errorf("Expected backups oldest first, got %q and %q", oldest, newest)
+	}
+}
+
+func TestParseBackupSuffix(t *testing.T) {
+	tests := []struct {
+		suffix string
+		n      int
+		ok     bool
+	}{
+		{suffix: "20240115T100000.001", ok: true},
+		{suffix: "20240115T100000.001.2", n: 2, ok: true},
+		{suffix: "audit"},
+		{suffix: "20240115T100000.001-old"},
+		{suffix: "20240115T100000.001.0"},
+		{suffix: "20240115T100000.001.02"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.suffix, func(t *testing.T) {
+			_, n, ok := parseBackupSuffix(tt.suffix)
+			if ok != tt.ok || n != tt.n {
+				t.Errorf("parseBackupSuffix(%q) = %d, %v, want %d, %v", tt.suffix, n, ok, tt.n, tt.ok)
+			}
+		})
+	}
+}
+
+func TestRotatingFile_AppendsAndKeepsLargeEntries(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "api.log")
+	if err := os.WriteFile(path, []byte("existing\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ := openTestFile(t, FileConfig{Path: path, MaxBytes: 1024})
+	file.Write([]byte("appended\n"))
+	file.Close()
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "existing\nappended\n" {
+		t.Errorf("Expected entries appended to the existing file, got %q", data)
+	}
+
+	// An entry larger than the limit is written whole to a fresh file
+	large := strings.Repeat("x", 2048) + "\n"
+	file, _ = openTestFile(t, FileConfig{Path: path, MaxBytes: 1024})
+	if _, err := file.Write([]byte(large)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, _ = os.ReadFile(path)
+	if string(data) != large {
+		t.Errorf("Expected the large entry whole in the active file, got %d bytes", len(data))
+	}
+}
+
+func TestRotatingFile_WriteAfterClose(t *testing.T) {
+	file, _ := openTestFile(t, FileConfig{Path: filepath.Join(t.TempDir(), "api.log")})
+	file.Close()
+	if _, err := file.Write([]byte("late\n")); err == nil {
+		t.Error("Expected writes after close to fail")
+	}
+}
+
+func TestRotatingFile_PruneFailureKeepsEntry(t *testing.T) {
+	// Arrange: the oldest backup is a directory that cannot be removed
+	dir := t.TempDir()
+	stuck := filepath.Join(dir, "api-20240101T000000.000.log")
+	if err := os.MkdirAll(filepath.Join(stuck, "keep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var errorLog bytes.Buffer
+	file, clock := openTestFile(t, FileConfig{Path: filepath.Join(dir, "api.log"), MaxBytes: 10, MaxBackups: 1, ErrorLog: &errorLog})
+
+	// Act
+	file.Write([]byte("first\n"))
+	clock.Advance(time.Second)
+	n, err := file.Write([]byte("second\n"))
+
+	// Assert
+	if err != nil || n != len("second\n") {
+		t.Fatalf("Expected the entry written despite the prune failure, got %d, %v", n, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "api.log"))
+	if string(data) != "second\n" {
+		t.Errorf("Expected the entry in the new file, got %q", data)
+	}
+	if !strings.Contains(errorLog.String(), "failed to remove rotated log files") {
+		t.Errorf("Expected the prune failure in the error log, got %q", errorLog.String())
+	}
+}
+
+func TestRotatingFile_RenameFailureReopens(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	var errorLog bytes.Buffer
+	file, _ := openTestFile(t, FileConfig{Path: filepath.Join(dir, "api.log"), MaxBytes: 10, ErrorLog: &errorLog})
+	file.rename = func(oldpath, newpath string) error { return errors.New("device busy") }
+
+	// Act
+	file.Write([]byte("first\n"))
+	n, err := file.Write([]byte("second\n"))
+
+	// Assert
+	if err != nil || n != len("second\n") {
+		t.Fatalf("Expected the entry appended despite the rename failure, got %d, %v", n, err)
+	}
+	if !strings.Contains(errorLog.String(), "device busy") {
+		t.Errorf("Expected the rename failure in the error log, got %q", errorLog.String())
+	}
+
+	// Rotation succeeds once renaming works again
+	file.rename = os.Rename
+	if _, err := file.Write([]byte("third\n")); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "api.log"))
+	if string(data) != "third\n" {
+		t.Errorf("Expected the active file rotated, got %q", data)
+	}
+	rotated := rotatedFiles(t, dir)
+	if len(rotated) != 1 {
+		t.Fatalf("Expected one rotated file, got %v", rotated)
+	}
+	if data, _ := os.ReadFile(rotated[0]); string(data) != "first\nsecond\n" {
+		t.Errorf("Expected the entries written before rotation kept, got %q", data)
+	}
+}
@@ -0,0 +1,303 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+const (
+	// DefaultSamplingInterval is the sampling window used when none is configured
+	DefaultSamplingInterval = time.Second
+
+	// DefaultSamplingReportInterval is how often sampled-out counts are
+	// reported when no interval is configured
+	DefaultSamplingReportInterval = time.Minute
+
+	// samplingBuckets is the number of counters per level. Messages are
+	// hashed into buckets, so memory stays bounded whatever is logged.
+	samplingBuckets = 4096
+
+	// otherMessages reports the entries sampled out once samplingBuckets
+	// distinct messages are already counted
+	otherMessages = "(other)"
+)
+
+// SamplingConfig limits how often the same message is logged. Within each
+// interval the first Initial entries with a given level and message are
+// logged, then every Thereafter-th one. Errors and audit entries, carrying
+// service.FieldAudit, are never sampled.
+type SamplingConfig struct {
+	// Initial is the number of entries logged per message and interval.
+	// Sampling is disabled when zero.
+	Initial int
+
+	// Thereafter logs one in every Thereafter entries past Initial, or none
+	// if zero
+	Thereafter int
+
+	// Interval is the sampling window, DefaultSamplingInterval if zero
+	Interval time.Duration
+
+	// ReportInterval is how often the number of sampled-out entries is
+	// logged, DefaultSamplingReportInterval if zero. Reports are written
+	// with the next entry once the interval has elapsed, and whenever the
+	// logger is flushed or closed.
+	ReportInterval time.Duration
+}
+
+// Enabled reports whether entries are sampled
+func (c SamplingConfig) Enabled() bool {
+	return c.Initial > 0
+}
+
+// Validate checks the sampling settings
+func (c SamplingConfig) Validate() error {
+	var errs []error
+	if c.Initial < 0 {
+		errs = append(errs, errors.New("initial must not be negative"))
+	}
+	if c.Thereafter < 0 {
+		errs = append(errs, errors.New("thereafter must not be negative"))
+	}
+	if c.Interval < 0 {
+		errs = append(errs, errors.New("interval must not be negative"))
+	}
+	if c.ReportInterval < 0 {
+		errs = append(errs, errors.New("report interval must not be negative"))
+	}
+	return errors.Join(errs...)
+}
+
+// SamplingHandler is a slog.Handler that drops repeated entries below the
+// error level according to a SamplingConfig
+type SamplingHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+// sampler holds the counters shared by a handler and every handler derived
+// from it with WithAttrs or WithGroup
+type sampler struct {
+	config SamplingConfig
+	now    func() time.Time
+
+	// counters holds the debug, info and warn buckets
+	counters [3][samplingBuckets]samplingCounter
+
+	// report receives the sampled-out counts; it is the handler the sampler
+	// was created with, so reports carry no request attributes
+	report slog.Handler
+
+	// redactor cleans the messages reported as keys, which the redacting
+	// handler below, redacting values only, would write as they are. It is
+	// the redactor of that handler.
+	redactor *Redactor
+
+	// nextReport is when the counts are next due, in Unix nanoseconds
+	nextReport atomic.Int64
+
+	mu      sync.Mutex
+	dropped map[string]uint64
+}
+
+// samplingCounter counts the entries of one bucket within the current interval
+type samplingCounter struct {
+	resetAt atomic.Int64
+	count   atomic.Uint64
+}
+
+// NewSamplingHandler wraps next so repeated entries are sampled, reporting
+// the sampled-out messages cleaned by redactor, NewRedactor(nil) if nil
+func NewSamplingHandler(next slog.Handler, config SamplingConfig, redactor *Redactor) *SamplingHandler {
+	if config.Interval == 0 {
+		config.Interval = DefaultSamplingInterval
+	}
+	if config.ReportInterval == 0 {
+		config.ReportInterval = DefaultSamplingReportInterval
+	}
+	if redactor == nil {
+		redactor = NewRedactor(nil)
+	}
+
+	return &SamplingHandler{
+		next: next,
+		sampler: &sampler{
+			config:   config,
+			now:      time.Now,
+			report:   next,
+			redactor: redactor,
+			dropped:  make(map[string]uint64),
+		},
+	}
+}
+
+// Enabled reports whether the wrapped handler handles records at level
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes the record on unless it is sampled out, reporting the
+// sampled-out counts first when they are due
+func (h *SamplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	now := h.sampler.now()
+	h.sampler.reportDropped(ctx, now)
+
+	if !h.sampler.allow(record, now) {
+		return nil
+	}
+	return h.next.Handle(ctx, record)
+}
+
+// Flush reports the entries sampled out so far without waiting for the report
+// interval, as none may follow, e.g. at the end of a Lambda invocation
+func (h *SamplingHandler) Flush(ctx context.Context) error {
+	now := h.sampler.now()
+	h.sampler.nextReport.Store(now.Add(h.sampler.config.ReportInterval).UnixNano())
+	return h.sampler.writeReport(ctx, now)
+}
+
+// WithAttrs returns a handler with the attributes bound that shares the
+// sampling counters
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup returns a handler with the group opened that shares the sampling
+// counters
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+// allow reports whether the record is logged, counting it otherwise
+func (s *sampler) allow(record slog.Record, now time.Time) bool {
+	if record.Level >= slog.LevelError || isAudit(record) {
+		return true
+	}
+
+	counter := &s.counters[levelBucket(record.Level)][messageBucket(record.Message)]
+	n := counter.inc(now, s.config.Interval)
+
+	initial := uint64(s.config.Initial)
+	if n <= initial || (s.config.Thereafter > 0 && (n-initial)%uint64(s.config.Thereafter) == 0) {
+		return true
+	}
+
+	s.mu.Lock()
+	message := record.Message
+	if _, ok := s.dropped[message]; !ok && len(s.dropped) >= samplingBuckets {
+		message = otherMessages
+	}
+	s.dropped[message]++
+	s.mu.Unlock()
+	return false
+}
+
+// isAudit reports whether the record belongs to the audit trail
+func isAudit(record slog.Record) bool {
+	audit := false
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == service.FieldAudit && attr.Value.Kind() == slog.KindBool && attr.Value.Bool() {
+			audit = true
+			return false
+		}
+		return true
+	})
+	return audit
+}
+
+// inc counts an entry, starting a new interval if the current one has ended,
+// and returns the number of entries in the interval
+func (c *samplingCounter) inc(now time.Time, interval time.Duration) uint64 {
+	resetAt := c.resetAt.Load()
+	if now.UnixNano() < resetAt {
+		return c.count.Add(1)
+	}
+
+	c.count.Store(1)
+	if !c.resetAt.CompareAndSwap(resetAt, now.Add(interval).UnixNano()) {
+		// Another entry started the interval concurrently
+		return c.count.Add(1)
+	}
+	return 1
+}
+
+// levelBucket maps levels below error to a counter set
+func levelBucket(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 0
+	case level < slog.LevelWarn:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// messageBucket hashes a message into a counter index
+func messageBucket(message string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(message))
+	return int(hash.Sum32() % samplingBuckets)
+}
+
+// reportDropped logs the sampled-out counts per message once the report
+// interval has elapsed and resets them
+func (s *sampler) reportDropped(ctx context.Context, now time.Time) {
+	due := s.nextReport.Load()
+	if now.UnixNano() < due || !s.nextReport.CompareAndSwap(due, now.Add(s.config.ReportInterval).UnixNano()) {
+		return
+	}
+	s.writeReport(ctx, now)
+}
+
+// writeReport logs the sampled-out counts per message and resets them
+func (s *sampler) writeReport(ctx context.Context, now time.Time) error {
+	s.mu.Lock()
+	dropped := s.dropped
+	if len(dropped) > 0 {
+		s.dropped = make(map[string]uint64)
+	}
+	s.mu.Unlock()
+
+	if len(dropped) == 0 {
+		return nil
+	}
+
+	// Messages are redacted first, merging those differing only by the
+	// sensitive data they carried
+	redacted := make(map[string]uint64, len(dropped))
+	var total uint64
+	for message, count := range dropped {
+		redacted[s.redactor.RedactString(message)] += count
+		total += count
+	}
+	messages := make([]string, 0, len(redacted))
+	for message := range redacted {
+		messages = append(messages, message)
+	}
+	sort.Strings(messages)
+
+	counts := make([]any, 0, len(messages))
+	for _, message := range messages {
+		counts = append(counts, slog.Uint64(message, redacted[message]))
+	}
+
+	if !s.report.Enabled(ctx, slog.LevelWarn) {
+		return nil
+	}
+
+	record := slog.NewRecord(now, slog.LevelWarn, "Log entries sampled out", 0)
+	record.AddAttrs(
+		slog.Uint64("sampled_out_total", total),
+		slog.Group("sampled_out", counts...),
+	)
+	return s.report.Handle(ctx, record)
+}
@@ -0,0 +1,343 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// fakeClock is a manually advanced time source
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newSampledLogger(config SamplingConfig) (*slog.Logger, *bytes.Buffer, *fakeClock) {
+	var buf bytes.Buffer
+	clock := &fakeClock{now: time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)}
+
+	handler := NewSamplingHandler(slog.NewJSONHandler(&buf, nil), config, nil)
+	handler.sampler.now = clock.Now
+
+	return slog.New(handler), &buf, clock
+}
+
+// logEntries parses the JSON lines written to buf
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse log entry %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func countMessage(entries []map[string]interface{}, message string) int {
+	count := 0
+	for _, entry := range entries {
+		if entry["msg"] == message {
+			count++
+		}
+	}
+	return count
+}
+
+func TestSamplingHandler_FirstThenEvery(t *testing.T) {
+	logger, buf, _ := newSampledLogger(SamplingConfig{Initial: 2, Thereafter: 3})
+
+	for i := 0; i < 10; i++ {
+		logger.Info("HTTP request processed", "n", i+1)
+	}
+	logger.Info("Other message")
+
+	var kept []float64
+	for _, entry := range logEntries(t, buf) {
+		if entry["msg"] == "HTTP request processed" {
+			kept = append(kept, entry["n"].(float64))
+		}
+	}
+
+	// 1 and 2 are the initial entries, then every third: 5 and 8
+	if len(kept) != 4 || kept[0] != 1 || kept[1] != 2 || kept[2] != 5 || kept[3] != 8 {
+		t.Errorf("Expected entries [1 2 5 8], got %v", kept)
+	}
+	if countMessage(logEntries(t, buf), "Other message") != 1 {
+		t.Error("Expected other messages to be counted separately")
+	}
+}
+
+func TestSamplingHandler_NeverDropsErrors(t *testing.T) {
+	logger, buf, _ := newSampledLogger(SamplingConfig{Initial: 1})
+
+	for i := 0; i < 50; i++ {
+		logger.Error("Failed to create chargeback")
+		logger.Warn("Rejected chargeback request body")
+	}
+
+	entries := logEntries(t, buf)
+	if got := countMessage(entries, "Failed to create chargeback"); got != 50 {
+		t.Errorf("Expected every error to be logged, got %d", got)
+	}
+	if got := countMessage(entries, "Rejected chargeback request body"); got != 1 {
+		t.Errorf("Expected warnings to be sampled, got %d", got)
+	}
+}
+
+func TestSamplingHandler_ResetsEachInterval(t *testing.T) {
+	logger, buf, clock := newSampledLogger(SamplingConfig{Initial: 2, Interval: time.Second})
+
+	for i := 0; i < 5; i++ {
+		logger.Info("tick")
+	}
+	clock.Advance(time.Second)
+	for i := 0; i < 5; i++ {
+		logger.Info("tick")
+	}
+
+	if got := countMessage(logEntries(t, buf), "tick"); got != 4 {
+		t.Errorf("Expected 2 entries per interval, got %d", got)
+	}
+}
+
+func TestSamplingHandler_ReportsSampledOut(t *testing.T) {
+	logger, buf, clock := newSampledLogger(SamplingConfig{Initial: 1, ReportInterval: time.Minute})
+
+	for i := 0; i < 5; i++ {
+		logger.Info("tick")
+		logger.Debug("tock")
+	}
+
+	// Nothing is reported before the interval elapses
+	clock.Advance(30 * time.Second)
+	logger.Info("still quiet")
+	if countMessage(logEntries(t, buf), "Log entries sampled out") != 0 {
+		t.Fatal("Expected no report before the report interval")
+	}
+
+	clock.Advance(31 * time.Second)
+	logger.Info("next")
+
+	var report map[string]interface{}
+	for _, entry := range logEntries(t, buf) {
+		if entry["msg"] == "Log entries sampled out" {
+			report = entry
+		}
+	}
+	if report == nil {
+		t.Fatalf("Expected a sampled-out report, got:\n%s", buf.String())
+	}
+	if report["level"] != "WARN" || report["sampled_out_total"] != float64(4) {
+		t.Errorf("Expected a warning with 4 sampled-out entries, got %v", report)
+	}
+	counts, _ := report["sampled_out"].(map[string]interface{})
+	if counts["tick"] != float64(4) {
+		t.Errorf("Expected 4 sampled-out 'tick' entries, got %v", report["sampled_out"])
+	}
+
+	// Counts restart after a report
+	clock.Advance(time.Minute)
+	buf.Reset()
+	logger.Info("after report")
+	if countMessage(logEntries(t, buf), "Log entries sampled out") != 0 {
+		t.Error("Expected no report when nothing was sampled out")
+	}
+}
+
+func TestSamplingHandler_ReportRedactsMessages(t *testing.T) {
+	logger, buf, clock := newSampledLogger(SamplingConfig{Initial: 1, ReportInterval: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		logger.Info("Declined card 4111111111111111")
+		logger.Info("Declined card 5555555555554444")
+	}
+	clock.Advance(time.Minute)
+	logger.Info("next")
+
+	var counts map[string]interface{}
+	for _, entry := range logEntries(t, buf) {
+		if entry["msg"] == "Log entries sampled out" {
+			counts, _ = entry["sampled_out"].(map[string]interface{})
+		}
+	}
+	if len(counts) != 1 || counts["Declined card "+RedactedValue] != float64(4) {
+		t.Errorf("Expected the card numbers to be redacted from the reported messages, got %v", counts)
+	}
+}
+
+func TestSamplingHandler_Concurrent(t *testing.T) {
+	logger, buf, _ := newSampledLogger(SamplingConfig{Initial: 10})
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				logger.With("worker", g).Info("HTTP request processed")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := countMessage(logEntries(t, buf), "HTTP request processed"); got != 10 {
+		t.Errorf("Expected exactly 10 entries across goroutines, got %d", got)
+	}
+}
+
+func TestStructuredLogger_Sampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level:    service.LogLevelInfo,
+		Format:   FormatJSON,
+		Sampling: SamplingConfig{Initial: 3},
+	}, &buf)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		logger.Info(ctx, "HTTP request processed")
+		logger.Error(ctx, "Recovered from panic")
+	}
+
+	entries := logEntries(t, &buf)
+	if got := countMessage(entries, "HTTP request processed"); got != 3 {
+		t.Errorf("Expected 3 info entries, got %d", got)
+	}
+	if got := countMessage(entries, "Recovered from panic"); got != 20 {
+		t.Errorf("Expected 20 error entries, got %d", got)
+	}
+}
+
+func TestStructuredLogger_AuditEntriesSurviveSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level:    service.LogLevelWarn,
+		Format:   FormatJSON,
+		Sampling: SamplingConfig{Initial: 3},
+	}, &buf)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 20; i++ {
+		logger.Info(ctx, "Authorization decision", map[string]interface{}{service.FieldAudit: true, "decision": "allow"})
+		logger.Warn(ctx, "Authorization decision", map[string]interface{}{service.FieldAudit: true, "decision": "deny"})
+		logger.Info(ctx, "HTTP request processed")
+		logger.Warn(ctx, "Rejected chargeback request body")
+	}
+
+	entries := logEntries(t, &buf)
+	if got := countMessage(entries, "Authorization decision"); got != 40 {
+		t.Errorf("Expected every audit entry below the level and past sampling, got %d", got)
+	}
+	if got := countMessage(entries, "HTTP request processed"); got != 0 {
+		t.Errorf("Expected info entries below the level to be dropped, got %d", got)
+	}
+	if got := countMessage(entries, "Rejected chargeback request body"); got != 3 {
+		t.Errorf("Expected other warnings to be sampled, got %d", got)
+	}
+}
+
+func TestStructuredLogger_FlushReportsSampledOut(t *testing.T) {
+	for _, flush := range []string{"Flush", "Close"} {
+		t.Run(flush, func(t *testing.T) {
+			// Arrange
+			var buf bytes.Buffer
+			logger, err := NewStructuredLogger(LoggerConfig{
+				Level:      service.LogLevelInfo,
+				Format:     FormatJSON,
+				RedactKeys: []string{"merchant_secret"},
+				Sampling:   SamplingConfig{Initial: 1, ReportInterval: time.Hour},
+			}, &buf)
+			if err != nil {
+				t.Fatalf("NewStructuredLogger() error = %v", err)
+			}
+			ctx := context.Background()
+			for i := 0; i < 5; i++ {
+				logger.Info(ctx, "HTTP request processed")
+			}
+
+			// Act: the invocation ends long before the report interval
+			if flush == "Flush" {
+				err = logger.Flush(ctx)
+			} else {
+				err = logger.Close(ctx)
+			}
+
+			// Assert
+			if err != nil {
+				t.Fatalf("%s() error = %v", flush, err)
+			}
+			var report map[string]interface{}
+			for _, entry := range logEntries(t, &buf) {
+				if entry["msg"] == "Log entries sampled out" {
+					report = entry
+				}
+			}
+			if report == nil || report["sampled_out_total"] != float64(4) {
+				t.Errorf("Expected 4 sampled-out entries reported on %s, got:\n%s", flush, buf.String())
+			}
+
+			// Counts are reported once
+			buf.Reset()
+			logger.Flush(ctx)
+			if countMessage(logEntries(t, &buf), "Log entries sampled out") != 0 {
+				t.Error("Expected no second report without new sampled-out entries")
+			}
+		})
+	}
+}
+
+func TestStructuredLogger_SamplingUsesConfiguredRedactor(t *testing.T) {
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level:      service.LogLevelInfo,
+		Format:     FormatJSON,
+		RedactKeys: []string{"merchant_secret"},
+		Sampling:   SamplingConfig{Initial: 1},
+	}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	if !logger.sampling.sampler.redactor.DeniesKey("merchant_secret") {
+		t.Error("Expected the sampling report to use the redactor with the configured keys")
+	}
+}
+
+func TestSamplingConfig_Validate(t *testing.T) {
+	if err := (SamplingConfig{Initial: 100, Thereafter: 100, Interval: time.Second}).Validate(); err != nil {
+		t.Errorf("Expected valid config, got %v", err)
+	}
+	if err := (SamplingConfig{Initial: -1, Interval: -time.Second}).Validate(); err == nil {
+		t.Error("Expected negative settings to be rejected")
+	}
+}
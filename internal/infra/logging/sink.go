@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// SinkConfig describes one output of the structured logger
+type SinkConfig struct {
+	// Format is the encoding of the entries. FormatOTLP exports them to a
+	// collector instead of writing them.
+	Format LogFormat
+
+	// MinLevel drops entries below it for this sink only, on top of the
+	// logger level
+	MinLevel service.LogLevel
+
+	// Writer receives the entries, the writer given to NewStructuredLogger
+	// if nil
+	Writer io.Writer
+
+	// File writes the entries to a rotating file instead of Writer
+	File *FileConfig
+
+	// EMF configures the metrics of FormatEMF entries
+	EMF EMFConfig
+
+	// OTLP configures the exporter of FormatOTLP sinks
+	OTLP OTLPConfig
+}
+
+// Validate checks the sink configuration
+func (c SinkConfig) Validate() error {
+	if !c.Format.IsValid() {
+		return fmt.Errorf("invalid log format: %v", c.Format)
+	}
+	if !c.MinLevel.IsValid() {
+		return fmt.Errorf("invalid minimum level: %v", c.MinLevel)
+	}
+	if c.Format == FormatOTLP {
+		if err := c.OTLP.Validate(); err != nil {
+			return fmt.Errorf("invalid OTLP exporter: %w", err)
+		}
+	}
+	if c.File != nil {
+		if c.Format == FormatOTLP {
+			return errors.New("OTLP sinks cannot write to a file")
+		}
+		if err := c.File.Validate(); err != nil {
+			return fmt.Errorf("invalid log file: %w", err)
+		}
+	}
+	return nil
+}
+
+// sinkResources is what the logger must flush and close on shutdown
+type sinkResources struct {
+	exporters []*OTLPExporter
+	files     []*RotatingFile
+}
+
+// Flush sends the entries buffered by the exporters
+func (r *sinkResources) Flush(ctx context.Context) error {
+	var errs []error
+	for _, exporter := range r.exporters {
+		errs = append(errs, exporter.Flush(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes the exporters and closes the files
+func (r *sinkResources) Close(ctx context.Context) error {
+	var errs []error
+	for _, exporter := range r.exporters {
+		errs = append(errs, exporter.Close(ctx))
+	}
+	for _, file := range r.files {
+		errs = append(errs, file.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// newSinkHandler creates the handler writing to a sink, recording the
+// exporters and files it opens in resources
+func newSinkHandler(sink SinkConfig, config LoggerConfig, writer io.Writer, resources *sinkResources) (slog.Handler, error) {
+	// Levels are filtered by the dynamic level and the fan-out
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	if sink.Format == FormatOTLP {
+		exporter := NewOTLPExporter(sink.OTLP, config.ServiceName, config.Version)
+		resources.exporters = append(resources.exporters, exporter)
+		return NewOTLPHandler(exporter), nil
+	}
+
+	if sink.Writer != nil {
+		writer = sink.Writer
+	}
+	if sink.File != nil {
+		file, err := OpenRotatingFile(*sink.File)
+		if err != nil {
+			return nil, err
+		}
+		resources.files = append(resources.files, file)
+		writer = file
+	}
+
+	switch sink.Format {
+	case FormatJSON:
+		return slog.NewJSONHandler(writer, opts), nil
+	case FormatText:
+		return slog.NewTextHandler(writer, opts), nil
+	case FormatEMF:
+		return NewEMFHandler(slog.NewJSONHandler(writer, opts), sink.EMF.withDefaults(config.ServiceName)), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format: %v", sink.Format)
+	}
+}
+
+// fanoutHandler is a slog.Handler sending each record to every sink whose
+// minimum level it reaches
+type fanoutHandler struct {
+	handlers  []slog.Handler
+	minLevels []slog.Level
+}
+
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for i, handler := range h.handlers {
+		if level >= h.minLevels[i] && handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *fanoutHandler) Handle(ctx context.Context, record slog.Record) error {
+	var errs []error
+	for i, handler := range h.handlers {
+		if record.Level >= h.minLevels[i] && handler.Enabled(ctx, record.Level) {
+			errs = append(errs, handler.Handle(ctx, record.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers, minLevels: h.minLevels}
+}
+
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers, minLevels: h.minLevels}
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+func TestStructuredLogger_SinkMinLevels(t *testing.T) {
+	var all, warnings bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level: service.LogLevelDebug,
+		Sinks: []SinkConfig{
+			{Format: FormatJSON, Writer: &all},
+			{Format: FormatText, MinLevel: service.LogLevelWarn, Writer: &warnings},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+	ctx := context.Background()
+
+	logger.Debug(ctx, "debug entry")
+	logger.Info(ctx, "info entry")
+	logger.Warn(ctx, "warn entry")
+	logger.Error(ctx, "error entry")
+
+	entries := logEntries(t, &all)
+	if len(entries) != 4 {
+		t.Errorf("Expected every entry in the JSON sink, got %d", len(entries))
+	}
+
+	output := warnings.String()
+	if strings.Contains(output, "debug entry") || strings.Contains(output, "info entry") {
+		t.Errorf("Expected entries below warn to skip the text sink, got:\n%s", output)
+	}
+	if !strings.Contains(output, "warn entry") || !strings.Contains(output, "error entry") {
+		t.Errorf("Expected warn and error entries in the text sink, got:\n%s", output)
+	}
+}
+
+func TestStructuredLogger_SinksAreRedacted(t *testing.T) {
+	var first, second bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level: service.LogLevelInfo,
+		Sinks: []SinkConfig{
+			{Format: FormatJSON, Writer: &first},
+			{Format: FormatEMF, Writer: &second},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	logger.Info(context.Background(), "card received", map[string]interface{}{"card_number": testPANs[0]})
+
+	for name, buf := range map[string]*bytes.Buffer{"json": &first, "emf": &second} {
+		if strings.Contains(buf.String(), testPANs[0]) {
+			t.Errorf("Expected the %s sink to receive redacted entries, got:\n%s", name, buf.String())
+		}
+	}
+}
+
+func TestStructuredLogger_FileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "api.log")
+	logger, err := NewStructuredLogger(LoggerConfig{
+		Level: service.LogLevelInfo,
+		Sinks: []SinkConfig{{Format: FormatJSON, File: &FileConfig{Path: path}}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	logger.Info(context.Background(), "written to file")
+	if err := logger.Close(context.Background()); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(data), "written to file") {
+		t.Errorf("Expected the entry in the log file, got %q", data)
+	}
+}
+
+func TestSinkConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sink    SinkConfig
+		wantErr bool
+	}{
+		{name: "json", sink: SinkConfig{Format: FormatJSON}},
+		{name: "otlp", sink: SinkConfig{Format: FormatOTLP, OTLP: OTLPConfig{Endpoint: "http://localhost:4318"}}},
+		{name: "otlp without endpoint", sink: SinkConfig{Format: FormatOTLP}, wantErr: true},
+		{name: "otlp with invalid endpoint", sink: SinkConfig{Format: FormatOTLP, OTLP: OTLPConfig{Endpoint: "localhost:4318"}}, wantErr: true},
+		{name: "otlp to file", sink: SinkConfig{Format: FormatOTLP, OTLP: OTLPConfig{Endpoint: "http://collector"}, File: &FileConfig{Path: "api.log"}}, wantErr: true},
+		{name: "file without path", sink: SinkConfig{Format: FormatJSON, File: &FileConfig{}}, wantErr: true},
+		{name: "invalid format", sink: SinkConfig{Format: LogFormat(999)}, wantErr: true},
+		{name: "invalid minimum level", sink: SinkConfig{Format: FormatJSON, MinLevel: service.LogLevel(999)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.sink.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewStructuredLogger(LoggerConfig{Format: FormatOTLP}, nil); err == nil {
+		t.Error("Expected an OTLP format without sinks to be rejected")
+	}
+}
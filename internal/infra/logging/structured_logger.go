@@ -2,12 +2,15 @@ package logging
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
 )
 
 // LogFormat represents the output format for logs
@@ -19,6 +22,13 @@ const (
 
 	// FormatText outputs logs in human-readable text format
 	FormatText
+
+	// FormatEMF outputs JSON logs in CloudWatch Embedded Metric Format, so
+	// numeric fields such as duration_ms become metrics
+	FormatEMF
+
+	// FormatOTLP exports logs to an OpenTelemetry collector, see SinkConfig
+	FormatOTLP
 )
 
 // String returns the string representation of the log format
@@ -28,6 +38,10 @@ func (f LogFormat) String() string {
 		return "json"
 	case FormatText:
 		return "text"
+	case FormatEMF:
+		return "emf"
+	case FormatOTLP:
+		return "otlp"
 	default:
 		return "unknown"
 	}
@@ -35,7 +49,7 @@ func (f LogFormat) String() string {
 
 // IsValid checks if the log format is valid
 func (f LogFormat) IsValid() bool {
-	return f >= FormatJSON && f <= FormatText
+	return f >= FormatJSON && f <= FormatOTLP
 }
 
 // LoggerConfig holds the configuration for the structured logger
@@ -43,14 +57,44 @@ type LoggerConfig struct {
 	// Level is the minimum log level to output
 	Level service.LogLevel
 
-	// Format determines the output format (JSON or text)
+	// Format determines the output format (JSON, text or EMF) when Sinks
+	// is empty
 	Format LogFormat
 
+	// Sinks lists the outputs of the logger, each with its own format and
+	// minimum level. When empty, entries are written in Format to the
+	// writer given to NewStructuredLogger.
+	Sinks []SinkConfig
+
 	// ServiceName is the name of the service for structured logging
 	ServiceName string
 
 	// Version is the version of the service
 	Version string
+
+	// ContextKeys names the context identifiers added to every entry, see
+	// requestctx.DefaultFields. Nil selects the defaults; an empty slice
+	// disables extraction.
+	ContextKeys []string
+
+	// RedactKeys lists field names whose values are never logged, in
+	// addition to DefaultRedactKeys
+	RedactKeys []string
+
+	// Sampling limits repeated entries below the error level, disabled by
+	// default
+	Sampling SamplingConfig
+
+	// LevelSource reloads the level while running, see DynamicLevel. Level
+	// is used until the first load and whenever loading fails.
+	LevelSource LevelSource
+
+	// LevelTTL is how long a loaded level is used, DefaultLevelTTL if zero
+	LevelTTL time.Duration
+
+	// LevelOverrideKey verifies per-request level overrides, which are
+	// rejected when empty
+	LevelOverrideKey []byte
 }
 
 // Validate validates the logger configuration
@@ -63,15 +107,34 @@ func (c LoggerConfig) Validate() error {
 		return fmt.Errorf("invalid log format: %v", c.Format)
 	}
 
+	if len(c.Sinks) == 0 && c.Format == FormatOTLP {
+		return fmt.Errorf("invalid log format: %v requires a sink with an endpoint", c.Format)
+	}
+
+	for i, sink := range c.Sinks {
+		if err := sink.Validate(); err != nil {
+			return fmt.Errorf("invalid sink %d: %w", i, err)
+		}
+	}
+
+	if err := validateContextKeys(c.ContextKeys); err != nil {
+		return fmt.Errorf("invalid context keys: %w", err)
+	}
+
+	if err := c.Sampling.Validate(); err != nil {
+		return fmt.Errorf("invalid sampling: %w", err)
+	}
+
 	return nil
 }
 
 // StructuredLogger implements the domain Logger interface using Go's slog package
 type StructuredLogger struct {
-	logger      *slog.Logger
-	level       service.LogLevel
-	config      LoggerConfig
-	contextKeys []string
+	logger    *slog.Logger
+	levels    *DynamicLevel
+	config    LoggerConfig
+	resources *sinkResources
+	sampling  *SamplingHandler
 }
 
 // NewStructuredLogger creates a new structured logger with the given configuration
@@ -85,27 +148,49 @@ func NewStructuredLogger(config LoggerConfig, writer io.Writer) (*StructuredLogg
 		writer = os.Stdout
 	}
 
-	// Convert domain log level to slog level
-	slogLevel := convertLogLevel(config.Level)
+	sinks := config.Sinks
+	if len(sinks) == 0 {
+		sinks = []SinkConfig{{Format: config.Format, MinLevel: service.LogLevelDebug}}
+	}
 
-	// Create handler options
-	opts := &slog.HandlerOptions{
-		Level: slogLevel,
+	// The level can change while running, so the sinks accept every level
+	// reaching their minimum and filtering is left to the dynamic level
+	levels := NewDynamicLevel(config.Level, config.LevelSource, config.LevelTTL, config.LevelOverrideKey)
+	resources := &sinkResources{}
+	fanout := &fanoutHandler{}
+	for _, sink := range sinks {
+		sinkHandler, err := newSinkHandler(sink, config, writer, resources)
+		if err != nil {
+			resources.Close(context.Background())
+			return nil, fmt.Errorf("failed to create %s log sink: %w", sink.Format, err)
+		}
+		fanout.handlers = append(fanout.handlers, sinkHandler)
+		fanout.minLevels = append(fanout.minLevels, convertLogLevel(sink.MinLevel))
 	}
 
-	// Create appropriate handler based on format
-	var handler slog.Handler
-	switch config.Format {
-	case FormatJSON:
-		handler = slog.NewJSONHandler(writer, opts)
-	case FormatText:
-		handler = slog.NewTextHandler(writer, opts)
-	default:
-		return nil, fmt.Errorf("unsupported log format: %v", config.Format)
+	var handler slog.Handler = fanout
+	handler = &levelHandler{next: handler, levels: levels}
+
+	// Remove card numbers and other personal data before anything is written
+	redactor := NewRedactor(config.RedactKeys)
+	handler = NewRedactingHandler(handler, redactor)
+
+	// Drop repeated entries before paying for their redaction
+	var sampling *SamplingHandler
+	if config.Sampling.Enabled() {
+		sampling = NewSamplingHandler(handler, config.Sampling, redactor)
+		handler = sampling
+	}
+
+	// Add the request identifiers carried by the context of every call
+	contextHandler, err := NewContextHandler(handler, config.ContextKeys)
+	if err != nil {
+		resources.Close(context.Background())
+		return nil, fmt.Errorf("invalid logger config: %w", err)
 	}
 
 	// Create base logger
-	logger := slog.New(handler)
+	logger := slog.New(contextHandler)
 
 	// Add service metadata if provided
 	if config.ServiceName != "" {
@@ -117,12 +202,41 @@ func NewStructuredLogger(config LoggerConfig, writer io.Writer) (*StructuredLogg
 	}
 
 	return &StructuredLogger{
-		logger: logger,
-		level:  config.Level,
-		config: config,
+		logger:    logger,
+		levels:    levels,
+		config:    config,
+		resources: resources,
+		sampling:  sampling,
 	}, nil
 }
 
+// Levels returns the level deciding which entries are logged, to change it
+// while running
+func (s *StructuredLogger) Levels() *DynamicLevel {
+	return s.levels
+}
+
+// Flush reports the entries sampled out so far and sends the entries
+// buffered by OTLP sinks. Lambda handlers call it before returning, as the
+// execution environment may be frozen afterwards.
+func (s *StructuredLogger) Flush(ctx context.Context) error {
+	return errors.Join(s.flushSampling(ctx), s.resources.Flush(ctx))
+}
+
+// Close reports the entries sampled out, flushes the OTLP sinks and closes
+// the log files. Entries logged afterwards to those sinks are lost.
+func (s *StructuredLogger) Close(ctx context.Context) error {
+	return errors.Join(s.flushSampling(ctx), s.resources.Close(ctx))
+}
+
+// flushSampling reports the sampled-out entries, if sampling is enabled
+func (s *StructuredLogger) flushSampling(ctx context.Context) error {
+	if s.sampling == nil {
+		return nil
+	}
+	return s.sampling.Flush(ctx)
+}
+
 // convertLogLevel converts domain log level to slog level
 func convertLogLevel(level service.LogLevel) slog.Level {
 	switch level {
@@ -162,9 +276,11 @@ func (s *StructuredLogger) Log(ctx context.Context, entry service.LogEntry) erro
 		return fmt.Errorf("invalid log entry: %w", err)
 	}
 
-	// Check if the level is enabled
+	// Check if the level is enabled; audit entries are written whatever the
+	// level, so only the minimum levels of the sinks apply to them
 	slogLevel := convertDomainLevelToSlog(entry.Level)
-	if !s.logger.Enabled(ctx, slogLevel) {
+	audit := entry.IsAudit()
+	if !audit && !s.logger.Enabled(ctx, slogLevel) {
 		return nil
 	}
 
@@ -174,6 +290,14 @@ func (s *StructuredLogger) Log(ctx context.Context, entry service.LogEntry) erro
 		attrs = append(attrs, slog.Any(key, value))
 	}
 
+	if audit {
+		// Handle the record directly, as the logger would drop it when the
+		// dynamic level is above its level
+		record := slog.NewRecord(time.Now(), slogLevel, entry.Message, 0)
+		record.AddAttrs(attrs...)
+		return s.logger.Handler().Handle(ctx, record)
+	}
+
 	// Log with the appropriate level
 	s.logger.LogAttrs(ctx, slogLevel, entry.Message, attrs...)
 
@@ -252,36 +376,28 @@ func (s *StructuredLogger) Error(ctx context.Context, message string, fields ...
 	return s.Log(ctx, entry)
 }
 
-// WithContext returns a logger instance with additional context
-// This can be used to add request-scoped fields like correlation IDs
+// WithContext returns a logger bound to the context identifiers of ctx, for
+// code that logs with a different context. Log already adds the identifiers
+// of the context it is given, so calling this is optional.
 func (s *StructuredLogger) WithContext(ctx context.Context) service.Logger {
-	// Extract correlation ID from context if available
 	contextLogger := s.logger
 
-	if correlationID := ctx.Value("correlation_id"); correlationID != nil {
-		if id, ok := correlationID.(string); ok {
-			contextLogger = contextLogger.With("correlation_id", id)
-		}
+	keys := s.config.ContextKeys
+	if keys == nil {
+		keys = requestctx.DefaultFields
 	}
-
-	// Extract request ID from context if available
-	if requestID := ctx.Value("request_id"); requestID != nil {
-		if id, ok := requestID.(string); ok {
-			contextLogger = contextLogger.With("request_id", id)
-		}
-	}
-
-	// Extract user ID from context if available
-	if userID := ctx.Value("user_id"); userID != nil {
-		if id, ok := userID.(string); ok {
-			contextLogger = contextLogger.With("user_id", id)
+	for _, key := range keys {
+		if value := requestctx.Lookup(ctx, key); value != "" {
+			contextLogger = contextLogger.With(slog.String(key, value))
 		}
 	}
 
 	return &StructuredLogger{
-		logger: contextLogger,
-		level:  s.level,
-		config: s.config,
+		logger:    contextLogger,
+		levels:    s.levels,
+		config:    s.config,
+		resources: s.resources,
+		sampling:  s.sampling,
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
 )
 
 // TestStructuredLogger_New tests the creation of a new structured logger
@@ -378,21 +379,59 @@ func TestStructuredLogger_WithContext(t *testing.T) {
 	}
 
 	// Create context with correlation ID
-	ctx := context.WithValue(context.Background(), "correlation_id", "test-123")
+	ctx := requestctx.WithCorrelationID(context.Background(), "test-123")
 
 	contextLogger := logger.WithContext(ctx)
 	if contextLogger == nil {
 		t.Error("WithContext() should not return nil")
 	}
 
+	// The bound ID is kept even when logging with another context, and not duplicated
 	err = contextLogger.Info(ctx, "Context test message")
 	if err != nil {
 		t.Errorf("Info() error = %v", err)
 	}
+	err = contextLogger.Info(context.Background(), "Context test message")
+	if err != nil {
+		t.Errorf("Info() error = %v", err)
+	}
 
-	output := buf.String()
-	if output == "" {
-		t.Error("Expected output with context")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 log lines, got %d", len(lines))
+	}
+	for _, line := range lines {
+		if strings.Count(line, "correlation_id") != 1 || !strings.Contains(line, `"correlation_id":"test-123"`) {
+			t.Errorf("Expected exactly one correlation_id in %s", line)
+		}
+	}
+}
+
+// TestStructuredLogger_ContextIdentifiers tests that request identifiers are
+// attached without calling WithContext
+func TestStructuredLogger_ContextIdentifiers(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := NewStructuredLogger(LoggerConfig{Level: service.LogLevelInfo, Format: FormatJSON}, &buf)
+	if err != nil {
+		t.Fatalf("NewStructuredLogger() error = %v", err)
+	}
+
+	ctx := requestctx.WithRequestID(context.Background(), "req-1")
+	ctx = requestctx.WithCorrelationID(ctx, "corr-1")
+
+	if err := logger.Info(ctx, "Automatic context", map[string]interface{}{"correlation_id": "explicit"}); err != nil {
+		t.Fatalf("Info() error = %v", err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Failed to parse log entry: %v", err)
+	}
+	if entry["request_id"] != "req-1" {
+		t.Errorf("Expected request_id 'req-1', got %v", entry["request_id"])
+	}
+	if entry["correlation_id"] != "explicit" {
+		t.Errorf("Expected explicit field to win over the context, got %v", entry["correlation_id"])
 	}
 }
 
@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// emfMaxValues is the number of values CloudWatch accepts per metric in a
+// single document
+const emfMaxValues = 100
+
+// EMFConfig configures CloudWatch Embedded Metric Format documents
+type EMFConfig struct {
+	// Namespace of the metrics, the service name if empty
+	Namespace string
+
+	// Dimensions lists the labels used as metric dimensions; the other
+	// labels are written as properties, searchable in Logs Insights but not
+	// aggregated. Every label but the service.HighCardinalityLabels is a
+	// dimension if nil.
+	Dimensions []string
+
+	// FlushInterval writes buffered values periodically when positive.
+	// Lambda handlers flush before returning instead.
+	FlushInterval time.Duration
+
+	// Writer receives the documents, os.Stdout if nil
+	Writer io.Writer
+
+	// ErrorLog receives failed background flushes, os.Stderr if nil
+	ErrorLog io.Writer
+}
+
+// EMF is a service.Metrics buffering values and writing them as EMF
+// documents, one per label set, which CloudWatch turns into metrics. Counters
+// are summed, gauges keep their last value and histogram values are written
+// as lists.
+type EMF struct {
+	config      EMFConfig
+	serviceName string
+	dimensions  map[string]bool
+	now         func() time.Time
+
+	mu     sync.Mutex
+	groups map[string]*emfGroup
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// emfGroup holds the buffered values of one label set
+type emfGroup struct {
+	labels service.Labels
+	values map[string]*emfValue
+	names  []string
+}
+
+// emfValue is the buffered value of one metric
+type emfValue struct {
+	metric service.Metric
+	value  float64
+	values []float64
+}
+
+// NewEMF creates an EMF recorder for the given service. With a
+// FlushInterval it flushes in the background until closed.
+func NewEMF(config EMFConfig, serviceName string) *EMF {
+	if config.Namespace == "" {
+		config.Namespace = serviceName
+	}
+	if config.Namespace == "" {
+		config.Namespace = "chargeback-api"
+	}
+	if config.Writer == nil {
+		config.Writer = os.Stdout
+	}
+	if config.ErrorLog == nil {
+		config.ErrorLog = os.Stderr
+	}
+
+	var dimensions map[string]bool
+	if config.Dimensions != nil {
+		dimensions = make(map[string]bool, len(config.Dimensions))
+		for _, name := range config.Dimensions {
+			dimensions[name] = true
+		}
+	}
+
+	m := &EMF{
+		config:      config,
+		serviceName: serviceName,
+		dimensions:  dimensions,
+		now:         time.Now,
+		groups:      make(map[string]*emfGroup),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	if config.FlushInterval > 0 {
+		go m.run()
+	} else {
+		close(m.done)
+	}
+	return m
+}
+
+// run flushes every FlushInterval until the recorder is closed
+func (m *EMF) run() {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.config.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Flush(context.Background()); err != nil {
+				fmt.Fprintf(m.config.ErrorLog, "emf metrics: %v\n", err)
+			}
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Add increments a counter by value
+func (m *EMF) Add(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.value(metric, labels).value += value
+}
+
+// Observe records a value in a histogram. The label set is written as soon
+// as a histogram holds the most values a document may carry.
+func (m *EMF) Observe(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	v := m.value(metric, labels)
+	v.values = append(v.values, value)
+	if len(v.values) < emfMaxValues {
+		return
+	}
+
+	key := labelsKey(labels)
+	if err := m.write(m.groups[key]); err != nil {
+		fmt.Fprintf(m.config.ErrorLog, "emf metrics: %v\n", err)
+	}
+	delete(m.groups, key)
+}
+
+// Set updates the value of a gauge
+func (m *EMF) Set(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.value(metric, labels).value = value
+}
+
+// value returns the buffered value of a metric, creating it if needed. The
+// caller holds the lock.
+func (m *EMF) value(metric service.Metric, labels service.Labels) *emfValue {
+	key := labelsKey(labels)
+	group, ok := m.groups[key]
+	if !ok {
+		group = &emfGroup{labels: copyLabels(labels), values: make(map[string]*emfValue)}
+		m.groups[key] = group
+	}
+
+	v, ok := group.values[metric.Name]
+	if !ok {
+		v = &emfValue{metric: metric}
+		group.values[metric.Name] = v
+		group.names = append(group.names, metric.Name)
+	}
+	return v
+}
+
+// Flush writes one document per buffered label set
+func (m *EMF) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := make([]string, 0, len(m.groups))
+	for key := range m.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, m.write(m.groups[key]))
+	}
+	m.groups = make(map[string]*emfGroup)
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("failed to write EMF documents: %w", err)
+	}
+	return nil
+}
+
+// Close stops the background flush and flushes the buffered values
+func (m *EMF) Close(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	<-m.done
+	return m.Flush(ctx)
+}
+
+// isDimension reports whether a label is a dimension rather than a property
+func (m *EMF) isDimension(name string) bool {
+	if m.dimensions == nil {
+		return !slices.Contains(service.HighCardinalityLabels, name)
+	}
+	return m.dimensions[name]
+}
+
+// write writes the document of a label set. The caller holds the lock.
+func (m *EMF) write(group *emfGroup) error {
+	document := make(map[string]interface{}, len(group.labels)+len(group.names)+2)
+
+	dimensions := []string{}
+	if m.serviceName != "" {
+		document["service"] = m.serviceName
+		dimensions = append(dimensions, "service")
+	}
+	for _, name := range sortedKeys(group.labels) {
+		document[name] = group.labels[name]
+		if m.isDimension(name) {
+			dimensions = append(dimensions, name)
+		}
+	}
+
+	metrics := make([]map[string]string, 0, len(group.names))
+	for _, name := range group.names {
+		v := group.values[name]
+		metrics = append(metrics, map[string]string{"Name": name, "Unit": string(v.metric.Unit)})
+		if v.metric.Kind == service.MetricHistogram {
+			document[name] = v.values
+		} else {
+			document[name] = v.value
+		}
+	}
+
+	document["_aws"] = map[string]interface{}{
+		"Timestamp": m.now().UnixMilli(),
+		"CloudWatchMetrics": []map[string]interface{}{{
+			"Namespace":  m.config.Namespace,
+			"Dimensions": [][]string{dimensions},
+			"Metrics":    metrics,
+		}},
+	}
+
+	line, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	_, err = m.config.Writer.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+var (
+	testCounter   = service.Metric{Name: "requests_total", Kind: service.MetricCounter, Unit: service.UnitCount, Help: "Requests."}
+	testGauge     = service.Metric{Name: "in_flight", Kind: service.MetricGauge, Unit: service.UnitNone}
+	testHistogram = service.Metric{Name: "latency_ms", Kind: service.MetricHistogram, Unit: service.UnitMilliseconds, Buckets: []float64{10, 100}}
+)
+
+// decodeDocuments decodes one EMF document per line
+func decodeDocuments(t *testing.T, output string) []map[string]interface{} {
+	t.Helper()
+	var documents []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		if line == "" {
+			continue
+		}
+		var document map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &document); err != nil {
+			t.Fatalf("Failed to decode %q: %v", line, err)
+		}
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+// emfMetadata returns the CloudWatch metadata of a document
+func emfMetadata(t *testing.T, document map[string]interface{}) map[string]interface{} {
+	t.Helper()
+	aws, ok := document["_aws"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected _aws metadata, got %v", document)
+	}
+	return aws["CloudWatchMetrics"].([]interface{})[0].(map[string]interface{})
+}
+
+func TestEMF_Flush(t *testing.T) {
+	var buf bytes.Buffer
+	emf := NewEMF(EMFConfig{Writer: &buf, Dimensions: []string{"route"}}, "chargeback-api")
+	emf.now = func() time.Time { return time.UnixMilli(1700000000123) }
+	ctx := context.Background()
+
+	labels := service.Labels{"route": "/chargebacks", "merchant_id": "merchant-1"}
+	emf.Add(ctx, testCounter, 1, labels)
+	emf.Add(ctx, testCounter, 2, labels)
+	emf.Set(ctx, testGauge, 5, labels)
+	emf.Set(ctx, testGauge, 3, labels)
+	emf.Observe(ctx, testHistogram, 12.5, labels)
+	emf.Observe(ctx, testHistogram, 40, labels)
+	emf.Add(ctx, testCounter, 1, service.Labels{"route": "/health"})
+
+	if buf.Len() != 0 {
+		t.Fatalf("Expected values to be buffered until flushed, got %s", buf.String())
+	}
+	if err := emf.Flush(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	documents := decodeDocuments(t, buf.String())
+	if len(documents) != 2 {
+		t.Fatalf("Expected one document per label set, got %d", len(documents))
+	}
+
+	// Label sets are written in order of their sorted labels
+	document := documents[0]
+	if document["requests_total"] != 3.0 || document["in_flight"] != 3.0 {
+		t.Errorf("Expected the summed counter and the last gauge value, got %v and %v", document["requests_total"], document["in_flight"])
+	}
+	if values := document["latency_ms"].([]interface{}); len(values) != 2 || values[0] != 12.5 {
+		t.Errorf("Expected the histogram values, got %v", values)
+	}
+	if document["service"] != "chargeback-api" || document["merchant_id"] != "merchant-1" {
+		t.Errorf("Expected the service and labels as properties, got %v", document)
+	}
+
+	metadata := emfMetadata(t, document)
+	if metadata["Namespace"] != "chargeback-api" {
+		t.Errorf("Expected the service name as namespace, got %v", metadata["Namespace"])
+	}
+	dimensions, _ := json.Marshal(metadata["Dimensions"])
+	if string(dimensions) != `[["service","route"]]` {
+		t.Errorf("Expected only allowed labels as dimensions, got %s", dimensions)
+	}
+	metrics, _ := json.Marshal(metadata["Metrics"])
+	if string(metrics) != `[{"Name":"requests_total","Unit":"Count"},{"Name":"in_flight","Unit":"None"},{"Name":"latency_ms","Unit":"Milliseconds"}]` {
+		t.Errorf("Unexpected metric definitions %s", metrics)
+	}
+	if timestamp := document["_aws"].(map[string]interface{})["Timestamp"]; timestamp != 1700000000123.0 {
+		t.Errorf("Expected the flush time in milliseconds, got %v", timestamp)
+	}
+
+	// Flushed values are not written again
+	buf.Reset()
+	emf.Flush(ctx)
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing after a second flush, got %s", buf.String())
+	}
+}
+
+func TestEMF_DefaultDimensions(t *testing.T) {
+	var buf bytes.Buffer
+	emf := NewEMF(EMFConfig{Writer: &buf}, "chargeback-api")
+	ctx := context.Background()
+
+	emf.Add(ctx, testCounter, 1, service.Labels{"reason": "fraud", "merchant_id": "merchant-1"})
+	if err := emf.Flush(ctx); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Merchants stay searchable as properties without a metric each
+	document := decodeDocuments(t, buf.String())[0]
+	dimensions, _ := json.Marshal(emfMetadata(t, document)["Dimensions"])
+	if string(dimensions) != `[["service","reason"]]` || document["merchant_id"] != "merchant-1" {
+		t.Errorf("Expected merchant_id as a property only, got dimensions %s in %v", dimensions, document)
+	}
+}
+
+func TestEMF_FullHistogram(t *testing.T) {
+	var buf bytes.Buffer
+	emf := NewEMF(EMFConfig{Writer: &buf, Namespace: "Chargebacks"}, "")
+	ctx := context.Background()
+
+	for i := 0; i < emfMaxValues+1; i++ {
+		emf.Observe(ctx, testHistogram, float64(i), nil)
+	}
+
+	documents := decodeDocuments(t, buf.String())
+	if len(documents) != 1 {
+		t.Fatalf("Expected a document once the histogram is full, got %d", len(documents))
+	}
+	if values := documents[0]["latency_ms"].([]interface{}); len(values) != emfMaxValues {
+		t.Errorf("Expected %d values, got %d", emfMaxValues, len(values))
+	}
+	if metadata := emfMetadata(t, documents[0]); metadata["Namespace"] != "Chargebacks" {
+		t.Errorf("Expected the configured namespace, got %v", metadata["Namespace"])
+	}
+
+	buf.Reset()
+	emf.Flush(ctx)
+	if values := decodeDocuments(t, buf.String())[0]["latency_ms"].([]interface{}); len(values) != 1 {
+		t.Errorf("Expected the remaining value on flush, got %v", values)
+	}
+}
+
+func TestEMF_BackgroundFlush(t *testing.T) {
+	var buf syncBuffer
+	emf := NewEMF(EMFConfig{Writer: &buf, FlushInterval: 10 * time.Millisecond}, "chargeback-api")
+	emf.Add(context.Background(), testCounter, 1, nil)
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "requests_total") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "requests_total") {
+		t.Error("Expected the background flush to write the counter")
+	}
+
+	if err := emf.Close(context.Background()); err != nil {
+		t.Errorf("Unexpected close error: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// Memory is a service.Metrics keeping every value in memory, for tests
+type Memory struct {
+	mu     sync.Mutex
+	values map[string]float64
+	series map[string][]float64
+}
+
+// NewMemory creates an empty in-memory recorder
+func NewMemory() *Memory {
+	return &Memory{
+		values: make(map[string]float64),
+		series: make(map[string][]float64),
+	}
+}
+
+// Add increments a counter by value
+func (m *Memory) Add(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[metric.Name+labelsKey(labels)] += value
+}
+
+// Observe records a value in a histogram
+func (m *Memory) Observe(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metric.Name + labelsKey(labels)
+	m.series[key] = append(m.series[key], value)
+}
+
+// Set updates the value of a gauge
+func (m *Memory) Set(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[metric.Name+labelsKey(labels)] = value
+}
+
+// Value returns the value of a counter or gauge with exactly these labels,
+// zero if never recorded
+func (m *Memory) Value(name string, labels service.Labels) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[name+labelsKey(labels)]
+}
+
+// Observations returns the values recorded in a histogram with exactly
+// these labels, in recording order
+func (m *Memory) Observations(name string, labels service.Labels) []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]float64(nil), m.series[name+labelsKey(labels)]...)
+}
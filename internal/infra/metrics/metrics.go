@@ -0,0 +1,174 @@
+// Package metrics implements service.Metrics: CloudWatch Embedded Metric
+// Format for Lambda, a Prometheus endpoint for the local server and an
+// in-memory recorder for tests
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// Exporter selects where metrics are published
+type Exporter string
+
+const (
+	// ExporterNone discards every value
+	ExporterNone Exporter = "none"
+
+	// ExporterEMF writes CloudWatch Embedded Metric Format documents
+	ExporterEMF Exporter = "emf"
+
+	// ExporterPrometheus serves the Prometheus text exposition format
+	ExporterPrometheus Exporter = "prometheus"
+)
+
+// ParseExporter converts an exporter name, case-insensitively
+func ParseExporter(name string) (Exporter, error) {
+	switch exporter := Exporter(strings.ToLower(strings.TrimSpace(name))); exporter {
+	case ExporterNone, ExporterEMF, ExporterPrometheus:
+		return exporter, nil
+	case "":
+		return ExporterNone, nil
+	default:
+		return ExporterNone, fmt.Errorf("unknown metrics exporter %q (want none, emf or prometheus)", name)
+	}
+}
+
+// DefaultBuckets are the histogram bucket upper bounds used when a metric
+// defines none. They suit latencies in milliseconds.
+var DefaultBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Config configures metrics
+type Config struct {
+	// Exporter selects where metrics are published, ExporterNone if empty
+	Exporter Exporter
+
+	// ServiceName is added to every EMF document as the "service" dimension
+	ServiceName string
+
+	// EMF configures ExporterEMF
+	EMF EMFConfig
+
+	// PrometheusLabels lists the service.HighCardinalityLabels kept in
+	// Prometheus series; the others are left out
+	PrometheusLabels []string
+}
+
+// Validate checks the metrics configuration
+func (c Config) Validate() error {
+	if _, err := ParseExporter(string(c.Exporter)); err != nil {
+		return err
+	}
+	if c.EMF.FlushInterval < 0 {
+		return fmt.Errorf("EMF flush interval must not be negative, got %s", c.EMF.FlushInterval)
+	}
+	for _, name := range c.PrometheusLabels {
+		if !slices.Contains(service.HighCardinalityLabels, name) {
+			return fmt.Errorf("unknown Prometheus label %q (want one of %s)", name, strings.Join(service.HighCardinalityLabels, ", "))
+		}
+	}
+	return nil
+}
+
+// Provider owns the metrics created by Setup
+type Provider struct {
+	metrics service.Metrics
+	handler http.Handler
+	emf     *EMF
+}
+
+// Setup creates the configured metrics
+func Setup(config Config) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid metrics config: %w", err)
+	}
+
+	switch config.Exporter {
+	case ExporterEMF:
+		emf := NewEMF(config.EMF, config.ServiceName)
+		return &Provider{metrics: emf, emf: emf}, nil
+	case ExporterPrometheus:
+		prometheus := NewPrometheus(config.PrometheusLabels...)
+		return &Provider{metrics: prometheus, handler: prometheus}, nil
+	default:
+		return &Provider{metrics: service.NopMetrics{}}, nil
+	}
+}
+
+// Metrics returns the recorder handed to the use cases and the API
+func (p *Provider) Metrics() service.Metrics {
+	return p.metrics
+}
+
+// Handler serves the Prometheus exposition, nil for other exporters
+func (p *Provider) Handler() http.Handler {
+	return p.handler
+}
+
+// Flush writes the buffered EMF documents. Lambda handlers call it before
+// returning, as the execution environment may be frozen afterwards.
+func (p *Provider) Flush(ctx context.Context) error {
+	if p.emf == nil {
+		return nil
+	}
+	return p.emf.Flush(ctx)
+}
+
+// Close flushes the buffered values and stops background flushing
+func (p *Provider) Close(ctx context.Context) error {
+	if p.emf == nil {
+		return nil
+	}
+	return p.emf.Close(ctx)
+}
+
+// labelsKey identifies a label set, with labels sorted by name
+func labelsKey(labels service.Labels) string {
+	var b strings.Builder
+	for _, key := range sortedKeys(labels) {
+		b.WriteByte(0)
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(labels[key])
+	}
+	return b.String()
+}
+
+// sortedKeys returns the label names in ascending order
+func sortedKeys(labels service.Labels) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// copyLabels returns a copy of labels the caller may not modify later
+func copyLabels(labels service.Labels) service.Labels {
+	copied := make(service.Labels, len(labels))
+	for key, value := range labels {
+		copied[key] = value
+	}
+	return copied
+}
+
+// buckets returns the bucket upper bounds of a histogram
+func buckets(metric service.Metric) []float64 {
+	if len(metric.Buckets) > 0 {
+		return metric.Buckets
+	}
+	return DefaultBuckets
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes and reads
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestParseExporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Exporter
+		wantErr bool
+	}{
+		{name: "", want: ExporterNone},
+		{name: "none", want: ExporterNone},
+		{name: "EMF", want: ExporterEMF},
+		{name: " prometheus ", want: ExporterPrometheus},
+		{name: "statsd", want: ExporterNone, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExporter(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected exporter '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+
+	none, err := Setup(Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := none.Metrics().(service.NopMetrics); !ok || none.Handler() != nil {
+		t.Errorf("Expected discarded metrics without a handler, got %T", none.Metrics())
+	}
+	if err := none.Flush(ctx); err != nil {
+		t.Errorf("Unexpected flush error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	emf, err := Setup(Config{Exporter: ExporterEMF, ServiceName: "chargeback-lambda", EMF: EMFConfig{Writer: &buf}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	emf.Metrics().Add(ctx, testCounter, 1, nil)
+	if err := emf.Close(ctx); err != nil {
+		t.Fatalf("Unexpected close error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"requests_total":1`) {
+		t.Errorf("Expected the counter to be written on close, got %s", buf.String())
+	}
+
+	prometheus, err := Setup(Config{Exporter: ExporterPrometheus})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if prometheus.Handler() == nil {
+		t.Error("Expected a Prometheus handler")
+	}
+
+	if _, err := Setup(Config{Exporter: "statsd"}); err == nil {
+		t.Error("Expected an error for an unknown exporter")
+	}
+	if _, err := Setup(Config{EMF: EMFConfig{FlushInterval: -1}}); err == nil {
+		t.Error("Expected an error for a negative flush interval")
+	}
+}
+
+func TestMemory(t *testing.T) {
+	memory := NewMemory()
+	ctx := context.Background()
+	labels := service.Labels{"reason": "fraud"}
+
+	memory.Add(ctx, testCounter, 1, labels)
+	memory.Add(ctx, testCounter, 1, service.Labels{"reason": "fraud"})
+	memory.Set(ctx, testGauge, 7, nil)
+	memory.Observe(ctx, testHistogram, 1.5, labels)
+	labels["reason"] = "modified"
+
+	if got := memory.Value("requests_total", service.Labels{"reason": "fraud"}); got != 2 {
+		t.Errorf("Expected counter 2, got %v", got)
+	}
+	if got := memory.Value("in_flight", nil); got != 7 {
+		t.Errorf("Expected gauge 7, got %v", got)
+	}
+	if got := memory.Observations("latency_ms", service.Labels{"reason": "fraud"}); len(got) != 1 || got[0] != 1.5 {
+		t.Errorf("Expected one observation, got %v", got)
+	}
+	if got := memory.Value("requests_total", service.Labels{"reason": "other"}); got != 0 {
+		t.Errorf("Expected zero for other labels, got %v", got)
+	}
+}
@@ -0,0 +1,233 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// prometheusContentType is the content type of the text exposition format
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Prometheus is a service.Metrics keeping cumulative values in memory and
+// serving them in the Prometheus text exposition format
+type Prometheus struct {
+	dropped []string
+
+	mu       sync.Mutex
+	families map[string]*prometheusFamily
+}
+
+// prometheusFamily holds the series of one metric
+type prometheusFamily struct {
+	metric service.Metric
+	series map[string]*prometheusSeries
+}
+
+// prometheusSeries holds the value of one label set. Histograms keep a count
+// per bucket, not cumulated.
+type prometheusSeries struct {
+	labels service.Labels
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewPrometheus creates an empty Prometheus recorder. The
+// service.HighCardinalityLabels are left out of every series, summing the
+// values they qualify, unless named in keep.
+func NewPrometheus(keep ...string) *Prometheus {
+	var dropped []string
+	for _, name := range service.HighCardinalityLabels {
+		if !slices.Contains(keep, name) {
+			dropped = append(dropped, name)
+		}
+	}
+	return &Prometheus{dropped: dropped, families: make(map[string]*prometheusFamily)}
+}
+
+// Add increments a counter by value
+func (p *Prometheus) Add(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.series(metric, labels).value += value
+}
+
+// Observe records a value in a histogram
+func (p *Prometheus) Observe(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	series := p.series(metric, labels)
+	bounds := buckets(metric)
+	if series.counts == nil {
+		series.counts = make([]uint64, len(bounds))
+	}
+	if i := sort.SearchFloat64s(bounds, value); i < len(bounds) {
+		series.counts[i]++
+	}
+	series.sum += value
+	series.count++
+}
+
+// Set updates the value of a gauge
+func (p *Prometheus) Set(ctx context.Context, metric service.Metric, value float64, labels service.Labels) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.series(metric, labels).value = value
+}
+
+// series returns the series of a label set, creating it if needed. The
+// caller holds the lock.
+func (p *Prometheus) series(metric service.Metric, labels service.Labels) *prometheusSeries {
+	name := prometheusName(metric.Name)
+	family, ok := p.families[name]
+	if !ok {
+		family = &prometheusFamily{metric: metric, series: make(map[string]*prometheusSeries)}
+		p.families[name] = family
+	}
+
+	if len(p.dropped) > 0 {
+		kept := make(service.Labels, len(labels))
+		for name, value := range labels {
+			if !slices.Contains(p.dropped, name) {
+				kept[name] = value
+			}
+		}
+		labels = kept
+	}
+
+	key := labelsKey(labels)
+	series, ok := family.series[key]
+	if !ok {
+		series = &prometheusSeries{labels: copyLabels(labels)}
+		family.series[key] = series
+	}
+	return series
+}
+
+// ServeHTTP writes every metric in the text exposition format
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	w.WriteHeader(http.StatusOK)
+
+	out := bufio.NewWriter(w)
+	defer out.Flush()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	names := make([]string, 0, len(p.families))
+	for name := range p.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := p.families[name]
+
+		if family.metric.Help != "" {
+			out.WriteString("# HELP " + name + " " + escapeHelp(family.metric.Help) + "\n")
+		}
+		out.WriteString("# TYPE " + name + " " + family.metric.Kind.String() + "\n")
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			series := family.series[key]
+			if family.metric.Kind != service.MetricHistogram {
+				writeSample(out, name, series.labels, "", "", series.value)
+				continue
+			}
+
+			var cumulative uint64
+			for i, bound := range buckets(family.metric) {
+				if series.counts != nil {
+					cumulative += series.counts[i]
+				}
+				writeSample(out, name+"_bucket", series.labels, "le", formatFloat(bound), float64(cumulative))
+			}
+			writeSample(out, name+"_bucket", series.labels, "le", "+Inf", float64(series.count))
+			writeSample(out, name+"_sum", series.labels, "", "", series.sum)
+			writeSample(out, name+"_count", series.labels, "", "", float64(series.count))
+		}
+	}
+}
+
+// writeSample writes one sample line, with an extra label if extraName is set
+func writeSample(out *bufio.Writer, name string, labels service.Labels, extraName, extraValue string, value float64) {
+	out.WriteString(name)
+
+	keys := sortedKeys(labels)
+	if len(keys) > 0 || extraName != "" {
+		out.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(prometheusName(key) + `="` + escapeLabelValue(labels[key]) + `"`)
+		}
+		if extraName != "" {
+			if len(keys) > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		out.WriteByte('}')
+	}
+
+	out.WriteString(" " + formatFloat(value) + "\n")
+}
+
+// formatFloat formats a sample value as Prometheus expects it
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
+
+// prometheusName replaces the characters not allowed in metric and label names
+func prometheusName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_', r == ':':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// escapeLabelValue escapes backslashes, quotes and line feeds
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes backslashes and line feeds
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
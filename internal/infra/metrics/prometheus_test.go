@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+func TestPrometheus_ServeHTTP(t *testing.T) {
+	prometheus := NewPrometheus()
+	ctx := context.Background()
+
+	prometheus.Add(ctx, testCounter, 2, service.Labels{"route": "/chargebacks", "method": "POST"})
+	prometheus.Add(ctx, testCounter, 1, service.Labels{"route": "/chargebacks", "method": "POST"})
+	prometheus.Set(ctx, testGauge, 4, nil)
+	prometheus.Observe(ctx, testHistogram, 5, service.Labels{"operation": "Save"})
+	prometheus.Observe(ctx, testHistogram, 50, service.Labels{"operation": "Save"})
+	prometheus.Observe(ctx, testHistogram, 500, service.Labels{"operation": "Save"})
+	prometheus.Add(ctx, service.Metric{Name: "odd-name", Kind: service.MetricCounter, Help: "Back\\slash\nnewline."}, 1, service.Labels{"value": "say \"hi\"\n"})
+
+	recorder := httptest.NewRecorder()
+	prometheus.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != prometheusContentType {
+		t.Errorf("Expected Content-Type %q, got %q", prometheusContentType, contentType)
+	}
+
+	want := `# TYPE in_flight gauge
+in_flight 4
+# TYPE latency_ms histogram
+latency_ms_bucket{operation="Save",le="10"} 1
+latency_ms_bucket{operation="Save",le="100"} 2
+latency_ms_bucket{operation="Save",le="+Inf"} 3
+latency_ms_sum{operation="Save"} 555
+latency_ms_count{operation="Save"} 3
+# HELP odd_name Back\\slash\nnewline.
+# TYPE odd_name counter
+odd_name{value="say \"hi\"\n"} 1
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{method="POST",route="/chargebacks"} 3
+`
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("Unexpected exposition:\n got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPrometheus_DefaultBuckets(t *testing.T) {
+	prometheus := NewPrometheus()
+	prometheus.Observe(context.Background(), service.Metric{Name: "wait_ms", Kind: service.MetricHistogram}, 3, nil)
+
+	recorder := httptest.NewRecorder()
+	prometheus.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := recorder.Body.String()
+	for _, want := range []string{`wait_ms_bucket{le="1"} 0`, `wait_ms_bucket{le="5"} 1`, `wait_ms_bucket{le="10000"} 1`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in:\n%s", want, body)
+		}
+	}
+}
+
+func TestPrometheus_HighCardinalityLabels(t *testing.T) {
+	counter := service.Metric{Name: "created_total", Kind: service.MetricCounter}
+	record := func(prometheus *Prometheus) string {
+		ctx := context.Background()
+		prometheus.Add(ctx, counter, 1, service.Labels{"reason": "fraud", "merchant_id": "merchant-1"})
+		prometheus.Add(ctx, counter, 1, service.Labels{"reason": "fraud", "merchant_id": "merchant-2"})
+		recorder := httptest.NewRecorder()
+		prometheus.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return recorder.Body.String()
+	}
+
+	// Merchants are summed unless kept
+	if body := record(NewPrometheus()); !strings.Contains(body, `created_total{reason="fraud"} 2`) || strings.Contains(body, "merchant-1") {
+		t.Errorf("Expected one series without the merchant, got:\n%s", body)
+	}
+	if body := record(NewPrometheus("merchant_id")); !strings.Contains(body, `created_total{merchant_id="merchant-1",reason="fraud"} 1`) {
+		t.Errorf("Expected a series per merchant, got:\n%s", body)
+	}
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/repository"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// MetricRepositoryDuration is the latency of repository calls by operation
+// and outcome: "ok" or "error"
+var MetricRepositoryDuration = service.Metric{
+	Name: "repository_duration_ms",
+	Kind: service.MetricHistogram,
+	Unit: service.UnitMilliseconds,
+	Help: "Latency of chargeback repository calls in milliseconds, by operation and outcome.",
+}
+
+// ChargebackRepository is a repository.ChargebackRepository recording the
+// latency of every call of the wrapped repository
+type ChargebackRepository struct {
+	next    repository.ChargebackRepository
+	metrics service.Metrics
+	now     func() time.Time
+}
+
+// NewChargebackRepository wraps a repository, recording into metrics
+func NewChargebackRepository(next repository.ChargebackRepository, metrics service.Metrics) *ChargebackRepository {
+	return &ChargebackRepository{
+		next:    next,
+		metrics: metrics,
+		now:     time.Now,
+	}
+}
+
+// observe records the latency of an operation started at start
+func (r *ChargebackRepository) observe(ctx context.Context, operation string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	r.metrics.Observe(ctx, MetricRepositoryDuration, milliseconds(r.now().Sub(start)), service.Labels{
+		"operation": operation,
+		"outcome":   outcome,
+	})
+}
+
+// Save stores a new chargeback
+func (r *ChargebackRepository) Save(ctx context.Context, chargeback *entity.Chargeback) (err error) {
+	defer func(start time.Time) { r.observe(ctx, "Save", start, err) }(r.now())
+	return r.next.Save(ctx, chargeback)
+}
+
+// FindByID returns the chargeback with the given ID
+func (r *ChargebackRepository) FindByID(ctx context.Context, id string) (chargeback *entity.Chargeback, err error) {
+	defer func(start time.Time) { r.observe(ctx, "FindByID", start, err) }(r.now())
+	return r.next.FindByID(ctx, id)
+}
+
+// FindByTransactionID returns the chargeback of a transaction
+func (r *ChargebackRepository) FindByTransactionID(ctx context.Context, transactionID string) (chargeback *entity.Chargeback, err error) {
+	defer func(start time.Time) { r.observe(ctx, "FindByTransactionID", start, err) }(r.now())
+	return r.next.FindByTransactionID(ctx, transactionID)
+}
+
+// FindByMerchantID returns the chargebacks of a merchant
+func (r *ChargebackRepository) FindByMerchantID(ctx context.Context, merchantID string) (chargebacks []*entity.Chargeback, err error) {
+	defer func(start time.Time) { r.observe(ctx, "FindByMerchantID", start, err) }(r.now())
+	return r.next.FindByMerchantID(ctx, merchantID)
+}
+
+// Update replaces a stored chargeback
+func (r *ChargebackRepository) Update(ctx context.Context, chargeback *entity.Chargeback) (err error) {
+	defer func(start time.Time) { r.observe(ctx, "Update", start, err) }(r.now())
+	return r.next.Update(ctx, chargeback)
+}
+
+// Delete removes the chargeback with the given ID
+func (r *ChargebackRepository) Delete(ctx context.Context, id string) (err error) {
+	defer func(start time.Time) { r.observe(ctx, "Delete", start, err) }(r.now())
+	return r.next.Delete(ctx, id)
+}
+
+// FindByStatus returns the chargebacks in a status
+func (r *ChargebackRepository) FindByStatus(ctx context.Context, status entity.ChargebackStatus) (chargebacks []*entity.Chargeback, err error) {
+	defer func(start time.Time) { r.observe(ctx, "FindByStatus", start, err) }(r.now())
+	return r.next.FindByStatus(ctx, status)
+}
+
+// List returns a page of chargebacks
+func (r *ChargebackRepository) List(ctx context.Context, offset, limit int) (chargebacks []*entity.Chargeback, err error) {
+	defer func(start time.Time) { r.observe(ctx, "List", start, err) }(r.now())
+	return r.next.List(ctx, offset, limit)
+}
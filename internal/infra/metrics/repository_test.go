@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// stubRepository fails every call with err
+type stubRepository struct {
+	err error
+}
+
+func (r *stubRepository) Save(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.err
+}
+func (r *stubRepository) FindByID(ctx context.Context, id string) (*entity.Chargeback, error) {
+	return nil, r.err
+}
+func (r *stubRepository) FindByTransactionID(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+	return nil, r.err
+}
+func (r *stubRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]*entity.Chargeback, error) {
+	return nil, r.err
+}
+func (r *stubRepository) Update(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.err
+}
+func (r *stubRepository) Delete(ctx context.Context, id string) error {
+	return r.err
+}
+func (r *stubRepository) FindByStatus(ctx context.Context, status entity.ChargebackStatus) ([]*entity.Chargeback, error) {
+	return nil, r.err
+}
+func (r *stubRepository) List(ctx context.Context, offset, limit int) ([]*entity.Chargeback, error) {
+	return nil, r.err
+}
+
+// steppingClock advances by step on every reading
+func steppingClock(step time.Duration) func() time.Time {
+	now := time.Unix(1700000000, 0)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestChargebackRepository_Latency(t *testing.T) {
+	memory := NewMemory()
+	stub := &stubRepository{}
+	repo := NewChargebackRepository(stub, memory)
+	repo.now = steppingClock(1500 * time.Microsecond)
+	ctx := context.Background()
+
+	repo.Save(ctx, &entity.Chargeback{})
+	repo.FindByID(ctx, "cb_123")
+	repo.FindByTransactionID(ctx, "txn_123")
+	repo.FindByMerchantID(ctx, "merchant_456")
+	repo.Update(ctx, &entity.Chargeback{})
+	repo.Delete(ctx, "cb_123")
+	repo.FindByStatus(ctx, entity.StatusPending)
+	repo.List(ctx, 0, 10)
+
+	for _, operation := range []string{"Save", "FindByID", "FindByTransactionID", "FindByMerchantID", "Update", "Delete", "FindByStatus", "List"} {
+		got := memory.Observations("repository_duration_ms", service.Labels{"operation": operation, "outcome": "ok"})
+		if len(got) != 1 || got[0] != 1.5 {
+			t.Errorf("%s: expected one 1.5ms observation, got %v", operation, got)
+		}
+	}
+
+	stub.err = errors.New("throttled")
+	if _, err := repo.FindByTransactionID(ctx, "txn_123"); err == nil {
+		t.Fatal("Expected the error of the wrapped repository")
+	}
+	if got := memory.Observations("repository_duration_ms", service.Labels{"operation": "FindByTransactionID", "outcome": "error"}); len(got) != 1 {
+		t.Errorf("Expected the failed call to be recorded, got %v", got)
+	}
+}
@@ -0,0 +1,176 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// maxTakeAttempts bounds the retries of a token taken concurrently by
+// another instance
+const maxTakeAttempts = 3
+
+// DynamoDBAPI is the part of the DynamoDB client used by DynamoDBStore
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+}
+
+// DynamoDBStore keeps buckets and counters in a table keyed by "limit_key",
+// shared by every instance. Items carry their expiry in "expires_at", in
+// Unix seconds, for the table's TTL.
+type DynamoDBStore struct {
+	client    DynamoDBAPI
+	tableName string
+}
+
+// NewDynamoDBStore creates a store in the given table
+func NewDynamoDBStore(client DynamoDBAPI, tableName string) *DynamoDBStore {
+	return &DynamoDBStore{client: client, tableName: tableName}
+}
+
+// Take takes a token from the bucket of key. The bucket is read, refilled
+// and written back on condition that no other instance wrote it meanwhile,
+// retrying a few times if one did.
+func (s *DynamoDBStore) Take(ctx context.Context, key string, bucket Bucket, now time.Time) (service.RateLimitResult, error) {
+	for attempt := 0; attempt < maxTakeAttempts; attempt++ {
+		output, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName:      aws.String(s.tableName),
+			Key:            s.key(key),
+			ConsistentRead: aws.Bool(true),
+		})
+		if err != nil {
+			return service.RateLimitResult{}, fmt.Errorf("failed to read bucket: %w", err)
+		}
+
+		tokens, last, version := float64(bucket.Burst), now, int64(0)
+		if len(output.Item) > 0 {
+			if tokens, last, version, err = decodeBucket(output.Item); err != nil {
+				return service.RateLimitResult{}, err
+			}
+		}
+
+		tokens, result := bucket.take(tokens, last, now)
+		if !result.Allowed {
+			return result, nil
+		}
+
+		// The version guards the bucket against concurrent writes
+		input := &dynamodb.PutItemInput{
+			TableName: aws.String(s.tableName),
+			Item: map[string]types.AttributeValue{
+				"limit_key":  &types.AttributeValueMemberS{Value: key},
+				"tokens":     &types.AttributeValueMemberN{Value: strconv.FormatFloat(tokens, 'f', -1, 64)},
+				"updated_at": number(now.UnixMicro()),
+				"version":    number(version + 1),
+				"expires_at": number(now.Add(result.Reset).Add(time.Minute).Unix()),
+			},
+			ConditionExpression: aws.String("attribute_not_exists(limit_key)"),
+		}
+		if version > 0 {
+			input.ConditionExpression = aws.String("#version = :version")
+			input.ExpressionAttributeNames = map[string]string{"#version": "version"}
+			input.ExpressionAttributeValues = map[string]types.AttributeValue{":version": number(version)}
+		}
+
+		_, err = s.client.PutItem(ctx, input)
+		if isConditionFailed(err) {
+			continue
+		}
+		if err != nil {
+			return service.RateLimitResult{}, fmt.Errorf("failed to write bucket: %w", err)
+		}
+		return result, nil
+	}
+
+	return service.RateLimitResult{}, fmt.Errorf("bucket %s kept changing after %d attempts", key, maxTakeAttempts)
+}
+
+// Increment adds one to the counter of key unless it reached limit, with a
+// single conditional atomic update
+func (s *DynamoDBStore) Increment(ctx context.Context, key string, limit int64, now, expires time.Time) (int64, bool, error) {
+	output, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:           aws.String(s.tableName),
+		Key:                 s.key(key),
+		UpdateExpression:    aws.String("ADD #count :one SET expires_at = if_not_exists(expires_at, :expires)"),
+		ConditionExpression: aws.String("attribute_not_exists(#count) OR #count < :limit"),
+		ExpressionAttributeNames: map[string]string{
+			"#count": "count",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":     number(1),
+			":limit":   number(limit),
+			":expires": number(expires.Unix()),
+		},
+		ReturnValues: types.ReturnValueUpdatedNew,
+	})
+	if isConditionFailed(err) {
+		return limit, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to increment counter: %w", err)
+	}
+
+	count, err := numberAttribute(output.Attributes, "count")
+	if err != nil {
+		return 0, false, err
+	}
+	return int64(count), true, nil
+}
+
+// key returns the primary key of an item
+func (s *DynamoDBStore) key(key string) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"limit_key": &types.AttributeValueMemberS{Value: key},
+	}
+}
+
+// decodeBucket returns the tokens, last update and version of a bucket item
+func decodeBucket(item map[string]types.AttributeValue) (float64, time.Time, int64, error) {
+	tokens, err := numberAttribute(item, "tokens")
+	if err != nil {
+		return 0, time.Time{}, 0, err
+	}
+	updatedAt, err := numberAttribute(item, "updated_at")
+	if err != nil {
+		return 0, time.Time{}, 0, err
+	}
+	version, err := numberAttribute(item, "version")
+	if err != nil {
+		return 0, time.Time{}, 0, err
+	}
+	return tokens, time.UnixMicro(int64(updatedAt)), int64(version), nil
+}
+
+// numberAttribute returns the number attribute name of item
+func numberAttribute(item map[string]types.AttributeValue, name string) (float64, error) {
+	attribute, ok := item[name].(*types.AttributeValueMemberN)
+	if !ok {
+		return 0, fmt.Errorf("rate limit item has no number %s", name)
+	}
+	value, err := strconv.ParseFloat(attribute.Value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("rate limit item has an invalid %s %q", name, attribute.Value)
+	}
+	return value, nil
+}
+
+// number returns a number attribute value
+func number(value int64) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(value, 10)}
+}
+
+// isConditionFailed reports whether a write was refused by its condition
+func isConditionFailed(err error) bool {
+	var conditionFailed *types.ConditionalCheckFailedException
+	return errors.As(err, &conditionFailed)
+}
@@ -0,0 +1,217 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// stubDynamoDB keeps items by limit key, evaluating the conditions written
+// by DynamoDBStore. beforePut runs before each put, e.g. to simulate a
+// concurrent writer.
+type stubDynamoDB struct {
+	items     map[string]map[string]types.AttributeValue
+	err       error
+	puts      int
+	updates   []*dynamodb.UpdateItemInput
+	beforePut func(s *stubDynamoDB)
+}
+
+func (s *stubDynamoDB) item(key map[string]types.AttributeValue) (string, map[string]types.AttributeValue) {
+	name := key["limit_key"].(*types.AttributeValueMemberS).Value
+	if s.items == nil {
+		s.items = make(map[string]map[string]types.AttributeValue)
+	}
+	return name, s.items[name]
+}
+
+func (s *stubDynamoDB) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	_, item := s.item(params.Key)
+	return &dynamodb.GetItemOutput{Item: item}, nil
+}
+
+func (s *stubDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	s.puts++
+	if s.beforePut != nil {
+		s.beforePut(s)
+	}
+	name, existing := s.item(map[string]types.AttributeValue{"limit_key": params.Item["limit_key"]})
+
+	switch aws.ToString(params.ConditionExpression) {
+	case "attribute_not_exists(limit_key)":
+		if existing != nil {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	case "#version = :version":
+		if existing == nil || existing["version"].(*types.AttributeValueMemberN).Value != params.ExpressionAttributeValues[":version"].(*types.AttributeValueMemberN).Value {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	}
+	s.items[name] = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (s *stubDynamoDB) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	s.updates = append(s.updates, params)
+	if s.err != nil {
+		return nil, s.err
+	}
+	name, existing := s.item(params.Key)
+
+	count := int64(0)
+	if existing != nil {
+		count, _ = strconv.ParseInt(existing["count"].(*types.AttributeValueMemberN).Value, 10, 64)
+	}
+	limit, _ := strconv.ParseInt(params.ExpressionAttributeValues[":limit"].(*types.AttributeValueMemberN).Value, 10, 64)
+	if count >= limit {
+		return nil, &types.ConditionalCheckFailedException{}
+	}
+
+	count++
+	s.items[name] = map[string]types.AttributeValue{
+		"limit_key": params.Key["limit_key"],
+		"count":     number(count),
+	}
+	return &dynamodb.UpdateItemOutput{Attributes: map[string]types.AttributeValue{"count": number(count)}}, nil
+}
+
+func TestDynamoDBStore_Take(t *testing.T) {
+	// Arrange
+	client := &stubDynamoDB{}
+	store := NewDynamoDBStore(client, "chargeback-rate-limits")
+	bucket := Bucket{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	// Act & Assert
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(ctx, "rate#merchant:merchant-1", bucket, testNow)
+		if err != nil {
+			t.Fatalf("Take() error = %v", err)
+		}
+		if !result.Allowed {
+			t.Fatalf("Expected request %d to be allowed, got %+v", i, result)
+		}
+	}
+
+	result, err := store.Take(ctx, "rate#merchant:merchant-1", bucket, testNow)
+	if err != nil || result.Allowed || result.RetryAfter != time.Second {
+		t.Fatalf("Expected the empty bucket to reject for a second, got %+v, %v", result, err)
+	}
+	if client.puts != 2 {
+		t.Errorf("Expected rejections not to write, got %d puts", client.puts)
+	}
+
+	item := client.items["rate#merchant:merchant-1"]
+	if item["version"].(*types.AttributeValueMemberN).Value != "2" {
+		t.Errorf("Expected the version to count writes, got %v", item["version"])
+	}
+	wantExpiry := strconv.FormatInt(testNow.Add(2*time.Second+time.Minute).Unix(), 10)
+	if item["expires_at"].(*types.AttributeValueMemberN).Value != wantExpiry {
+		t.Errorf("Expected the item to expire once refilled, at %s, got %v", wantExpiry, item["expires_at"])
+	}
+
+	result, _ = store.Take(ctx, "rate#merchant:merchant-1", bucket, testNow.Add(time.Second))
+	if !result.Allowed {
+		t.Errorf("Expected a refilled token to be taken, got %+v", result)
+	}
+}
+
+func TestDynamoDBStore_Take_Concurrent(t *testing.T) {
+	// Arrange: another instance takes a token before the first put
+	client := &stubDynamoDB{}
+	store := NewDynamoDBStore(client, "chargeback-rate-limits")
+	bucket := Bucket{Rate: 1, Burst: 2}
+	ctx := context.Background()
+	store.Take(ctx, "rate#caller", bucket, testNow)
+
+	interfered := false
+	client.beforePut = func(s *stubDynamoDB) {
+		if !interfered {
+			interfered = true
+			item := s.items["rate#caller"]
+			item["version"] = number(10)
+			item["tokens"] = &types.AttributeValueMemberN{Value: "0"}
+		}
+	}
+
+	// Act
+	result, err := store.Take(ctx, "rate#caller", bucket, testNow)
+
+	// Assert: the retry sees the token taken by the other instance
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if result.Allowed {
+		t.Errorf("Expected the retry to find the bucket empty, got %+v", result)
+	}
+	if client.puts != 2 {
+		t.Errorf("Expected the conflicting put to be retried once, got %d puts", client.puts)
+	}
+
+	// Giving up after repeated conflicts
+	client.beforePut = func(s *stubDynamoDB) {
+		s.items["rate#other"] = map[string]types.AttributeValue{
+			"limit_key":  &types.AttributeValueMemberS{Value: "rate#other"},
+			"tokens":     &types.AttributeValueMemberN{Value: "2"},
+			"updated_at": number(testNow.UnixMicro()),
+			"version":    number(int64(s.puts) + 100),
+		}
+	}
+	if _, err := store.Take(ctx, "rate#other", bucket, testNow); err == nil || !strings.Contains(err.Error(), "kept changing") {
+		t.Errorf("Expected to give up on contention, got %v", err)
+	}
+}
+
+func TestDynamoDBStore_Increment(t *testing.T) {
+	// Arrange
+	client := &stubDynamoDB{}
+	store := NewDynamoDBStore(client, "chargeback-rate-limits")
+	ctx := context.Background()
+	expires := testNow.Add(48 * time.Hour)
+
+	// Act & Assert
+	for i := int64(1); i <= 2; i++ {
+		count, ok, err := store.Increment(ctx, "quota#merchant-1#2024-01-15", 2, testNow, expires)
+		if err != nil || !ok || count != i {
+			t.Fatalf("Expected count %d, got %d, %v, %v", i, count, ok, err)
+		}
+	}
+	count, ok, err := store.Increment(ctx, "quota#merchant-1#2024-01-15", 2, testNow, expires)
+	if err != nil || ok || count != 2 {
+		t.Errorf("Expected the limit to refuse the increment, got %d, %v, %v", count, ok, err)
+	}
+
+	update := client.updates[0]
+	if aws.ToString(update.UpdateExpression) != "ADD #count :one SET expires_at = if_not_exists(expires_at, :expires)" {
+		t.Errorf("Expected an atomic counter update, got %q", aws.ToString(update.UpdateExpression))
+	}
+	if update.ExpressionAttributeValues[":expires"].(*types.AttributeValueMemberN).Value != strconv.FormatInt(expires.Unix(), 10) {
+		t.Errorf("Expected the counter to expire at %v", expires)
+	}
+	if aws.ToString(update.TableName) != "chargeback-rate-limits" {
+		t.Errorf("Expected the configured table, got %q", aws.ToString(update.TableName))
+	}
+}
+
+func TestDynamoDBStore_Errors(t *testing.T) {
+	client := &stubDynamoDB{err: errors.New("throttled")}
+	store := NewDynamoDBStore(client, "chargeback-rate-limits")
+	ctx := context.Background()
+
+	if _, err := store.Take(ctx, "rate#caller", Bucket{Rate: 1, Burst: 1}, testNow); err == nil || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("Expected the read error, got %v", err)
+	}
+	if _, _, err := store.Increment(ctx, "quota#merchant-1#2024-01-15", 1, testNow, testNow); err == nil || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("Expected the update error, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// sweepInterval is how often the memory store drops full buckets and
+// expired counters
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets and counters in the process. Limits are not
+// shared between processes.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]bucketState
+	counters  map[string]counterState
+	lastSweep time.Time
+}
+
+// bucketState is the tokens of a bucket at a point in time
+type bucketState struct {
+	tokens float64
+	last   time.Time
+	full   time.Time
+}
+
+// counterState is a counter and when it is dropped
+type counterState struct {
+	count   int64
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:  make(map[string]bucketState),
+		counters: make(map[string]counterState),
+	}
+}
+
+// Take takes a token from the bucket of key
+func (s *MemoryStore) Take(ctx context.Context, key string, bucket Bucket, now time.Time) (service.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	state, ok := s.buckets[key]
+	if !ok {
+		state = bucketState{tokens: float64(bucket.Burst), last: now}
+	}
+
+	tokens, result := bucket.take(state.tokens, state.last, now)
+	if result.Allowed {
+		s.buckets[key] = bucketState{tokens: tokens, last: now, full: now.Add(result.Reset)}
+	}
+	return result, nil
+}
+
+// Increment adds one to the counter of key unless it reached limit
+func (s *MemoryStore) Increment(ctx context.Context, key string, limit int64, now, expires time.Time) (int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep(now)
+
+	state, ok := s.counters[key]
+	if !ok {
+		state = counterState{expires: expires}
+	}
+	if state.count >= limit {
+		return state.count, false, nil
+	}
+
+	state.count++
+	s.counters[key] = state
+	return state.count, true, nil
+}
+
+// sweep drops the buckets that refilled, which are the same as new ones,
+// and the expired counters
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, state := range s.buckets {
+		if !now.Before(state.full) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, state := range s.counters {
+		if now.After(state.expires) {
+			delete(s.counters, key)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore_Sweep(t *testing.T) {
+	// Arrange
+	store := NewMemoryStore()
+	ctx := context.Background()
+	bucket := Bucket{Rate: 1, Burst: 2}
+
+	store.Take(ctx, "rate#caller-1", bucket, testNow)
+	store.Increment(ctx, "quota#merchant-1#2024-01-15", 10, testNow, testNow.Add(time.Hour))
+	if len(store.buckets) != 1 || len(store.counters) != 1 {
+		t.Fatalf("Expected one bucket and one counter, got %d and %d", len(store.buckets), len(store.counters))
+	}
+
+	// Act: the bucket refilled and the counter expired
+	store.Take(ctx, "rate#caller-2", bucket, testNow.Add(2*time.Hour))
+
+	// Assert
+	if _, ok := store.buckets["rate#caller-1"]; ok {
+		t.Error("Expected the refilled bucket to be dropped")
+	}
+	if len(store.counters) != 0 {
+		t.Errorf("Expected the expired counter to be dropped, got %v", store.counters)
+	}
+	if _, ok := store.buckets["rate#caller-2"]; !ok {
+		t.Error("Expected the new bucket to be kept")
+	}
+}
+
+func TestMemoryStore_Concurrent(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, ok, _ := store.Increment(ctx, "quota#merchant-1#2024-01-15", 20, testNow, testNow.Add(time.Hour))
+			if ok {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed != 20 {
+		t.Errorf("Expected exactly the limit to be counted, got %d", allowed)
+	}
+}
@@ -0,0 +1,249 @@
+// Package ratelimit implements service.RateLimiter and service.Quota: token
+// buckets limiting the request rate of each caller and daily counters
+// limiting the chargebacks each merchant opens, kept in memory for the local
+// server or in DynamoDB, shared by every Lambda instance
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// Backend selects where limits are kept
+type Backend string
+
+const (
+	// BackendMemory keeps limits in the process, for a single server
+	BackendMemory Backend = "memory"
+
+	// BackendDynamoDB keeps limits in a DynamoDB table, shared by every
+	// instance
+	BackendDynamoDB Backend = "dynamodb"
+)
+
+// ParseBackend converts a backend name, case-insensitively
+func ParseBackend(name string) (Backend, error) {
+	switch backend := Backend(strings.ToLower(strings.TrimSpace(name))); backend {
+	case BackendMemory, BackendDynamoDB:
+		return backend, nil
+	case "":
+		return BackendMemory, nil
+	default:
+		return BackendMemory, fmt.Errorf("unknown rate limit backend %q (want memory or dynamodb)", name)
+	}
+}
+
+// Config configures rate limits and daily quotas
+type Config struct {
+	// Rate is the sustained number of requests a second allowed to each
+	// caller. The rate is not limited if zero.
+	Rate float64
+
+	// Burst is how many requests a caller may send at once, the rate
+	// rounded up if zero
+	Burst int64
+
+	// DailyQuota is how many chargebacks each merchant may open a UTC day.
+	// Merchants are not limited if zero.
+	DailyQuota int64
+
+	// MerchantQuotas overrides DailyQuota for some merchants. Zero exempts
+	// the merchant.
+	MerchantQuotas map[string]int64
+
+	// Backend selects where limits are kept, BackendMemory if empty
+	Backend Backend
+
+	// Table is the DynamoDB table of BackendDynamoDB, required once a limit
+	// is set. It is keyed by the string attribute "limit_key", and its TTL
+	// attribute should be "expires_at".
+	Table string
+}
+
+// Validate checks the rate limit configuration
+func (c Config) Validate() error {
+	var errs []error
+	backend, err := ParseBackend(string(c.Backend))
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if c.Rate < 0 || math.IsInf(c.Rate, 0) || math.IsNaN(c.Rate) {
+		errs = append(errs, fmt.Errorf("rate must be a non-negative number, got %v", c.Rate))
+	}
+	if c.Burst < 0 {
+		errs = append(errs, fmt.Errorf("burst must not be negative, got %d", c.Burst))
+	}
+	if c.DailyQuota < 0 {
+		errs = append(errs, fmt.Errorf("daily quota must not be negative, got %d", c.DailyQuota))
+	}
+	for merchantID, quota := range c.MerchantQuotas {
+		if quota < 0 {
+			errs = append(errs, fmt.Errorf("daily quota of merchant %s must not be negative, got %d", merchantID, quota))
+		}
+	}
+	if backend == BackendDynamoDB && c.Table == "" && (c.RateLimited() || c.QuotaLimited()) {
+		errs = append(errs, errors.New("table is required by the dynamodb backend"))
+	}
+	return errors.Join(errs...)
+}
+
+// RateLimited reports whether the request rate is limited
+func (c Config) RateLimited() bool {
+	return c.Rate > 0
+}
+
+// QuotaLimited reports whether any merchant has a daily quota
+func (c Config) QuotaLimited() bool {
+	if c.DailyQuota > 0 {
+		return true
+	}
+	for _, quota := range c.MerchantQuotas {
+		if quota > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// QuotaFor returns the daily quota of a merchant, zero if unlimited
+func (c Config) QuotaFor(merchantID string) int64 {
+	if quota, ok := c.MerchantQuotas[merchantID]; ok {
+		return quota
+	}
+	return c.DailyQuota
+}
+
+// Bucket is a token bucket holding up to Burst tokens, refilled at Rate
+// tokens a second. Each request takes a token.
+type Bucket struct {
+	Rate  float64
+	Burst int64
+}
+
+// take refills the tokens left at last until now and takes one, returning
+// the tokens left. A rejected request takes nothing.
+func (b Bucket) take(tokens float64, last, now time.Time) (float64, service.RateLimitResult) {
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(float64(b.Burst), tokens+elapsed.Seconds()*b.Rate)
+	}
+
+	result := service.RateLimitResult{Limit: b.Burst}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = b.refill(1 - tokens)
+	}
+	result.Remaining = int64(tokens)
+	result.Reset = b.refill(float64(b.Burst) - tokens)
+	return tokens, result
+}
+
+// refill returns how long the bucket takes to gain tokens
+func (b Bucket) refill(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / b.Rate * float64(time.Second)))
+}
+
+// Store keeps token buckets and counters
+type Store interface {
+	// Take takes a token from the bucket of key, which starts full
+	Take(ctx context.Context, key string, bucket Bucket, now time.Time) (service.RateLimitResult, error)
+
+	// Increment adds one to the counter of key unless it reached limit,
+	// returning the count and whether it was incremented. The counter is
+	// dropped after expires.
+	Increment(ctx context.Context, key string, limit int64, now, expires time.Time) (int64, bool, error)
+}
+
+// Limiter is a service.RateLimiter and a service.Quota keeping its limits in
+// a Store
+type Limiter struct {
+	store  Store
+	config Config
+	bucket Bucket
+	now    func() time.Time
+}
+
+// NewLimiter creates a limiter keeping its limits in store
+func NewLimiter(store Store, config Config) *Limiter {
+	burst := config.Burst
+	if burst == 0 {
+		burst = max(1, int64(math.Ceil(config.Rate)))
+	}
+
+	return &Limiter{
+		store:  store,
+		config: config,
+		bucket: Bucket{Rate: config.Rate, Burst: burst},
+		now:    time.Now,
+	}
+}
+
+// Setup creates a limiter keeping its limits in the configured backend. The
+// DynamoDB client is only used by BackendDynamoDB.
+func Setup(config Config, dynamo DynamoDBAPI) (*Limiter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate limit config: %w", err)
+	}
+
+	var store Store
+	switch backend, _ := ParseBackend(string(config.Backend)); backend {
+	case BackendDynamoDB:
+		store = NewDynamoDBStore(dynamo, config.Table)
+	default:
+		store = NewMemoryStore()
+	}
+	return NewLimiter(store, config), nil
+}
+
+// Allow takes one request from the bucket of the caller identified by key.
+// Every request is allowed if the rate is not limited.
+func (l *Limiter) Allow(ctx context.Context, key string) (service.RateLimitResult, error) {
+	if !l.config.RateLimited() {
+		return service.RateLimitResult{Allowed: true}, nil
+	}
+
+	result, err := l.store.Take(ctx, "rate#"+key, l.bucket, l.now())
+	if err != nil {
+		return service.RateLimitResult{}, fmt.Errorf("failed to take from rate limit: %w", err)
+	}
+	return result, nil
+}
+
+// Consume counts one chargeback against the quota of the merchant for the
+// current UTC day. Merchants without a quota are always allowed.
+func (l *Limiter) Consume(ctx context.Context, merchantID string) (service.RateLimitResult, error) {
+	quota := l.config.QuotaFor(merchantID)
+	if quota <= 0 {
+		return service.RateLimitResult{Allowed: true}, nil
+	}
+
+	now := l.now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	reset := day.Add(24 * time.Hour).Sub(now)
+
+	// Counters outlive their day, so clock skew between instances cannot
+	// restart one
+	key := "quota#" + merchantID + "#" + day.Format(time.DateOnly)
+	count, allowed, err := l.store.Increment(ctx, key, quota, now, day.Add(48*time.Hour))
+	if err != nil {
+		return service.RateLimitResult{}, fmt.Errorf("failed to count against daily quota: %w", err)
+	}
+
+	result := service.RateLimitResult{
+		Allowed:   allowed,
+		Limit:     quota,
+		Remaining: max(0, quota-count),
+		Reset:     reset,
+	}
+	if !allowed {
+		result.RetryAfter = reset
+	}
+	return result, nil
+}
@@ -0,0 +1,221 @@
+package ratelimit
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testNow is a fixed point in time, ten hours into a UTC day
+var testNow = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// newTestLimiter returns a limiter in memory whose clock is *now
+func newTestLimiter(config Config, now *time.Time) *Limiter {
+	limiter := NewLimiter(NewMemoryStore(), config)
+	limiter.now = func() time.Time { return *now }
+	return limiter
+}
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Backend
+		wantErr bool
+	}{
+		{name: "", want: BackendMemory},
+		{name: "memory", want: BackendMemory},
+		{name: " DynamoDB ", want: BackendDynamoDB},
+		{name: "redis", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBackend(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBackend(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("ParseBackend(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "disabled", config: Config{}},
+		{name: "memory", config: Config{Rate: 10, Burst: 20, DailyQuota: 1000}},
+		{name: "dynamodb", config: Config{Rate: 10, Backend: BackendDynamoDB, Table: "chargeback-rate-limits"}},
+		{name: "negative rate", config: Config{Rate: -1}, wantErr: "rate must be"},
+		{name: "negative burst", config: Config{Burst: -1}, wantErr: "burst must not be negative"},
+		{name: "negative quota", config: Config{DailyQuota: -1}, wantErr: "daily quota must not be negative"},
+		{name: "negative merchant quota", config: Config{MerchantQuotas: map[string]int64{"merchant-1": -5}}, wantErr: "merchant merchant-1"},
+		{name: "dynamodb without table", config: Config{DailyQuota: 100, Backend: BackendDynamoDB}, wantErr: "table is required"},
+		{name: "disabled dynamodb without table", config: Config{Backend: BackendDynamoDB}},
+		{name: "unknown backend", config: Config{Backend: "redis"}, wantErr: "unknown rate limit backend"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestConfig_Quotas(t *testing.T) {
+	config := Config{DailyQuota: 100, MerchantQuotas: map[string]int64{"merchant-big": 5000, "merchant-exempt": 0}}
+
+	if got := config.QuotaFor("merchant-1"); got != 100 {
+		t.Errorf("Expected the default quota, got %d", got)
+	}
+	if got := config.QuotaFor("merchant-big"); got != 5000 {
+		t.Errorf("Expected the merchant quota, got %d", got)
+	}
+	if got := config.QuotaFor("merchant-exempt"); got != 0 {
+		t.Errorf("Expected the merchant to be exempt, got %d", got)
+	}
+
+	if !(Config{MerchantQuotas: map[string]int64{"merchant-1": 10}}).QuotaLimited() {
+		t.Error("Expected a merchant quota alone to limit")
+	}
+	if (Config{MerchantQuotas: map[string]int64{"merchant-1": 0}}).QuotaLimited() {
+		t.Error("Expected exemptions alone not to limit")
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	// Arrange: 2 requests a second with bursts of 3
+	now := testNow
+	limiter := newTestLimiter(Config{Rate: 2, Burst: 3}, &now)
+	ctx := context.Background()
+
+	// Act & Assert: the burst is allowed at once
+	for i := int64(0); i < 3; i++ {
+		result, err := limiter.Allow(ctx, "merchant:merchant-1")
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !result.Allowed || result.Limit != 3 || result.Remaining != 2-i {
+			t.Errorf("Request %d: expected allowed with %d remaining, got %+v", i, 2-i, result)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, "merchant:merchant-1")
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("Expected the request past the burst to be rejected, got %+v", result)
+	}
+	if result.RetryAfter != 500*time.Millisecond || result.Reset != 1500*time.Millisecond {
+		t.Errorf("Expected a retry after 500ms and a reset after 1.5s, got %+v", result)
+	}
+
+	// Other callers have their own bucket
+	if result, _ := limiter.Allow(ctx, "merchant:merchant-2"); !result.Allowed {
+		t.Errorf("Expected another caller to be allowed, got %+v", result)
+	}
+
+	// Tokens refill at the rate
+	now = now.Add(500 * time.Millisecond)
+	if result, _ := limiter.Allow(ctx, "merchant:merchant-1"); !result.Allowed {
+		t.Errorf("Expected a refilled token to be taken, got %+v", result)
+	}
+	if result, _ := limiter.Allow(ctx, "merchant:merchant-1"); result.Allowed {
+		t.Errorf("Expected the bucket to be empty again, got %+v", result)
+	}
+}
+
+func TestLimiter_Allow_Disabled(t *testing.T) {
+	now := testNow
+	limiter := newTestLimiter(Config{}, &now)
+
+	for i := 0; i < 100; i++ {
+		if result, err := limiter.Allow(context.Background(), "merchant:merchant-1"); err != nil || !result.Allowed {
+			t.Fatalf("Expected every request to be allowed, got %+v, %v", result, err)
+		}
+	}
+}
+
+func TestLimiter_DefaultBurst(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want int64
+	}{
+		{rate: 0.5, want: 1},
+		{rate: 10, want: 10},
+		{rate: 2.5, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := NewLimiter(NewMemoryStore(), Config{Rate: tt.rate}).bucket.Burst; got != tt.want {
+			t.Errorf("Expected a burst of %d for a rate of %v, got %d", tt.want, tt.rate, got)
+		}
+	}
+}
+
+func TestLimiter_Consume(t *testing.T) {
+	// Arrange
+	now := testNow
+	limiter := newTestLimiter(Config{DailyQuota: 2, MerchantQuotas: map[string]int64{"merchant-exempt": 0}}, &now)
+	ctx := context.Background()
+
+	// Act & Assert
+	for i := int64(1); i <= 2; i++ {
+		result, err := limiter.Consume(ctx, "merchant-1")
+		if err != nil {
+			t.Fatalf("Consume() error = %v", err)
+		}
+		if !result.Allowed || result.Limit != 2 || result.Remaining != 2-i || result.Reset != 14*time.Hour {
+			t.Errorf("Chargeback %d: expected allowed until midnight with %d remaining, got %+v", i, 2-i, result)
+		}
+	}
+
+	result, _ := limiter.Consume(ctx, "merchant-1")
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != 14*time.Hour {
+		t.Errorf("Expected the quota to be exceeded until midnight, got %+v", result)
+	}
+
+	for i := 0; i < 5; i++ {
+		if result, _ := limiter.Consume(ctx, "merchant-exempt"); !result.Allowed {
+			t.Fatalf("Expected an exempt merchant to be allowed, got %+v", result)
+		}
+	}
+
+	// The quota restarts the next UTC day
+	now = now.Add(14 * time.Hour)
+	if result, _ := limiter.Consume(ctx, "merchant-1"); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("Expected a new quota the next day, got %+v", result)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	limiter, err := Setup(Config{Rate: 1}, nil)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if _, ok := limiter.store.(*MemoryStore); !ok {
+		t.Errorf("Expected a memory store by default, got %T", limiter.store)
+	}
+
+	limiter, err = Setup(Config{Rate: 1, Backend: BackendDynamoDB, Table: "chargeback-rate-limits"}, &stubDynamoDB{})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if _, ok := limiter.store.(*DynamoDBStore); !ok {
+		t.Errorf("Expected a DynamoDB store, got %T", limiter.store)
+	}
+
+	if _, err := Setup(Config{Rate: -1}, nil); err == nil || !strings.Contains(err.Error(), "invalid rate limit config") {
+		t.Errorf("Expected an invalid config error, got %v", err)
+	}
+}
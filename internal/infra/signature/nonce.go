@@ -0,0 +1,114 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// sweepInterval is how often the memory store drops expired nonces
+const sweepInterval = time.Minute
+
+// NonceStore remembers the nonces of verified requests
+type NonceStore interface {
+	// Remember records the nonce until expires, reporting false if it is
+	// already recorded
+	Remember(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// MemoryNonceStore remembers nonces in the process. Replays to other
+// processes are not detected.
+type MemoryNonceStore struct {
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewMemoryNonceStore creates an empty store
+func NewMemoryNonceStore() *MemoryNonceStore {
+	return &MemoryNonceStore{
+		nonces: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Remember records the nonce until expires unless it is recorded
+func (s *MemoryNonceStore) Remember(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+
+	if until, ok := s.nonces[nonce]; ok && now.Before(until) {
+		return false, nil
+	}
+	s.nonces[nonce] = expires
+	return true, nil
+}
+
+// sweep drops the expired nonces
+func (s *MemoryNonceStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for nonce, expires := range s.nonces {
+		if !now.Before(expires) {
+			delete(s.nonces, nonce)
+		}
+	}
+}
+
+// PutItemAPI is the part of the DynamoDB client used by DynamoDBNonceStore
+type PutItemAPI interface {
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+}
+
+// DynamoDBNonceStore remembers nonces in a table keyed by "nonce_key",
+// shared by every instance. Items carry their expiry in "expires_at", in
+// Unix seconds, for the table's TTL.
+type DynamoDBNonceStore struct {
+	client    PutItemAPI
+	tableName string
+	now       func() time.Time
+}
+
+// NewDynamoDBNonceStore creates a store in the given table
+func NewDynamoDBNonceStore(client PutItemAPI, tableName string) *DynamoDBNonceStore {
+	return &DynamoDBNonceStore{client: client, tableName: tableName, now: time.Now}
+}
+
+// Remember puts the nonce on condition that it is absent or expired. The
+// TTL deletes items late, so expired items are overwritten rather than
+// trusted.
+func (s *DynamoDBNonceStore) Remember(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	_, err := s.client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.tableName),
+		Item: map[string]types.AttributeValue{
+			"nonce_key":  &types.AttributeValueMemberS{Value: nonce},
+			"expires_at": &types.AttributeValueMemberN{Value: strconv.FormatInt(expires.Unix(), 10)},
+		},
+		ConditionExpression: aws.String("attribute_not_exists(nonce_key) OR expires_at <= :now"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(s.now().Unix(), 10)},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to remember nonce: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,114 @@
+package signature
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// stubDynamoDB keeps items by nonce key, evaluating the condition written by
+// DynamoDBNonceStore
+type stubDynamoDB struct {
+	items map[string]map[string]types.AttributeValue
+	err   error
+	puts  []*dynamodb.PutItemInput
+}
+
+func (s *stubDynamoDB) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	s.puts = append(s.puts, params)
+	if s.err != nil {
+		return nil, s.err
+	}
+	if s.items == nil {
+		s.items = make(map[string]map[string]types.AttributeValue)
+	}
+
+	key := params.Item["nonce_key"].(*types.AttributeValueMemberS).Value
+	if existing, ok := s.items[key]; ok {
+		expires, _ := strconv.ParseInt(existing["expires_at"].(*types.AttributeValueMemberN).Value, 10, 64)
+		now, _ := strconv.ParseInt(params.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value, 10, 64)
+		if expires > now {
+			return nil, &types.ConditionalCheckFailedException{}
+		}
+	}
+	s.items[key] = params.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func TestMemoryNonceStore_Remember(t *testing.T) {
+	// Arrange
+	now := testNow
+	store := NewMemoryNonceStore()
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// Act & Assert
+	if fresh, err := store.Remember(ctx, "acquirer-a#nonce-1", now.Add(time.Minute)); err != nil || !fresh {
+		t.Fatalf("Expected a new nonce to be fresh, got %v, %v", fresh, err)
+	}
+	if fresh, _ := store.Remember(ctx, "acquirer-a#nonce-1", now.Add(time.Minute)); fresh {
+		t.Error("Expected a remembered nonce not to be fresh")
+	}
+	if fresh, _ := store.Remember(ctx, "acquirer-b#nonce-1", now.Add(time.Minute)); !fresh {
+		t.Error("Expected the nonce of another source to be fresh")
+	}
+
+	// Expired nonces are forgotten
+	now = now.Add(2 * time.Minute)
+	if fresh, _ := store.Remember(ctx, "acquirer-a#nonce-1", now.Add(time.Minute)); !fresh {
+		t.Error("Expected an expired nonce to be fresh again")
+	}
+	if len(store.nonces) != 1 {
+		t.Errorf("Expected expired nonces to be swept, got %d", len(store.nonces))
+	}
+}
+
+func TestDynamoDBNonceStore_Remember(t *testing.T) {
+	// Arrange
+	client := &stubDynamoDB{}
+	store := NewDynamoDBNonceStore(client, "chargeback-nonces")
+	now := testNow
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+	expires := testNow.Add(5 * time.Minute)
+
+	// Act & Assert
+	if fresh, err := store.Remember(ctx, "acquirer-a#nonce-1", expires); err != nil || !fresh {
+		t.Fatalf("Expected a new nonce to be fresh, got %v, %v", fresh, err)
+	}
+	if fresh, err := store.Remember(ctx, "acquirer-a#nonce-1", expires); err != nil || fresh {
+		t.Errorf("Expected a remembered nonce not to be fresh, got %v, %v", fresh, err)
+	}
+
+	put := client.puts[0]
+	if aws.ToString(put.TableName) != "chargeback-nonces" {
+		t.Errorf("Expected the configured table, got %q", aws.ToString(put.TableName))
+	}
+	if aws.ToString(put.ConditionExpression) != "attribute_not_exists(nonce_key) OR expires_at <= :now" {
+		t.Errorf("Expected a conditional put, got %q", aws.ToString(put.ConditionExpression))
+	}
+	if put.Item["expires_at"].(*types.AttributeValueMemberN).Value != strconv.FormatInt(expires.Unix(), 10) {
+		t.Errorf("Expected the item to expire at %v, got %v", expires, put.Item["expires_at"])
+	}
+
+	// Items the TTL has not deleted yet are overwritten once expired
+	now = expires
+	if fresh, _ := store.Remember(ctx, "acquirer-a#nonce-1", now.Add(5*time.Minute)); !fresh {
+		t.Error("Expected an expired nonce to be fresh again")
+	}
+}
+
+func TestDynamoDBNonceStore_Error(t *testing.T) {
+	store := NewDynamoDBNonceStore(&stubDynamoDB{err: errors.New("throttled")}, "chargeback-nonces")
+
+	if _, err := store.Remember(context.Background(), "acquirer-a#nonce-1", testNow); err == nil || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("Expected the put error, got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+// Package signature implements service.SignatureVerifier: requests pushed by
+// acquirers are signed with HMAC-SHA256 or RSA-PSS under a key configured
+// for each source, and rejected when stale or replayed
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+const (
+	// DefaultWindow is how far the timestamp of a request may be from now
+	DefaultWindow = 5 * time.Minute
+
+	// maxNonceLength bounds the nonces remembered for each request
+	maxNonceLength = 128
+)
+
+// Algorithm is how a source signs its requests
+type Algorithm string
+
+const (
+	// AlgorithmHMACSHA256 is an HMAC-SHA256 under a secret shared with the
+	// source
+	AlgorithmHMACSHA256 Algorithm = "hmac-sha256"
+
+	// AlgorithmRSAPSSSHA256 is an RSA-PSS signature over SHA-256, verified
+	// with the public key of the source
+	AlgorithmRSAPSSSHA256 Algorithm = "rsa-pss-sha256"
+)
+
+// ParseAlgorithm converts an algorithm name, case-insensitively
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch algorithm := Algorithm(strings.ToLower(strings.TrimSpace(name))); algorithm {
+	case AlgorithmHMACSHA256, AlgorithmRSAPSSSHA256:
+		return algorithm, nil
+	default:
+		return "", fmt.Errorf("unknown signature algorithm %q (want hmac-sha256 or rsa-pss-sha256)", name)
+	}
+}
+
+// Source is a sender of signed requests and the key verifying them
+type Source struct {
+	// ID identifies the source in the X-Signature-Source header and in the
+	// chargebacks it pushes
+	ID string `json:"id"`
+
+	// Algorithm is how the source signs
+	Algorithm Algorithm `json:"algorithm"`
+
+	// Secret is the key shared with a hmac-sha256 source
+	Secret string `json:"secret,omitempty"`
+
+	// PublicKey is the PEM public key of a rsa-pss-sha256 source
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// ParseSources decodes a JSON array of sources
+func ParseSources(raw string) ([]Source, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var sources []Source
+	if err := json.Unmarshal([]byte(raw), &sources); err != nil {
+		return nil, fmt.Errorf("want a JSON array of sources: %w", err)
+	}
+	return sources, nil
+}
+
+// Config configures the verification of signed requests
+type Config struct {
+	// Sources lists the senders whose requests are accepted
+	Sources []Source
+
+	// Window is how far the timestamp of a request may be from now, in
+	// either direction, DefaultWindow if zero. Nonces are remembered as
+	// long as their request may be replayed within it.
+	Window time.Duration
+
+	// NonceTable is the DynamoDB table remembering nonces, shared by every
+	// instance, or in memory if empty. It is keyed by the string attribute
+	// "nonce_key", and its TTL attribute should be "expires_at".
+	NonceTable string
+}
+
+// Validate checks the signature configuration, including every key
+func (c Config) Validate() error {
+	var errs []error
+	if len(c.Sources) == 0 {
+		errs = append(errs, errors.New("at least one source is required"))
+	}
+	seen := make(map[string]bool, len(c.Sources))
+	for i, source := range c.Sources {
+		if source.ID == "" {
+			errs = append(errs, fmt.Errorf("source %d has no ID", i))
+			continue
+		}
+		if seen[source.ID] {
+			errs = append(errs, fmt.Errorf("source %s is listed twice", source.ID))
+		}
+		seen[source.ID] = true
+		if _, err := newKey(source); err != nil {
+			errs = append(errs, fmt.Errorf("source %s: %w", source.ID, err))
+		}
+	}
+	if c.Window < 0 {
+		errs = append(errs, fmt.Errorf("window must not be negative, got %s", c.Window))
+	}
+	return errors.Join(errs...)
+}
+
+// key verifies the signature of a payload
+type key interface {
+	verify(payload, signature []byte) bool
+}
+
+// hmacKey verifies HMAC-SHA256 signatures
+type hmacKey []byte
+
+func (k hmacKey) verify(payload, signature []byte) bool {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), signature)
+}
+
+// rsaPSSKey verifies RSA-PSS signatures over SHA-256
+type rsaPSSKey struct {
+	publicKey *rsa.PublicKey
+}
+
+func (k rsaPSSKey) verify(payload, signature []byte) bool {
+	digest := sha256.Sum256(payload)
+	return rsa.VerifyPSS(k.publicKey, crypto.SHA256, digest[:], signature, &rsa.PSSOptions{
+		SaltLength: rsa.PSSSaltLengthAuto,
+	}) == nil
+}
+
+// newKey returns the key verifying the signatures of source
+func newKey(source Source) (key, error) {
+	algorithm, err := ParseAlgorithm(string(source.Algorithm))
+	if err != nil {
+		return nil, err
+	}
+
+	switch algorithm {
+	case AlgorithmHMACSHA256:
+		if source.Secret == "" {
+			return nil, errors.New("secret is required by hmac-sha256")
+		}
+		return hmacKey(source.Secret), nil
+	default:
+		block, _ := pem.Decode([]byte(source.PublicKey))
+		if block == nil {
+			return nil, errors.New("public key must be PEM encoded")
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+		publicKey, ok := parsed.(*rsa.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key must be RSA, got %T", parsed)
+		}
+		return rsaPSSKey{publicKey: publicKey}, nil
+	}
+}
+
+// Payload returns the bytes signed by a source: the timestamp, the nonce
+// and the raw body, joined by dots
+func Payload(timestamp, nonce string, body []byte) []byte {
+	payload := make([]byte, 0, len(timestamp)+len(nonce)+len(body)+2)
+	payload = append(payload, timestamp...)
+	payload = append(payload, '.')
+	payload = append(payload, nonce...)
+	payload = append(payload, '.')
+	return append(payload, body...)
+}
+
+// Verifier is a service.SignatureVerifier of the configured sources
+type Verifier struct {
+	keys   map[string]key
+	window time.Duration
+	nonces NonceStore
+	now    func() time.Time
+}
+
+// NewVerifier creates a verifier remembering nonces in the given store. The
+// configuration must be valid.
+func NewVerifier(config Config, nonces NonceStore) (*Verifier, error) {
+	if config.Window == 0 {
+		config.Window = DefaultWindow
+	}
+
+	keys := make(map[string]key, len(config.Sources))
+	for _, source := range config.Sources {
+		k, err := newKey(source)
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", source.ID, err)
+		}
+		keys[source.ID] = k
+	}
+
+	return &Verifier{
+		keys:   keys,
+		window: config.Window,
+		nonces: nonces,
+		now:    time.Now,
+	}, nil
+}
+
+// Setup creates the configured verifier, remembering nonces through the
+// given DynamoDB client when a nonce table is set
+func Setup(config Config, dynamo PutItemAPI) (*Verifier, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid signature config: %w", err)
+	}
+
+	var nonces NonceStore = NewMemoryNonceStore()
+	if config.NonceTable != "" {
+		nonces = NewDynamoDBNonceStore(dynamo, config.NonceTable)
+	}
+	return NewVerifier(config, nonces)
+}
+
+// Verify checks the source, timestamp and signature of the request, then
+// remembers its nonce. Only verified requests consume a nonce, so forged
+// requests cannot burn the nonces of genuine ones.
+func (v *Verifier) Verify(ctx context.Context, request service.SignedRequest) (*entity.Principal, error) {
+	k, ok := v.keys[request.Source]
+	if !ok {
+		return nil, invalid(fmt.Sprintf("unknown source %q", request.Source))
+	}
+
+	if request.Nonce == "" || len(request.Nonce) > maxNonceLength {
+		return nil, invalid(fmt.Sprintf("nonce must have 1 to %d characters", maxNonceLength))
+	}
+
+	seconds, err := strconv.ParseInt(request.Timestamp, 10, 64)
+	if err != nil {
+		return nil, invalid(fmt.Sprintf("timestamp %q is not in Unix seconds", request.Timestamp))
+	}
+	signedAt := time.Unix(seconds, 0)
+	if skew := v.now().Sub(signedAt); skew > v.window || skew < -v.window {
+		return nil, invalid(fmt.Sprintf("timestamp is %s away, outside the %s window", skew.Round(time.Second), v.window))
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(request.Signature)
+	if err != nil {
+		return nil, invalid("signature is not base64")
+	}
+	if !k.verify(Payload(request.Timestamp, request.Nonce, request.Body), signature) {
+		return nil, invalid(fmt.Sprintf("signature of source %s does not match", request.Source))
+	}
+
+	// The timestamp is rejected once past the window, so the nonce need not
+	// be remembered any longer
+	fresh, err := v.nonces.Remember(ctx, request.Source+"#"+request.Nonce, signedAt.Add(v.window))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check nonce: %w", err)
+	}
+	if !fresh {
+		return nil, invalid(fmt.Sprintf("nonce of source %s was already used", request.Source))
+	}
+
+	return &entity.Principal{
+		ID:     request.Source,
+		Method: entity.AuthMethodSignature,
+		Roles:  []entity.Role{entity.RoleAcquirer},
+	}, nil
+}
+
+// invalid wraps service.ErrInvalidSignature with the reason of a rejection
+func invalid(reason string) error {
+	return fmt.Errorf("%w: %s", service.ErrInvalidSignature, reason)
+}
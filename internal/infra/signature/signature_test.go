@@ -0,0 +1,363 @@
+package signature
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// testNow is the fixed clock of the verifiers under test
+var testNow = time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+
+// testBody is a dispute notification pushed by an acquirer
+var testBody = []byte(`{"transaction_id":"txn-123","merchant_id":"merchant-456"}`)
+
+// newRSAKey returns an RSA key and its public key in PEM
+func newRSAKey(t *testing.T) (*rsa.PrivateKey, string) {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey() error = %v", err)
+	}
+	return privateKey, string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+// signHMAC signs a request as an hmac-sha256 source
+func signHMAC(secret string, request service.SignedRequest) service.SignedRequest {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(Payload(request.Timestamp, request.Nonce, request.Body))
+	request.Signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	return request
+}
+
+// signRSAPSS signs a request as a rsa-pss-sha256 source
+func signRSAPSS(t *testing.T, privateKey *rsa.PrivateKey, request service.SignedRequest) service.SignedRequest {
+	t.Helper()
+	digest := sha256.Sum256(Payload(request.Timestamp, request.Nonce, request.Body))
+	signature, err := rsa.SignPSS(rand.Reader, privateKey, crypto.SHA256, digest[:], nil)
+	if err != nil {
+		t.Fatalf("SignPSS() error = %v", err)
+	}
+	request.Signature = base64.StdEncoding.EncodeToString(signature)
+	return request
+}
+
+// newTestVerifier returns a verifier whose clock is *now, remembering nonces
+// in memory
+func newTestVerifier(t *testing.T, config Config, now *time.Time) *Verifier {
+	t.Helper()
+	nonces := NewMemoryNonceStore()
+	nonces.now = func() time.Time { return *now }
+	verifier, err := NewVerifier(config, nonces)
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	verifier.now = func() time.Time { return *now }
+	return verifier
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources(`[{"id":"acquirer-a","algorithm":"hmac-sha256","secret":"s3cret"},{"id":"acquirer-b","algorithm":"rsa-pss-sha256","public_key":"PEM"}]`)
+	if err != nil {
+		t.Fatalf("ParseSources() error = %v", err)
+	}
+	if len(sources) != 2 || sources[0].ID != "acquirer-a" || sources[0].Secret != "s3cret" || sources[1].Algorithm != AlgorithmRSAPSSSHA256 || sources[1].PublicKey != "PEM" {
+		t.Errorf("Unexpected sources %+v", sources)
+	}
+
+	if sources, err := ParseSources(" "); err != nil || sources != nil {
+		t.Errorf("Expected no sources, got %+v, %v", sources, err)
+	}
+	if _, err := ParseSources(`{"id":"acquirer-a"}`); err == nil || !strings.Contains(err.Error(), "JSON array") {
+		t.Errorf("Expected a JSON array error, got %v", err)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	_, publicKey := newRSAKey(t)
+
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "hmac", config: Config{Sources: []Source{{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"}}}},
+		{name: "rsa-pss", config: Config{Sources: []Source{{ID: "acquirer-b", Algorithm: "RSA-PSS-SHA256", PublicKey: publicKey}}}},
+		{name: "no sources", config: Config{}, wantErr: "at least one source"},
+		{name: "no ID", config: Config{Sources: []Source{{Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"}}}, wantErr: "source 0 has no ID"},
+		{name: "duplicate", config: Config{Sources: []Source{
+			{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"},
+			{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "other"},
+		}}, wantErr: "listed twice"},
+		{name: "unknown algorithm", config: Config{Sources: []Source{{ID: "acquirer-a", Algorithm: "md5"}}}, wantErr: "unknown signature algorithm"},
+		{name: "no secret", config: Config{Sources: []Source{{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256}}}, wantErr: "secret is required"},
+		{name: "invalid public key", config: Config{Sources: []Source{{ID: "acquirer-b", Algorithm: AlgorithmRSAPSSSHA256, PublicKey: "not a key"}}}, wantErr: "PEM encoded"},
+		{name: "negative window", config: Config{Sources: []Source{{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"}}, Window: -time.Second}, wantErr: "window must not be negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	// Arrange
+	privateKey, publicKey := newRSAKey(t)
+	now := testNow
+	verifier := newTestVerifier(t, Config{Sources: []Source{
+		{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"},
+		{ID: "acquirer-b", Algorithm: AlgorithmRSAPSSSHA256, PublicKey: publicKey},
+	}}, &now)
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+
+	tests := []struct {
+		name    string
+		request service.SignedRequest
+		source  string
+	}{
+		{
+			name:    "hmac-sha256",
+			request: signHMAC("s3cret", service.SignedRequest{Source: "acquirer-a", Timestamp: timestamp, Nonce: "nonce-1", Body: testBody}),
+			source:  "acquirer-a",
+		},
+		{
+			name:    "rsa-pss-sha256",
+			request: signRSAPSS(t, privateKey, service.SignedRequest{Source: "acquirer-b", Timestamp: timestamp, Nonce: "nonce-1", Body: testBody}),
+			source:  "acquirer-b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			principal, err := verifier.Verify(context.Background(), tt.request)
+
+			// Assert
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if principal.ID != tt.source || principal.Method != entity.AuthMethodSignature || !principal.HasRole(entity.RoleAcquirer) || principal.MerchantID != "" {
+				t.Errorf("Expected an acquirer principal of %s, got %+v", tt.source, principal)
+			}
+		})
+	}
+}
+
+func TestVerifier_Verify_Rejected(t *testing.T) {
+	_, publicKey := newRSAKey(t)
+	timestamp := strconv.FormatInt(testNow.Unix(), 10)
+	valid := signHMAC("s3cret", service.SignedRequest{Source: "acquirer-a", Timestamp: timestamp, Nonce: "nonce-1", Body: testBody})
+
+	tests := []struct {
+		name    string
+		request func() service.SignedRequest
+		reason  string
+	}{
+		{
+			name: "unknown source",
+			request: func() service.SignedRequest {
+				return signHMAC("s3cret", service.SignedRequest{Source: "acquirer-x", Timestamp: timestamp, Nonce: "nonce-1", Body: testBody})
+			},
+			reason: `unknown source "acquirer-x"`,
+		},
+		{
+			name: "tampered body",
+			request: func() service.SignedRequest {
+				request := valid
+				request.Body = []byte(`{"transaction_id":"txn-123","merchant_id":"merchant-999"}`)
+				return request
+			},
+			reason: "does not match",
+		},
+		{
+			name: "wrong secret",
+			request: func() service.SignedRequest {
+				return signHMAC("guess", service.SignedRequest{Source: "acquirer-a", Timestamp: timestamp, Nonce: "nonce-1", Body: testBody})
+			},
+			reason: "does not match",
+		},
+		{
+			name: "hmac under an rsa source",
+			request: func() service.SignedRequest {
+				return signHMAC("s3cret", service.SignedRequest{Source: "acquirer-b", Timestamp: timestamp, Nonce: "nonce-1", Body: testBody})
+			},
+			reason: "does not match",
+		},
+		{
+			name: "not base64",
+			request: func() service.SignedRequest {
+				request := valid
+				request.Signature = "%%%"
+				return request
+			},
+			reason: "not base64",
+		},
+		{
+			name: "stale",
+			request: func() service.SignedRequest {
+				stale := strconv.FormatInt(testNow.Add(-6*time.Minute).Unix(), 10)
+				return signHMAC("s3cret", service.SignedRequest{Source: "acquirer-a", Timestamp: stale, Nonce: "nonce-1", Body: testBody})
+			},
+			reason: "outside the 5m0s window",
+		},
+		{
+			name: "from the future",
+			request: func() service.SignedRequest {
+				future := strconv.FormatInt(testNow.Add(6*time.Minute).Unix(), 10)
+				return signHMAC("s3cret", service.SignedRequest{Source: "acquirer-a", Timestamp: future, Nonce: "nonce-1", Body: testBody})
+			},
+			reason: "outside the 5m0s window",
+		},
+		{
+			name: "timestamp not in seconds",
+			request: func() service.SignedRequest {
+				return signHMAC("s3cret", service.SignedRequest{Source: "acquirer-a", Timestamp: testNow.Format(time.RFC3339), Nonce: "nonce-1", Body: testBody})
+			},
+			reason: "not in Unix seconds",
+		},
+		{
+			name: "no nonce",
+			request: func() service.SignedRequest {
+				return signHMAC("s3cret", service.SignedRequest{Source: "acquirer-a", Timestamp: timestamp, Body: testBody})
+			},
+			reason: "nonce must have",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			now := testNow
+			verifier := newTestVerifier(t, Config{Sources: []Source{
+				{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"},
+				{ID: "acquirer-b", Algorithm: AlgorithmRSAPSSSHA256, PublicKey: publicKey},
+			}}, &now)
+
+			// Act
+			principal, err := verifier.Verify(context.Background(), tt.request())
+
+			// Assert
+			if !errors.Is(err, service.ErrInvalidSignature) || !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("Expected an invalid signature error containing %q, got %v", tt.reason, err)
+			}
+			if principal != nil {
+				t.Errorf("Expected no principal, got %+v", principal)
+			}
+
+			// A rejected request does not consume its nonce
+			if _, err := verifier.Verify(context.Background(), valid); err != nil {
+				t.Errorf("Expected the genuine request to be accepted after a rejection, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifier_Verify_Replay(t *testing.T) {
+	// Arrange
+	now := testNow
+	verifier := newTestVerifier(t, Config{
+		Sources: []Source{{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"}},
+		Window:  time.Minute,
+	}, &now)
+	request := signHMAC("s3cret", service.SignedRequest{
+		Source:    "acquirer-a",
+		Timestamp: strconv.FormatInt(testNow.Unix(), 10),
+		Nonce:     "nonce-1",
+		Body:      testBody,
+	})
+	ctx := context.Background()
+
+	// Act & Assert
+	if _, err := verifier.Verify(ctx, request); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := verifier.Verify(ctx, request); !errors.Is(err, service.ErrInvalidSignature) || !strings.Contains(err.Error(), "already used") {
+		t.Errorf("Expected the replay to be rejected, got %v", err)
+	}
+
+	// Once the nonce is forgotten, the timestamp rejects the replay
+	now = now.Add(time.Minute)
+	if _, err := verifier.Verify(ctx, request); !errors.Is(err, service.ErrInvalidSignature) || !strings.Contains(err.Error(), "outside the 1m0s window") {
+		t.Errorf("Expected the late replay to be rejected, got %v", err)
+	}
+}
+
+func TestVerifier_Verify_NonceStoreUnavailable(t *testing.T) {
+	verifier, err := NewVerifier(Config{
+		Sources: []Source{{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"}},
+	}, NewDynamoDBNonceStore(&stubDynamoDB{err: errors.New("throttled")}, "chargeback-nonces"))
+	if err != nil {
+		t.Fatalf("NewVerifier() error = %v", err)
+	}
+	verifier.now = func() time.Time { return testNow }
+
+	_, err = verifier.Verify(context.Background(), signHMAC("s3cret", service.SignedRequest{
+		Source:    "acquirer-a",
+		Timestamp: strconv.FormatInt(testNow.Unix(), 10),
+		Nonce:     "nonce-1",
+		Body:      testBody,
+	}))
+
+	if err == nil || errors.Is(err, service.ErrInvalidSignature) || !strings.Contains(err.Error(), "throttled") {
+		t.Errorf("Expected the store error, got %v", err)
+	}
+}
+
+func TestSetup(t *testing.T) {
+	config := Config{Sources: []Source{{ID: "acquirer-a", Algorithm: AlgorithmHMACSHA256, Secret: "s3cret"}}}
+
+	verifier, err := Setup(config, nil)
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if _, ok := verifier.nonces.(*MemoryNonceStore); !ok {
+		t.Errorf("Expected nonces in memory by default, got %T", verifier.nonces)
+	}
+	if verifier.window != DefaultWindow {
+		t.Errorf("Expected the default window, got %s", verifier.window)
+	}
+
+	config.NonceTable = "chargeback-nonces"
+	verifier, err = Setup(config, &stubDynamoDB{})
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	if _, ok := verifier.nonces.(*DynamoDBNonceStore); !ok {
+		t.Errorf("Expected nonces in DynamoDB, got %T", verifier.nonces)
+	}
+
+	if _, err := Setup(Config{}, nil); err == nil || !strings.Contains(err.Error(), "invalid signature config") {
+		t.Errorf("Expected an invalid config error, got %v", err)
+	}
+}
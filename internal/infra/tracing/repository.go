@@ -0,0 +1,140 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/repository"
+)
+
+// instrumentationName identifies the spans created by this package
+const instrumentationName = "github.com/DiegoSantos90/chargeback-lambda/internal/infra/tracing"
+
+// ChargebackRepository is a repository.ChargebackRepository creating a client
+// span around every call of the wrapped repository. Spans carry the
+// operation and identifiers only; card numbers and descriptions are never
+// recorded.
+type ChargebackRepository struct {
+	next      repository.ChargebackRepository
+	tableName string
+	tracer    trace.Tracer
+}
+
+// NewChargebackRepository wraps a repository backed by the given DynamoDB table
+func NewChargebackRepository(next repository.ChargebackRepository, tableName string) *ChargebackRepository {
+	return &ChargebackRepository{
+		next:      next,
+		tableName: tableName,
+		tracer:    otel.Tracer(instrumentationName),
+	}
+}
+
+// start opens the span of a repository operation
+func (r *ChargebackRepository) start(ctx context.Context, operation string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("db.system.name", "aws.dynamodb"),
+		attribute.String("db.operation.name", operation),
+		attribute.String("db.collection.name", r.tableName),
+	)
+	return r.tracer.Start(ctx, "ChargebackRepository."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end records the outcome of an operation and closes its span
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.SetAttributes(attribute.String("error.type", errorType(err)))
+		span.SetStatus(codes.Error, "repository call failed")
+	}
+	span.End()
+}
+
+// Save stores a new chargeback
+func (r *ChargebackRepository) Save(ctx context.Context, chargeback *entity.Chargeback) (err error) {
+	ctx, span := r.start(ctx, "Save", chargebackAttributes(chargeback)...)
+	defer func() { end(span, err) }()
+	return r.next.Save(ctx, chargeback)
+}
+
+// FindByID returns the chargeback with the given ID
+func (r *ChargebackRepository) FindByID(ctx context.Context, id string) (chargeback *entity.Chargeback, err error) {
+	ctx, span := r.start(ctx, "FindByID", attribute.String("chargeback.id", id))
+	defer func() { end(span, err) }()
+	chargeback, err = r.next.FindByID(ctx, id)
+	span.SetAttributes(attribute.Bool("db.found", chargeback != nil))
+	return chargeback, err
+}
+
+// FindByTransactionID returns the chargeback of a transaction
+func (r *ChargebackRepository) FindByTransactionID(ctx context.Context, transactionID string) (chargeback *entity.Chargeback, err error) {
+	ctx, span := r.start(ctx, "FindByTransactionID", attribute.String("chargeback.transaction_id", transactionID))
+	defer func() { end(span, err) }()
+	chargeback, err = r.next.FindByTransactionID(ctx, transactionID)
+	span.SetAttributes(attribute.Bool("db.found", chargeback != nil))
+	return chargeback, err
+}
+
+// FindByMerchantID returns the chargebacks of a merchant
+func (r *ChargebackRepository) FindByMerchantID(ctx context.Context, merchantID string) (chargebacks []*entity.Chargeback, err error) {
+	ctx, span := r.start(ctx, "FindByMerchantID", attribute.String("chargeback.merchant_id", merchantID))
+	defer func() { end(span, err) }()
+	chargebacks, err = r.next.FindByMerchantID(ctx, merchantID)
+	span.SetAttributes(attribute.Int("db.response.returned_rows", len(chargebacks)))
+	return chargebacks, err
+}
+
+// Update replaces a stored chargeback
+func (r *ChargebackRepository) Update(ctx context.Context, chargeback *entity.Chargeback) (err error) {
+	ctx, span := r.start(ctx, "Update", chargebackAttributes(chargeback)...)
+	defer func() { end(span, err) }()
+	return r.next.Update(ctx, chargeback)
+}
+
+// Delete removes the chargeback with the given ID
+func (r *ChargebackRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, span := r.start(ctx, "Delete", attribute.String("chargeback.id", id))
+	defer func() { end(span, err) }()
+	return r.next.Delete(ctx, id)
+}
+
+// FindByStatus returns the chargebacks in a status
+func (r *ChargebackRepository) FindByStatus(ctx context.Context, status entity.ChargebackStatus) (chargebacks []*entity.Chargeback, err error) {
+	ctx, span := r.start(ctx, "FindByStatus", attribute.String("chargeback.status", string(status)))
+	defer func() { end(span, err) }()
+	chargebacks, err = r.next.FindByStatus(ctx, status)
+	span.SetAttributes(attribute.Int("db.response.returned_rows", len(chargebacks)))
+	return chargebacks, err
+}
+
+// List returns a page of chargebacks
+func (r *ChargebackRepository) List(ctx context.Context, offset, limit int) (chargebacks []*entity.Chargeback, err error) {
+	ctx, span := r.start(ctx, "List", attribute.Int("db.query.offset", offset), attribute.Int("db.query.limit", limit))
+	defer func() { end(span, err) }()
+	chargebacks, err = r.next.List(ctx, offset, limit)
+	span.SetAttributes(attribute.Int("db.response.returned_rows", len(chargebacks)))
+	return chargebacks, err
+}
+
+// chargebackAttributes describes a chargeback without its card number or
+// free-text description
+func chargebackAttributes(chargeback *entity.Chargeback) []attribute.KeyValue {
+	if chargeback == nil {
+		return nil
+	}
+	return []attribute.KeyValue{
+		attribute.String("chargeback.id", chargeback.ID),
+		attribute.String("chargeback.transaction_id", chargeback.TransactionID),
+		attribute.String("chargeback.merchant_id", chargeback.MerchantID),
+		attribute.String("chargeback.status", string(chargeback.Status)),
+		attribute.String("chargeback.reason", string(chargeback.Reason)),
+		attribute.String("chargeback.currency", chargeback.Currency),
+		attribute.Float64("chargeback.amount", chargeback.Amount),
+	}
+}
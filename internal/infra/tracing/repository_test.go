@@ -0,0 +1,133 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+)
+
+// stubRepository returns found for every lookup and fails with err
+type stubRepository struct {
+	found *entity.Chargeback
+	err   error
+}
+
+func (r *stubRepository) Save(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.err
+}
+func (r *stubRepository) FindByID(ctx context.Context, id string) (*entity.Chargeback, error) {
+	return r.found, r.err
+}
+func (r *stubRepository) FindByTransactionID(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+	return r.found, r.err
+}
+func (r *stubRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]*entity.Chargeback, error) {
+	return []*entity.Chargeback{r.found}, r.err
+}
+func (r *stubRepository) Update(ctx context.Context, chargeback *entity.Chargeback) error {
+	return r.err
+}
+func (r *stubRepository) Delete(ctx context.Context, id string) error {
+	return r.err
+}
+func (r *stubRepository) FindByStatus(ctx context.Context, status entity.ChargebackStatus) ([]*entity.Chargeback, error) {
+	return []*entity.Chargeback{r.found}, r.err
+}
+func (r *stubRepository) List(ctx context.Context, offset, limit int) ([]*entity.Chargeback, error) {
+	return []*entity.Chargeback{r.found, r.found}, r.err
+}
+
+func testChargeback() *entity.Chargeback {
+	return &entity.Chargeback{
+		ID:            "cb_123",
+		TransactionID: "txn_123",
+		MerchantID:    "merchant_456",
+		Amount:        100.50,
+		Currency:      "USD",
+		CardNumber:    "4111111111111111",
+		Reason:        entity.ReasonFraud,
+		Status:        entity.StatusPending,
+		Description:   "Customer Jane Doe called about it",
+	}
+}
+
+func TestChargebackRepository_Spans(t *testing.T) {
+	spans := recordSpans(t)
+	chargeback := testChargeback()
+	repo := NewChargebackRepository(&stubRepository{found: chargeback}, "chargebacks")
+	ctx := context.Background()
+
+	repo.Save(ctx, chargeback)
+	repo.FindByID(ctx, "cb_123")
+	repo.FindByTransactionID(ctx, "txn_123")
+	repo.FindByMerchantID(ctx, "merchant_456")
+	repo.Update(ctx, chargeback)
+	repo.Delete(ctx, "cb_123")
+	repo.FindByStatus(ctx, entity.StatusPending)
+	repo.List(ctx, 0, 10)
+
+	ended := spans.Ended()
+	want := []string{"Save", "FindByID", "FindByTransactionID", "FindByMerchantID", "Update", "Delete", "FindByStatus", "List"}
+	if len(ended) != len(want) {
+		t.Fatalf("Expected %d spans, got %d", len(want), len(ended))
+	}
+
+	for i, span := range ended {
+		if span.Name() != "ChargebackRepository."+want[i] {
+			t.Errorf("Expected span 'ChargebackRepository.%s', got '%s'", want[i], span.Name())
+		}
+		if span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("%s: expected a client span, got %v", span.Name(), span.SpanKind())
+		}
+
+		attrs := attributes(span)
+		if attrs["db.system.name"].AsString() != "aws.dynamodb" || attrs["db.collection.name"].AsString() != "chargebacks" {
+			t.Errorf("%s: unexpected database attributes %v", span.Name(), attrs)
+		}
+		if attrs["db.operation.name"].AsString() != want[i] {
+			t.Errorf("%s: unexpected operation %s", span.Name(), attrs["db.operation.name"].AsString())
+		}
+		for key, value := range attrs {
+			if v := value.Emit(); strings.Contains(v, "4111") || strings.Contains(v, "Jane") {
+				t.Errorf("%s: attribute %s leaks %q", span.Name(), key, v)
+			}
+		}
+		if span.Status().Code == codes.Error {
+			t.Errorf("%s: unexpected error status", span.Name())
+		}
+	}
+
+	if attrs := attributes(ended[1]); !attrs["db.found"].AsBool() {
+		t.Error("Expected db.found on FindByID")
+	}
+	if attrs := attributes(ended[7]); attrs["db.response.returned_rows"].AsInt64() != 2 {
+		t.Errorf("Expected 2 returned rows on List, got %v", attrs["db.response.returned_rows"].AsInt64())
+	}
+}
+
+func TestChargebackRepository_Error(t *testing.T) {
+	spans := recordSpans(t)
+	repo := NewChargebackRepository(&stubRepository{err: fmt.Errorf("get item cb_123: %w", notFoundError{})}, "chargebacks")
+
+	_, err := repo.FindByID(context.Background(), "cb_123")
+	if err == nil {
+		t.Fatal("Expected the error of the wrapped repository")
+	}
+
+	span := spans.Ended()[0]
+	if span.Status().Code != codes.Error || span.Status().Description != "repository call failed" {
+		t.Errorf("Unexpected status %v", span.Status())
+	}
+	if got := attributes(span)["error.type"].AsString(); got != "tracing.notFoundError" {
+		t.Errorf("Unexpected error.type %s", got)
+	}
+	if len(span.Events()) != 0 {
+		t.Errorf("Expected no error events carrying messages, got %v", span.Events())
+	}
+}
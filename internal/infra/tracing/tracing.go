@@ -0,0 +1,222 @@
+// Package tracing configures OpenTelemetry tracing: the exporters, the W3C
+// and X-Ray propagators and the instrumentation of the repository
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/contrib/propagators/aws/xray"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporter selects where spans are sent
+type Exporter string
+
+const (
+	// ExporterNone disables tracing; spans are created by a no-op tracer
+	ExporterNone Exporter = "none"
+
+	// ExporterOTLP sends spans to an OpenTelemetry collector over OTLP/HTTP
+	ExporterOTLP Exporter = "otlp"
+
+	// ExporterXRay sends spans to the X-Ray daemon as segment documents
+	ExporterXRay Exporter = "xray"
+
+	// ExporterStdout writes spans as JSON, for tests and local debugging
+	ExporterStdout Exporter = "stdout"
+)
+
+// ParseExporter converts an exporter name, case-insensitively
+func ParseExporter(name string) (Exporter, error) {
+	switch exporter := Exporter(strings.ToLower(strings.TrimSpace(name))); exporter {
+	case ExporterNone, ExporterOTLP, ExporterXRay, ExporterStdout:
+		return exporter, nil
+	case "":
+		return ExporterNone, nil
+	default:
+		return ExporterNone, fmt.Errorf("unknown trace exporter %q (want none, otlp, xray or stdout)", name)
+	}
+}
+
+// otlpTracesPath is the OTLP/HTTP traces path appended to the endpoint
+const otlpTracesPath = "/v1/traces"
+
+// Config configures tracing
+type Config struct {
+	// Exporter selects where spans are sent, ExporterNone if empty
+	Exporter Exporter
+
+	// ServiceName and Version describe the traced service
+	ServiceName string
+	Version     string
+
+	// Endpoint is the base URL of the OTLP collector, such as
+	// http://localhost:4318. The OTEL_EXPORTER_OTLP_* variables are used
+	// when empty.
+	Endpoint string
+
+	// Headers are added to every OTLP export request
+	Headers map[string]string
+
+	// XRayDaemonAddress is the UDP address of the X-Ray daemon,
+	// DefaultXRayDaemonAddress if empty
+	XRayDaemonAddress string
+
+	// SampleRatio is the fraction of new traces recorded. Requests joining
+	// a trace follow the sampling decision of their caller.
+	SampleRatio float64
+
+	// Writer receives the spans of ExporterStdout, os.Stdout if nil
+	Writer io.Writer
+}
+
+// Validate checks the tracing configuration
+func (c Config) Validate() error {
+	if _, err := ParseExporter(string(c.Exporter)); err != nil {
+		return err
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("sample ratio must be between 0 and 1, got %v", c.SampleRatio)
+	}
+	if c.Endpoint != "" {
+		endpoint, err := url.Parse(c.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("endpoint must be an http(s) URL, got %q", c.Endpoint)
+		}
+	}
+	return nil
+}
+
+// Propagator reads and writes the W3C traceparent and X-Ray trace headers.
+// When a request carries both, the X-Ray header wins, so spans join the
+// trace started by API Gateway and Lambda.
+func Propagator() propagation.TextMapPropagator {
+	return propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		xray.Propagator{},
+	)
+}
+
+// Provider owns the tracer provider installed by Setup
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Setup installs the global tracer provider and propagator. With
+// ExporterNone the global no-op tracer is kept, and the returned provider
+// does nothing.
+func Setup(ctx context.Context, config Config) (*Provider, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid tracing config: %w", err)
+	}
+
+	otel.SetTextMapPropagator(Propagator())
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return &Provider{}, nil
+	}
+
+	attrs := []attribute.KeyValue{attribute.String("service.name", config.ServiceName)}
+	if config.Version != "" {
+		attrs = append(attrs, attribute.String("service.version", config.Version))
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attrs...)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		// X-Ray trace IDs start with the epoch second; they remain valid
+		// W3C trace IDs for every other backend
+		sdktrace.WithIDGenerator(xray.NewIDGenerator()),
+	)
+	otel.SetTracerProvider(provider)
+
+	return &Provider{provider: provider}, nil
+}
+
+// newExporter creates the configured span exporter, or nil when disabled
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if config.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(strings.TrimSuffix(config.Endpoint, "/")+otlpTracesPath))
+		}
+		if len(config.Headers) > 0 {
+			opts = append(opts, otlptracehttp.WithHeaders(config.Headers))
+		}
+		exporter, err := otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterXRay:
+		exporter, err := NewXRayExporter(config.XRayDaemonAddress, config.ServiceName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create X-Ray trace exporter: %w", err)
+		}
+		return exporter, nil
+	case ExporterStdout:
+		writer := config.Writer
+		if writer == nil {
+			writer = os.Stdout
+		}
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(writer))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout trace exporter: %w", err)
+		}
+		return exporter, nil
+	default:
+		return nil, nil
+	}
+}
+
+// ForceFlush exports the finished spans. Lambda handlers call it before
+// returning, as the execution environment may be frozen afterwards.
+func (p *Provider) ForceFlush(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	return p.provider.ForceFlush(ctx)
+}
+
+// Shutdown flushes the finished spans and stops the exporter
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	return p.provider.Shutdown(ctx)
+}
+
+// XRayTraceID formats a trace ID the way X-Ray and the logs show it, such as
+// "1-5759e988-bd862e3fe1be46a994272793"
+func XRayTraceID(traceID trace.TraceID) string {
+	hex := traceID.String()
+	return "1-" + hex[:8] + "-" + hex[8:]
+}
+
+// errorType names the type of err for the error.type span attribute. Error
+// messages are not recorded, as they may quote request data.
+func errorType(err error) string {
+	for inner := errors.Unwrap(err); inner != nil; inner = errors.Unwrap(err) {
+		err = inner
+	}
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,222 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// restoreGlobals puts back the global tracer provider and propagator
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		if otel.GetTracerProvider() != previousProvider {
+			otel.SetTracerProvider(previousProvider)
+		}
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+}
+
+// recordSpans installs a global tracer provider keeping the finished spans.
+// Tracers must be created after the call.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	restoreGlobals(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+// attributes indexes the attributes of a finished span by key
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, attr := range span.Attributes() {
+		attrs[attr.Key] = attr.Value
+	}
+	return attrs
+}
+
+func TestParseExporter(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Exporter
+		wantErr bool
+	}{
+		{name: "", want: ExporterNone},
+		{name: "none", want: ExporterNone},
+		{name: "OTLP", want: ExporterOTLP},
+		{name: " xray ", want: ExporterXRay},
+		{name: "stdout", want: ExporterStdout},
+		{name: "jaeger", want: ExporterNone, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseExporter(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected exporter '%s', got '%s'", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr string
+	}{
+		{name: "disabled", config: Config{}},
+		{name: "otlp", config: Config{Exporter: ExporterOTLP, Endpoint: "http://localhost:4318", SampleRatio: 0.5}},
+		{name: "unknown exporter", config: Config{Exporter: "jaeger"}, wantErr: "unknown trace exporter"},
+		{name: "negative ratio", config: Config{SampleRatio: -0.1}, wantErr: "sample ratio"},
+		{name: "ratio above one", config: Config{SampleRatio: 1.5}, wantErr: "sample ratio"},
+		{name: "endpoint without scheme", config: Config{Exporter: ExporterOTLP, Endpoint: "localhost:4318"}, wantErr: "endpoint"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing '%s', got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestPropagator(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "reads W3C traceparent",
+			headers: map[string]string{"traceparent": "00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01"},
+			want:    "5759e988bd862e3fe1be46a994272793",
+		},
+		{
+			name:    "reads X-Ray header",
+			headers: map[string]string{"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"},
+			want:    "5759e988bd862e3fe1be46a994272793",
+		},
+		{
+			name: "prefers X-Ray header",
+			headers: map[string]string{
+				"traceparent":     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+				"X-Amzn-Trace-Id": "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			},
+			want: "5759e988bd862e3fe1be46a994272793",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carrier := propagation.MapCarrier{}
+			for k, v := range tt.headers {
+				carrier.Set(k, v)
+			}
+
+			ctx := Propagator().Extract(context.Background(), carrier)
+
+			if got := trace.SpanContextFromContext(ctx).TraceID().String(); got != tt.want {
+				t.Errorf("Expected trace ID %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetup_Stdout(t *testing.T) {
+	restoreGlobals(t)
+
+	var buf bytes.Buffer
+	provider, err := Setup(context.Background(), Config{
+		Exporter:    ExporterStdout,
+		ServiceName: "chargeback-api",
+		Version:     "1.2.3",
+		SampleRatio: 1,
+		Writer:      &buf,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	traceID := span.SpanContext().TraceID()
+	span.End()
+
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatalf("Unexpected flush error: %v", err)
+	}
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{`"Name":"operation"`, traceID.String(), `"Value":"chargeback-api"`, `"Value":"1.2.3"`} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %s, got: %s", want, output)
+		}
+	}
+
+	// X-Ray trace IDs start with the epoch second of the trace
+	if xrayID := XRayTraceID(traceID); len(xrayID) != 35 || !strings.HasPrefix(xrayID, "1-") {
+		t.Errorf("Unexpected X-Ray trace ID %s", xrayID)
+	}
+}
+
+func TestSetup_None(t *testing.T) {
+	restoreGlobals(t)
+
+	provider, err := Setup(context.Background(), Config{Exporter: ExporterNone})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Errorf("Unexpected flush error: %v", err)
+	}
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+
+	// The propagator is installed even when spans are not exported
+	carrier := propagation.MapCarrier{"traceparent": "00-5759e988bd862e3fe1be46a994272793-53995c3f42cd8ad8-01"}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), carrier)
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("Expected the global propagator to read traceparent")
+	}
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string { return "not found" }
+
+func TestErrorType(t *testing.T) {
+	err := fmt.Errorf("failed to query: %w", fmt.Errorf("card 4111111111111111: %w", notFoundError{}))
+	if got := errorType(err); got != "tracing.notFoundError" {
+		t.Errorf("Expected the innermost error type, got %s", got)
+	}
+	if got := errorType(errors.New("plain")); got != "*errors.errorString" {
+		t.Errorf("Expected *errors.errorString, got %s", got)
+	}
+}
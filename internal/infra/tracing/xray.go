@@ -0,0 +1,267 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// DefaultXRayDaemonAddress is where the X-Ray daemon listens by default
+	DefaultXRayDaemonAddress = "127.0.0.1:2000"
+
+	// xrayDaemonAddressEnv is set by Lambda when active tracing is enabled
+	xrayDaemonAddressEnv = "AWS_XRAY_DAEMON_ADDRESS"
+
+	// xrayHeader precedes every segment document sent to the daemon
+	xrayHeader = `{"format": "json", "version": 1}` + "\n"
+
+	// xrayMaxNameLength bounds segment names
+	xrayMaxNameLength = 200
+)
+
+// XRayExporter sends spans to the X-Ray daemon over UDP. Spans without a
+// parent become segments named after the service; the others become
+// subsegments of their parent, including the segment Lambda creates for
+// each invocation.
+type XRayExporter struct {
+	serviceName string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewXRayExporter creates an exporter sending to the daemon at address, or
+// at AWS_XRAY_DAEMON_ADDRESS or DefaultXRayDaemonAddress if empty
+func NewXRayExporter(address, serviceName string) (*XRayExporter, error) {
+	if address == "" {
+		address = os.Getenv(xrayDaemonAddressEnv)
+	}
+	if address == "" {
+		address = DefaultXRayDaemonAddress
+	}
+
+	// The address may list TCP and UDP endpoints: "tcp:host:port udp:host:port"
+	for _, part := range strings.Fields(address) {
+		if udp, ok := strings.CutPrefix(part, "udp:"); ok {
+			address = udp
+			break
+		}
+	}
+
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the X-Ray daemon at %s: %w", address, err)
+	}
+
+	return &XRayExporter{serviceName: serviceName, conn: conn}, nil
+}
+
+// ExportSpans sends one segment document per span
+func (e *XRayExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return errors.New("X-Ray exporter is shut down")
+	}
+
+	var errs []error
+	for _, span := range spans {
+		document, err := json.Marshal(e.segment(span))
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if _, err := e.conn.Write(append([]byte(xrayHeader), document...)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown closes the connection to the daemon
+func (e *XRayExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.conn == nil {
+		return nil
+	}
+	err := e.conn.Close()
+	e.conn = nil
+	return err
+}
+
+// xraySegment is an X-Ray segment or independent subsegment document
+type xraySegment struct {
+	Name        string                            `json:"name"`
+	ID          string                            `json:"id"`
+	TraceID     string                            `json:"trace_id"`
+	ParentID    string                            `json:"parent_id,omitempty"`
+	Type        string                            `json:"type,omitempty"`
+	StartTime   float64                           `json:"start_time"`
+	EndTime     float64                           `json:"end_time"`
+	Namespace   string                            `json:"namespace,omitempty"`
+	Error       bool                              `json:"error,omitempty"`
+	Fault       bool                              `json:"fault,omitempty"`
+	HTTP        *xrayHTTP                         `json:"http,omitempty"`
+	Annotations map[string]interface{}            `json:"annotations,omitempty"`
+	Metadata    map[string]map[string]interface{} `json:"metadata,omitempty"`
+}
+
+type xrayHTTP struct {
+	Request  *xrayHTTPRequest  `json:"request,omitempty"`
+	Response *xrayHTTPResponse `json:"response,omitempty"`
+}
+
+type xrayHTTPRequest struct {
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+type xrayHTTPResponse struct {
+	Status int64 `json:"status,omitempty"`
+}
+
+// segment converts a span to a segment document
+func (e *XRayExporter) segment(span sdktrace.ReadOnlySpan) xraySegment {
+	spanContext := span.SpanContext()
+	segment := xraySegment{
+		Name:      xraySegmentName(span.Name()),
+		ID:        spanContext.SpanID().String(),
+		TraceID:   XRayTraceID(spanContext.TraceID()),
+		StartTime: xrayTime(span.StartTime()),
+		EndTime:   xrayTime(span.EndTime()),
+	}
+
+	if parent := span.Parent(); parent.IsValid() {
+		segment.Type = "subsegment"
+		segment.ParentID = parent.SpanID().String()
+	} else if e.serviceName != "" {
+		segment.Name = xraySegmentName(e.serviceName)
+	}
+
+	var status int64
+	for _, attr := range span.Attributes() {
+		switch attr.Key {
+		case "http.request.method":
+			segment.httpRequest().Method = attr.Value.AsString()
+		case "url.path":
+			segment.httpRequest().URL = attr.Value.AsString()
+		case "http.response.status_code":
+			status = attr.Value.AsInt64()
+			segment.httpResponse().Status = status
+		case "db.system.name":
+			if attr.Value.AsString() == "aws.dynamodb" {
+				segment.Namespace = "aws"
+			}
+		}
+		segment.annotate(attr)
+	}
+
+	if span.Status().Code == codes.Error {
+		if status >= 400 && status < 500 {
+			segment.Error = true
+		} else {
+			segment.Fault = true
+		}
+	}
+
+	if span.SpanKind() == trace.SpanKindClient && segment.Namespace == "" {
+		segment.Namespace = "remote"
+	}
+
+	return segment
+}
+
+func (s *xraySegment) httpRequest() *xrayHTTPRequest {
+	if s.HTTP == nil {
+		s.HTTP = &xrayHTTP{}
+	}
+	if s.HTTP.Request == nil {
+		s.HTTP.Request = &xrayHTTPRequest{}
+	}
+	return s.HTTP.Request
+}
+
+func (s *xraySegment) httpResponse() *xrayHTTPResponse {
+	if s.HTTP == nil {
+		s.HTTP = &xrayHTTP{}
+	}
+	if s.HTTP.Response == nil {
+		s.HTTP.Response = &xrayHTTPResponse{}
+	}
+	return s.HTTP.Response
+}
+
+// annotate records scalar attributes as searchable annotations and the others
+// as metadata
+func (s *xraySegment) annotate(attr attribute.KeyValue) {
+	key := xrayAnnotationKey(string(attr.Key))
+
+	var value interface{}
+	switch attr.Value.Type() {
+	case attribute.BOOL:
+		value = attr.Value.AsBool()
+	case attribute.INT64:
+		value = attr.Value.AsInt64()
+	case attribute.FLOAT64:
+		value = attr.Value.AsFloat64()
+	case attribute.STRING:
+		value = attr.Value.AsString()
+	default:
+		if s.Metadata == nil {
+			s.Metadata = map[string]map[string]interface{}{"default": {}}
+		}
+		s.Metadata["default"][string(attr.Key)] = attr.Value.AsInterface()
+		return
+	}
+
+	if s.Annotations == nil {
+		s.Annotations = make(map[string]interface{})
+	}
+	s.Annotations[key] = value
+}
+
+// xrayTime converts a time to epoch seconds with a fractional part
+func xrayTime(t time.Time) float64 {
+	return float64(t.Unix()) + float64(t.Nanosecond())/1e9
+}
+
+// xraySegmentName replaces the characters X-Ray rejects in segment names
+func xraySegmentName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsSpace(r) || strings.ContainsRune(`_.:/%&#=+\-@`, r) {
+			return r
+		}
+		return '_'
+	}, name)
+	if len(name) > xrayMaxNameLength {
+		name = name[:xrayMaxNameLength]
+	}
+	return name
+}
+
+// xrayAnnotationKey replaces the characters X-Ray rejects in annotation keys,
+// which may only hold letters, digits and underscores
+func xrayAnnotationKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return '_'
+	}, key)
+}
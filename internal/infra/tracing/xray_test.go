@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// daemonStub receives the documents sent to the X-Ray daemon
+type daemonStub struct {
+	conn net.PacketConn
+}
+
+func newDaemonStub(t *testing.T) *daemonStub {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &daemonStub{conn: conn}
+}
+
+// receive returns the next segment, checking the header preceding it
+func (d *daemonStub) receive(t *testing.T) map[string]interface{} {
+	t.Helper()
+	buf := make([]byte, 64<<10)
+	d.conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := d.conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to receive segment: %v", err)
+	}
+
+	header, document, ok := bytes.Cut(buf[:n], []byte("\n"))
+	if !ok || string(header)+"\n" != xrayHeader {
+		t.Fatalf("Expected the X-Ray header, got %q", buf[:n])
+	}
+
+	var segment map[string]interface{}
+	if err := json.Unmarshal(document, &segment); err != nil {
+		t.Fatalf("Failed to decode segment %q: %v", document, err)
+	}
+	return segment
+}
+
+func TestXRayExporter_ExportSpans(t *testing.T) {
+	daemon := newDaemonStub(t)
+	exporter, err := NewXRayExporter("tcp:127.0.0.1:2000 udp:"+daemon.conn.LocalAddr().String(), "chargeback-api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer exporter.Shutdown(context.Background())
+
+	traceID, _ := trace.TraceIDFromHex("5759e988bd862e3fe1be46a994272793")
+	rootID, _ := trace.SpanIDFromHex("53995c3f42cd8ad8")
+	childID, _ := trace.SpanIDFromHex("b7ad6b7169203331")
+	start := time.Unix(1700000000, 0)
+
+	root := tracetest.SpanStub{
+		Name:        "ServeHTTP POST",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: rootID}),
+		SpanKind:    trace.SpanKindServer,
+		StartTime:   start,
+		EndTime:     start.Add(250 * time.Millisecond),
+		Attributes: []attribute.KeyValue{
+			attribute.String("http.request.method", "POST"),
+			attribute.String("url.path", "/chargebacks"),
+			attribute.Int("http.response.status_code", 503),
+			attribute.StringSlice("tags", []string{"a", "b"}),
+		},
+		Status: sdktrace.Status{Code: codes.Error},
+	}
+	child := tracetest.SpanStub{
+		Name:        "ChargebackRepository.Save",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: childID}),
+		Parent:      root.SpanContext,
+		SpanKind:    trace.SpanKindClient,
+		StartTime:   start,
+		EndTime:     start.Add(100 * time.Millisecond),
+		Attributes: []attribute.KeyValue{
+			attribute.String("db.system.name", "aws.dynamodb"),
+			attribute.String("chargeback.merchant_id", "merchant-1"),
+		},
+	}
+
+	if err := exporter.ExportSpans(context.Background(), tracetest.SpanStubs{root, child}.Snapshots()); err != nil {
+		t.Fatalf("Unexpected export error: %v", err)
+	}
+
+	segment := daemon.receive(t)
+	if segment["name"] != "chargeback-api" {
+		t.Errorf("Expected the segment to be named after the service, got %v", segment["name"])
+	}
+	if segment["trace_id"] != "1-5759e988-bd862e3fe1be46a994272793" || segment["id"] != "53995c3f42cd8ad8" {
+		t.Errorf("Unexpected identifiers: %v %v", segment["trace_id"], segment["id"])
+	}
+	if _, ok := segment["parent_id"]; ok {
+		t.Error("Expected no parent on a root segment")
+	}
+	if segment["start_time"] != 1700000000.0 || segment["end_time"] != 1700000000.25 {
+		t.Errorf("Unexpected times: %v %v", segment["start_time"], segment["end_time"])
+	}
+	if segment["fault"] != true || segment["error"] != nil {
+		t.Errorf("Expected a fault for a 5xx status, got fault %v error %v", segment["fault"], segment["error"])
+	}
+	http := segment["http"].(map[string]interface{})
+	if http["request"].(map[string]interface{})["url"] != "/chargebacks" || http["response"].(map[string]interface{})["status"] != 503.0 {
+		t.Errorf("Unexpected http block: %v", http)
+	}
+	if annotations := segment["annotations"].(map[string]interface{}); annotations["http_request_method"] != "POST" {
+		t.Errorf("Expected sanitized annotation keys, got %v", annotations)
+	}
+	if metadata := segment["metadata"].(map[string]interface{})["default"].(map[string]interface{}); metadata["tags"] == nil {
+		t.Errorf("Expected slices in metadata, got %v", metadata)
+	}
+
+	subsegment := daemon.receive(t)
+	if subsegment["type"] != "subsegment" || subsegment["parent_id"] != "53995c3f42cd8ad8" {
+		t.Errorf("Expected a subsegment of the root, got type %v parent %v", subsegment["type"], subsegment["parent_id"])
+	}
+	if subsegment["name"] != "ChargebackRepository.Save" || subsegment["namespace"] != "aws" {
+		t.Errorf("Unexpected subsegment name %v namespace %v", subsegment["name"], subsegment["namespace"])
+	}
+}
+
+func TestXRayExporter_Shutdown(t *testing.T) {
+	daemon := newDaemonStub(t)
+	exporter, err := NewXRayExporter(daemon.conn.LocalAddr().String(), "chargeback-api")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+	if err := exporter.Shutdown(context.Background()); err != nil {
+		t.Errorf("Expected a second shutdown to succeed, got %v", err)
+	}
+	if err := exporter.ExportSpans(context.Background(), nil); err == nil {
+		t.Error("Expected an error exporting after shutdown")
+	}
+}
+
+func TestXRayNames(t *testing.T) {
+	if got := xraySegmentName("GET /chargebacks/{id}"); got != "GET /chargebacks/_id_" {
+		t.Errorf("Unexpected segment name %q", got)
+	}
+	if got := xrayAnnotationKey("db.system.name"); got != "db_system_name" {
+		t.Errorf("Unexpected annotation key %q", got)
+	}
+}
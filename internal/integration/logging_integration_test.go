@@ -8,6 +8,7 @@ import (
 
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
 )
 
 // TestLoggingIntegration_EndToEnd tests the complete logging flow
@@ -112,8 +113,8 @@ func TestLoggingIntegration_ContextualLogging(t *testing.T) {
 	}
 
 	// Create context with request metadata
-	ctx := context.WithValue(context.Background(), "correlation_id", "req-123")
-	ctx = context.WithValue(ctx, "user_id", "user-456")
+	ctx := requestctx.WithCorrelationID(context.Background(), "req-123")
+	ctx = requestctx.WithUserID(ctx, "user-456")
 
 	contextLogger := logger.WithContext(ctx)
 
@@ -0,0 +1,72 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/logging"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+// leakyUseCase fails with an error echoing the request, as a careless
+// implementation could
+type leakyUseCase struct{}
+
+func (leakyUseCase) Execute(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+	return nil, fmt.Errorf("failed to process %+v", req)
+}
+
+// TestLoggingIntegration_NoPANInRequestLogs sends card numbers through the API
+// and checks none reaches the log output
+func TestLoggingIntegration_NoPANInRequestLogs(t *testing.T) {
+	const pan = "4111111111111111"
+
+	var buf bytes.Buffer
+	logger, err := logging.NewStructuredLogger(logging.LoggerConfig{
+		Level:  service.LogLevelDebug,
+		Format: logging.FormatJSON,
+	}, &buf)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	apiRouter := router.NewChargebackRouter(router.Config{}, leakyUseCase{}, logger)
+	handler := middleware.Standard(middleware.Options{Logger: logger, Routes: apiRouter}).Then(apiRouter)
+
+	bodies := map[string]string{
+		"use case error": `{"transaction_id":"txn-1","merchant_id":"m-1","amount":10,"currency":"USD",` +
+			`"card_number":"` + pan + `","reason":"fraud","description":"card ` + pan + `",` +
+			`"transaction_date":"2024-01-15T10:30:00Z"}`,
+		"invalid date":  `{"transaction_id":"txn-1","merchant_id":"m-1","amount":10,"currency":"USD","card_number":"4111 1111 1111 1111","reason":"fraud","transaction_date":"` + pan + `"}`,
+		"unknown field": `{"card":"` + pan + `"}`,
+		"invalid JSON":  `{"card_number":` + pan + `,`,
+	}
+
+	for name, body := range bodies {
+		req := httptest.NewRequest(http.MethodPost, "/chargebacks", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "client/"+pan)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if buf.Len() == 0 {
+			t.Fatalf("%s: expected log output", name)
+		}
+	}
+
+	output := buf.String()
+	compact := strings.NewReplacer(" ", "", "-", "").Replace(output)
+	if strings.Contains(compact, pan) {
+		t.Errorf("PAN leaked into request logs:\n%s", output)
+	}
+	if !strings.Contains(output, "Failed to create chargeback") || !strings.Contains(output, logging.RedactedValue) {
+		t.Errorf("Expected the use case failure to be logged redacted, got:\n%s", output)
+	}
+}
@@ -0,0 +1,204 @@
+// Package requestctx carries request-scoped identifiers through a
+// context.Context using typed keys, so they cannot collide with values set by
+// other packages
+package requestctx
+
+import (
+	"context"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+)
+
+const (
+	// RequestIDHeader carries the ID of a single request
+	RequestIDHeader = "X-Request-ID"
+
+	// CorrelationIDHeader carries the ID shared by every request of a flow
+	CorrelationIDHeader = "X-Correlation-ID"
+)
+
+// contextKey is unexported so only this package can read or write its values
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	correlationIDKey
+	userIDKey
+	merchantIDKey
+	chargebackIDKey
+	traceIDKey
+	principalKey
+	authorizerClaimsKey
+	callbackSourceKey
+)
+
+// Log field names of the identifiers carried by a context
+const (
+	FieldRequestID     = "request_id"
+	FieldCorrelationID = "correlation_id"
+	FieldUserID        = "user_id"
+	FieldMerchantID    = "merchant_id"
+	FieldChargebackID  = "chargeback_id"
+	FieldTraceID       = "trace_id"
+)
+
+// fieldKeys maps every log field name to its context key
+var fieldKeys = map[string]contextKey{
+	FieldRequestID:     requestIDKey,
+	FieldCorrelationID: correlationIDKey,
+	FieldUserID:        userIDKey,
+	FieldMerchantID:    merchantIDKey,
+	FieldChargebackID:  chargebackIDKey,
+	FieldTraceID:       traceIDKey,
+}
+
+// DefaultFields lists the fields loggers extract from a context, in output order
+var DefaultFields = []string{
+	FieldRequestID,
+	FieldCorrelationID,
+	FieldTraceID,
+	FieldUserID,
+	FieldMerchantID,
+	FieldChargebackID,
+}
+
+// WithRequestID returns a context carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID, or "" if none was set
+func RequestID(ctx context.Context) string {
+	return stringValue(ctx, requestIDKey)
+}
+
+// WithCorrelationID returns a context carrying the correlation ID
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey, id)
+}
+
+// CorrelationID returns the correlation ID, or "" if none was set
+func CorrelationID(ctx context.Context) string {
+	return stringValue(ctx, correlationIDKey)
+}
+
+// WithUserID returns a context carrying the ID of the calling user
+func WithUserID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, userIDKey, id)
+}
+
+// UserID returns the ID of the calling user, or "" if none was set
+func UserID(ctx context.Context) string {
+	return stringValue(ctx, userIDKey)
+}
+
+// WithMerchantID returns a context carrying the ID of the merchant a request acts on
+func WithMerchantID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, merchantIDKey, id)
+}
+
+// MerchantID returns the merchant ID, or "" if none was set
+func MerchantID(ctx context.Context) string {
+	return stringValue(ctx, merchantIDKey)
+}
+
+// WithChargebackID returns a context carrying the ID of the chargeback a request acts on
+func WithChargebackID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, chargebackIDKey, id)
+}
+
+// ChargebackID returns the chargeback ID, or "" if none was set
+func ChargebackID(ctx context.Context) string {
+	return stringValue(ctx, chargebackIDKey)
+}
+
+// WithTraceID returns a context carrying the distributed trace ID
+func WithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey, id)
+}
+
+// TraceID returns the distributed trace ID, or "" if none was set
+func TraceID(ctx context.Context) string {
+	return stringValue(ctx, traceIDKey)
+}
+
+// WithPrincipal returns a context carrying the authenticated caller. The
+// caller's ID is carried as the user ID as well, so log entries name it.
+func WithPrincipal(ctx context.Context, principal *entity.Principal) context.Context {
+	ctx = context.WithValue(ctx, principalKey, principal)
+	return WithUserID(ctx, principal.ID)
+}
+
+// Principal returns the authenticated caller, or nil if none was set
+func Principal(ctx context.Context) *entity.Principal {
+	if ctx == nil {
+		return nil
+	}
+	principal, _ := ctx.Value(principalKey).(*entity.Principal)
+	return principal
+}
+
+// WithAuthorizerClaims returns a context carrying the claims established by
+// an API Gateway authorizer. Only the Lambda adapter sets them, from the
+// event, so clients cannot forge them.
+func WithAuthorizerClaims(ctx context.Context, claims map[string]string) context.Context {
+	return context.WithValue(ctx, authorizerClaimsKey, claims)
+}
+
+// AuthorizerClaims returns the claims of an API Gateway authorizer, or nil if
+// none were set
+func AuthorizerClaims(ctx context.Context) map[string]string {
+	if ctx == nil {
+		return nil
+	}
+	claims, _ := ctx.Value(authorizerClaimsKey).(map[string]string)
+	return claims
+}
+
+// WithCallbackSource returns a context carrying the source whose signature
+// verified a pushed callback. Only the signature middleware sets it.
+func WithCallbackSource(ctx context.Context, source string) context.Context {
+	return context.WithValue(ctx, callbackSourceKey, source)
+}
+
+// CallbackSource returns the verified source of a callback, or "" if the
+// request is not one
+func CallbackSource(ctx context.Context) string {
+	return stringValue(ctx, callbackSourceKey)
+}
+
+// IsField reports whether name is the log field name of a context identifier
+func IsField(name string) bool {
+	_, ok := fieldKeys[name]
+	return ok
+}
+
+// Lookup returns the identifier stored under a log field name, or "" if the
+// context does not carry it or the name is unknown
+func Lookup(ctx context.Context, name string) string {
+	key, ok := fieldKeys[name]
+	if !ok {
+		return ""
+	}
+	return stringValue(ctx, key)
+}
+
+// Fields returns the identifiers present in the context keyed by their log
+// field name
+func Fields(ctx context.Context) map[string]string {
+	fields := make(map[string]string, len(fieldKeys))
+	for name, key := range fieldKeys {
+		if id := stringValue(ctx, key); id != "" {
+			fields[name] = id
+		}
+	}
+	return fields
+}
+
+func stringValue(ctx context.Context, key contextKey) string {
+	if ctx == nil {
+		return ""
+	}
+	value, _ := ctx.Value(key).(string)
+	return value
+}
@@ -0,0 +1,54 @@
+package requestctx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+)
+
+func TestFields(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithCorrelationID(ctx, "corr-1")
+
+	fields := Fields(ctx)
+
+	if len(fields) != 2 || fields["request_id"] != "req-1" || fields["correlation_id"] != "corr-1" {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+}
+
+func TestTypedKeys_IgnoreStringKeys(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "request_id", "forged")
+
+	if id := RequestID(ctx); id != "" {
+		t.Errorf("Expected no request ID from a string key, got %q", id)
+	}
+}
+
+func TestWithPrincipal(t *testing.T) {
+	principal := &entity.Principal{ID: "key-1", Method: entity.AuthMethodAPIKey, MerchantID: "merchant-456"}
+
+	ctx := WithPrincipal(context.Background(), principal)
+
+	if got := Principal(ctx); got != principal {
+		t.Errorf("Expected the principal, got %+v", got)
+	}
+	if id := UserID(ctx); id != "key-1" {
+		t.Errorf("Expected the principal ID as user ID, got %q", id)
+	}
+	if got := Principal(context.Background()); got != nil {
+		t.Errorf("Expected no principal, got %+v", got)
+	}
+}
+
+func TestWithCallbackSource(t *testing.T) {
+	ctx := WithCallbackSource(context.Background(), "acquirer-a")
+
+	if source := CallbackSource(ctx); source != "acquirer-a" {
+		t.Errorf("Expected the callback source, got %q", source)
+	}
+	if source := CallbackSource(context.Background()); source != "" {
+		t.Errorf("Expected no callback source, got %q", source)
+	}
+}
@@ -2,13 +2,15 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/handler"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
 )
@@ -20,15 +22,77 @@ type CreateChargebackUseCase interface {
 
 // Server represents the HTTP server
 type Server struct {
-	config            ServerConfig
-	mux               *http.ServeMux
-	chargebackHandler *handler.ChargebackHandler
-	logger            service.Logger
+	config  ServerConfig
+	router  *router.Router
+	handler http.Handler
+	logger  service.Logger
 }
 
+// Default timeouts applied when the corresponding ServerConfig field is zero
+const (
+	DefaultReadTimeout     = 15 * time.Second
+	DefaultWriteTimeout    = 15 * time.Second
+	DefaultIdleTimeout     = 60 * time.Second
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// DefaultMetricsPath is where metrics are served when ServerConfig.MetricsPath is empty
+const DefaultMetricsPath = "/metrics"
+
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Port string `json:"port"`
+
+	// ReadTimeout bounds reading the whole request, including the body
+	ReadTimeout time.Duration `json:"read_timeout"`
+
+	// WriteTimeout bounds the time from the end of the request headers to the end of the response
+	WriteTimeout time.Duration `json:"write_timeout"`
+
+	// IdleTimeout bounds how long keep-alive connections wait for the next request
+	IdleTimeout time.Duration `json:"idle_timeout"`
+
+	// ShutdownTimeout bounds how long in-flight requests may drain on shutdown
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"`
+
+	// RequestTimeout bounds handling of a single request, disabled if zero
+	RequestTimeout time.Duration `json:"request_timeout"`
+
+	// API configures the chargeback API router
+	API router.Config `json:"-"`
+
+	// CORS is the cross-origin policy, shared with the Lambda adapter
+	CORS middleware.CORSPolicy `json:"-"`
+
+	// LogLevels adjusts the log level while running, disabled if nil
+	LogLevels middleware.LogLevels `json:"-"`
+
+	// MetricsHandler serves the Prometheus exposition at MetricsPath,
+	// disabled if nil
+	MetricsHandler http.Handler `json:"-"`
+
+	// MetricsPath is where MetricsHandler is served, DefaultMetricsPath if empty
+	MetricsPath string `json:"metrics_path"`
+
+	// Authenticator checks the credentials of every request outside
+	// PublicPaths, and those presented to them, disabled if nil
+	Authenticator service.Authenticator `json:"-"`
+
+	// SignatureVerifier verifies the callbacks to SignedPaths, disabled if
+	// nil
+	SignatureVerifier service.SignatureVerifier `json:"-"`
+
+	// SignedPaths are the callbacks signed by acquirers,
+	// middleware.DefaultSignedPaths if nil
+	SignedPaths []string `json:"signed_paths"`
+
+	// RateLimiter limits the request rate of each caller outside
+	// PublicPaths, disabled if nil
+	RateLimiter service.RateLimiter `json:"-"`
+
+	// PublicPaths are served without credentials or rate limits,
+	// middleware.DefaultPublicPaths if nil
+	PublicPaths []string `json:"public_paths"`
 }
 
 // Validate validates the server configuration
@@ -42,147 +106,158 @@ func (c ServerConfig) Validate() error {
 		return fmt.Errorf("port must be a valid number")
 	}
 
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 || c.RequestTimeout < 0 {
+		return fmt.Errorf("timeouts must not be negative")
+	}
+
+	if err := c.CORS.Validate(); err != nil {
+		return fmt.Errorf("invalid CORS policy: %w", err)
+	}
+
 	return nil
 }
 
+// withDefaults fills unset fields with their default values
+func (c ServerConfig) withDefaults() ServerConfig {
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = DefaultReadTimeout
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = DefaultWriteTimeout
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = DefaultIdleTimeout
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if c.API.ServiceName == "" {
+		c.API.ServiceName = "chargeback-api"
+	}
+	if c.API.MaxBodyBytes <= 0 {
+		c.API.MaxBodyBytes = router.DefaultMaxBodyBytes
+	}
+	if c.MetricsPath == "" {
+		c.MetricsPath = DefaultMetricsPath
+	}
+	return c
+}
+
 // NewServer creates a new HTTP server
 func NewServer(config ServerConfig, createChargebackUC CreateChargebackUseCase, logger service.Logger) *Server {
+	config = config.withDefaults()
+
+	apiRouter := router.NewChargebackRouter(config.API, createChargebackUC, logger)
+
+	// Metrics are scraped from the local server only; Lambda publishes them
+	// as EMF documents
+	if config.MetricsHandler != nil {
+		apiRouter.Handle(http.MethodGet, config.MetricsPath, config.MetricsHandler)
+	}
+
 	server := &Server{
-		config:            config,
-		mux:               http.NewServeMux(),
-		chargebackHandler: handler.NewChargebackHandler(createChargebackUC),
-		logger:            logger,
+		config: config,
+		router: apiRouter,
+		logger: logger,
 	}
 
-	server.setupRoutes()
 	server.setupMiddleware()
 
 	return server
 }
 
-// setupRoutes configures the HTTP routes
-func (s *Server) setupRoutes() {
-	// Health check endpoint
-	s.mux.HandleFunc("/health", s.handleHealth)
-
-	// Chargeback endpoints
-	s.mux.HandleFunc("/chargebacks", s.chargebackHandler.CreateChargeback)
-}
-
-// setupMiddleware applies middleware to the server
+// setupMiddleware wraps the router with the middleware chain shared with the
+// Lambda adapter
 func (s *Server) setupMiddleware() {
-	// Middleware is applied through ServeHTTP method
+	s.handler = middleware.Standard(middleware.Options{
+		Logger:            s.logger,
+		Metrics:           s.config.API.Metrics,
+		LogLevels:         s.config.LogLevels,
+		CORS:              s.config.CORS,
+		Routes:            s.router,
+		RequestTimeout:    s.config.RequestTimeout,
+		MaxBodyBytes:      s.config.API.MaxBodyBytes,
+		Authenticator:     s.config.Authenticator,
+		SignatureVerifier: s.config.SignatureVerifier,
+		SignedPaths:       s.config.SignedPaths,
+		RateLimiter:       s.config.RateLimiter,
+		PublicPaths:       s.config.PublicPaths,
+	}).Then(s.router)
 }
 
 // ServeHTTP implements http.Handler interface
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Apply CORS middleware
-	s.corsMiddleware(w, r)
+	s.handler.ServeHTTP(w, r)
+}
 
-	// Return early for OPTIONS requests (handled by CORS middleware)
-	if r.Method == http.MethodOptions {
-		return
+// Run listens on the configured port and serves requests until ctx is
+// cancelled, then stops accepting connections and waits up to
+// ShutdownTimeout for in-flight requests to complete
+func (s *Server) Run(ctx context.Context) error {
+	if err := s.config.Validate(); err != nil {
+		return fmt.Errorf("invalid server configuration: %w", err)
 	}
 
-	// Create a response writer wrapper to capture status code
-	wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
-
-	start := time.Now()
-
-	// Check if route exists
-	if !s.routeExists(r.URL.Path) {
-		wrapped.Header().Set("Content-Type", "application/json")
-		wrapped.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(wrapped).Encode(map[string]string{"error": "Not found"})
-	} else {
-		s.mux.ServeHTTP(wrapped, r)
+	listener, err := net.Listen("tcp", ":"+s.config.Port)
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %s: %w", s.config.Port, err)
 	}
 
-	// Log the request
-	duration := time.Since(start)
-	s.logger.Info(r.Context(), "HTTP request processed", map[string]interface{}{
-		"method":      r.Method,
-		"path":        r.URL.Path,
-		"status_code": wrapped.statusCode,
-		"duration_ms": float64(duration.Nanoseconds()) / 1000000,
-		"user_agent":  r.Header.Get("User-Agent"),
-		"remote_addr": r.RemoteAddr,
-	})
+	return s.Serve(ctx, listener)
 }
 
-// routeExists checks if a route exists
-func (s *Server) routeExists(path string) bool {
-	validRoutes := []string{"/health", "/chargebacks"}
-	for _, route := range validRoutes {
-		if path == route {
-			return true
-		}
-	}
-	return false
+// Start listens on the configured port and serves requests until the server
+// fails.
+//
+// Deprecated: Use Run, which also shuts down gracefully once its context is
+// cancelled.
+func (s *Server) Start() error {
+	return s.Run(context.Background())
 }
 
-// handleHealth handles health check requests
-func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Method not allowed"})
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"service":   "chargeback-api",
-		"status":    "ok",
-		"timestamp": time.Now().Format(time.RFC3339),
+// Serve serves requests on the listener until ctx is cancelled, then shuts
+// down gracefully like Run
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	httpServer := &http.Server{
+		Handler:      s,
+		ReadTimeout:  s.config.ReadTimeout,
+		WriteTimeout: s.config.WriteTimeout,
+		IdleTimeout:  s.config.IdleTimeout,
+	}
+
+	s.logger.Info(ctx, "Starting HTTP server", map[string]interface{}{
+		"address":       listener.Addr().String(),
+		"read_timeout":  s.config.ReadTimeout.String(),
+		"write_timeout": s.config.WriteTimeout.String(),
+		"idle_timeout":  s.config.IdleTimeout.String(),
 	})
-}
 
-// corsMiddleware handles CORS (Cross-Origin Resource Sharing)
-func (s *Server) corsMiddleware(w http.ResponseWriter, r *http.Request) {
-	// Set CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.Serve(listener)
+	}()
 
-	// Handle preflight requests
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
-		return
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("HTTP server failed: %w", err)
+	case <-ctx.Done():
 	}
-}
 
-// responseWriter wraps http.ResponseWriter to capture status code
-type responseWriter struct {
-	http.ResponseWriter
-	statusCode int
-}
+	s.logger.Info(context.Background(), "Shutting down HTTP server", map[string]interface{}{
+		"shutdown_timeout": s.config.ShutdownTimeout.String(),
+	})
 
-func (rw *responseWriter) WriteHeader(code int) {
-	rw.statusCode = code
-	rw.ResponseWriter.WriteHeader(code)
-}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
 
-// Start starts the HTTP server
-func (s *Server) Start() error {
-	if err := s.config.Validate(); err != nil {
-		return fmt.Errorf("invalid server configuration: %w", err)
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to drain in-flight requests: %w", err)
 	}
 
-	addr := ":" + s.config.Port
-	s.logger.Info(context.Background(), "Starting HTTP server", map[string]interface{}{
-		"address": addr,
-		"port":    s.config.Port,
-	})
-
-	server := &http.Server{
-		Addr:         addr,
-		Handler:      s,
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("HTTP server failed: %w", err)
 	}
 
-	return server.ListenAndServe()
+	s.logger.Info(context.Background(), "HTTP server stopped")
+	return nil
 }
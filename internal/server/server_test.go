@@ -4,13 +4,18 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/middleware"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/api/http/router"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
 )
 
@@ -143,6 +148,94 @@ func TestServer_Routes_GET_Health(t *testing.T) {
 	}
 }
 
+// downHealthChecker reports DynamoDB down with an error naming its table
+type downHealthChecker struct{}
+
+func (downHealthChecker) Check(ctx context.Context) []service.DependencyHealth {
+	return []service.DependencyHealth{{Name: "dynamodb", Status: service.HealthDown, LastError: "table chargebacks not found"}}
+}
+
+// apiKeyAuthenticator accepts the API key "ops-key"
+type apiKeyAuthenticator struct{}
+
+func (apiKeyAuthenticator) Authenticate(ctx context.Context, credentials service.Credentials) (*entity.Principal, error) {
+	if credentials.APIKey != "ops-key" {
+		return nil, service.ErrUnauthenticated
+	}
+	return &entity.Principal{ID: "ops", Method: entity.AuthMethodAPIKey}, nil
+}
+
+func TestServer_Routes_GET_HealthReady_ErrorDetails(t *testing.T) {
+	server := NewServer(ServerConfig{
+		Port:          "8080",
+		API:           router.Config{Health: downHealthChecker{}},
+		Authenticator: apiKeyAuthenticator{},
+	}, &MockCreateChargebackUseCase{}, createTestLogger())
+
+	tests := []struct {
+		name      string
+		apiKey    string
+		wantError bool
+	}{
+		{name: "anonymous probe", wantError: false},
+		{name: "rejected key", apiKey: "forged", wantError: false},
+		{name: "authenticated caller", apiKey: "ops-key", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			req := httptest.NewRequest(http.MethodGet, "/health/ready", nil)
+			if tt.apiKey != "" {
+				req.Header.Set(middleware.APIKeyHeader, tt.apiKey)
+			}
+			recorder := httptest.NewRecorder()
+
+			// Act
+			server.ServeHTTP(recorder, req)
+
+			// Assert: the public path never rejects, but only shows
+			// authenticated callers why a dependency is down
+			if recorder.Code != http.StatusServiceUnavailable {
+				t.Errorf("Expected status code %d, got %d", http.StatusServiceUnavailable, recorder.Code)
+			}
+			if got := strings.Contains(recorder.Body.String(), "table chargebacks not found"); got != tt.wantError {
+				t.Errorf("Expected error details shown: %v, got body %s", tt.wantError, recorder.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_Routes_GET_Metrics(t *testing.T) {
+	// Arrange
+	prometheus := metrics.NewPrometheus()
+	prometheus.Add(context.Background(), service.MetricChargebacksCreated, 1, service.Labels{"reason": "fraud"})
+
+	mockUseCase := &MockCreateChargebackUseCase{}
+	server := NewServer(ServerConfig{
+		Port:           "8080",
+		MetricsHandler: prometheus,
+	}, mockUseCase, createTestLogger())
+	disabled := NewServer(ServerConfig{Port: "8080"}, mockUseCase, createTestLogger())
+
+	// Act
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	disabledRecorder := httptest.NewRecorder()
+	disabled.ServeHTTP(disabledRecorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), `chargebacks_created_total{reason="fraud"} 1`) {
+		t.Errorf("Expected the counter in the exposition, got %s", recorder.Body.String())
+	}
+	if disabledRecorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d without a handler, got %d", http.StatusNotFound, disabledRecorder.Code)
+	}
+}
+
 func TestServer_Routes_NotFound(t *testing.T) {
 	// Arrange
 	mockUseCase := &MockCreateChargebackUseCase{}
@@ -161,11 +254,39 @@ func TestServer_Routes_NotFound(t *testing.T) {
 	}
 }
 
+func TestServer_Routes_MethodNotAllowed(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockCreateChargebackUseCase{}
+	server := NewServer(ServerConfig{
+		Port: "8080",
+	}, mockUseCase, createTestLogger())
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/chargebacks", nil)
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status code %d, got %d", http.StatusMethodNotAllowed, recorder.Code)
+	}
+
+	if allow := recorder.Header().Get("Allow"); allow != http.MethodPost {
+		t.Errorf("Expected Allow header '%s', got '%s'", http.MethodPost, allow)
+	}
+}
+
 func TestServer_Middleware_CORS(t *testing.T) {
 	// Arrange
 	mockUseCase := &MockCreateChargebackUseCase{}
 	server := NewServer(ServerConfig{
 		Port: "8080",
+		CORS: middleware.CORSPolicy{
+			AllowedOrigins: []string{"https://example.com"},
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "Authorization"},
+			MaxAge:         10 * time.Minute,
+		},
 	}, mockUseCase, createTestLogger())
 
 	// Act
@@ -178,14 +299,15 @@ func TestServer_Middleware_CORS(t *testing.T) {
 	server.ServeHTTP(recorder, req)
 
 	// Assert
-	if recorder.Code != http.StatusOK {
-		t.Errorf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, recorder.Code)
 	}
 
 	expectedHeaders := map[string]string{
-		"Access-Control-Allow-Origin":  "*",
-		"Access-Control-Allow-Methods": "GET, POST, PUT, DELETE, OPTIONS",
-		"Access-Control-Allow-Headers": "Content-Type, Authorization",
+		"Access-Control-Allow-Origin":  "https://example.com",
+		"Access-Control-Allow-Methods": "POST",
+		"Access-Control-Allow-Headers": "Content-Type",
+		"Access-Control-Max-Age":       "600",
 	}
 
 	for header, expectedValue := range expectedHeaders {
@@ -195,6 +317,29 @@ func TestServer_Middleware_CORS(t *testing.T) {
 	}
 }
 
+func TestServer_Middleware_CORS_Disabled(t *testing.T) {
+	// Arrange - no allowed origins configured
+	mockUseCase := &MockCreateChargebackUseCase{}
+	server := NewServer(ServerConfig{
+		Port: "8080",
+	}, mockUseCase, createTestLogger())
+
+	// Act
+	req := httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "https://example.com")
+
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if origin := recorder.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("Expected no CORS headers, got Access-Control-Allow-Origin '%s'", origin)
+	}
+}
+
 func TestServer_Middleware_Logging(t *testing.T) {
 	// Arrange
 	mockUseCase := &MockCreateChargebackUseCase{}
@@ -213,6 +358,46 @@ func TestServer_Middleware_Logging(t *testing.T) {
 	}
 }
 
+func TestServer_Middleware_Recovery(t *testing.T) {
+	// Arrange - a panicking use case must not crash the server
+	mockUseCase := &MockCreateChargebackUseCase{
+		ExecuteFunc: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+			panic("repository exploded")
+		},
+	}
+	server := NewServer(ServerConfig{
+		Port: "8080",
+	}, mockUseCase, createTestLogger())
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"transaction_id":   "txn-456",
+		"merchant_id":      "merchant-123",
+		"amount":           99.99,
+		"currency":         "USD",
+		"card_number":      "1234567890123456",
+		"reason":           "fraud",
+		"transaction_date": "2023-01-15T10:30:00Z",
+	})
+
+	// Act
+	req := httptest.NewRequest(http.MethodPost, "/chargebacks", bytes.NewReader(payload))
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, req)
+
+	// Assert
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, recorder.Code)
+	}
+
+	var response map[string]interface{}
+	if err := json.NewDecoder(recorder.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response["error_id"] == nil || response["error_id"] == "" {
+		t.Errorf("Expected an error_id in the response, got %v", response)
+	}
+}
+
 func TestServerConfig_Validation(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -234,6 +419,11 @@ func TestServerConfig_Validation(t *testing.T) {
 			config: ServerConfig{Port: "abc"},
 			valid:  false,
 		},
+		{
+			name:   "negative timeout",
+			config: ServerConfig{Port: "8080", WriteTimeout: -time.Second},
+			valid:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -248,3 +438,98 @@ func TestServerConfig_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestServer_Start(t *testing.T) {
+	// Start reports configuration errors like Run
+	server := NewServer(ServerConfig{Port: "http"}, &MockCreateChargebackUseCase{}, createTestLogger())
+
+	if err := server.Start(); err == nil || !strings.Contains(err.Error(), "invalid server configuration") {
+		t.Errorf("Expected an invalid configuration error, got %v", err)
+	}
+}
+
+func TestServer_Serve_DrainsInFlightRequests(t *testing.T) {
+	// Arrange - the use case blocks until released so the request is in flight during shutdown
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockUseCase := &MockCreateChargebackUseCase{
+		ExecuteFunc: func(ctx context.Context, req usecase.CreateChargebackRequest) (*usecase.CreateChargebackResponse, error) {
+			close(started)
+			<-release
+			return &usecase.CreateChargebackResponse{ID: "chargeback-123", TransactionID: req.TransactionID}, nil
+		},
+	}
+
+	server := NewServer(ServerConfig{
+		Port:            "0",
+		ShutdownTimeout: 5 * time.Second,
+	}, mockUseCase, createTestLogger())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- server.Serve(ctx, listener)
+	}()
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"transaction_id":   "txn-456",
+		"merchant_id":      "merchant-123",
+		"amount":           99.99,
+		"currency":         "USD",
+		"card_number":      "1234567890123456",
+		"reason":           "fraud",
+		"transaction_date": "2023-01-15T10:30:00Z",
+	})
+
+	type result struct {
+		status int
+		err    error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Post("http://"+listener.Addr().String()+"/chargebacks", "application/json", bytes.NewReader(payload))
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		responses <- result{status: resp.StatusCode}
+	}()
+
+	// Act - shut down while the request is in flight
+	<-started
+	cancel()
+
+	select {
+	case err := <-serveDone:
+		t.Fatalf("Serve returned before the in-flight request completed: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+
+	// Assert
+	res := <-responses
+	if res.err != nil {
+		t.Fatalf("In-flight request failed: %v", res.err)
+	}
+	if res.status != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, res.status)
+	}
+
+	select {
+	case err := <-serveDone:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve did not return after shutdown")
+	}
+}
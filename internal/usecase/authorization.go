@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+)
+
+// Action is an operation on chargebacks subject to authorization
+type Action string
+
+const (
+	// ActionCreateChargeback opens a chargeback
+	ActionCreateChargeback Action = "chargeback:create"
+
+	// ActionReadChargeback reads or lists chargebacks
+	ActionReadChargeback Action = "chargeback:read"
+
+	// ActionApproveChargeback approves a pending chargeback
+	ActionApproveChargeback Action = "chargeback:approve"
+
+	// ActionRejectChargeback rejects a pending chargeback
+	ActionRejectChargeback Action = "chargeback:reject"
+)
+
+// Actions returns every action, in declaration order
+func Actions() []Action {
+	return []Action{
+		ActionCreateChargeback,
+		ActionReadChargeback,
+		ActionApproveChargeback,
+		ActionRejectChargeback,
+	}
+}
+
+// ErrForbidden is matched by every authorization denial
+var ErrForbidden = errors.New("forbidden")
+
+// ForbiddenError denies an action to the caller
+type ForbiddenError struct {
+	Action Action
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	return fmt.Sprintf("forbidden to %s: %s", e.Action, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrForbidden) match
+func (e *ForbiddenError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+// Authorizer decides whether the caller of ctx may perform an action on the
+// chargebacks of a merchant
+type Authorizer interface {
+	// Authorize returns nil if the action is allowed, or an error matching
+	// ErrForbidden
+	Authorize(ctx context.Context, action Action, merchantID string) error
+}
+
+// AllowAll allows every action. It stands in when authentication is
+// disabled.
+type AllowAll struct{}
+
+// Authorize allows the action
+func (AllowAll) Authorize(ctx context.Context, action Action, merchantID string) error {
+	return nil
+}
+
+// PolicyConfig lists what each role may do
+type PolicyConfig struct {
+	// Grants lists the actions each role may perform
+	Grants map[entity.Role][]Action
+
+	// Scopes names the scope each action needs. Scopes narrow roles: a
+	// caller presenting scopes, such as a token, must hold the scope of the
+	// action, while a caller without scopes is limited by its roles alone.
+	Scopes map[Action]string
+}
+
+// DefaultPolicyConfig lets merchant integrations create and read
+// chargebacks, acquirers push them, analysts read and review them, and
+// admins do everything
+func DefaultPolicyConfig() PolicyConfig {
+	return PolicyConfig{
+		Grants: map[entity.Role][]Action{
+			entity.RoleMerchant: {ActionCreateChargeback, ActionReadChargeback},
+			entity.RoleAcquirer: {ActionCreateChargeback},
+			entity.RoleAnalyst:  {ActionReadChargeback, ActionApproveChargeback, ActionRejectChargeback},
+			entity.RoleAdmin:    Actions(),
+		},
+		Scopes: map[Action]string{
+			ActionCreateChargeback:  "chargebacks:write",
+			ActionReadChargeback:    "chargebacks:read",
+			ActionApproveChargeback: "chargebacks:review",
+			ActionRejectChargeback:  "chargebacks:review",
+		},
+	}
+}
+
+// Policy authorizes the principal of the request context by role, scope and
+// merchant tenancy: callers belonging to a merchant, and every merchant
+// integration, may only act on that merchant's chargebacks. Admins act on
+// every merchant. Each decision is logged for audit, marked with
+// service.FieldAudit so it is neither filtered by level nor sampled.
+type Policy struct {
+	config PolicyConfig
+	logger service.Logger
+}
+
+// NewPolicy creates a policy logging its decisions to logger
+func NewPolicy(config PolicyConfig, logger service.Logger) *Policy {
+	return &Policy{config: config, logger: logger}
+}
+
+// Authorize allows or denies the action, logging the decision
+func (p *Policy) Authorize(ctx context.Context, action Action, merchantID string) error {
+	principal := requestctx.Principal(ctx)
+	reason := p.deny(principal, action, merchantID)
+
+	fields := map[string]interface{}{
+		"action":             string(action),
+		"target_merchant_id": merchantID,
+		"decision":           "allow",
+		service.FieldAudit:   true,
+	}
+	if principal != nil {
+		fields["principal_id"] = principal.ID
+		fields["auth_method"] = string(principal.Method)
+		fields["principal_merchant_id"] = principal.MerchantID
+		fields["roles"] = principal.Roles
+	}
+
+	if reason == "" {
+		p.logger.Info(ctx, "Authorization decision", fields)
+		return nil
+	}
+
+	fields["decision"] = "deny"
+	fields["reason"] = reason
+	p.logger.Warn(ctx, "Authorization decision", fields)
+	return &ForbiddenError{Action: action, Reason: reason}
+}
+
+// deny returns why the principal may not perform the action on the
+// merchant's chargebacks, or "" if it may
+func (p *Policy) deny(principal *entity.Principal, action Action, merchantID string) string {
+	if principal == nil {
+		return "no authenticated principal"
+	}
+
+	if !p.granted(principal, action) {
+		return fmt.Sprintf("no role of %s grants %s", principal.ID, action)
+	}
+
+	if scope := p.config.Scopes[action]; scope != "" && len(principal.Scopes) > 0 && !principal.HasScope(scope) {
+		return fmt.Sprintf("missing scope %s", scope)
+	}
+
+	if principal.HasRole(entity.RoleAdmin) {
+		return ""
+	}
+	if principal.HasRole(entity.RoleMerchant) && principal.MerchantID == "" {
+		return "merchant integration belongs to no merchant"
+	}
+	if principal.MerchantID != "" && principal.MerchantID != merchantID {
+		return fmt.Sprintf("%s belongs to merchant %s, not %q", principal.ID, principal.MerchantID, merchantID)
+	}
+	return ""
+}
+
+// granted reports whether a role of the principal grants the action
+func (p *Policy) granted(principal *entity.Principal, action Action) bool {
+	for _, role := range principal.Roles {
+		if slices.Contains(p.config.Grants[role], action) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+)
+
+// auditLogger keeps the logged entries
+type auditLogger struct {
+	mu      sync.Mutex
+	entries []service.LogEntry
+}
+
+func (l *auditLogger) record(level service.LogLevel, message string, fields []map[string]interface{}) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry := service.LogEntry{Level: level, Message: message, Fields: map[string]interface{}{}}
+	for _, f := range fields {
+		for k, v := range f {
+			entry.Fields[k] = v
+		}
+	}
+	l.entries = append(l.entries, entry)
+	return nil
+}
+
+func (l *auditLogger) Log(ctx context.Context, entry service.LogEntry) error {
+	return l.record(entry.Level, entry.Message, []map[string]interface{}{entry.Fields})
+}
+func (l *auditLogger) Debug(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(service.LogLevelDebug, message, fields)
+}
+func (l *auditLogger) Info(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(service.LogLevelInfo, message, fields)
+}
+func (l *auditLogger) Warn(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(service.LogLevelWarn, message, fields)
+}
+func (l *auditLogger) Error(ctx context.Context, message string, fields ...map[string]interface{}) error {
+	return l.record(service.LogLevelError, message, fields)
+}
+func (l *auditLogger) WithContext(ctx context.Context) service.Logger { return l }
+
+func TestPolicy_Authorize(t *testing.T) {
+	merchant := &entity.Principal{ID: "key-1", Method: entity.AuthMethodAPIKey, MerchantID: "merchant-1", Roles: []entity.Role{entity.RoleMerchant}}
+	analyst := &entity.Principal{ID: "user-1", Method: entity.AuthMethodJWT, Roles: []entity.Role{entity.RoleAnalyst}}
+	acquirer := &entity.Principal{ID: "acquirer-a", Method: entity.AuthMethodSignature, Roles: []entity.Role{entity.RoleAcquirer}}
+	admin := &entity.Principal{ID: "user-2", Method: entity.AuthMethodJWT, MerchantID: "merchant-1", Roles: []entity.Role{entity.RoleAdmin}}
+
+	tests := []struct {
+		name       string
+		principal  *entity.Principal
+		action     usecase.Action
+		merchantID string
+		wantReason string
+	}{
+		{name: "merchant creates for itself", principal: merchant, action: usecase.ActionCreateChargeback, merchantID: "merchant-1"},
+		{name: "merchant reads its own", principal: merchant, action: usecase.ActionReadChargeback, merchantID: "merchant-1"},
+		{name: "merchant creates for another merchant", principal: merchant, action: usecase.ActionCreateChargeback, merchantID: "merchant-2", wantReason: "belongs to merchant merchant-1"},
+		{name: "merchant reads another merchant", principal: merchant, action: usecase.ActionReadChargeback, merchantID: "merchant-2", wantReason: "belongs to merchant merchant-1"},
+		{name: "merchant approves", principal: merchant, action: usecase.ActionApproveChargeback, merchantID: "merchant-1", wantReason: "grants chargeback:approve"},
+		{
+			name:       "merchant without merchant ID",
+			principal:  &entity.Principal{ID: "key-2", Roles: []entity.Role{entity.RoleMerchant}},
+			action:     usecase.ActionCreateChargeback,
+			merchantID: "merchant-1",
+			wantReason: "belongs to no merchant",
+		},
+		{name: "analyst approves any merchant", principal: analyst, action: usecase.ActionApproveChargeback, merchantID: "merchant-2"},
+		{name: "analyst rejects", principal: analyst, action: usecase.ActionRejectChargeback, merchantID: "merchant-2"},
+		{name: "analyst creates", principal: analyst, action: usecase.ActionCreateChargeback, merchantID: "merchant-2", wantReason: "grants chargeback:create"},
+		{name: "acquirer creates for any merchant", principal: acquirer, action: usecase.ActionCreateChargeback, merchantID: "merchant-2"},
+		{name: "acquirer reads", principal: acquirer, action: usecase.ActionReadChargeback, merchantID: "merchant-2", wantReason: "grants chargeback:read"},
+		{name: "admin creates for another merchant", principal: admin, action: usecase.ActionCreateChargeback, merchantID: "merchant-2"},
+		{name: "admin approves", principal: admin, action: usecase.ActionApproveChargeback, merchantID: "merchant-2"},
+		{
+			name:       "scopes narrow roles",
+			principal:  &entity.Principal{ID: "user-3", Roles: []entity.Role{entity.RoleAdmin}, Scopes: []string{"chargebacks:read"}},
+			action:     usecase.ActionCreateChargeback,
+			merchantID: "merchant-1",
+			wantReason: "missing scope chargebacks:write",
+		},
+		{
+			name:       "matching scope",
+			principal:  &entity.Principal{ID: "user-4", Roles: []entity.Role{entity.RoleAnalyst}, Scopes: []string{"openid", "chargebacks:review"}},
+			action:     usecase.ActionRejectChargeback,
+			merchantID: "merchant-1",
+		},
+		{
+			name:       "no role",
+			principal:  &entity.Principal{ID: "user-5", MerchantID: "merchant-1"},
+			action:     usecase.ActionReadChargeback,
+			merchantID: "merchant-1",
+			wantReason: "grants chargeback:read",
+		},
+		{name: "anonymous", principal: nil, action: usecase.ActionReadChargeback, merchantID: "merchant-1", wantReason: "no authenticated principal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			logger := &auditLogger{}
+			policy := usecase.NewPolicy(usecase.DefaultPolicyConfig(), logger)
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = requestctx.WithPrincipal(ctx, tt.principal)
+			}
+
+			// Act
+			err := policy.Authorize(ctx, tt.action, tt.merchantID)
+
+			// Assert
+			if len(logger.entries) != 1 || logger.entries[0].Message != "Authorization decision" {
+				t.Fatalf("Expected one audit entry, got %+v", logger.entries)
+			}
+			entry := logger.entries[0]
+			if entry.Fields["action"] != string(tt.action) || entry.Fields["target_merchant_id"] != tt.merchantID {
+				t.Errorf("Expected the action and merchant in the audit entry, got %v", entry.Fields)
+			}
+			if !entry.IsAudit() {
+				t.Errorf("Expected the decision to be marked for audit, got %v", entry.Fields)
+			}
+			if tt.principal != nil && entry.Fields["principal_id"] != tt.principal.ID {
+				t.Errorf("Expected the principal in the audit entry, got %v", entry.Fields)
+			}
+
+			if tt.wantReason == "" {
+				if err != nil {
+					t.Errorf("Expected the action to be allowed, got %v", err)
+				}
+				if entry.Fields["decision"] != "allow" || entry.Level != service.LogLevelInfo {
+					t.Errorf("Expected an allow decision at info, got %s %v", entry.Level, entry.Fields)
+				}
+				return
+			}
+
+			if !errors.Is(err, usecase.ErrForbidden) {
+				t.Fatalf("Expected a forbidden error, got %v", err)
+			}
+			var forbidden *usecase.ForbiddenError
+			if !errors.As(err, &forbidden) || forbidden.Action != tt.action || !strings.Contains(forbidden.Reason, tt.wantReason) {
+				t.Errorf("Expected a denial of %s mentioning %q, got %v", tt.action, tt.wantReason, err)
+			}
+			if entry.Fields["decision"] != "deny" || entry.Level != service.LogLevelWarn || entry.Fields["reason"] != forbidden.Reason {
+				t.Errorf("Expected a deny decision at warn with the reason, got %s %v", entry.Level, entry.Fields)
+			}
+		})
+	}
+}
+
+func TestAllowAll_Authorize(t *testing.T) {
+	for _, action := range usecase.Actions() {
+		if err := (usecase.AllowAll{}).Authorize(context.Background(), action, "merchant-1"); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", action, err)
+		}
+	}
+}
@@ -2,11 +2,20 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/repository"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
 )
 
 // CreateChargebackRequest represents the input for creating a chargeback
@@ -15,54 +24,155 @@ type CreateChargebackRequest struct {
 	MerchantID      string                  `json:"merchant_id"`
 	Amount          float64                 `json:"amount"`
 	Currency        string                  `json:"currency"`
-	CardNumber      string                  `json:"card_number"`
+	CardNumber      string                  `json:"card_number" log:"redact"`
 	Reason          entity.ChargebackReason `json:"reason"`
 	Description     string                  `json:"description,omitempty"`
-	TransactionDate time.Time               `json:"transaction_date"`
+	TransactionDate time.Time               `json:"transaction_date" formats:"date-time,date"`
+}
+
+// TransactionDateLayouts lists the accepted transaction_date formats, from
+// full RFC3339 timestamps to date-only values interpreted as midnight UTC
+var TransactionDateLayouts = []string{
+	time.RFC3339Nano,
+	time.DateOnly,
+}
+
+// UnmarshalJSON decodes the request accepting every TransactionDateLayouts format
+func (r *CreateChargebackRequest) UnmarshalJSON(data []byte) error {
+	type plainRequest CreateChargebackRequest
+	aux := struct {
+		*plainRequest
+		TransactionDate *string `json:"transaction_date"`
+	}{
+		plainRequest: (*plainRequest)(r),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	if aux.TransactionDate == nil {
+		return nil
+	}
+
+	transactionDate, err := ParseTransactionDate(*aux.TransactionDate)
+	if err != nil {
+		return err
+	}
+	r.TransactionDate = transactionDate
+
+	return nil
+}
+
+// ParseTransactionDate parses a date in any of the TransactionDateLayouts
+func ParseTransactionDate(value string) (time.Time, error) {
+	for _, layout := range TransactionDateLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("transaction_date %q must be an RFC3339 timestamp or a YYYY-MM-DD date", value)
 }
 
 // CreateChargebackResponse represents the output of creating a chargeback
 type CreateChargebackResponse struct {
-	ID              string                  `json:"id"`
-	TransactionID   string                  `json:"transaction_id"`
-	MerchantID      string                  `json:"merchant_id"`
-	Amount          float64                 `json:"amount"`
-	Currency        string                  `json:"currency"`
-	CardNumber      string                  `json:"card_number"`
-	Reason          entity.ChargebackReason `json:"reason"`
-	Status          entity.ChargebackStatus `json:"status"`
-	Description     string                  `json:"description"`
-	TransactionDate time.Time               `json:"transaction_date"`
-	ChargebackDate  time.Time               `json:"chargeback_date"`
-	CreatedAt       time.Time               `json:"created_at"`
-	UpdatedAt       time.Time               `json:"updated_at"`
+	ID              string                   `json:"id"`
+	TransactionID   string                   `json:"transaction_id"`
+	MerchantID      string                   `json:"merchant_id"`
+	Amount          float64                  `json:"amount"`
+	Currency        string                   `json:"currency"`
+	CardNumber      string                   `json:"card_number"`
+	Reason          entity.ChargebackReason  `json:"reason"`
+	Status          entity.ChargebackStatus  `json:"status"`
+	Description     string                   `json:"description"`
+	TransactionDate time.Time                `json:"transaction_date"`
+	ChargebackDate  time.Time                `json:"chargeback_date"`
+	CreatedAt       time.Time                `json:"created_at"`
+	UpdatedAt       time.Time                `json:"updated_at"`
+	Channel         entity.ChargebackChannel `json:"channel"`
+	Source          string                   `json:"source,omitempty"`
 }
 
+// ErrChargebackExists is matched by the rejection of a transaction that
+// already has a chargeback, whichever merchant opened it
+var ErrChargebackExists = errors.New("chargeback already exists")
+
+// instrumentationName identifies the spans created by the use cases
+const instrumentationName = "github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
+
 // CreateChargebackUseCase handles the creation of chargebacks
 type CreateChargebackUseCase struct {
 	chargebackRepo repository.ChargebackRepository
+	metrics        service.Metrics
+	authorizer     Authorizer
+	quota          service.Quota
+	tracer         trace.Tracer
 }
 
-// NewCreateChargebackUseCase creates a new instance of CreateChargebackUseCase
-func NewCreateChargebackUseCase(chargebackRepo repository.ChargebackRepository) *CreateChargebackUseCase {
+// NewCreateChargebackUseCase creates a new instance of CreateChargebackUseCase.
+// Metrics are discarded if metrics is nil, every caller is allowed if
+// authorizer is nil, and merchants are not limited if quota is nil.
+func NewCreateChargebackUseCase(chargebackRepo repository.ChargebackRepository, metrics service.Metrics, authorizer Authorizer, quota service.Quota) *CreateChargebackUseCase {
+	if metrics == nil {
+		metrics = service.NopMetrics{}
+	}
+	if authorizer == nil {
+		authorizer = AllowAll{}
+	}
+
 	return &CreateChargebackUseCase{
 		chargebackRepo: chargebackRepo,
+		metrics:        metrics,
+		authorizer:     authorizer,
+		quota:          quota,
+		tracer:         otel.Tracer(instrumentationName),
 	}
 }
 
 // Execute creates a new chargeback following business rules
-func (uc *CreateChargebackUseCase) Execute(ctx context.Context, req CreateChargebackRequest) (*CreateChargebackResponse, error) {
-	// 1. Check if chargeback already exists for this transaction
+func (uc *CreateChargebackUseCase) Execute(ctx context.Context, req CreateChargebackRequest) (response *CreateChargebackResponse, err error) {
+	// The span describes the chargeback without the card number or the
+	// free-text description, and failures by step rather than by message
+	ctx, span := uc.tracer.Start(ctx, "CreateChargebackUseCase.Execute", trace.WithAttributes(
+		attribute.String("chargeback.transaction_id", req.TransactionID),
+		attribute.String("chargeback.merchant_id", req.MerchantID),
+		attribute.String("chargeback.reason", string(req.Reason)),
+		attribute.String("chargeback.currency", req.Currency),
+		attribute.Float64("chargeback.amount", req.Amount),
+	))
+	var failedStep string
+	defer func() {
+		if err != nil {
+			span.SetStatus(codes.Error, failedStep)
+		}
+		span.End()
+	}()
+
+	// 1. Check the caller may open chargebacks for the merchant, before
+	// revealing whether the transaction already has one
+	if err := uc.authorizer.Authorize(ctx, ActionCreateChargeback, req.MerchantID); err != nil {
+		failedStep = "forbidden"
+		return nil, err
+	}
+
+	// 2. Check if chargeback already exists for this transaction. The error
+	// names only the transaction, never the chargeback or its merchant.
 	existingChargeback, err := uc.chargebackRepo.FindByTransactionID(ctx, req.TransactionID)
 	if err != nil {
+		failedStep = "failed to check existing chargeback"
 		return nil, fmt.Errorf("failed to check existing chargeback: %w", err)
 	}
 
 	if existingChargeback != nil {
-		return nil, fmt.Errorf("chargeback already exists for transaction %s", req.TransactionID)
+		failedStep = "chargeback already exists"
+		uc.metrics.Add(ctx, service.MetricDuplicateRejections, 1, service.Labels{
+			service.LabelMerchantID: req.MerchantID,
+		})
+		return nil, fmt.Errorf("%w for transaction %s", ErrChargebackExists, req.TransactionID)
 	}
 
-	// 2. Create chargeback entity from request
+	// 3. Create chargeback entity from request
 	chargebackReq := entity.CreateChargebackRequest{
 		TransactionID:   req.TransactionID,
 		MerchantID:      req.MerchantID,
@@ -76,15 +186,65 @@ func (uc *CreateChargebackUseCase) Execute(ctx context.Context, req CreateCharge
 
 	chargeback, err := entity.NewChargeback(chargebackReq)
 	if err != nil {
+		failedStep = "invalid chargeback"
+		var validationErr *entity.ValidationError
+		if errors.As(err, &validationErr) {
+			for _, field := range validationErr.Fields {
+				uc.metrics.Add(ctx, service.MetricValidationFailures, 1, service.Labels{
+					"field":  field,
+					"source": "entity",
+				})
+			}
+		}
 		return nil, fmt.Errorf("failed to create chargeback entity: %w", err)
 	}
 
-	// 3. Save chargeback to repository
+	// Notifications pushed by acquirers record the source whose signature
+	// the request carried
+	if source := requestctx.CallbackSource(ctx); source != "" {
+		chargeback.ReceivedFrom(source)
+	}
+
+	// 4. Count the chargeback against the daily quota of the merchant, once
+	// it is known to be valid. A failed save still counts.
+	if uc.quota != nil {
+		result, err := uc.quota.Consume(ctx, req.MerchantID)
+		if err != nil {
+			failedStep = "failed to check daily quota"
+			return nil, fmt.Errorf("failed to check daily quota: %w", err)
+		}
+		if !result.Allowed {
+			failedStep = "daily quota exceeded"
+			uc.metrics.Add(ctx, service.MetricQuotaRejections, 1, service.Labels{
+				service.LabelMerchantID: req.MerchantID,
+			})
+			return nil, &QuotaExceededError{MerchantID: req.MerchantID, Result: result}
+		}
+	}
+
+	// 5. Save chargeback to repository
 	if err := uc.chargebackRepo.Save(ctx, chargeback); err != nil {
+		failedStep = "failed to save chargeback"
 		return nil, fmt.Errorf("failed to save chargeback: %w", err)
 	}
 
-	// 4. Return response
+	span.SetAttributes(
+		attribute.String("chargeback.id", chargeback.ID),
+		attribute.String("chargeback.status", string(chargeback.Status)),
+		attribute.String("chargeback.channel", string(chargeback.Channel)),
+	)
+
+	uc.metrics.Add(ctx, service.MetricChargebacksCreated, 1, service.Labels{
+		"reason":                string(chargeback.Reason),
+		"currency":              chargeback.Currency,
+		service.LabelMerchantID: chargeback.MerchantID,
+	})
+	uc.metrics.Observe(ctx, service.MetricChargebackAmount, chargeback.Amount, service.Labels{
+		"currency": chargeback.Currency,
+		"reason":   string(chargeback.Reason),
+	})
+
+	// 6. Return response
 	return &CreateChargebackResponse{
 		ID:              chargeback.ID,
 		TransactionID:   chargeback.TransactionID,
@@ -99,5 +259,7 @@ func (uc *CreateChargebackUseCase) Execute(ctx context.Context, req CreateCharge
 		ChargebackDate:  chargeback.ChargebackDate,
 		CreatedAt:       chargeback.CreatedAt,
 		UpdatedAt:       chargeback.UpdatedAt,
+		Channel:         chargeback.Channel,
+		Source:          chargeback.Source,
 	}, nil
 }
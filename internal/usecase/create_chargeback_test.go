@@ -2,11 +2,23 @@ package usecase_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
 	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/entity"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/metrics"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/infra/ratelimit"
+	"github.com/DiegoSantos90/chargeback-lambda/internal/requestctx"
 	"github.com/DiegoSantos90/chargeback-lambda/internal/usecase"
 )
 
@@ -85,13 +97,16 @@ func TestCreateChargebackUseCase_Execute_Success(t *testing.T) {
 			return nil, nil // No existing chargeback found
 		},
 		SaveFunc: func(ctx context.Context, chargeback *entity.Chargeback) error {
+			if chargeback.ID == "" {
+				t.Error("Expected the chargeback to have its ID before it is saved")
+			}
 			// Simulate successful save
 			chargeback.ID = "cb_12345"
 			return nil
 		},
 	}
 
-	useCase := usecase.NewCreateChargebackUseCase(mockRepo)
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
 	ctx := context.Background()
 
 	request := usecase.CreateChargebackRequest{
@@ -134,11 +149,65 @@ func TestCreateChargebackUseCase_Execute_Success(t *testing.T) {
 	}
 }
 
+func TestCreateChargebackUseCase_Execute_Callback(t *testing.T) {
+	// Arrange: an acquirer pushed a signed dispute notification
+	var saved *entity.Chargeback
+	mockRepo := &MockChargebackRepository{
+		FindByTransactionIDFunc: func(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+			return nil, nil
+		},
+		SaveFunc: func(ctx context.Context, chargeback *entity.Chargeback) error {
+			saved = chargeback
+			return nil
+		},
+	}
+	policy := usecase.NewPolicy(usecase.DefaultPolicyConfig(), &auditLogger{})
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, policy, nil)
+
+	acquirer := &entity.Principal{ID: "acquirer-a", Method: entity.AuthMethodSignature, Roles: []entity.Role{entity.RoleAcquirer}}
+	ctx := requestctx.WithCallbackSource(requestctx.WithPrincipal(context.Background(), acquirer), "acquirer-a")
+
+	request := usecase.CreateChargebackRequest{
+		TransactionID:   "tx-12345",
+		MerchantID:      "merchant-789",
+		Amount:          150.75,
+		Currency:        "USD",
+		CardNumber:      "4111111111111111",
+		Reason:          entity.ReasonConsumerDispute,
+		TransactionDate: time.Now().AddDate(0, 0, -5),
+	}
+
+	// Act
+	response, err := useCase.Execute(ctx, request)
+
+	// Assert
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if saved.Channel != entity.ChannelAcquirer || saved.Source != "acquirer-a" {
+		t.Errorf("Expected the acquirer channel to be saved, got %s %q", saved.Channel, saved.Source)
+	}
+	if response.Channel != entity.ChannelAcquirer || response.Source != "acquirer-a" {
+		t.Errorf("Expected the acquirer channel in the response, got %s %q", response.Channel, response.Source)
+	}
+
+	// Requests without a verified source come through the API
+	request.TransactionID = "tx-67890"
+	response, err = usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil).Execute(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Channel != entity.ChannelAPI || response.Source != "" {
+		t.Errorf("Expected the API channel, got %s %q", response.Channel, response.Source)
+	}
+}
+
 func TestCreateChargebackUseCase_Execute_DuplicateTransaction(t *testing.T) {
 	// Arrange
 	existingChargeback := &entity.Chargeback{
 		ID:            "cb_existing",
 		TransactionID: "tx-12345",
+		MerchantID:    "merchant-789",
 		Status:        entity.StatusPending,
 	}
 
@@ -148,7 +217,7 @@ func TestCreateChargebackUseCase_Execute_DuplicateTransaction(t *testing.T) {
 		},
 	}
 
-	useCase := usecase.NewCreateChargebackUseCase(mockRepo)
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
 	ctx := context.Background()
 
 	request := usecase.CreateChargebackRequest{
@@ -179,10 +248,164 @@ func TestCreateChargebackUseCase_Execute_DuplicateTransaction(t *testing.T) {
 	}
 }
 
+func TestCreateChargebackUseCase_Execute_TransactionOfAnotherMerchant(t *testing.T) {
+	// Arrange
+	saves := 0
+	mockRepo := &MockChargebackRepository{
+		FindByTransactionIDFunc: func(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+			return &entity.Chargeback{ID: "cb_other", TransactionID: transactionID, MerchantID: "merchant-other", Amount: 99.99}, nil
+		},
+		SaveFunc: func(ctx context.Context, chargeback *entity.Chargeback) error {
+			saves++
+			return nil
+		},
+	}
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
+
+	request := usecase.CreateChargebackRequest{
+		TransactionID:   "tx-12345",
+		MerchantID:      "merchant-789",
+		Amount:          150.75,
+		Currency:        "USD",
+		CardNumber:      "4111111111111111",
+		Reason:          entity.ReasonFraud,
+		TransactionDate: time.Now().AddDate(0, 0, -5),
+	}
+
+	// Act
+	response, err := useCase.Execute(context.Background(), request)
+
+	// Assert: a transaction has one chargeback whichever merchant opened
+	// it, and the rejection reveals nothing about the existing one
+	if !errors.Is(err, usecase.ErrChargebackExists) || response != nil || saves != 0 {
+		t.Fatalf("Expected the duplicate to be rejected, got %+v, %v", response, err)
+	}
+	for _, detail := range []string{"cb_other", "merchant-other", "99.99"} {
+		if strings.Contains(err.Error(), detail) {
+			t.Errorf("Expected the error not to reveal %q, got %q", detail, err.Error())
+		}
+	}
+}
+
+func TestCreateChargebackUseCase_Execute_Forbidden(t *testing.T) {
+	// Arrange
+	var lookups, saves int
+	mockRepo := &MockChargebackRepository{
+		FindByTransactionIDFunc: func(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+			lookups++
+			return nil, nil
+		},
+		SaveFunc: func(ctx context.Context, chargeback *entity.Chargeback) error {
+			saves++
+			return nil
+		},
+	}
+
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, usecase.NewPolicy(usecase.DefaultPolicyConfig(), &auditLogger{}), nil)
+	ctx := requestctx.WithPrincipal(context.Background(), &entity.Principal{
+		ID:         "key-1",
+		Method:     entity.AuthMethodAPIKey,
+		MerchantID: "merchant-123",
+		Roles:      []entity.Role{entity.RoleMerchant},
+	})
+
+	request := usecase.CreateChargebackRequest{
+		TransactionID:   "tx-12345",
+		MerchantID:      "merchant-789",
+		Amount:          150.75,
+		Currency:        "USD",
+		CardNumber:      "4111111111111111",
+		Reason:          entity.ReasonFraud,
+		TransactionDate: time.Now().AddDate(0, 0, -5),
+	}
+
+	// Act
+	response, err := useCase.Execute(ctx, request)
+
+	// Assert
+	if !errors.Is(err, usecase.ErrForbidden) {
+		t.Fatalf("Expected a forbidden error, got %v", err)
+	}
+	if response != nil {
+		t.Error("Expected nil response when forbidden")
+	}
+	if lookups != 0 || saves != 0 {
+		t.Errorf("Expected the repository to be untouched, got %d lookups and %d saves", lookups, saves)
+	}
+
+	// The same merchant may create its own chargeback
+	request.MerchantID = "merchant-123"
+	if _, err := useCase.Execute(ctx, request); err != nil {
+		t.Errorf("Expected the merchant's own chargeback to be created, got %v", err)
+	}
+	if saves != 1 {
+		t.Errorf("Expected 1 save, got %d", saves)
+	}
+}
+
+func TestCreateChargebackUseCase_Execute_QuotaExceeded(t *testing.T) {
+	// Arrange: merchant-789 may open one chargeback a day
+	var saves int
+	mockRepo := &MockChargebackRepository{
+		FindByTransactionIDFunc: func(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+			return nil, nil
+		},
+		SaveFunc: func(ctx context.Context, chargeback *entity.Chargeback) error {
+			saves++
+			return nil
+		},
+	}
+	memory := metrics.NewMemory()
+	quota := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Config{
+		MerchantQuotas: map[string]int64{"merchant-789": 1},
+	})
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, memory, nil, quota)
+	ctx := context.Background()
+
+	request := usecase.CreateChargebackRequest{
+		TransactionID:   "tx-12345",
+		MerchantID:      "merchant-789",
+		Amount:          150.75,
+		Currency:        "USD",
+		CardNumber:      "4111111111111111",
+		Reason:          entity.ReasonFraud,
+		TransactionDate: time.Now().AddDate(0, 0, -5),
+	}
+
+	// Act
+	_, first := useCase.Execute(ctx, request)
+	request.TransactionID = "tx-67890"
+	response, second := useCase.Execute(ctx, request)
+
+	// Assert
+	if first != nil {
+		t.Fatalf("Expected the first chargeback to be created, got %v", first)
+	}
+	if !errors.Is(second, usecase.ErrQuotaExceeded) || response != nil {
+		t.Fatalf("Expected the quota to be exceeded, got %v", second)
+	}
+	var exceeded *usecase.QuotaExceededError
+	if !errors.As(second, &exceeded) || exceeded.MerchantID != "merchant-789" || exceeded.Result.Limit != 1 || exceeded.Result.RetryAfter <= 0 {
+		t.Errorf("Expected the quota of merchant-789 and when it restarts, got %+v", exceeded)
+	}
+	if saves != 1 {
+		t.Errorf("Expected 1 save, got %d", saves)
+	}
+	if got := memory.Value("chargeback_quota_rejections_total", service.Labels{"merchant_id": "merchant-789"}); got != 1 {
+		t.Errorf("Expected 1 quota rejection, got %v", got)
+	}
+
+	// Merchants without a quota are unlimited
+	request.MerchantID = "merchant-other"
+	if _, err := useCase.Execute(ctx, request); err != nil {
+		t.Errorf("Expected merchants without a quota to be unlimited, got %v", err)
+	}
+}
+
 func TestCreateChargebackUseCase_Execute_InvalidRequest(t *testing.T) {
 	// Arrange
 	mockRepo := &MockChargebackRepository{}
-	useCase := usecase.NewCreateChargebackUseCase(mockRepo)
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
 	ctx := context.Background()
 
 	// Test cases for invalid requests
@@ -250,7 +473,7 @@ func TestCreateChargebackUseCase_Execute_RepositoryError(t *testing.T) {
 		},
 	}
 
-	useCase := usecase.NewCreateChargebackUseCase(mockRepo)
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
 	ctx := context.Background()
 
 	request := usecase.CreateChargebackRequest{
@@ -287,7 +510,7 @@ func TestCreateChargebackUseCase_Execute_SaveError(t *testing.T) {
 		},
 	}
 
-	useCase := usecase.NewCreateChargebackUseCase(mockRepo)
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
 	ctx := context.Background()
 
 	request := usecase.CreateChargebackRequest{
@@ -312,3 +535,180 @@ func TestCreateChargebackUseCase_Execute_SaveError(t *testing.T) {
 		t.Error("Expected nil response when save error occurs")
 	}
 }
+
+func TestCreateChargebackUseCase_Execute_Metrics(t *testing.T) {
+	// Arrange
+	memory := metrics.NewMemory()
+	var existing *entity.Chargeback
+	mockRepo := &MockChargebackRepository{
+		FindByTransactionIDFunc: func(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+			return existing, nil
+		},
+	}
+	useCase := usecase.NewCreateChargebackUseCase(mockRepo, memory, nil, nil)
+	ctx := context.Background()
+
+	request := usecase.CreateChargebackRequest{
+		TransactionID:   "tx-12345",
+		MerchantID:      "merchant-789",
+		Amount:          150.75,
+		Currency:        "USD",
+		CardNumber:      "4111111111111111",
+		Reason:          entity.ReasonFraud,
+		TransactionDate: time.Now().AddDate(0, 0, -5),
+	}
+
+	// Act
+	useCase.Execute(ctx, request)
+
+	invalid := request
+	invalid.Amount = 0
+	invalid.Currency = ""
+	useCase.Execute(ctx, invalid)
+
+	existing = &entity.Chargeback{ID: "cb_existing", MerchantID: "merchant-789"}
+	useCase.Execute(ctx, request)
+
+	// Assert
+	created := service.Labels{"reason": "fraud", "currency": "USD", "merchant_id": "merchant-789"}
+	if got := memory.Value("chargebacks_created_total", created); got != 1 {
+		t.Errorf("Expected 1 created chargeback, got %v", got)
+	}
+	if got := memory.Observations("chargeback_amount", service.Labels{"currency": "USD", "reason": "fraud"}); len(got) != 1 || got[0] != 150.75 {
+		t.Errorf("Expected the amount to be observed, got %v", got)
+	}
+	for _, field := range []string{"amount", "currency"} {
+		if got := memory.Value("chargeback_validation_failures_total", service.Labels{"field": field, "source": "entity"}); got != 1 {
+			t.Errorf("Expected 1 validation failure of %s, got %v", field, got)
+		}
+	}
+	if got := memory.Value("chargeback_duplicates_total", service.Labels{"merchant_id": "merchant-789"}); got != 1 {
+		t.Errorf("Expected 1 duplicate rejection, got %v", got)
+	}
+}
+
+func TestCreateChargebackUseCase_Execute_Tracing(t *testing.T) {
+	tests := []struct {
+		name       string
+		existing   *entity.Chargeback
+		wantStatus codes.Code
+		wantDesc   string
+	}{
+		{name: "created", wantStatus: codes.Unset},
+		{name: "duplicate", existing: &entity.Chargeback{ID: "cb_existing", MerchantID: "merchant-789"}, wantStatus: codes.Error, wantDesc: "chargeback already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			previous := otel.GetTracerProvider()
+			spans := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)))
+			t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+			mockRepo := &MockChargebackRepository{
+				FindByTransactionIDFunc: func(ctx context.Context, transactionID string) (*entity.Chargeback, error) {
+					return tt.existing, nil
+				},
+				SaveFunc: func(ctx context.Context, chargeback *entity.Chargeback) error {
+					if !trace.SpanContextFromContext(ctx).IsValid() {
+						t.Error("Expected the repository to be called within the use case span")
+					}
+					chargeback.ID = "cb_12345"
+					return nil
+				},
+			}
+			useCase := usecase.NewCreateChargebackUseCase(mockRepo, nil, nil, nil)
+
+			request := usecase.CreateChargebackRequest{
+				TransactionID:   "tx-12345",
+				MerchantID:      "merchant-789",
+				Amount:          150.75,
+				Currency:        "USD",
+				CardNumber:      "4111111111111111",
+				Reason:          entity.ReasonFraud,
+				Description:     "Suspicious transaction",
+				TransactionDate: time.Now().AddDate(0, 0, -5),
+			}
+
+			// Act
+			useCase.Execute(context.Background(), request)
+
+			// Assert
+			ended := spans.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("Expected 1 span, got %d", len(ended))
+			}
+			span := ended[0]
+
+			if span.Name() != "CreateChargebackUseCase.Execute" {
+				t.Errorf("Unexpected span name %s", span.Name())
+			}
+			if span.Status().Code != tt.wantStatus || span.Status().Description != tt.wantDesc {
+				t.Errorf("Expected status %v %q, got %v", tt.wantStatus, tt.wantDesc, span.Status())
+			}
+
+			attrs := make(map[string]string)
+			for _, attr := range span.Attributes() {
+				attrs[string(attr.Key)] = attr.Value.Emit()
+			}
+			if attrs["chargeback.merchant_id"] != "merchant-789" {
+				t.Errorf("Expected chargeback.merchant_id, got %v", attrs)
+			}
+			for key, value := range attrs {
+				if strings.Contains(value, "4111") || strings.Contains(value, "Suspicious") {
+					t.Errorf("Attribute %s leaks %q", key, value)
+				}
+			}
+			if tt.existing == nil && attrs["chargeback.id"] != "cb_12345" {
+				t.Errorf("Expected chargeback.id 'cb_12345', got '%s'", attrs["chargeback.id"])
+			}
+		})
+	}
+}
+
+func TestCreateChargebackRequest_UnmarshalJSON_TransactionDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		date     string
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "RFC3339", date: "2024-01-15T10:30:00Z", expected: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{name: "RFC3339 with offset", date: "2024-01-15T07:30:00-03:00", expected: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{name: "RFC3339 with fraction", date: "2024-01-15T10:30:00.5Z", expected: time.Date(2024, 1, 15, 10, 30, 0, 500000000, time.UTC)},
+		{name: "date only", date: "2024-01-15", expected: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "unsupported format", date: "15/01/2024", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			payload := []byte(`{"transaction_id":"tx-1","transaction_date":"` + tt.date + `"}`)
+
+			// Act
+			var req usecase.CreateChargebackRequest
+			err := json.Unmarshal(payload, &req)
+
+			// Assert
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			if !req.TransactionDate.Equal(tt.expected) {
+				t.Errorf("Expected transaction date %v, got %v", tt.expected, req.TransactionDate)
+			}
+
+			if req.TransactionID != "tx-1" {
+				t.Errorf("Expected TransactionID tx-1, got %s", req.TransactionID)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DiegoSantos90/chargeback-lambda/internal/domain/service"
+)
+
+// ErrQuotaExceeded is matched by every daily quota rejection
+var ErrQuotaExceeded = errors.New("daily quota exceeded")
+
+// QuotaExceededError rejects a chargeback of a merchant that used up its
+// daily quota
+type QuotaExceededError struct {
+	MerchantID string
+
+	// Result describes the quota, including when it restarts
+	Result service.RateLimitResult
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("merchant %s exceeded its daily quota of %d chargebacks", e.MerchantID, e.Result.Limit)
+}
+
+// Is makes errors.Is(err, ErrQuotaExceeded) match
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
@@ -176,6 +176,10 @@ echo ""
 echo "   # Deve retornar erro 400 com mensagem de validação"
 echo ""
 
+echo -e "${YELLOW}16. CONTRATO DA API (OpenAPI 3.1)${NC}"
+echo "   curl http://localhost:3000/openapi.json | jq ."
+echo ""
+
 echo -e "${GREEN}=================================================="
 echo "Para executar os comandos, copie e cole no terminal!"
 echo "==================================================${NC}"